@@ -0,0 +1,219 @@
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/echocat/lingress/kubernetes"
+	"github.com/echocat/lingress/leader"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"github.com/echocat/slf4g"
+	xacme "golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	clientkubernetes "k8s.io/client-go/kubernetes"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const (
+	// ChallengePathPrefix is the path prefix of HTTP-01 challenges.
+	ChallengePathPrefix = "/.well-known/acme-challenge/"
+)
+
+var (
+	ErrUnknownHost = errors.New("host is not configured by any ingress")
+	ErrNotLeader   = errors.New("certificates are only ordered by the leader")
+)
+
+// Acme issues certificates on demand for all hosts which are explicitly
+// configured by ingress configurations. It answers HTTP-01 challenges with
+// ServeHTTP and TLS-ALPN-01 challenges with GetCertificate.
+//
+// Only the leader orders and renews certificates; all other instances serve
+// the certificates and answer the challenges the leader has stored inside
+// the shared secrets.
+type Acme struct {
+	settings *settings.Settings
+
+	Hosts          rules.HostRepository
+	LeaderElection *leader.Election
+	Manager        *autocert.Manager
+	Logger         log.Logger
+
+	challengeHandler http.Handler
+
+	issued      map[string]issuedCertificate
+	issuedMutex sync.Mutex
+}
+
+func New(s *settings.Settings, hosts rules.HostRepository, leaderElection *leader.Election, logger log.Logger) (*Acme, error) {
+	return &Acme{
+		settings:       s,
+		Hosts:          hosts,
+		LeaderElection: leaderElection,
+		Logger:         logger,
+	}, nil
+}
+
+func (this *Acme) IsEnabled() bool {
+	return this.settings.Acme.Enabled.Get() && this.Manager != nil
+}
+
+func (this *Acme) Init(support.Channel) error {
+	s := this.settings.Acme
+	if !s.Enabled.Get() {
+		return nil
+	}
+	fail := func(err error) error {
+		return fmt.Errorf("cannot initialize ACME: %w", err)
+	}
+	if !s.AcceptTermsOfService.Get() {
+		return fail(errors.New("acme.acceptTermsOfService has to be set to true"))
+	}
+
+	environment, err := kubernetes.NewEnvironment(this.settings)
+	if err != nil {
+		return fail(err)
+	}
+	client, err := environment.NewClient()
+	if err != nil {
+		return fail(err)
+	}
+	namespace := this.settings.Kubernetes.Namespace
+	if namespace == "" {
+		return fail(errors.New("the namespace of lingress is unknown; kubernetes.namespace has to be set"))
+	}
+
+	transport, err := this.createTransport()
+	if err != nil {
+		return fail(err)
+	}
+	this.init(client, namespace, transport)
+
+	this.Logger.
+		With("directoryUrl", s.DirectoryUrl).
+		With("namespace", namespace).
+		Info("ACME certificate issuing enabled.")
+	return nil
+}
+
+func (this *Acme) init(client clientkubernetes.Interface, namespace string, transport http.RoundTripper) {
+	s := this.settings.Acme
+	this.Manager = &autocert.Manager{
+		Prompt:      autocert.AcceptTOS,
+		Email:       s.Email,
+		RenewBefore: s.RenewBefore,
+		HostPolicy:  this.hostPolicy,
+		Cache: &leaderOnlyCache{
+			Cache: &SecretCache{
+				Client:    client,
+				Namespace: namespace,
+				Prefix:    s.SecretPrefix,
+				Logger:    this.Logger,
+			},
+			check: this.checkLeader,
+		},
+		Client: &xacme.Client{
+			DirectoryURL: s.DirectoryUrl,
+			HTTPClient: &http.Client{
+				Transport: &leaderOnlyTransport{
+					RoundTripper: transport,
+					check:        this.checkLeader,
+				},
+			},
+		},
+	}
+	this.challengeHandler = this.Manager.HTTPHandler(nil)
+}
+
+func (this *Acme) createTransport() (http.RoundTripper, error) {
+	file := this.settings.Acme.DirectoryCaFile
+	if file == "" {
+		return http.DefaultTransport, nil
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("%s does not contain any PEM encoded certificate", file)
+	}
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{
+			RootCAs: pool,
+		},
+	}, nil
+}
+
+func (this *Acme) checkLeader() error {
+	if le := this.LeaderElection; le != nil && !le.IsLeader() {
+		return ErrNotLeader
+	}
+	return nil
+}
+
+func (this *Acme) hostPolicy(_ context.Context, plainHost string) error {
+	var host value.Fqdn
+	if err := host.Set(plainHost); err != nil {
+		return err
+	}
+	ok, err := this.Hosts.HasHost(host)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownHost, plainHost)
+	}
+	return nil
+}
+
+// IsChallenge returns true if the given request is an HTTP-01 challenge
+// which should be handled by ServeHTTP.
+func (this *Acme) IsChallenge(req *http.Request) bool {
+	return this.IsEnabled() && strings.HasPrefix(req.URL.Path, ChallengePathPrefix)
+}
+
+func (this *Acme) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	this.challengeHandler.ServeHTTP(resp, req)
+}
+
+// IsTlsAlpnChallenge returns true if the given handshake is a TLS-ALPN-01
+// challenge which has to be handled by GetCertificate.
+func (this *Acme) IsTlsAlpnChallenge(info *tls.ClientHelloInfo) bool {
+	return this.IsEnabled() && slices.Contains(info.SupportedProtos, xacme.ALPNProto)
+}
+
+// GetCertificate returns an already issued certificate for the requested
+// host or issues a new one (only if this instance is the leader). Hosts which
+// are not configured by any ingress are rejected before any certificate is
+// looked up; otherwise every client could cause requests to the cluster by
+// choosing arbitrary server names.
+func (this *Acme) GetCertificate(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if !this.IsEnabled() {
+		return nil, nil
+	}
+	if err := this.hostPolicy(info.Context(), strings.TrimSuffix(info.ServerName, ".")); err != nil {
+		return nil, err
+	}
+	if !this.IsTlsAlpnChallenge(info) && this.checkLeader() != nil {
+		return this.issuedCertificate(info)
+	}
+	return this.Manager.GetCertificate(info)
+}
+
+// ApplyToTlsConfig enables the TLS-ALPN-01 challenges for the given config.
+func (this *Acme) ApplyToTlsConfig(config *tls.Config) {
+	if this.IsEnabled() && !slices.Contains(config.NextProtos, xacme.ALPNProto) {
+		config.NextProtos = append(config.NextProtos, xacme.ALPNProto)
+	}
+}
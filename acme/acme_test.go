@@ -0,0 +1,417 @@
+package acme
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/echocat/lingress/leader"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/value"
+	"github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	xacme "golang.org/x/crypto/acme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Acme_GetCertificate(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := fake.NewClientset()
+	secrets := func() []string {
+		list, err := client.CoreV1().Secrets("lingress").List(context.Background(), metav1.ListOptions{})
+		g.Expect(err).To(BeNil())
+		var result []string
+		for _, secret := range list.Items {
+			result = append(result, secret.Annotations[secretKeyAnnotation])
+		}
+		return result
+	}
+
+	// Each instance of lingress is a replica; only the first one is the leader.
+	newReplica := func(ca *fakeCa, leading bool) *Acme {
+		s := settings.MustNew()
+		s.Acme.Enabled = value.True()
+		s.Acme.DirectoryUrl = ca.server.URL + "/directory"
+		s.LeaderElection.Enabled = value.False()
+		le, err := leader.New(&s, nil, log.GetRootLogger())
+		g.Expect(err).To(BeNil())
+		if leading {
+			g.Expect(le.Init(nil)).To(Succeed())
+		}
+		instance, err := New(&s, allHosts{}, le, log.GetRootLogger())
+		g.Expect(err).To(BeNil())
+		instance.init(client, "lingress", http.DefaultTransport)
+		return instance
+	}
+
+	var follower *Acme
+	ca := newFakeCa(t, func(domain, keyAuthorization string) error {
+		// The challenge is answered by another replica than the one which has ordered the certificate.
+		cert, err := follower.GetCertificate(&tls.ClientHelloInfo{
+			ServerName:      domain,
+			SupportedProtos: []string{xacme.ALPNProto},
+		})
+		if err != nil {
+			return err
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256([]byte(keyAuthorization))
+		expected, err := asn1.Marshal(sum[:])
+		if err != nil {
+			return err
+		}
+		for _, ext := range leaf.Extensions {
+			if ext.Id.Equal(asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}) && bytes.Equal(ext.Value, expected) {
+				return nil
+			}
+		}
+		return errors.New("challenge certificate does not contain the expected acmeIdentifier")
+	})
+	follower = newReplica(ca, false)
+	leading := newReplica(ca, true)
+
+	hello := &tls.ClientHelloInfo{
+		ServerName:        "example.org",
+		CipherSuites:      []uint16{tls.TLS_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		SupportedCurves:   []tls.CurveID{tls.CurveP256},
+		SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		SupportedVersions: []uint16{tls.VersionTLS13},
+	}
+
+	// Not the leader: neither orders nor writes anything.
+	_, err := follower.GetCertificate(hello)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(secrets()).To(BeEmpty())
+	orders, _ := ca.counts()
+	g.Expect(orders).To(Equal(0))
+
+	issued, err := leading.GetCertificate(hello)
+	g.Expect(err).To(BeNil())
+	orders, validations := ca.counts()
+	g.Expect(orders).To(Equal(1))
+	g.Expect(validations).To(Equal(1))
+	g.Expect(issued.Leaf.DNSNames).To(ConsistOf("example.org"))
+	g.Expect(secrets()).To(ContainElements("acme_account+key", "example.org"))
+
+	// Not the leader: serves the certificate issued by the leader (as soon
+	// as it has forgotten that there was none).
+	follower.issued = nil
+	served, err := follower.GetCertificate(hello)
+	g.Expect(err).To(BeNil())
+	g.Expect(served.Certificate).To(Equal(issued.Certificate))
+	orders, _ = ca.counts()
+	g.Expect(orders).To(Equal(1))
+}
+
+func Test_Acme_GetCertificate_ignoresUnknownHosts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	client := fake.NewClientset()
+	newReplica := func(leading bool) *Acme {
+		s := settings.MustNew()
+		s.Acme.Enabled = value.True()
+		s.Acme.DirectoryUrl = "http://127.0.0.1:1/directory"
+		s.LeaderElection.Enabled = value.False()
+		le, err := leader.New(&s, nil, log.GetRootLogger())
+		g.Expect(err).To(BeNil())
+		if leading {
+			g.Expect(le.Init(nil)).To(Succeed())
+		}
+		instance, err := New(&s, someHosts{"example.org"}, le, log.GetRootLogger())
+		g.Expect(err).To(BeNil())
+		instance.init(client, "lingress", http.DefaultTransport)
+		return instance
+	}
+
+	for _, instance := range []*Acme{newReplica(false), newReplica(true)} {
+		for _, hello := range []*tls.ClientHelloInfo{
+			{ServerName: "random.example.org"},
+			{ServerName: "random.example.org", SupportedProtos: []string{xacme.ALPNProto}},
+		} {
+			_, err := instance.GetCertificate(hello)
+			g.Expect(err).To(MatchError(ErrUnknownHost))
+		}
+		g.Expect(instance.issued).To(BeEmpty())
+	}
+	g.Expect(client.Actions()).To(BeEmpty())
+}
+
+func Test_Acme_loadIssuedCertificate_forgetsExpiredEntries(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	s.Acme.Enabled = value.True()
+	instance, err := New(&s, allHosts{}, nil, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	instance.init(fake.NewClientset(), "lingress", http.DefaultTransport)
+	instance.issued = map[string]issuedCertificate{
+		"expired.example.org": {loadedAt: time.Now().Add(-issuedCertificateTtl)},
+		"current.example.org": {loadedAt: time.Now()},
+	}
+
+	certificate, err := instance.loadIssuedCertificate("other.example.org")
+	g.Expect(err).To(BeNil())
+	g.Expect(certificate).To(BeNil())
+	g.Expect(instance.issued).To(HaveKey("current.example.org"))
+	g.Expect(instance.issued).To(HaveKey("other.example.org"))
+	g.Expect(instance.issued).NotTo(HaveKey("expired.example.org"))
+}
+
+type someHosts []value.Fqdn
+
+func (this someHosts) HasHost(candidate value.Fqdn) (bool, error) {
+	return slices.Contains(this, candidate), nil
+}
+
+type allHosts struct{}
+
+func (this allHosts) HasHost(value.Fqdn) (bool, error) {
+	return true, nil
+}
+
+// fakeCa is a minimal ACME server (RFC 8555) which supports only TLS-ALPN-01
+// challenges. It does not verify any signature but the key authorization of
+// the challenges using the given validate function.
+type fakeCa struct {
+	t        *testing.T
+	server   *httptest.Server
+	validate func(domain, keyAuthorization string) error
+
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+
+	mutex       sync.Mutex
+	nonce       int
+	thumbprint  string
+	domain      string
+	status      string
+	certificate []byte
+	orders      int
+	validations int
+}
+
+func newFakeCa(t *testing.T, validate func(domain, keyAuthorization string) error) *fakeCa {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := &fakeCa{
+		t:        t,
+		validate: validate,
+		key:      key,
+		cert:     cert,
+	}
+	result.server = httptest.NewServer(http.HandlerFunc(result.serveHTTP))
+	t.Cleanup(result.server.Close)
+	return result
+}
+
+func (this *fakeCa) serveHTTP(resp http.ResponseWriter, req *http.Request) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.nonce++
+	resp.Header().Set("Replay-Nonce", fmt.Sprint("nonce-", this.nonce))
+	url := this.server.URL
+
+	switch req.URL.Path {
+	case "/directory":
+		this.respond(resp, http.StatusOK, map[string]string{
+			"newNonce":   url + "/nonce",
+			"newAccount": url + "/account",
+			"newOrder":   url + "/order",
+		})
+	case "/nonce":
+		resp.WriteHeader(http.StatusOK)
+	case "/account":
+		var protected struct {
+			Jwk map[string]string `json:"jwk"`
+		}
+		this.parse(req, &protected, nil)
+		jwk := protected.Jwk
+		sum := sha256.Sum256([]byte(fmt.Sprintf(`{"crv":"%s","kty":"%s","x":"%s","y":"%s"}`, jwk["crv"], jwk["kty"], jwk["x"], jwk["y"])))
+		this.thumbprint = base64.RawURLEncoding.EncodeToString(sum[:])
+		resp.Header().Set("Location", url+"/account/1")
+		this.respond(resp, http.StatusCreated, map[string]string{"status": "valid"})
+	case "/order":
+		var payload struct {
+			Identifiers []struct{ Value string } `json:"identifiers"`
+		}
+		this.parse(req, nil, &payload)
+		this.orders++
+		this.domain = payload.Identifiers[0].Value
+		this.status = "pending"
+		resp.Header().Set("Location", url+"/order/1")
+		this.respond(resp, http.StatusCreated, this.order())
+	case "/order/1", "/finalize/1":
+		if req.URL.Path == "/finalize/1" {
+			this.finalize(req)
+		}
+		resp.Header().Set("Location", url+"/order/1")
+		this.respond(resp, http.StatusOK, this.order())
+	case "/authz/1":
+		this.respond(resp, http.StatusOK, map[string]any{
+			"status":     this.authorizationStatus(),
+			"identifier": map[string]string{"type": "dns", "value": this.domain},
+			"challenges": []any{this.challenge()},
+		})
+	case "/challenge/1":
+		this.validations++
+		if err := this.validate(this.domain, "token."+this.thumbprint); err != nil {
+			this.t.Logf("validation failed: %v", err)
+			this.status = "invalid"
+		} else {
+			this.status = "ready"
+		}
+		this.respond(resp, http.StatusOK, this.challenge())
+	case "/certificate/1":
+		resp.Header().Set("Content-Type", "application/pem-certificate-chain")
+		_, _ = resp.Write(this.certificate)
+	default:
+		resp.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (this *fakeCa) authorizationStatus() string {
+	switch this.status {
+	case "pending", "invalid":
+		return this.status
+	}
+	return "valid"
+}
+
+func (this *fakeCa) order() map[string]any {
+	result := map[string]any{
+		"status":         this.status,
+		"identifiers":    []any{map[string]string{"type": "dns", "value": this.domain}},
+		"authorizations": []string{this.server.URL + "/authz/1"},
+		"finalize":       this.server.URL + "/finalize/1",
+	}
+	if this.status == "valid" {
+		result["certificate"] = this.server.URL + "/certificate/1"
+	}
+	return result
+}
+
+func (this *fakeCa) challenge() map[string]string {
+	return map[string]string{
+		"type":   "tls-alpn-01",
+		"url":    this.server.URL + "/challenge/1",
+		"token":  "token",
+		"status": this.authorizationStatus(),
+	}
+}
+
+func (this *fakeCa) finalize(req *http.Request) {
+	var payload struct {
+		Csr string `json:"csr"`
+	}
+	this.parse(req, nil, &payload)
+	der, err := base64.RawURLEncoding.DecodeString(payload.Csr)
+	if err != nil {
+		this.t.Error(err)
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		this.t.Error(err)
+		return
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if der, err = x509.CreateCertificate(rand.Reader, template, this.cert, csr.PublicKey, this.key); err != nil {
+		this.t.Error(err)
+		return
+	}
+	this.certificate = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	this.status = "valid"
+}
+
+func (this *fakeCa) parse(req *http.Request, protected, payload any) {
+	var jws struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&jws); err != nil {
+		this.t.Error(err)
+		return
+	}
+	decode := func(encoded string, target any) {
+		if target == nil {
+			return
+		}
+		b, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			this.t.Error(err)
+			return
+		}
+		if err := json.Unmarshal(b, target); err != nil {
+			this.t.Error(err)
+		}
+	}
+	decode(jws.Protected, protected)
+	decode(jws.Payload, payload)
+}
+
+// counts returns how many orders were created and how many challenges were
+// validated.
+func (this *fakeCa) counts() (orders, validations int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.orders, this.validations
+}
+
+func (this *fakeCa) respond(resp http.ResponseWriter, status int, body any) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(status)
+	if err := json.NewEncoder(resp).Encode(body); err != nil {
+		this.t.Error(err)
+	}
+}
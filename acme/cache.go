@@ -0,0 +1,107 @@
+package acme
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/echocat/slf4g"
+	"golang.org/x/crypto/acme/autocert"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"strings"
+)
+
+const (
+	secretDataKey       = "data"
+	secretKeyAnnotation = "lingress.echocat.org/acme-key"
+	secretLabel         = "lingress.echocat.org/acme"
+
+	maxSecretNameLength = 253
+)
+
+// SecretCache stores the ACME account and all issued certificates as secrets
+// which allows all instances of lingress to share them.
+type SecretCache struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Prefix    string
+	Logger    log.Logger
+}
+
+func (this *SecretCache) Get(ctx context.Context, key string) ([]byte, error) {
+	secret, err := this.Client.CoreV1().Secrets(this.Namespace).Get(ctx, this.secretNameOf(key), metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return nil, autocert.ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := secret.Data[secretDataKey]
+	if !ok {
+		return nil, autocert.ErrCacheMiss
+	}
+	return data, nil
+}
+
+func (this *SecretCache) Put(ctx context.Context, key string, data []byte) error {
+	secrets := this.Client.CoreV1().Secrets(this.Namespace)
+	name := this.secretNameOf(key)
+
+	existing, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		_, err = secrets.Create(ctx, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   this.Namespace,
+				Labels:      map[string]string{secretLabel: "true"},
+				Annotations: map[string]string{secretKeyAnnotation: key},
+			},
+			Type: v1.SecretTypeOpaque,
+			Data: map[string][]byte{secretDataKey: data},
+		}, metav1.CreateOptions{})
+	} else if err == nil {
+		existing.Data = map[string][]byte{secretDataKey: data}
+		_, err = secrets.Update(ctx, existing, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	this.Logger.
+		With("key", key).
+		With("secret", this.Namespace+"/"+name).
+		Debug("ACME data stored.")
+	return nil
+}
+
+func (this *SecretCache) Delete(ctx context.Context, key string) error {
+	err := this.Client.CoreV1().Secrets(this.Namespace).Delete(ctx, this.secretNameOf(key), metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// secretNameOf converts the given key into a valid secret name. A hash of the
+// key is appended to prevent collisions of keys which only differ in
+// characters which are not allowed inside of secret names.
+func (this *SecretCache) secretNameOf(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	suffix := "-" + hex.EncodeToString(sum[:4])
+
+	var buf strings.Builder
+	for _, c := range strings.ToLower(key) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '.' || c == '-' {
+			buf.WriteRune(c)
+		} else {
+			buf.WriteRune('-')
+		}
+	}
+	name := this.Prefix + buf.String()
+	if max := maxSecretNameLength - len(suffix); len(name) > max {
+		name = name[:max]
+	}
+	return name + suffix
+}
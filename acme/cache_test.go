@@ -0,0 +1,48 @@
+package acme
+
+import (
+	"context"
+	"github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/acme/autocert"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func Test_SecretCache(t *testing.T) {
+	g := NewGomegaWithT(t)
+	ctx := context.Background()
+
+	instance := &SecretCache{
+		Client:    fake.NewClientset(),
+		Namespace: "lingress",
+		Prefix:    "lingress-acme-",
+		Logger:    log.GetRootLogger(),
+	}
+
+	_, err := instance.Get(ctx, "example.org")
+	g.Expect(err).To(Equal(autocert.ErrCacheMiss))
+
+	g.Expect(instance.Put(ctx, "example.org", []byte("a"))).To(Succeed())
+	g.Expect(instance.Get(ctx, "example.org")).To(Equal([]byte("a")))
+
+	g.Expect(instance.Put(ctx, "example.org", []byte("b"))).To(Succeed())
+	g.Expect(instance.Get(ctx, "example.org")).To(Equal([]byte("b")))
+
+	_, err = instance.Get(ctx, "example.org+rsa")
+	g.Expect(err).To(Equal(autocert.ErrCacheMiss))
+
+	g.Expect(instance.Delete(ctx, "example.org")).To(Succeed())
+	g.Expect(instance.Delete(ctx, "example.org")).To(Succeed())
+	_, err = instance.Get(ctx, "example.org")
+	g.Expect(err).To(Equal(autocert.ErrCacheMiss))
+}
+
+func Test_SecretCache_secretNameOf(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	instance := &SecretCache{Prefix: "lingress-acme-"}
+
+	g.Expect(instance.secretNameOf("acme_account+key")).To(MatchRegexp(`^lingress-acme-acme-account-key-[0-9a-f]{8}$`))
+	g.Expect(instance.secretNameOf("example.org+rsa")).NotTo(Equal(instance.secretNameOf("example.org_rsa")))
+}
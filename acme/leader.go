@@ -0,0 +1,148 @@
+package acme
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/acme/autocert"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// issuedCertificateTtl is the duration instances which are not the
+	// leader remember a certificate (or that there is none) before they
+	// load it again; this is how they get renewed certificates.
+	issuedCertificateTtl = time.Minute
+)
+
+type issuedCertificate struct {
+	certificate *tls.Certificate
+	loadedAt    time.Time
+}
+
+// issuedCertificate returns the certificate the leader has issued for the
+// requested host. Instances which are not the leader use it instead of
+// Manager.GetCertificate which would try to order missing certificates.
+func (this *Acme) issuedCertificate(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.ToLower(strings.TrimSuffix(info.ServerName, "."))
+	// Same keys as used by autocert for ECDSA and RSA certificates.
+	for _, key := range []string{name, name + "+rsa"} {
+		certificate, err := this.loadIssuedCertificate(key)
+		if err != nil {
+			return nil, err
+		}
+		if certificate != nil && info.SupportsCertificate(certificate) == nil {
+			return certificate, nil
+		}
+	}
+	return nil, fmt.Errorf("%w; no certificate was issued yet for %s", ErrNotLeader, info.ServerName)
+}
+
+func (this *Acme) loadIssuedCertificate(key string) (*tls.Certificate, error) {
+	this.issuedMutex.Lock()
+	entry, ok := this.issued[key]
+	this.issuedMutex.Unlock()
+	if ok && time.Since(entry.loadedAt) < issuedCertificateTtl {
+		return entry.certificate, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	data, err := this.Manager.Cache.Get(ctx, key)
+	if errors.Is(err, autocert.ErrCacheMiss) {
+		data, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var certificate *tls.Certificate
+	if data != nil {
+		if certificate, err = parseIssuedCertificate(data); err != nil {
+			return nil, fmt.Errorf("cannot parse certificate %s: %w", key, err)
+		}
+	}
+
+	now := time.Now()
+	this.issuedMutex.Lock()
+	defer this.issuedMutex.Unlock()
+	if this.issued == nil {
+		this.issued = map[string]issuedCertificate{}
+	}
+	// Forget all expired entries; so only the hosts requested within the
+	// TTL are remembered.
+	for candidateKey, candidate := range this.issued {
+		if now.Sub(candidate.loadedAt) >= issuedCertificateTtl {
+			delete(this.issued, candidateKey)
+		}
+	}
+	this.issued[key] = issuedCertificate{certificate, now}
+	return certificate, nil
+}
+
+// parseIssuedCertificate parses the format autocert stores certificates in:
+// the PEM encoded private key followed by the PEM encoded chain. Expired
+// certificates are ignored.
+func parseIssuedCertificate(data []byte) (*tls.Certificate, error) {
+	var keyPem, certPem []byte
+	for {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if strings.Contains(block.Type, "PRIVATE KEY") {
+			keyPem = append(keyPem, pem.EncodeToMemory(block)...)
+		} else {
+			certPem = append(certPem, pem.EncodeToMemory(block)...)
+		}
+		data = rest
+	}
+	certificate, err := tls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(certificate.Leaf.NotAfter) {
+		return nil, nil
+	}
+	return &certificate, nil
+}
+
+// leaderOnlyCache prevents instances which are not the leader from writing
+// into the shared cache (like a new account key, certificate or challenge)
+// while all instances can read from it.
+type leaderOnlyCache struct {
+	autocert.Cache
+	check func() error
+}
+
+func (this *leaderOnlyCache) Put(ctx context.Context, key string, data []byte) error {
+	if err := this.check(); err != nil {
+		return err
+	}
+	return this.Cache.Put(ctx, key, data)
+}
+
+func (this *leaderOnlyCache) Delete(ctx context.Context, key string) error {
+	if err := this.check(); err != nil {
+		return err
+	}
+	return this.Cache.Delete(ctx, key)
+}
+
+// leaderOnlyTransport prevents instances which are not the leader from
+// communicating with the ACME server at all; so they neither order nor renew
+// certificates.
+type leaderOnlyTransport struct {
+	http.RoundTripper
+	check func() error
+}
+
+func (this *leaderOnlyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := this.check(); err != nil {
+		return nil, err
+	}
+	return this.RoundTripper.RoundTrip(req)
+}
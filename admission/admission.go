@@ -0,0 +1,242 @@
+package admission
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/slf4g"
+	"github.com/echocat/slf4g/level"
+	sdk "github.com/echocat/slf4g/sdk/bridge"
+	"io"
+	admissionv1 "k8s.io/api/admission/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	maxReviewBytes = 3 << 20 // 3MB; which is above the maximum size of objects inside etcd.
+)
+
+// Admission serves a validating admission webhook which rejects Ingresses
+// that cannot be served by lingress, already while they are applied.
+type Admission struct {
+	settings *settings.Settings
+
+	Validator rules.IngressValidator
+	Logger    log.Logger
+
+	server http.Server
+
+	certificate      *tls.Certificate
+	certificateMtime time.Time
+	certificateMutex sync.Mutex
+}
+
+func New(s *settings.Settings, validator rules.IngressValidator, logger log.Logger) (*Admission, error) {
+	result := &Admission{
+		settings:  s,
+		Validator: validator,
+		Logger:    logger,
+		server: http.Server{
+			ErrorLog:          sdk.NewWrapper(logger, level.Debug),
+			ReadHeaderTimeout: 30 * time.Second,
+		},
+	}
+	result.server.Handler = result
+	result.server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: result.getCertificate,
+	}
+	return result, nil
+}
+
+func (this *Admission) IsEnabled() bool {
+	return this.settings.Admission.IsEnabled()
+}
+
+func (this *Admission) getLogger() log.Logger {
+	return this.Logger
+}
+
+func (this *Admission) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/validate" {
+		support.NewGenericResponse(http.StatusNotFound, http.StatusText(http.StatusNotFound), req).
+			StreamJsonTo(resp, req, this.getLogger)
+		return
+	}
+	if req.Method != "POST" {
+		support.NewGenericResponse(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), req).
+			StreamJsonTo(resp, req, this.getLogger)
+		return
+	}
+
+	var review admissionv1.AdmissionReview
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxReviewBytes)).Decode(&review); err != nil || review.Request == nil {
+		support.NewGenericResponse(http.StatusBadRequest, "Expected an AdmissionReview with a request.", req).
+			StreamJsonTo(resp, req, this.getLogger)
+		return
+	}
+
+	review.Response = this.review(review.Request)
+	review.Request = nil
+
+	resp.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(resp).Encode(&review); err != nil {
+		this.Logger.
+			WithError(err).
+			Debug("Cannot write admission review response.")
+	}
+}
+
+func (this *Admission) review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	result := &admissionv1.AdmissionResponse{
+		UID:     req.UID,
+		Allowed: true,
+	}
+	if req.Kind.Group != networkingv1.GroupName ||
+		req.Kind.Version != "v1" ||
+		req.Kind.Kind != "Ingress" ||
+		req.Operation == admissionv1.Delete {
+		return result
+	}
+
+	l := this.Logger.
+		With("ingress", req.Namespace+"/"+req.Name).
+		With("operation", req.Operation)
+
+	var ingress networkingv1.Ingress
+	if err := json.Unmarshal(req.Object.Raw, &ingress); err != nil {
+		result.Allowed = false
+		result.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusBadRequest,
+			Reason:  metav1.StatusReasonBadRequest,
+			Message: fmt.Sprintf("cannot decode ingress: %v", err),
+		}
+		return result
+	}
+	if ingress.Namespace == "" {
+		// On creation the namespace is not always part of the object itself.
+		ingress.Namespace = req.Namespace
+	}
+
+	warnings, err := this.Validator.ValidateIngress(&ingress)
+	result.Warnings = warnings
+
+	var ve *rules.ValidationError
+	if errors.As(err, &ve) {
+		l.With("problems", ve.Problems).
+			Info("Ingress rejected.")
+		result.Allowed = false
+		result.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusUnprocessableEntity,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: "ingress cannot be served by lingress: " + ve.Error(),
+		}
+	} else if err != nil {
+		l.WithError(err).
+			Warn("Cannot validate ingress.")
+		result.Allowed = false
+		result.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusInternalServerError,
+			Reason:  metav1.StatusReasonInternalError,
+			Message: fmt.Sprintf("cannot validate ingress: %v", err),
+		}
+	}
+
+	return result
+}
+
+// getCertificate returns the configured certificate and reloads it if the
+// file was changed in the meantime; for example by cert-manager.
+func (this *Admission) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s := this.settings.Admission
+
+	fi, err := os.Stat(s.CertificateFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load certificate of admission webhook: %w", err)
+	}
+
+	this.certificateMutex.Lock()
+	defer this.certificateMutex.Unlock()
+
+	if this.certificate != nil && fi.ModTime().Equal(this.certificateMtime) {
+		return this.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(s.CertificateFile, s.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load certificate of admission webhook: %w", err)
+	}
+	this.certificate = &certificate
+	this.certificateMtime = fi.ModTime()
+	this.Logger.
+		With("certificate", s.CertificateFile).
+		Debug("Certificate of admission webhook loaded.")
+
+	return this.certificate, nil
+}
+
+func (this *Admission) Init(stop support.Channel) error {
+	if !this.IsEnabled() {
+		return nil
+	}
+
+	if this.Validator == nil {
+		return fmt.Errorf("the configured rules repository does not support the validation of ingresses")
+	}
+	s := this.settings.Admission
+	if s.CertificateFile == "" || s.PrivateKeyFile == "" {
+		return fmt.Errorf("admission.certificate and admission.privateKey are required to serve the admission webhook")
+	}
+	if _, err := this.getCertificate(nil); err != nil {
+		return err
+	}
+
+	go this.shutdownListener(stop)
+
+	this.server.Addr = s.ListenAddress
+	ln, err := net.Listen("tcp", this.server.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := this.server.ServeTLS(ln, "", ""); err != nil && err != http.ErrServerClosed {
+			this.Logger.
+				WithError(err).
+				With("addr", this.server.Addr).
+				Error("Server is unable to serve admission webhook.")
+			stop.Broadcast()
+		}
+	}()
+	this.Logger.
+		With("addr", this.server.Addr).
+		Info("Serve admission webhook...")
+
+	return nil
+}
+
+func (this *Admission) shutdownListener(stop support.Channel) {
+	stop.Wait()
+	ctx, cancelFnc := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancelFnc()
+	if err := this.server.Shutdown(ctx); err != nil {
+		this.Logger.
+			WithError(err).
+			With("addr", this.server.Addr).
+			Warn("Cannot graceful shutdown admission webhook.")
+	}
+}
@@ -0,0 +1,75 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	admissionv1 "k8s.io/api/admission/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type validatorFunc func(*networkingv1.Ingress) ([]string, error)
+
+func (this validatorFunc) ValidateIngress(ingress *networkingv1.Ingress) ([]string, error) {
+	return this(ingress)
+}
+
+func Test_Admission_ServeHTTP(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	var validated *networkingv1.Ingress
+	instance, err := New(&s, validatorFunc(func(ingress *networkingv1.Ingress) ([]string, error) {
+		validated = ingress
+		if ingress.Name == "broken" {
+			return []string{"a warning"}, &rules.ValidationError{Problems: []string{"first", "second"}}
+		}
+		return nil, nil
+	}), log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	review := func(name string) *admissionv1.AdmissionReview {
+		object, err := json.Marshal(&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		g.Expect(err).To(BeNil())
+		body, err := json.Marshal(&admissionv1.AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+			Request: &admissionv1.AdmissionRequest{
+				UID:       "4711",
+				Kind:      metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+				Namespace: "foo",
+				Name:      name,
+				Operation: admissionv1.Create,
+				Object:    runtime.RawExtension{Raw: object},
+			},
+		})
+		g.Expect(err).To(BeNil())
+
+		resp := httptest.NewRecorder()
+		instance.ServeHTTP(resp, httptest.NewRequest("POST", "/validate", bytes.NewReader(body)))
+		g.Expect(resp.Code).To(Equal(http.StatusOK))
+
+		var result admissionv1.AdmissionReview
+		g.Expect(json.Unmarshal(resp.Body.Bytes(), &result)).To(Succeed())
+		g.Expect(result.Response).NotTo(BeNil())
+		g.Expect(result.Response.UID).To(BeEquivalentTo("4711"))
+		return &result
+	}
+
+	actual := review("good")
+	g.Expect(actual.Response.Allowed).To(BeTrue())
+	g.Expect(validated.Namespace).To(Equal("foo"))
+
+	actual = review("broken")
+	g.Expect(actual.Response.Allowed).To(BeFalse())
+	g.Expect(actual.Response.Warnings).To(Equal([]string{"a warning"}))
+	g.Expect(actual.Response.Result.Code).To(BeEquivalentTo(http.StatusUnprocessableEntity))
+	g.Expect(actual.Response.Result.Message).To(Equal("ingress cannot be served by lingress: first; second"))
+}
@@ -0,0 +1,407 @@
+package certificates
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"github.com/echocat/slf4g"
+	"golang.org/x/crypto/ocsp"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maximumOcspResponseSize limits the amount of bytes which will be read
+	// from responses of OCSP responders.
+	maximumOcspResponseSize = 1 << 20
+)
+
+var (
+	errNoOcspServer = errors.New("certificate does not contain any OCSP server")
+	errNoIssuer     = errors.New("certificate chain does not contain the issuer")
+)
+
+// Monitor fetches OCSP responses for all served certificates, staples them
+// into TLS handshakes and warns about certificates which are about to expire.
+// Beside the certificates of Certificates it also monitors all certificates
+// passed to Staple (like the ones issued by ACME) as long as they are served.
+// As CertificateRepository it provides all of these certificates.
+type Monitor struct {
+	settings *settings.Settings
+
+	Certificates rules.CertificateRepository
+	Client       *http.Client
+	Logger       log.Logger
+
+	// Resolution is the interval in which it will be checked which of the
+	// certificates are due to be refreshed.
+	Resolution time.Duration
+	// RetryInterval is the amount of time after a failed OCSP request will
+	// be retried.
+	RetryInterval time.Duration
+	// WarningInterval is the amount of time after a warning about an expiring
+	// certificate will be repeated.
+	WarningInterval time.Duration
+	// ServedRetention is the amount of time a certificate which is not part
+	// of Certificates is still monitored after it was passed to Staple the
+	// last time.
+	ServedRetention time.Duration
+
+	mutex   sync.RWMutex
+	staples map[string]*staple
+	warned  map[string]time.Time
+	served  map[string]*servedCertificate
+	// known contains the fingerprints of the certificates of Certificates
+	// at the last check.
+	known map[string]bool
+}
+
+type servedCertificate struct {
+	certificate *tls.Certificate
+	// lastServed is the UnixNano the certificate was passed to Staple the last time.
+	lastServed atomic.Int64
+}
+
+type staple struct {
+	response   []byte
+	nextUpdate time.Time
+	refreshAt  time.Time
+}
+
+func New(s *settings.Settings, certificates rules.CertificateRepository, logger log.Logger) (*Monitor, error) {
+	return &Monitor{
+		settings:        s,
+		Certificates:    certificates,
+		Client:          &http.Client{Timeout: 10 * time.Second},
+		Logger:          logger,
+		Resolution:      10 * time.Second,
+		RetryInterval:   5 * time.Minute,
+		WarningInterval: 24 * time.Hour,
+		ServedRetention: 24 * time.Hour,
+		staples:         map[string]*staple{},
+		warned:          map[string]time.Time{},
+		served:          map[string]*servedCertificate{},
+	}, nil
+}
+
+func (this *Monitor) Init(stop support.Channel) error {
+	go this.run(stop)
+	return nil
+}
+
+// Staple returns a copy of the given certificate which contains the current
+// OCSP response. If there is no valid response the given certificate is
+// returned as is.
+func (this *Monitor) Staple(certificate *tls.Certificate) *tls.Certificate {
+	if certificate == nil || certificate.Leaf == nil {
+		return certificate
+	}
+
+	key := support.CertificateFingerprint(certificate.Leaf)
+	this.mutex.RLock()
+	s := this.staples[key]
+	served := this.served[key]
+	known := this.known[key]
+	this.mutex.RUnlock()
+
+	now := time.Now()
+	if served != nil {
+		served.lastServed.Store(now.UnixNano())
+	} else if !known {
+		// Not monitored yet; so it is not (yet) part of Certificates.
+		this.markServed(key, certificate, now)
+	}
+
+	if !this.settings.Tls.OcspStapling.Get() || s == nil || s.response == nil || (!s.nextUpdate.IsZero() && now.After(s.nextUpdate)) {
+		return certificate
+	}
+
+	result := *certificate
+	result.OCSPStaple = s.response
+	return &result
+}
+
+// AllCertificates returns the certificates of Certificates together with the
+// ones which were passed to Staple within ServedRetention (like the ones
+// issued by ACME). Each certificate is contained only once.
+func (this *Monitor) AllCertificates() ([]rules.LoadedCertificate, error) {
+	all, err := this.Certificates.AllCertificates()
+	if err != nil {
+		return nil, err
+	}
+	return rules.MergeLoadedCertificates(append(all, this.servedCertificates(time.Now())...)), nil
+}
+
+// FindCertificatesBy returns the certificates of Certificates.
+func (this *Monitor) FindCertificatesBy(query rules.CertificateQuery) (rules.Certificates, error) {
+	return this.Certificates.FindCertificatesBy(query)
+}
+
+// markServed ensures that the given certificate is monitored even if it is
+// not part of Certificates.
+func (this *Monitor) markServed(key string, certificate *tls.Certificate, now time.Time) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if _, ok := this.served[key]; ok {
+		return
+	}
+	entry := &servedCertificate{certificate: certificate}
+	entry.lastServed.Store(now.UnixNano())
+	this.served[key] = entry
+}
+
+// servedCertificates returns all certificates which were passed to Staple
+// within ServedRetention.
+func (this *Monitor) servedCertificates(now time.Time) []rules.LoadedCertificate {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	var result []rules.LoadedCertificate
+	for _, candidate := range this.served {
+		if now.Sub(time.Unix(0, candidate.lastServed.Load())) > this.ServedRetention {
+			continue
+		}
+		var hosts value.WildcardSupportingFqdns
+		for _, dns := range candidate.certificate.Leaf.DNSNames {
+			hosts = append(hosts, value.WildcardSupportingFqdn(dns))
+		}
+		result = append(result, rules.LoadedCertificate{
+			Certificate: candidate.certificate,
+			Hosts:       hosts,
+		})
+	}
+	return result
+}
+
+// pruneServed forgets all certificates which were not passed to Staple
+// within ServedRetention or which are part of Certificates (now).
+func (this *Monitor) pruneServed(now time.Time, known map[string]bool) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	this.known = known
+	for key, candidate := range this.served {
+		if known[key] || now.Sub(time.Unix(0, candidate.lastServed.Load())) > this.ServedRetention {
+			delete(this.served, key)
+		}
+	}
+}
+
+func (this *Monitor) run(stop support.Channel) {
+	this.check(time.Now())
+
+	ticker := time.NewTicker(this.Resolution)
+	defer ticker.Stop()
+
+	stopCh := support.ToChan(stop)
+	for {
+		select {
+		case now := <-ticker.C:
+			this.check(now)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (this *Monitor) check(now time.Time) {
+	all, err := this.Certificates.AllCertificates()
+	if err != nil {
+		this.Logger.
+			WithError(err).
+			Warn("Cannot retrieve certificates to monitor.")
+		return
+	}
+
+	known := map[string]bool{}
+	for _, candidate := range all {
+		if candidate.Certificate != nil && candidate.Certificate.Leaf != nil {
+			known[support.CertificateFingerprint(candidate.Certificate.Leaf)] = true
+		}
+	}
+	this.pruneServed(now, known)
+	all = rules.MergeLoadedCertificates(append(all, this.servedCertificates(now)...))
+
+	seen := map[string]bool{}
+	for _, candidate := range all {
+		if candidate.Certificate == nil || candidate.Certificate.Leaf == nil {
+			continue
+		}
+		key := support.CertificateFingerprint(candidate.Certificate.Leaf)
+		seen[key] = true
+
+		this.checkExpiry(now, key, candidate)
+		if this.settings.Tls.OcspStapling.Get() && this.isRefreshDue(now, key) {
+			this.refresh(now, key, candidate)
+		}
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for key := range this.staples {
+		if !seen[key] {
+			delete(this.staples, key)
+		}
+	}
+	for key := range this.warned {
+		if !seen[key] {
+			delete(this.warned, key)
+		}
+	}
+}
+
+func (this *Monitor) checkExpiry(now time.Time, key string, candidate rules.LoadedCertificate) {
+	leaf := candidate.Certificate.Leaf
+	if now.Add(this.settings.Tls.ExpiryWarning).Before(leaf.NotAfter) {
+		return
+	}
+
+	this.mutex.Lock()
+	last, ok := this.warned[key]
+	if ok && now.Sub(last) < this.WarningInterval {
+		this.mutex.Unlock()
+		return
+	}
+	this.warned[key] = now
+	this.mutex.Unlock()
+
+	l := this.Logger.
+		With("hosts", candidate.Hosts).
+		With("sources", candidate.Sources).
+		With("serial", leaf.SerialNumber.Text(16)).
+		With("notAfter", leaf.NotAfter)
+	if now.After(leaf.NotAfter) {
+		l.Error("Served certificate is expired.")
+	} else {
+		l.Warn("Served certificate will expire soon.")
+	}
+}
+
+func (this *Monitor) isRefreshDue(now time.Time, key string) bool {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	s := this.staples[key]
+	return s == nil || !now.Before(s.refreshAt)
+}
+
+func (this *Monitor) refresh(now time.Time, key string, candidate rules.LoadedCertificate) {
+	l := this.Logger.
+		With("hosts", candidate.Hosts).
+		With("serial", candidate.Certificate.Leaf.SerialNumber.Text(16))
+
+	response, raw, err := this.fetch(candidate.Certificate)
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	s := this.staples[key]
+	if s == nil {
+		s = &staple{}
+		this.staples[key] = s
+	}
+
+	if errors.Is(err, errNoOcspServer) {
+		s.refreshAt = now.Add(this.WarningInterval)
+		return
+	}
+	if err != nil {
+		l.WithError(err).Warn("Cannot fetch OCSP response; will retry later.")
+		s.refreshAt = now.Add(this.RetryInterval)
+		return
+	}
+
+	if response.Status != ocsp.Good {
+		if response.Status == ocsp.Revoked {
+			l.With("revokedAt", response.RevokedAt).
+				Error("Served certificate was revoked.")
+		} else {
+			l.Warn("OCSP responder does not know served certificate.")
+		}
+		s.response = nil
+		s.nextUpdate = response.NextUpdate
+		s.refreshAt = refreshTimeOf(now, response, this.RetryInterval)
+		return
+	}
+
+	s.response = raw
+	s.nextUpdate = response.NextUpdate
+	s.refreshAt = refreshTimeOf(now, response, this.RetryInterval)
+	l.With("nextUpdate", response.NextUpdate).
+		Debug("OCSP response fetched.")
+}
+
+// refreshTimeOf returns the time at which the given response should be
+// refreshed. This is halfway between ThisUpdate and NextUpdate but never
+// earlier than now plus minimum.
+func refreshTimeOf(now time.Time, response *ocsp.Response, minimum time.Duration) time.Time {
+	if response.NextUpdate.IsZero() {
+		return now.Add(time.Hour)
+	}
+	result := response.ThisUpdate.Add(response.NextUpdate.Sub(response.ThisUpdate) / 2)
+	if earliest := now.Add(minimum); result.Before(earliest) {
+		return earliest
+	}
+	return result
+}
+
+func (this *Monitor) fetch(certificate *tls.Certificate) (*ocsp.Response, []byte, error) {
+	fail := func(err error) (*ocsp.Response, []byte, error) {
+		return nil, nil, err
+	}
+
+	leaf := certificate.Leaf
+	if len(leaf.OCSPServer) == 0 {
+		return fail(errNoOcspServer)
+	}
+	if len(certificate.Certificate) < 2 {
+		return fail(errNoIssuer)
+	}
+	issuer, err := x509.ParseCertificate(certificate.Certificate[1])
+	if err != nil {
+		return fail(fmt.Errorf("cannot parse issuer: %w", err))
+	}
+
+	body, err := ocsp.CreateRequest(leaf, issuer, &ocsp.RequestOptions{Hash: crypto.SHA1})
+	if err != nil {
+		return fail(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(body))
+	if err != nil {
+		return fail(err)
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := this.Client.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fail(fmt.Errorf("OCSP responder %s responded with unexpected status %d", leaf.OCSPServer[0], resp.StatusCode))
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maximumOcspResponseSize))
+	if err != nil {
+		return fail(err)
+	}
+
+	response, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		return fail(fmt.Errorf("cannot parse OCSP response of %s: %w", leaf.OCSPServer[0], err))
+	}
+
+	return response, raw, nil
+}
@@ -0,0 +1,206 @@
+package certificates
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ocsp"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_refreshTimeOf(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	g.Expect(refreshTimeOf(now, &ocsp.Response{
+		ThisUpdate: now.Add(-time.Hour),
+		NextUpdate: now.Add(47 * time.Hour),
+	}, time.Minute)).To(Equal(now.Add(23 * time.Hour)))
+
+	g.Expect(refreshTimeOf(now, &ocsp.Response{
+		ThisUpdate: now.Add(-48 * time.Hour),
+		NextUpdate: now.Add(time.Hour),
+	}, time.Minute)).To(Equal(now.Add(time.Minute)))
+
+	g.Expect(refreshTimeOf(now, &ocsp.Response{
+		ThisUpdate: now,
+	}, time.Minute)).To(Equal(now.Add(time.Hour)))
+}
+
+func Test_Monitor_staplesServedCertificates(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).To(BeNil())
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	g.Expect(err).To(BeNil())
+	ca, err := x509.ParseCertificate(caDer)
+	g.Expect(err).To(BeNil())
+
+	var requests int32
+	responder := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		request, err := ocsp.ParseRequest(body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		response, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: request.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+		}, caKey)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_, _ = resp.Write(response)
+	}))
+	defer responder.Close()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).To(BeNil())
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "app.example.org"},
+		DNSNames:     []string{"app.example.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		OCSPServer:   []string{responder.URL},
+	}, ca, &key.PublicKey, caKey)
+	g.Expect(err).To(BeNil())
+	leaf, err := x509.ParseCertificate(der)
+	g.Expect(err).To(BeNil())
+	// Like the ones issued by ACME, it is not part of the repository.
+	certificate := &tls.Certificate{Certificate: [][]byte{der, caDer}, PrivateKey: key, Leaf: leaf}
+
+	s := settings.MustNew()
+	s.Tls.ExpiryWarning = time.Minute
+	instance, err := New(&s, noCertificates{}, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	// Unknown yet; so it is served without staple...
+	g.Expect(instance.Staple(certificate).OCSPStaple).To(BeNil())
+
+	// ... but monitored from now on.
+	now := time.Now()
+	instance.check(now)
+	g.Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	stapled := instance.Staple(certificate)
+	g.Expect(stapled.OCSPStaple).NotTo(BeEmpty())
+	response, err := ocsp.ParseResponseForCert(stapled.OCSPStaple, leaf, ca)
+	g.Expect(err).To(BeNil())
+	g.Expect(response.Status).To(Equal(ocsp.Good))
+
+	// The response is only refreshed when it is due.
+	instance.check(now.Add(time.Minute))
+	g.Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	instance.check(now.Add(31 * time.Minute))
+	g.Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+
+	// Provided together with the ones of the repository.
+	all, err := instance.AllCertificates()
+	g.Expect(err).To(BeNil())
+	g.Expect(all).To(HaveLen(1))
+	g.Expect(all[0].Certificate).To(Equal(certificate))
+
+	// Not served anymore: forgotten.
+	instance.check(now.Add(instance.ServedRetention + time.Hour))
+	g.Expect(instance.Staple(certificate).OCSPStaple).To(BeNil())
+}
+
+type noCertificates struct{}
+
+func (this noCertificates) FindCertificatesBy(rules.CertificateQuery) (rules.Certificates, error) {
+	return nil, nil
+}
+
+func (this noCertificates) AllCertificates() ([]rules.LoadedCertificate, error) {
+	return nil, nil
+}
+
+func Test_Monitor_AllCertificates(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	certificateOf := func(host string) *tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		g.Expect(err).To(BeNil())
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: host},
+			DNSNames:     []string{host},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}, &x509.Certificate{SerialNumber: big.NewInt(1)}, &key.PublicKey, key)
+		g.Expect(err).To(BeNil())
+		leaf, err := x509.ParseCertificate(der)
+		g.Expect(err).To(BeNil())
+		return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+	}
+	loaded := certificateOf("loaded.example.org")
+	issued := certificateOf("issued.example.org")
+
+	s := settings.MustNew()
+	s.Tls.OcspStapling = value.False()
+	instance, err := New(&s, someCertificates{{
+		Certificate: loaded,
+		Hosts:       value.WildcardSupportingFqdns{"loaded.example.org"},
+		Sources:     []string{"Ingress/foo/bar"},
+	}}, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	instance.check(time.Now())
+
+	// Also without OCSP stapling, served certificates are monitored; but
+	// only the ones which are not part of the repository.
+	g.Expect(instance.Staple(loaded)).To(Equal(loaded))
+	g.Expect(instance.Staple(issued)).To(Equal(issued))
+
+	all, err := instance.AllCertificates()
+	g.Expect(err).To(BeNil())
+	g.Expect(all).To(HaveLen(2))
+	g.Expect(all[0].Certificate).To(Equal(loaded))
+	g.Expect(all[0].Sources).To(Equal([]string{"Ingress/foo/bar"}))
+	g.Expect(all[1].Certificate).To(Equal(issued))
+	g.Expect(all[1].Hosts).To(Equal(value.WildcardSupportingFqdns{"issued.example.org"}))
+	g.Expect(all[1].Sources).To(BeEmpty())
+}
+
+type someCertificates []rules.LoadedCertificate
+
+func (this someCertificates) FindCertificatesBy(rules.CertificateQuery) (rules.Certificates, error) {
+	return nil, nil
+}
+
+func (this someCertificates) AllCertificates() ([]rules.LoadedCertificate, error) {
+	return this, nil
+}
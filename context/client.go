@@ -1,6 +1,7 @@
 package context
 
 import (
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"github.com/echocat/lingress/server"
@@ -19,6 +20,8 @@ const (
 	FieldClientAddress   = "address"
 	FieldClientStatus    = "status"
 	FieldClientDuration  = "duration"
+
+	FieldClientCertificate = "certificate"
 )
 
 var (
@@ -105,6 +108,27 @@ func (this *Client) ApplyToMap(prefix string, to *map[string]interface{}) {
 	if d := this.Duration; d > -1 {
 		(*to)[prefix+FieldClientDuration] = d / time.Microsecond
 	}
+	if c := this.Certificate(); c != nil {
+		(*to)[prefix+FieldClientCertificate] = map[string]interface{}{
+			"subject":     c.Subject.String(),
+			"issuer":      c.Issuer.String(),
+			"fingerprint": support.CertificateFingerprint(c),
+		}
+	}
+}
+
+// Certificate returns the verified certificate of the client if it was
+// requested and provided while the TLS handshake; otherwise nil.
+func (this *Client) Certificate() *x509.Certificate {
+	req := this.Request
+	if req == nil || req.TLS == nil {
+		return nil
+	}
+	chains := req.TLS.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil
+	}
+	return chains[0][0]
 }
 
 func (this Client) schemeOf(req *http.Request) string {
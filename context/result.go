@@ -28,6 +28,8 @@ var (
 	ResultFailedWithUnauthorized        SimpleResult = 8
 	ResultFailedWithClientGone          SimpleResult = 9
 	ResultFailedWithIllegalHost         SimpleResult = 10
+	ResultFailedWithUpstreamTimeout     SimpleResult = 11
+	ResultFailedWithTooManyRequests     SimpleResult = 12
 )
 
 var (
@@ -43,6 +45,8 @@ var (
 		ResultFallback:                      "fallback",
 		ResultFailedWithClientGone:          "clientGone",
 		ResultFailedWithIllegalHost:         "illegalHost",
+		ResultFailedWithUpstreamTimeout:     "upstreamTimeout",
+		ResultFailedWithTooManyRequests:     "tooManyRequests",
 	}
 
 	resultToStatus = map[Result]int{
@@ -57,6 +61,8 @@ var (
 		ResultFallback:                      http.StatusOK,
 		ResultFailedWithClientGone:          http.StatusGone,
 		ResultFailedWithIllegalHost:         http.StatusUnprocessableEntity,
+		ResultFailedWithUpstreamTimeout:     http.StatusGatewayTimeout,
+		ResultFailedWithTooManyRequests:     http.StatusTooManyRequests,
 	}
 )
 
@@ -18,6 +18,7 @@ const (
 	FieldUpstreamProto    = "proto"
 	FieldUpstreamSource   = "source"
 	FieldUpstreamMatches  = "matches"
+	FieldUpstreamAttempts = "attempts"
 )
 
 type Upstream struct {
@@ -29,6 +30,40 @@ type Upstream struct {
 	Status   int
 	Started  time.Time
 	Duration time.Duration
+
+	// Attempts contains each attempt to send the request to the upstream. It
+	// contains more than one entry if the request was retried.
+	Attempts []UpstreamAttempt
+}
+
+type UpstreamAttempt struct {
+	Address  net.Addr
+	Status   int
+	Duration time.Duration
+	Error    error
+	// RetriedBecause contains the condition because of which this attempt was
+	// retried. It is empty if this attempt was not retried.
+	RetriedBecause string
+}
+
+func (this UpstreamAttempt) AsMap() map[string]interface{} {
+	result := make(map[string]interface{})
+	if addr := this.Address; addr != nil {
+		result[FieldUpstreamAddress] = addr.String()
+	}
+	if s := this.Status; s > 0 {
+		result[FieldUpstreamStatus] = s
+	}
+	if d := this.Duration; d > 0 {
+		result[FieldUpstreamDuration] = d / time.Microsecond
+	}
+	if err := this.Error; err != nil {
+		result["error"] = err.Error()
+	}
+	if v := this.RetriedBecause; v != "" {
+		result["retriedBecause"] = v
+	}
+	return result
 }
 
 func (this *Upstream) configure() {
@@ -39,6 +74,7 @@ func (this *Upstream) configure() {
 	this.Status = -1
 	this.Started = emptyTime
 	this.Duration = 0
+	this.Attempts = this.Attempts[:0]
 }
 
 func (this *Upstream) clean() {
@@ -59,6 +95,7 @@ func (this *Upstream) clean() {
 	this.Status = -1
 	this.Started = emptyTime
 	this.Duration = 0
+	this.Attempts = this.Attempts[:0]
 }
 
 func (this *Upstream) AsMap(r rules.Rule) map[string]interface{} {
@@ -94,4 +131,11 @@ func (this *Upstream) ApplyToMap(r rules.Rule, prefix string, to *map[string]int
 		(*to)[prefix+FieldUpstreamSource] = r.Source().String()
 		(*to)[prefix+FieldUpstreamMatches] = r.Host().String() + "/" + strings.Join(r.Path(), "/")
 	}
+	if len(this.Attempts) > 1 {
+		attempts := make([]map[string]interface{}, len(this.Attempts))
+		for i, attempt := range this.Attempts {
+			attempts[i] = attempt.AsMap()
+		}
+		(*to)[prefix+FieldUpstreamAttempts] = attempts
+	}
 }
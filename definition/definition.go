@@ -1,6 +1,7 @@
 package definition
 
 import (
+	"context"
 	"fmt"
 	"github.com/echocat/lingress/support"
 	"github.com/echocat/slf4g"
@@ -8,7 +9,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/tools/cache"
 	"reflect"
-	"sync/atomic"
+	"sync"
+	"time"
 )
 
 type Definition struct {
@@ -17,7 +19,20 @@ type Definition struct {
 
 	typeDescription string
 	informer        cache.SharedInformer
-	lastError       atomic.Value
+	lastError       support.LastError
+	watchError      support.LastError
+	// If namespaces is restricted, informer is not used; instead there is one
+	// informer (created by newInformer) for each watched namespace.
+	namespaces       *Namespaces
+	newInformer      func(namespace string) cache.SharedInformer
+	namespaced       map[string]*namespacedInformer
+	namespacedMutex  sync.RWMutex
+	namespacedEvents sync.Mutex
+	namespacedCtx    context.Context
+	namespacedCancel context.CancelFunc
+	// dependencies are the definitions whose elements are referenced by the
+	// ones of this definition (like Services by Ingresses).
+	dependencies []*Definition
 
 	OnElementAdded   OnElementChangedFunc
 	OnElementUpdated OnElementUpdatedFunc
@@ -38,15 +53,53 @@ func newDefinition(typeDescription string, informer cache.SharedInformer, logger
 	}, nil
 }
 
+// newNamespacedDefinition creates a Definition for objects which are located
+// inside of namespaces. If namespaces is restricted, it maintains an informer
+// for each watched namespace; otherwise one informer for all namespaces.
+func newNamespacedDefinition(typeDescription string, namespaces *Namespaces, newInformer func(namespace string) cache.SharedInformer, logger log.Logger) (*Definition, error) {
+	if !namespaces.IsRestricted() {
+		return newDefinition(typeDescription, newInformer(metav1.NamespaceAll), logger)
+	}
+	result, err := newDefinition(typeDescription, nil, logger)
+	if err != nil {
+		return nil, err
+	}
+	result.namespaces = namespaces
+	result.newInformer = newInformer
+	result.namespaced = map[string]*namespacedInformer{}
+	return result, nil
+}
+
+type namespacedInformer struct {
+	informer cache.SharedInformer
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
 func (this *Definition) SetInformer(informer cache.SharedInformer) {
 	this.informer = informer
 }
 
+// dependOn registers the definitions whose elements are referenced by the
+// ones of this definition. The elements of a namespace which starts being
+// watched at runtime are not reported before the ones of the dependencies are
+// synchronized for this namespace; otherwise the referenced elements would be
+// handled as missing.
+func (this *Definition) dependOn(dependencies ...*Definition) {
+	this.dependencies = append(this.dependencies, dependencies...)
+}
+
 func (this *Definition) Init(stop support.Channel) error {
+	if this.namespaces != nil {
+		return this.initNamespaced(stop)
+	}
 	if this.informer == nil {
 		panic(fmt.Sprintf("definition %s has no informer", this.typeDescription))
 	}
 
+	if err := this.recordWatchErrorsOf(this.informer); err != nil {
+		return err
+	}
 	_, err := this.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc:    this.onClusterElementAdded,
 		UpdateFunc: this.onClusterElementUpdated,
@@ -62,17 +115,240 @@ func (this *Definition) Init(stop support.Channel) error {
 		stop.Broadcast()
 		return fmt.Errorf("initial %s synchronization failed", this.typeDescription)
 	}
-	if err := this.lastError.Load(); err != nil {
+	if _, err := this.lastError.Load(); err != nil {
 		stop.Broadcast()
-		return fmt.Errorf("initial %s synchronization failed: %w", this.typeDescription, err.(error))
+		return fmt.Errorf("initial %s synchronization failed: %w", this.typeDescription, err)
 	}
 	return nil
 }
 
+func (this *Definition) initNamespaced(stop support.Channel) error {
+	this.namespacedCtx, this.namespacedCancel = context.WithCancel(context.Background())
+	support.ChannelDoOnEvent(stop, this.namespacedCancel)
+
+	this.namespaces.Subscribe(NamespacesSubscriber{
+		OnAdded:   this.onNamespaceAdded,
+		OnRemoved: this.onNamespaceRemoved,
+	})
+
+	if !cache.WaitForCacheSync(this.namespacedCtx.Done(), this.HasSynced) {
+		stop.Broadcast()
+		return fmt.Errorf("initial %s synchronization failed", this.typeDescription)
+	}
+	if _, err := this.lastError.Load(); err != nil {
+		stop.Broadcast()
+		return fmt.Errorf("initial %s synchronization failed: %w", this.typeDescription, err)
+	}
+	return nil
+}
+
+func (this *Definition) onNamespaceAdded(namespace string) {
+	this.namespacedMutex.Lock()
+	defer this.namespacedMutex.Unlock()
+
+	if _, ok := this.namespaced[namespace]; ok {
+		return
+	}
+
+	target := &namespacedInformer{
+		informer: this.newInformer(namespace),
+	}
+	target.ctx, target.cancel = context.WithCancel(this.namespacedCtx)
+
+	if err := this.recordWatchErrorsOf(target.informer); err != nil {
+		this.Logger.
+			WithError(err).
+			With("namespace", namespace).
+			Error("Cannot watch namespace.")
+		target.cancel()
+		return
+	}
+
+	// The informers of all namespaces are reporting concurrently; so the events
+	// are serialized. Events which are delivered after the namespace was
+	// removed are ignored, because all of its elements are already reported
+	// as removed.
+	if _, err := target.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(new interface{}) {
+			this.namespacedEvents.Lock()
+			defer this.namespacedEvents.Unlock()
+			if target.ctx.Err() == nil {
+				this.onClusterElementAdded(new)
+			}
+		},
+		UpdateFunc: func(old, new interface{}) {
+			this.namespacedEvents.Lock()
+			defer this.namespacedEvents.Unlock()
+			if target.ctx.Err() == nil {
+				this.onClusterElementUpdated(old, new)
+			}
+		},
+		DeleteFunc: func(old interface{}) {
+			this.namespacedEvents.Lock()
+			defer this.namespacedEvents.Unlock()
+			if target.ctx.Err() == nil {
+				this.onClusterElementRemoved(old)
+			}
+		},
+	}); err != nil {
+		this.Logger.
+			WithError(err).
+			With("namespace", namespace).
+			Error("Cannot watch namespace.")
+		target.cancel()
+		return
+	}
+
+	this.namespaced[namespace] = target
+	go func() {
+		defer runtime.HandleCrash()
+		if !cache.WaitForCacheSync(target.ctx.Done(), this.dependenciesHaveSynced(namespace)) {
+			return
+		}
+		target.informer.Run(target.ctx.Done())
+	}()
+
+	this.Logger.
+		With("namespace", namespace).
+		Debug("Namespace watched.")
+}
+
+func (this *Definition) onNamespaceRemoved(namespace string) {
+	this.namespacedMutex.Lock()
+	target, ok := this.namespaced[namespace]
+	delete(this.namespaced, namespace)
+	this.namespacedMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	this.namespacedEvents.Lock()
+	defer this.namespacedEvents.Unlock()
+	target.cancel()
+	for _, item := range target.informer.GetStore().List() {
+		this.onClusterElementRemoved(item)
+	}
+
+	this.Logger.
+		With("namespace", namespace).
+		Debug("Namespace no longer watched.")
+}
+
 func (this *Definition) HasSynced() bool {
+	if this.namespaces != nil {
+		if !this.namespaces.HasSynced() {
+			return false
+		}
+		this.namespacedMutex.RLock()
+		defer this.namespacedMutex.RUnlock()
+		for _, candidate := range this.namespaced {
+			if !candidate.informer.HasSynced() {
+				return false
+			}
+		}
+		return true
+	}
 	return this.informer.HasSynced()
 }
 
+// hasSyncedNamespace reports whether the elements of the given namespace are
+// synchronized.
+func (this *Definition) hasSyncedNamespace(namespace string) bool {
+	if this.namespaces == nil {
+		return this.informer.HasSynced()
+	}
+	this.namespacedMutex.RLock()
+	target, ok := this.namespaced[namespace]
+	this.namespacedMutex.RUnlock()
+	return ok && target.informer.HasSynced()
+}
+
+func (this *Definition) dependenciesHaveSynced(namespace string) cache.InformerSynced {
+	return func() bool {
+		for _, dependency := range this.dependencies {
+			if !dependency.hasSyncedNamespace(namespace) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// LastWatchError returns the last error which occurred while listing or
+// watching the elements and the time it occurred at. Errors of handling single
+// elements are not included; they are caused by the elements themselves and
+// not by losing the connection to the cluster.
+func (this *Definition) LastWatchError() (time.Time, error) {
+	return this.watchError.Load()
+}
+
+func (this *Definition) recordWatchErrorsOf(informer cache.SharedInformer) error {
+	if err := informer.SetWatchErrorHandlerWithContext(func(ctx context.Context, r *cache.Reflector, err error) {
+		this.watchError.Store(err)
+		cache.DefaultWatchErrorHandler(ctx, r, err)
+	}); err != nil {
+		return fmt.Errorf("cannot watch errors of %s: %w", this.typeDescription, err)
+	}
+	return nil
+}
+
+// getByKey returns the element with the given key (<namespace>/<name> or
+// <name> for elements without namespace).
+func (this *Definition) getByKey(key string) (interface{}, bool, error) {
+	if this.namespaces == nil {
+		return this.informer.GetStore().GetByKey(key)
+	}
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	this.namespacedMutex.RLock()
+	target, ok := this.namespaced[namespace]
+	this.namespacedMutex.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	return target.informer.GetStore().GetByKey(key)
+}
+
+// byIndex returns all elements of the given namespace which are indexed by
+// the given indexName with the given value. The index has to be added by
+// newInformer.
+func (this *Definition) byIndex(namespace, indexName, indexedValue string) ([]interface{}, error) {
+	informer := this.informer
+	if this.namespaces != nil {
+		this.namespacedMutex.RLock()
+		target, ok := this.namespaced[namespace]
+		this.namespacedMutex.RUnlock()
+		if !ok {
+			return nil, nil
+		}
+		informer = target.informer
+	}
+	indexInformer, ok := informer.(cache.SharedIndexInformer)
+	if !ok {
+		return nil, fmt.Errorf("informer of %s does not support indexes", this.typeDescription)
+	}
+	return indexInformer.GetIndexer().ByIndex(indexName, indexedValue)
+}
+
+// list returns all elements which are currently known.
+func (this *Definition) list() []interface{} {
+	if this.namespaces == nil {
+		return this.informer.GetStore().List()
+	}
+
+	this.namespacedMutex.RLock()
+	defer this.namespacedMutex.RUnlock()
+	var result []interface{}
+	for _, candidate := range this.namespaced {
+		result = append(result, candidate.informer.GetStore().List()...)
+	}
+	return result
+}
+
 func (this *Definition) Run(stop support.Channel) {
 	defer runtime.HandleCrash()
 	this.Logger.Info("Definition store started.")
@@ -0,0 +1,61 @@
+package definition
+
+import (
+	"errors"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"testing"
+)
+
+func Test_Definition_LastWatchError_ignores_failing_elements(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "broken"}}
+	client := fake.NewClientset(ingress)
+	instance, err := NewIngress(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	instance.OnElementUpdated = func(support.ObjectReference, metav1.Object, metav1.Object) error {
+		return errors.New("illegal annotation")
+	}
+	g.Expect(instance.Init(stop)).To(Succeed())
+
+	updated := ingress.DeepCopy()
+	updated.Annotations = map[string]string{"foo": "bar"}
+	g.Expect(client.Tracker().Update(networkingv1.SchemeGroupVersion.WithResource("ingresses"), updated, "foo")).To(Succeed())
+	g.Eventually(func() error {
+		_, err := instance.lastError.Load()
+		return err
+	}).Should(MatchError("illegal annotation"))
+
+	_, err = instance.LastWatchError()
+	g.Expect(err).To(BeNil())
+}
+
+func Test_Definition_LastWatchError_reports_lost_watches(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	client := fake.NewClientset()
+	client.PrependWatchReactor("ingresses", func(k8stesting.Action) (bool, watch.Interface, error) {
+		return true, nil, errors.New("connection lost")
+	})
+	instance, err := NewIngress(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(instance.Init(stop)).To(Succeed())
+
+	g.Eventually(func() error {
+		_, err := instance.LastWatchError()
+		return err
+	}).Should(MatchError(ContainSubstring("connection lost")))
+}
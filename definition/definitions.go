@@ -5,30 +5,78 @@ import (
 	"github.com/echocat/lingress/settings"
 	"github.com/echocat/lingress/support"
 	log "github.com/echocat/slf4g"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"time"
 )
 
 type Definitions struct {
+	// Namespaces decides which namespaces are watched by Ingress, Service,
+	// Secret, EndpointSlice, Gateway and HttpRoute.
+	Namespaces *Namespaces
+
 	ServiceSecrets *ServiceSecret
 	Ingress        *Ingress
+	IngressClass   *IngressClass
 	Service        *Service
+	Secret         *Secret
+	EndpointSlice  *EndpointSlice
+
+	// GatewayClass, Gateway and HttpRoute are only present if the support
+	// of the Gateway API is enabled.
+	GatewayClass *GatewayClass
+	Gateway      *Gateway
+	HttpRoute    *HttpRoute
 }
 
-func New(s *settings.Settings, client kubernetes.Interface, resyncAfter time.Duration, logger log.Logger) (*Definitions, error) {
-	if serviceSecrets, err := NewServiceSecrets(s, client, resyncAfter, logger); err != nil {
+func New(s *settings.Settings, client kubernetes.Interface, dynamicClient dynamic.Interface, resyncAfter time.Duration, logger log.Logger) (*Definitions, error) {
+	if namespaces, err := NewNamespaces(s, client, resyncAfter, logger); err != nil {
+		return nil, fmt.Errorf("cannot create namespace definition store: %v", err)
+	} else if serviceSecrets, err := NewServiceSecrets(s, client, resyncAfter, logger); err != nil {
 		return nil, fmt.Errorf("cannot create service secrets definition store: %v", err)
-	} else if ingress, err := NewIngress(client, resyncAfter, logger); err != nil {
+	} else if ingress, err := NewIngress(client, namespaces, resyncAfter, logger); err != nil {
 		return nil, fmt.Errorf("cannot create ingress definition store: %v", err)
-	} else if service, err := NewService(client, resyncAfter, logger); err != nil {
+	} else if ingressClass, err := NewIngressClass(client, resyncAfter, logger); err != nil {
+		return nil, fmt.Errorf("cannot create ingress class definition store: %v", err)
+	} else if service, err := NewService(client, namespaces, resyncAfter, logger); err != nil {
 		return nil, fmt.Errorf("cannot create service definition store: %v", err)
+	} else if secret, err := NewSecret(s, client, namespaces, resyncAfter, logger); err != nil {
+		return nil, fmt.Errorf("cannot create secret definition store: %v", err)
+	} else if endpointSlice, err := NewEndpointSlice(s, client, namespaces, resyncAfter, logger); err != nil {
+		return nil, fmt.Errorf("cannot create endpoint slice definition store: %v", err)
 	} else {
-		return &Definitions{
+		result := &Definitions{
+			Namespaces:     namespaces,
 			ServiceSecrets: serviceSecrets,
 			Ingress:        ingress,
+			IngressClass:   ingressClass,
 			Service:        service,
-		}, nil
+			Secret:         secret,
+			EndpointSlice:  endpointSlice,
+		}
+		ingress.dependOn(service.Definition, secret.Definition, endpointSlice.Definition)
+		if s.Gateway.IsEnabled() {
+			if err := result.newGatewayDefinitions(dynamicClient, namespaces, resyncAfter, logger); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+}
+
+func (this *Definitions) newGatewayDefinitions(client dynamic.Interface, namespaces *Namespaces, resyncAfter time.Duration, logger log.Logger) (err error) {
+	if this.GatewayClass, err = NewGatewayClass(client, resyncAfter, logger); err != nil {
+		return fmt.Errorf("cannot create gateway class definition store: %v", err)
+	}
+	if this.Gateway, err = NewGateway(client, namespaces, resyncAfter, logger); err != nil {
+		return fmt.Errorf("cannot create gateway definition store: %v", err)
+	}
+	if this.HttpRoute, err = NewHttpRoute(client, namespaces, resyncAfter, logger); err != nil {
+		return fmt.Errorf("cannot create http route definition store: %v", err)
 	}
+	this.Gateway.dependOn(this.Secret.Definition)
+	this.HttpRoute.dependOn(this.Service.Definition, this.EndpointSlice.Definition)
+	return nil
 }
 
 func (this *Definitions) SetNamespace(namespace string) {
@@ -36,6 +84,10 @@ func (this *Definitions) SetNamespace(namespace string) {
 }
 
 func (this *Definitions) Init(stop support.Channel) error {
+	if err := this.Namespaces.Init(stop); err != nil {
+		return err
+	}
+
 	if err := this.ServiceSecrets.Init(stop); err != nil {
 		return err
 	}
@@ -44,15 +96,71 @@ func (this *Definitions) Init(stop support.Channel) error {
 		return err
 	}
 
+	if err := this.Secret.Init(stop); err != nil {
+		return err
+	}
+
+	if err := this.EndpointSlice.Init(stop); err != nil {
+		return err
+	}
+
+	if err := this.IngressClass.Init(stop); err != nil {
+		return err
+	}
+
 	if err := this.Ingress.Init(stop); err != nil {
 		return err
 	}
 
+	if this.HttpRoute != nil {
+		if err := this.GatewayClass.Init(stop); err != nil {
+			return err
+		}
+
+		if err := this.Gateway.Init(stop); err != nil {
+			return err
+		}
+
+		if err := this.HttpRoute.Init(stop); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (this *Definitions) HasSynced() bool {
-	return this.Ingress.HasSynced() &&
+	return this.Namespaces.HasSynced() &&
+		this.Ingress.HasSynced() &&
+		this.IngressClass.HasSynced() &&
 		this.Service.HasSynced() &&
-		this.ServiceSecrets.HasSynced()
+		this.Secret.HasSynced() &&
+		this.EndpointSlice.HasSynced() &&
+		this.ServiceSecrets.HasSynced() &&
+		(this.HttpRoute == nil || (this.GatewayClass.HasSynced() &&
+			this.Gateway.HasSynced() &&
+			this.HttpRoute.HasSynced()))
+}
+
+// LastWatchError returns the most recent error which occurred while listing
+// or watching the elements of any of the definitions and the time it occurred
+// at.
+func (this *Definitions) LastWatchError() (time.Time, error) {
+	candidates := []func() (time.Time, error){
+		this.Namespaces.LastWatchError,
+		this.Ingress.LastWatchError,
+		this.IngressClass.LastWatchError,
+		this.Service.LastWatchError,
+		this.Secret.LastWatchError,
+		this.EndpointSlice.LastWatchError,
+		this.ServiceSecrets.LastWatchError,
+	}
+	if this.HttpRoute != nil {
+		candidates = append(candidates,
+			this.GatewayClass.LastWatchError,
+			this.Gateway.LastWatchError,
+			this.HttpRoute.LastWatchError,
+		)
+	}
+	return support.LatestError(candidates...)
 }
@@ -0,0 +1,93 @@
+package definition
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"time"
+)
+
+const endpointSliceByServiceIndex = "service"
+
+type EndpointSlice struct {
+	*Definition
+
+	settings *settings.Settings
+}
+
+func NewEndpointSlice(s *settings.Settings, client kubernetes.Interface, namespaces *Namespaces, resyncAfter time.Duration, logger log.Logger) (*EndpointSlice, error) {
+	newInformer := func(namespace string) cache.SharedInformer {
+		informerFactory := informers.NewSharedInformerFactoryWithOptions(client, resyncAfter, informers.WithNamespace(namespace))
+		result := informerFactory.Discovery().V1().EndpointSlices().Informer()
+		if err := result.AddIndexers(cache.Indexers{endpointSliceByServiceIndex: endpointSliceByService}); err != nil {
+			// Can only happen if the informer is already started or the index is already present.
+			panic(err)
+		}
+		return result
+	}
+	if definition, err := newNamespacedDefinition("endpoint-slice", namespaces, newInformer, logger); err != nil {
+		return nil, err
+	} else {
+		return &EndpointSlice{
+			Definition: definition,
+			settings:   s,
+		}, nil
+	}
+}
+
+func (this *EndpointSlice) IsEnabled() bool {
+	return this.settings.Discovery.Endpoints.Get()
+}
+
+func (this *EndpointSlice) Init(stop support.Channel) error {
+	if !this.IsEnabled() {
+		this.Logger.Debug("discovery.endpoints is not enabled. No endpoint slices will be evaluated = All upstreams will be addressed by the clusterIP of their services.")
+		return nil
+	}
+	return this.Definition.Init(stop)
+}
+
+func (this *EndpointSlice) HasSynced() bool {
+	if !this.IsEnabled() {
+		return true
+	}
+	return this.Definition.HasSynced()
+}
+
+// ByService returns all endpoint slices which belongs to the service with the
+// given namespace and name.
+func (this *EndpointSlice) ByService(namespace, name string) ([]*discoveryv1.EndpointSlice, error) {
+	if !this.IsEnabled() {
+		return nil, nil
+	}
+	items, err := this.byIndex(namespace, endpointSliceByServiceIndex, namespace+"/"+name)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get endpoint slices of service %s/%s from cache: %w", namespace, name, err)
+	}
+	result := make([]*discoveryv1.EndpointSlice, 0, len(items))
+	for _, item := range items {
+		if candidate, ok := item.(*discoveryv1.EndpointSlice); ok {
+			result = append(result, candidate)
+		}
+	}
+	return result, nil
+}
+
+// endpointSliceByService indexes endpoint slices by <namespace>/<service> of
+// the service they belong to (label kubernetes.io/service-name).
+func endpointSliceByService(obj interface{}) ([]string, error) {
+	slice, ok := obj.(*discoveryv1.EndpointSlice)
+	if !ok {
+		return nil, nil
+	}
+	service := slice.Labels[discoveryv1.LabelServiceName]
+	if service == "" {
+		return nil, nil
+	}
+	return []string{slice.Namespace + "/" + service}, nil
+}
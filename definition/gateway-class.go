@@ -0,0 +1,39 @@
+package definition
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/gateway"
+	log "github.com/echocat/slf4g"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"time"
+)
+
+type GatewayClass struct {
+	*Definition
+}
+
+func NewGatewayClass(client dynamic.Interface, resyncAfter time.Duration, logger log.Logger) (*GatewayClass, error) {
+	informerFactory := dynamicinformer.NewDynamicSharedInformerFactory(client, resyncAfter)
+	informer := informerFactory.ForResource(gateway.GatewayClassesResource).Informer()
+	if definition, err := newDefinition("gatewayClass", informer, logger); err != nil {
+		return nil, err
+	} else {
+		return &GatewayClass{
+			Definition: definition,
+		}, nil
+	}
+}
+
+func (this *GatewayClass) Get(key string) (*gateway.GatewayClass, error) {
+	if item, exists, err := this.getByKey(key); err != nil {
+		return nil, fmt.Errorf("cannot get gateway class %s from cache: %v", key, err)
+	} else if !exists {
+		return nil, nil
+	} else if result, err := gateway.GatewayClassOf(item.(metav1.Object)); err != nil {
+		return nil, fmt.Errorf("cannot get gateway class %s from cache: %v", key, err)
+	} else {
+		return result, nil
+	}
+}
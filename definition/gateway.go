@@ -0,0 +1,42 @@
+package definition
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/gateway"
+	log "github.com/echocat/slf4g"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"time"
+)
+
+type Gateway struct {
+	*Definition
+}
+
+func NewGateway(client dynamic.Interface, namespaces *Namespaces, resyncAfter time.Duration, logger log.Logger) (*Gateway, error) {
+	newInformer := func(namespace string) cache.SharedInformer {
+		informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, resyncAfter, namespace, nil)
+		return informerFactory.ForResource(gateway.GatewaysResource).Informer()
+	}
+	if definition, err := newNamespacedDefinition("gateway", namespaces, newInformer, logger); err != nil {
+		return nil, err
+	} else {
+		return &Gateway{
+			Definition: definition,
+		}, nil
+	}
+}
+
+func (this *Gateway) Get(key string) (*gateway.Gateway, error) {
+	if item, exists, err := this.getByKey(key); err != nil {
+		return nil, fmt.Errorf("cannot get gateway %s from cache: %v", key, err)
+	} else if !exists {
+		return nil, nil
+	} else if result, err := gateway.GatewayOf(item.(metav1.Object)); err != nil {
+		return nil, fmt.Errorf("cannot get gateway %s from cache: %v", key, err)
+	} else {
+		return result, nil
+	}
+}
@@ -0,0 +1,87 @@
+package definition
+
+import (
+	"context"
+	"fmt"
+	"github.com/echocat/lingress/gateway"
+	log "github.com/echocat/slf4g"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"time"
+)
+
+type HttpRoute struct {
+	*Definition
+
+	Client dynamic.Interface
+}
+
+func NewHttpRoute(client dynamic.Interface, namespaces *Namespaces, resyncAfter time.Duration, logger log.Logger) (*HttpRoute, error) {
+	newInformer := func(namespace string) cache.SharedInformer {
+		informerFactory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, resyncAfter, namespace, nil)
+		return informerFactory.ForResource(gateway.HTTPRoutesResource).Informer()
+	}
+	if definition, err := newNamespacedDefinition("httpRoute", namespaces, newInformer, logger); err != nil {
+		return nil, err
+	} else {
+		return &HttpRoute{
+			Definition: definition,
+			Client:     client,
+		}, nil
+	}
+}
+
+func (this *HttpRoute) Get(key string) (*gateway.HTTPRoute, error) {
+	if item, exists, err := this.getByKey(key); err != nil {
+		return nil, fmt.Errorf("cannot get http route %s from cache: %v", key, err)
+	} else if !exists {
+		return nil, nil
+	} else if result, err := gateway.HTTPRouteOf(item.(metav1.Object)); err != nil {
+		return nil, fmt.Errorf("cannot get http route %s from cache: %v", key, err)
+	} else {
+		return result, nil
+	}
+}
+
+// All returns every HTTPRoute which is currently known by this store.
+func (this *HttpRoute) All() []metav1.Object {
+	items := this.list()
+	result := make([]metav1.Object, len(items))
+	for i, item := range items {
+		result[i] = item.(metav1.Object)
+	}
+	return result
+}
+
+// UpdateStatus replaces status.parents of the given HTTPRoute with the given
+// parents and writes it back to the cluster.
+func (this *HttpRoute) UpdateStatus(ctx context.Context, in metav1.Object, parents []gateway.RouteParentStatus) error {
+	u, ok := in.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("expected an unstructured object but got %T", in)
+	}
+	plainParents := make([]any, len(parents))
+	for i, parent := range parents {
+		plain, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&parent)
+		if err != nil {
+			return fmt.Errorf("cannot convert status of http route %s/%s: %w", u.GetNamespace(), u.GetName(), err)
+		}
+		plainParents[i] = plain
+	}
+
+	target := u.DeepCopy()
+	if err := unstructured.SetNestedSlice(target.Object, plainParents, "status", "parents"); err != nil {
+		return fmt.Errorf("cannot set status of http route %s/%s: %w", u.GetNamespace(), u.GetName(), err)
+	}
+	if _, err := this.Client.
+		Resource(gateway.HTTPRoutesResource).
+		Namespace(u.GetNamespace()).
+		UpdateStatus(ctx, target, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("cannot update status of http route %s/%s: %w", u.GetNamespace(), u.GetName(), err)
+	}
+	return nil
+}
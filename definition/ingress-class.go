@@ -0,0 +1,63 @@
+package definition
+
+import (
+	"fmt"
+	log "github.com/echocat/slf4g"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"time"
+)
+
+const (
+	AnnotationIsDefaultIngressClass = "ingressclass.kubernetes.io/is-default-class"
+)
+
+type IngressClass struct {
+	*Definition
+}
+
+func NewIngressClass(client kubernetes.Interface, resyncAfter time.Duration, logger log.Logger) (*IngressClass, error) {
+	informerFactory := informers.NewSharedInformerFactory(client, resyncAfter)
+	informer := informerFactory.Networking().V1().IngressClasses().Informer()
+	if definition, err := newDefinition("ingress-class", informer, logger); err != nil {
+		return nil, err
+	} else {
+		return &IngressClass{
+			Definition: definition,
+		}, nil
+	}
+}
+
+func (this *IngressClass) Get(key string) (*networkingv1.IngressClass, error) {
+	if item, exists, err := this.getByKey(key); err != nil {
+		return nil, fmt.Errorf("cannot get ingress class %s from cache: %v", key, err)
+	} else if !exists {
+		return nil, nil
+	} else {
+		return item.(*networkingv1.IngressClass), nil
+	}
+}
+
+// IsOwnedBy reports whether the IngressClass with the given name exists and
+// has the given controller as spec.controller.
+func (this *IngressClass) IsOwnedBy(name, controller string) (bool, error) {
+	if candidate, err := this.Get(name); err != nil {
+		return false, err
+	} else {
+		return candidate != nil && candidate.Spec.Controller == controller, nil
+	}
+}
+
+// HasDefaultOwnedBy reports whether there is an IngressClass with the given
+// controller as spec.controller which is marked as default class of the
+// cluster.
+func (this *IngressClass) HasDefaultOwnedBy(controller string) bool {
+	for _, item := range this.list() {
+		candidate, ok := item.(*networkingv1.IngressClass)
+		if ok && candidate.Spec.Controller == controller && candidate.Annotations[AnnotationIsDefaultIngressClass] == "true" {
+			return true
+		}
+	}
+	return false
+}
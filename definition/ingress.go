@@ -6,6 +6,7 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"time"
 )
 
@@ -13,10 +14,12 @@ type Ingress struct {
 	*Definition
 }
 
-func NewIngress(client kubernetes.Interface, resyncAfter time.Duration, logger log.Logger) (*Ingress, error) {
-	informerFactory := informers.NewSharedInformerFactory(client, resyncAfter)
-	informer := informerFactory.Networking().V1().Ingresses().Informer()
-	if definition, err := newDefinition("ingress", informer, logger); err != nil {
+func NewIngress(client kubernetes.Interface, namespaces *Namespaces, resyncAfter time.Duration, logger log.Logger) (*Ingress, error) {
+	newInformer := func(namespace string) cache.SharedInformer {
+		informerFactory := informers.NewSharedInformerFactoryWithOptions(client, resyncAfter, informers.WithNamespace(namespace))
+		return informerFactory.Networking().V1().Ingresses().Informer()
+	}
+	if definition, err := newNamespacedDefinition("ingress", namespaces, newInformer, logger); err != nil {
 		return nil, err
 	} else {
 		return &Ingress{
@@ -26,7 +29,7 @@ func NewIngress(client kubernetes.Interface, resyncAfter time.Duration, logger l
 }
 
 func (this *Ingress) Get(key string) (*networkingv1.Ingress, error) {
-	if item, exists, err := this.informer.GetStore().GetByKey(key); err != nil {
+	if item, exists, err := this.getByKey(key); err != nil {
 		return nil, fmt.Errorf("cannot get ingress %s from cache: %v", key, err)
 	} else if !exists {
 		return nil, nil
@@ -34,3 +37,15 @@ func (this *Ingress) Get(key string) (*networkingv1.Ingress, error) {
 		return item.(*networkingv1.Ingress), nil
 	}
 }
+
+// All returns every Ingress which is currently known by this store.
+func (this *Ingress) All() []*networkingv1.Ingress {
+	items := this.list()
+	result := make([]*networkingv1.Ingress, 0, len(items))
+	for _, item := range items {
+		if candidate, ok := item.(*networkingv1.Ingress); ok {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
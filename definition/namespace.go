@@ -0,0 +1,195 @@
+package definition
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Namespaces decides which namespaces are watched by the namespaced
+// definitions (like Ingress, Service and Secret). These are either all
+// namespaces or the configured ones (kubernetes.namespaces) together with
+// the ones which are matching kubernetes.namespaceSelector. Namespaces of
+// the selector are added and removed while their labels are changing.
+type Namespaces struct {
+	definition *Definition
+
+	names    map[string]bool
+	selector labels.Selector
+
+	current     map[string]bool
+	subscribers []NamespacesSubscriber
+	mutex       sync.Mutex
+}
+
+// NamespacesSubscriber is notified about each namespace which is added to or
+// removed from the watched namespaces.
+type NamespacesSubscriber struct {
+	OnAdded   func(namespace string)
+	OnRemoved func(namespace string)
+}
+
+func NewNamespaces(s *settings.Settings, client kubernetes.Interface, resyncAfter time.Duration, logger log.Logger) (*Namespaces, error) {
+	result := &Namespaces{
+		names:   map[string]bool{},
+		current: map[string]bool{},
+	}
+	for _, name := range s.Kubernetes.Namespaces {
+		result.names[name] = true
+	}
+
+	if v := s.Kubernetes.NamespaceSelector; v != "" {
+		selector, err := labels.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("illegal namespace selector %q: %w", v, err)
+		}
+		result.selector = selector
+
+		informerFactory := informers.NewSharedInformerFactoryWithOptions(
+			client,
+			resyncAfter,
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.LabelSelector = selector.String()
+			}),
+		)
+		informer := informerFactory.Core().V1().Namespaces().Informer()
+		definition, err := newDefinition("namespace", informer, logger)
+		if err != nil {
+			return nil, err
+		}
+		definition.OnElementAdded = result.onElementAdded
+		definition.OnElementUpdated = result.onElementUpdated
+		definition.OnElementRemoved = result.onElementRemoved
+		result.definition = definition
+	}
+
+	return result, nil
+}
+
+// IsRestricted reports whether not all namespaces are watched.
+func (this *Namespaces) IsRestricted() bool {
+	return this != nil && (len(this.names) > 0 || this.selector != nil)
+}
+
+func (this *Namespaces) Init(stop support.Channel) error {
+	for name := range this.names {
+		this.add(name)
+	}
+	if this.definition != nil {
+		return this.definition.Init(stop)
+	}
+	return nil
+}
+
+func (this *Namespaces) HasSynced() bool {
+	return this.definition == nil || this.definition.HasSynced()
+}
+
+func (this *Namespaces) LastWatchError() (time.Time, error) {
+	if this.definition == nil {
+		return time.Time{}, nil
+	}
+	return this.definition.LastWatchError()
+}
+
+// Subscribe registers the given subscriber; OnAdded is called immediately for
+// each namespace which is already watched.
+func (this *Namespaces) Subscribe(subscriber NamespacesSubscriber) {
+	this.mutex.Lock()
+	this.subscribers = append(this.subscribers, subscriber)
+	current := slices.Collect(maps.Keys(this.current))
+	this.mutex.Unlock()
+
+	if subscriber.OnAdded != nil {
+		for _, namespace := range current {
+			subscriber.OnAdded(namespace)
+		}
+	}
+}
+
+// Contains reports whether the given namespace is watched.
+func (this *Namespaces) Contains(namespace string) bool {
+	if !this.IsRestricted() {
+		return true
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.current[namespace]
+}
+
+// add watches the given namespace. The subscribers are notified without
+// holding the mutex; so they are free to call Contains (or to start
+// informers) without blocking others.
+func (this *Namespaces) add(namespace string) {
+	this.mutex.Lock()
+	if this.current[namespace] {
+		this.mutex.Unlock()
+		return
+	}
+	this.current[namespace] = true
+	subscribers := slices.Clone(this.subscribers)
+	this.mutex.Unlock()
+
+	for _, subscriber := range subscribers {
+		if subscriber.OnAdded != nil {
+			subscriber.OnAdded(namespace)
+		}
+	}
+}
+
+// remove stops watching the given namespace unless it is configured
+// explicitly by kubernetes.namespaces.
+func (this *Namespaces) remove(namespace string) {
+	this.mutex.Lock()
+	if this.names[namespace] || !this.current[namespace] {
+		this.mutex.Unlock()
+		return
+	}
+	delete(this.current, namespace)
+	subscribers := slices.Clone(this.subscribers)
+	this.mutex.Unlock()
+
+	for _, subscriber := range subscribers {
+		if subscriber.OnRemoved != nil {
+			subscriber.OnRemoved(namespace)
+		}
+	}
+}
+
+func (this *Namespaces) matches(namespace *v1.Namespace) bool {
+	if this.selector != nil && !this.selector.Matches(labels.Set(namespace.Labels)) {
+		return false
+	}
+	// Terminating namespaces are removed right away; their elements will be
+	// deleted anyway.
+	return namespace.Status.Phase != v1.NamespaceTerminating
+}
+
+func (this *Namespaces) onElementAdded(ref support.ObjectReference, new metav1.Object) error {
+	if this.matches(new.(*v1.Namespace)) {
+		this.add(ref.Name())
+	} else {
+		this.remove(ref.Name())
+	}
+	return nil
+}
+
+func (this *Namespaces) onElementUpdated(ref support.ObjectReference, _, new metav1.Object) error {
+	return this.onElementAdded(ref, new)
+}
+
+func (this *Namespaces) onElementRemoved(ref support.ObjectReference) error {
+	this.remove(ref.Name())
+	return nil
+}
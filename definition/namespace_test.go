@@ -0,0 +1,161 @@
+package definition
+
+import (
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sync"
+	"testing"
+)
+
+func Test_Namespaces_restrictIngress(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	namespaceA := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a", Labels: map[string]string{"team": "x"}}}
+	namespaceC := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "c", Labels: map[string]string{"team": "x"}}}
+	client := fake.NewClientset(
+		namespaceA,
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "b"}},
+		namespaceC,
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "d"}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "x"}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "y"}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "c", Name: "z"}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "d", Name: "w"}},
+	)
+
+	s := settings.MustNew()
+	s.Kubernetes.Namespaces = []string{"a", "b"}
+	s.Kubernetes.NamespaceSelector = "team=x"
+	namespaces, err := NewNamespaces(&s, client, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(namespaces.Init(stop)).To(Succeed())
+
+	instance, err := NewIngress(client, namespaces, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	var removed []string
+	var removedMutex sync.Mutex
+	instance.OnElementRemoved = func(ref support.ObjectReference) error {
+		removedMutex.Lock()
+		defer removedMutex.Unlock()
+		removed = append(removed, ref.ShortString())
+		return nil
+	}
+	g.Expect(instance.Init(stop)).To(Succeed())
+
+	names := func() []string {
+		var result []string
+		for _, candidate := range instance.All() {
+			result = append(result, candidate.Namespace+"/"+candidate.Name)
+		}
+		return result
+	}
+	// The configured namespaces together with the ones of the selector.
+	g.Expect(names()).To(ConsistOf("a/x", "b/y", "c/z"))
+	g.Expect(instance.Get("d/w")).To(BeNil())
+
+	// Labels of a are not matching anymore, but it is configured explicitly...
+	namespaceA.Labels = nil
+	g.Expect(namespaces.onElementUpdated(mustObjectReferenceOf(g, namespaceA), nil, namespaceA)).To(Succeed())
+	g.Expect(namespaces.Contains("a")).To(BeTrue())
+	g.Expect(names()).To(ConsistOf("a/x", "b/y", "c/z"))
+
+	// ... while c is only selected by its labels.
+	namespaceC.Labels = nil
+	g.Expect(namespaces.onElementUpdated(mustObjectReferenceOf(g, namespaceC), nil, namespaceC)).To(Succeed())
+	g.Expect(names()).To(ConsistOf("a/x", "b/y"))
+	g.Expect(removed).To(Equal([]string{"c/z"}))
+}
+
+func Test_Namespaces_subscribersMayCallBack(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	s.Kubernetes.Namespaces = []string{"a"}
+	namespaces, err := NewNamespaces(&s, fake.NewClientset(), 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	var contained []bool
+	namespaces.Subscribe(NamespacesSubscriber{
+		OnAdded: func(namespace string) {
+			contained = append(contained, namespaces.Contains(namespace))
+		},
+	})
+	g.Expect(namespaces.Init(support.NewChannel())).To(Succeed())
+	g.Expect(contained).To(Equal([]bool{true}))
+}
+
+func mustObjectReferenceOf(g *WithT, of support.ObjectReferenceSource) support.ObjectReference {
+	result, err := support.NewObjectReferenceOf(of)
+	g.Expect(err).To(BeNil())
+	return result
+}
+
+func Test_Namespaces_addedNamespaceWaitsForDependencies(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "a"}}
+	client := fake.NewClientset(
+		namespace,
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "backend"}},
+		&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "app"}},
+	)
+	// The Services of the namespace are listed only after release is closed.
+	release := make(chan struct{})
+	client.PrependReactor("list", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		<-release
+		return false, nil, nil
+	})
+
+	s := settings.MustNew()
+	s.Kubernetes.NamespaceSelector = "team=x"
+	namespaces, err := NewNamespaces(&s, client, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(namespaces.Init(stop)).To(Succeed())
+
+	service, err := NewService(client, namespaces, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(service.Init(stop)).To(Succeed())
+	instance, err := NewIngress(client, namespaces, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	instance.dependOn(service.Definition)
+	var serviceKnown []bool
+	var serviceKnownMutex sync.Mutex
+	instance.OnElementAdded = func(support.ObjectReference, metav1.Object) error {
+		found, err := service.Get("a/backend")
+		serviceKnownMutex.Lock()
+		defer serviceKnownMutex.Unlock()
+		serviceKnown = append(serviceKnown, err == nil && found != nil)
+		return nil
+	}
+	g.Expect(instance.Init(stop)).To(Succeed())
+	added := func() []bool {
+		serviceKnownMutex.Lock()
+		defer serviceKnownMutex.Unlock()
+		return append([]bool(nil), serviceKnown...)
+	}
+
+	// The namespace starts matching after Init...
+	namespace.Labels = map[string]string{"team": "x"}
+	g.Expect(namespaces.onElementUpdated(mustObjectReferenceOf(g, namespace), nil, namespace)).To(Succeed())
+
+	// ... but its Ingresses are not reported before its Services are known.
+	g.Consistently(added, "300ms").Should(BeEmpty())
+	g.Expect(instance.HasSynced()).To(BeFalse())
+	close(release)
+	g.Eventually(added).Should(Equal([]bool{true}))
+	g.Eventually(instance.HasSynced).Should(BeTrue())
+}
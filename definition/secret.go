@@ -0,0 +1,75 @@
+package definition
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/settings"
+	log "github.com/echocat/slf4g"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"strings"
+	"time"
+)
+
+const lastAppliedConfigurationAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+type Secret struct {
+	*Definition
+}
+
+// NewSecret watches only the secrets which are matching
+// kubernetes.secretLabelSelector and kubernetes.secretFieldSelector (by
+// default all but the types which are never referenced by an Ingress) to not
+// keep every secret of the cluster in memory.
+func NewSecret(s *settings.Settings, client kubernetes.Interface, namespaces *Namespaces, resyncAfter time.Duration, logger log.Logger) (*Secret, error) {
+	newInformer := func(namespace string) cache.SharedInformer {
+		informerFactory := informers.NewSharedInformerFactoryWithOptions(
+			client,
+			resyncAfter,
+			informers.WithNamespace(namespace),
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.LabelSelector = strings.Join(s.Kubernetes.SecretLabelSelector, ",")
+				options.FieldSelector = strings.Join(s.Kubernetes.GetSecretFieldSelector(), ",")
+			}),
+			informers.WithTransform(stripSecret),
+		)
+		return informerFactory.Core().V1().Secrets().Informer()
+	}
+	if definition, err := newNamespacedDefinition("secret", namespaces, newInformer, logger); err != nil {
+		return nil, err
+	} else {
+		return &Secret{
+			Definition: definition,
+		}, nil
+	}
+}
+
+// stripSecret removes everything from the secret which is not required but
+// would be kept in memory as long as the secret exists.
+func stripSecret(obj interface{}) (interface{}, error) {
+	if secret, ok := obj.(*v1.Secret); ok {
+		secret.ManagedFields = nil
+		if _, ok := secret.Annotations[lastAppliedConfigurationAnnotation]; ok {
+			annotations := make(map[string]string, len(secret.Annotations)-1)
+			for key, value := range secret.Annotations {
+				if key != lastAppliedConfigurationAnnotation {
+					annotations[key] = value
+				}
+			}
+			secret.Annotations = annotations
+		}
+	}
+	return obj, nil
+}
+
+func (this *Secret) Get(key string) (*v1.Secret, error) {
+	if item, exists, err := this.getByKey(key); err != nil {
+		return nil, fmt.Errorf("cannot get secret %s from cache: %v", key, err)
+	} else if !exists {
+		return nil, nil
+	} else {
+		return item.(*v1.Secret), nil
+	}
+}
@@ -0,0 +1,97 @@
+package definition
+
+import (
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sync"
+	"testing"
+)
+
+func Test_Secret_scoped(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	client := fake.NewClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:     "a",
+			Name:          "tls",
+			Labels:        map[string]string{"lingress": "true"},
+			Annotations:   map[string]string{"foo": "bar", lastAppliedConfigurationAnnotation: "{}"},
+			ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+		},
+		Type: v1.SecretTypeTLS,
+	})
+	var listed []metav1.ListOptions
+	var listedMutex sync.Mutex
+	client.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		listedMutex.Lock()
+		defer listedMutex.Unlock()
+		restrictions := action.(k8stesting.ListAction).GetListRestrictions()
+		listed = append(listed, metav1.ListOptions{
+			LabelSelector: restrictions.Labels.String(),
+			FieldSelector: restrictions.Fields.String(),
+		})
+		return false, nil, nil
+	})
+
+	s := settings.MustNew()
+	s.Kubernetes.SecretLabelSelector = []string{"lingress=true"}
+	instance, err := NewSecret(&s, client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(instance.Init(stop)).To(Succeed())
+
+	listedMutex.Lock()
+	g.Expect(listed).To(HaveLen(1))
+	g.Expect(listed[0].LabelSelector).To(Equal("lingress=true"))
+	g.Expect(listed[0].FieldSelector).To(ContainSubstring("type!=helm.sh/release.v1"))
+	g.Expect(listed[0].FieldSelector).To(ContainSubstring("type!=kubernetes.io/service-account-token"))
+	listedMutex.Unlock()
+
+	secret, err := instance.Get("a/tls")
+	g.Expect(err).To(BeNil())
+	g.Expect(secret).NotTo(BeNil())
+	g.Expect(secret.ManagedFields).To(BeNil())
+	g.Expect(secret.Annotations).To(Equal(map[string]string{"foo": "bar"}))
+}
+
+func Test_Secret_fieldSelector(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fieldSelectorOf := func(configured ...string) string {
+		stop := support.NewChannel()
+		defer stop.Broadcast()
+
+		client := fake.NewClientset()
+		var fieldSelector string
+		var fieldSelectorMutex sync.Mutex
+		client.PrependReactor("list", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			fieldSelectorMutex.Lock()
+			defer fieldSelectorMutex.Unlock()
+			fieldSelector = action.(k8stesting.ListAction).GetListRestrictions().Fields.String()
+			return false, nil, nil
+		})
+
+		s := settings.MustNew()
+		s.Kubernetes.SecretFieldSelector = configured
+		instance, err := NewSecret(&s, client, nil, 0, log.GetRootLogger())
+		g.Expect(err).To(BeNil())
+		g.Expect(instance.Init(stop)).To(Succeed())
+
+		fieldSelectorMutex.Lock()
+		defer fieldSelectorMutex.Unlock()
+		return fieldSelector
+	}
+
+	g.Expect(fieldSelectorOf()).To(ContainSubstring("type!=helm.sh/release.v1"))
+	g.Expect(fieldSelectorOf("type=kubernetes.io/tls")).To(Equal("type=kubernetes.io/tls"))
+	g.Expect(fieldSelectorOf("")).To(Equal(""))
+}
@@ -39,11 +39,17 @@ func (this *ServiceSecret) SetNamespace(namespace string) {
 	this.namespace = namespace
 }
 
+// IsEnabled reports whether any of tls.secretNames, tls.secretNamePatterns,
+// tls.secretLabelSelector or tls.secretFieldSelector was specified.
+func (this *ServiceSecret) IsEnabled() bool {
+	return len(this.settings.Tls.SecretNames) > 0 ||
+		this.settings.Tls.SecretNamePattern != nil ||
+		len(this.settings.Tls.SecretLabelSelector) > 0 ||
+		len(this.settings.Tls.SecretFieldSelector) > 0
+}
+
 func (this *ServiceSecret) Init(stop support.Channel) error {
-	if len(this.settings.Tls.SecretNames) == 0 &&
-		this.settings.Tls.SecretNamePattern == nil &&
-		len(this.settings.Tls.SecretLabelSelector) == 0 &&
-		len(this.settings.Tls.SecretFieldSelector) == 0 {
+	if !this.IsEnabled() {
 		this.Logger.Info("Neither tls.secretNames nor tls.secretNamePatterns nor tls.secretLabelSelector nor tls.secretFieldSelector was specified. No service secret will be evaluated = No service specific TLS certificate will be available.")
 		return nil
 	}
@@ -61,12 +67,22 @@ func (this *ServiceSecret) Init(stop support.Channel) error {
 	return this.Definition.Init(stop)
 }
 
-func (this *ServiceSecret) Get(key string) (*v1.Service, error) {
-	if item, exists, err := this.informer.GetStore().GetByKey(key); err != nil {
+func (this *ServiceSecret) HasSynced() bool {
+	if !this.IsEnabled() {
+		return true
+	}
+	return this.Definition.HasSynced()
+}
+
+func (this *ServiceSecret) Get(key string) (*v1.Secret, error) {
+	if !this.IsEnabled() {
+		return nil, nil
+	}
+	if item, exists, err := this.getByKey(key); err != nil {
 		return nil, fmt.Errorf("cannot get secrets %s from cache: %v", key, err)
 	} else if !exists {
 		return nil, nil
 	} else {
-		return item.(*v1.Service), nil
+		return item.(*v1.Secret), nil
 	}
 }
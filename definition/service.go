@@ -6,6 +6,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"time"
 )
 
@@ -13,10 +14,12 @@ type Service struct {
 	*Definition
 }
 
-func NewService(client kubernetes.Interface, resyncAfter time.Duration, logger log.Logger) (*Service, error) {
-	informerFactory := informers.NewSharedInformerFactory(client, resyncAfter)
-	informer := informerFactory.Core().V1().Services().Informer()
-	if definition, err := newDefinition("service", informer, logger); err != nil {
+func NewService(client kubernetes.Interface, namespaces *Namespaces, resyncAfter time.Duration, logger log.Logger) (*Service, error) {
+	newInformer := func(namespace string) cache.SharedInformer {
+		informerFactory := informers.NewSharedInformerFactoryWithOptions(client, resyncAfter, informers.WithNamespace(namespace))
+		return informerFactory.Core().V1().Services().Informer()
+	}
+	if definition, err := newNamespacedDefinition("service", namespaces, newInformer, logger); err != nil {
 		return nil, err
 	} else {
 		return &Service{
@@ -26,7 +29,7 @@ func NewService(client kubernetes.Interface, resyncAfter time.Duration, logger l
 }
 
 func (this *Service) Get(key string) (*v1.Service, error) {
-	if item, exists, err := this.informer.GetStore().GetByKey(key); err != nil {
+	if item, exists, err := this.getByKey(key); err != nil {
 		return nil, fmt.Errorf("cannot get service %s from cache: %v", key, err)
 	} else if !exists {
 		return nil, nil
@@ -0,0 +1,95 @@
+package fallback
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/i18n"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// grpcStatus is a status code of the gRPC protocol.
+// See https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+type grpcStatus uint8
+
+const (
+	grpcStatusCancelled         grpcStatus = 1
+	grpcStatusUnknown           grpcStatus = 2
+	grpcStatusInvalidArgument   grpcStatus = 3
+	grpcStatusDeadlineExceeded  grpcStatus = 4
+	grpcStatusPermissionDenied  grpcStatus = 7
+	grpcStatusResourceExhausted grpcStatus = 8
+	grpcStatusUnimplemented     grpcStatus = 12
+	grpcStatusInternal          grpcStatus = 13
+	grpcStatusUnavailable       grpcStatus = 14
+	grpcStatusUnauthenticated   grpcStatus = 16
+)
+
+var (
+	resultToGrpcStatus = map[context.Result]grpcStatus{
+		context.ResultUnknown:                       grpcStatusUnknown,
+		context.ResultFailedWithUnexpectedError:     grpcStatusInternal,
+		context.ResultFailedWithRuleNotFound:        grpcStatusUnimplemented,
+		context.ResultFailedWithUpstreamUnavailable: grpcStatusUnavailable,
+		context.ResultFailedWithAccessDenied:        grpcStatusPermissionDenied,
+		context.ResultFailedWithUnauthorized:        grpcStatusUnauthenticated,
+		context.ResultFailedWithClientGone:          grpcStatusCancelled,
+		context.ResultFailedWithIllegalHost:         grpcStatusInvalidArgument,
+		context.ResultFailedWithUpstreamTimeout:     grpcStatusDeadlineExceeded,
+		context.ResultFailedWithTooManyRequests:     grpcStatusResourceExhausted,
+	}
+)
+
+// grpcStatusOf maps the given result to a gRPC status. If the result is not
+// known the HTTP status code is mapped as described in
+// https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+func grpcStatusOf(result context.Result, statusCode int) grpcStatus {
+	if v, ok := resultToGrpcStatus[result]; ok {
+		return v
+	}
+	switch statusCode {
+	case http.StatusBadRequest:
+		return grpcStatusInternal
+	case http.StatusUnauthorized:
+		return grpcStatusUnauthenticated
+	case http.StatusForbidden:
+		return grpcStatusPermissionDenied
+	case http.StatusNotFound:
+		return grpcStatusUnimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return grpcStatusUnavailable
+	default:
+		return grpcStatusUnknown
+	}
+}
+
+func isGrpcRequest(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc")
+}
+
+// StatusAsGrpc responds with a trailers-only response which contains the
+// gRPC status inside the headers. This is understood by gRPC and gRPC-Web
+// clients.
+func (this *Fallback) StatusAsGrpc(ctx *context.Context, statusCode int, _ string, _ bool, lc *i18n.LocalizationContext) {
+	h := ctx.Client.Response.Header()
+	h.Set("Content-Type", ctx.Client.Request.Header.Get("Content-Type"))
+	h.Set("Grpc-Status", strconv.Itoa(int(grpcStatusOf(ctx.Result, statusCode))))
+	h.Set("Grpc-Message", encodeGrpcMessage(localizeStatus(statusCode, lc)))
+	h.Del("Content-Length")
+	ctx.Client.Response.WriteHeader(http.StatusOK)
+}
+
+// encodeGrpcMessage percent encodes all characters of the given message
+// which are not allowed inside the grpc-message header.
+func encodeGrpcMessage(message string) string {
+	var result strings.Builder
+	for i := 0; i < len(message); i++ {
+		if c := message[i]; c >= ' ' && c <= '~' && c != '%' {
+			result.WriteByte(c)
+		} else {
+			result.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return result.String()
+}
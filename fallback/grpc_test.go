@@ -0,0 +1,25 @@
+package fallback
+
+import (
+	"github.com/echocat/lingress/context"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"testing"
+)
+
+func Test_grpcStatusOf(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(grpcStatusOf(context.ResultFailedWithUpstreamUnavailable, http.StatusServiceUnavailable)).To(Equal(grpcStatusUnavailable))
+	g.Expect(grpcStatusOf(context.ResultFailedWithUpstreamTimeout, http.StatusGatewayTimeout)).To(Equal(grpcStatusDeadlineExceeded))
+	g.Expect(grpcStatusOf(context.ResultFailedWithRuleNotFound, http.StatusNotFound)).To(Equal(grpcStatusUnimplemented))
+	g.Expect(grpcStatusOf(nil, http.StatusForbidden)).To(Equal(grpcStatusPermissionDenied))
+	g.Expect(grpcStatusOf(nil, http.StatusTeapot)).To(Equal(grpcStatusUnknown))
+}
+
+func Test_encodeGrpcMessage(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(encodeGrpcMessage("Service unavailable")).To(Equal("Service unavailable"))
+	g.Expect(encodeGrpcMessage("100% nicht verfügbar")).To(Equal("100%25 nicht verf%C3%BCgbar"))
+}
@@ -16,6 +16,10 @@ func (this *Fallback) Status(ctx *context.Context, statusCode int, path string,
 		return
 	}
 	lc := newLocationContextForCtx(ctx, this.Bundle)
+	if isGrpcRequest(ctx.Client.Request) {
+		this.StatusAsGrpc(ctx, statusCode, path, canHandleTemporary, lc)
+		return
+	}
 	switch support.NegotiateContentTypeOf(ctx.Client.Request, "application/x-yaml", "application/xml", "application/json", "text/html", "text/plain") {
 	case "application/json":
 		this.StatusAsJson(ctx, statusCode, path, canHandleTemporary, lc)
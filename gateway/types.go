@@ -0,0 +1,323 @@
+// Package gateway contains the subset of the Gateway API
+// (gateway.networking.k8s.io/v1) which is understood by lingress. The objects
+// are watched using the dynamic client and converted from their unstructured
+// representation into these types.
+package gateway
+
+import (
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	Group = "gateway.networking.k8s.io"
+
+	KindGateway = "Gateway"
+	KindService = "Service"
+
+	ProtocolHTTP  = "HTTP"
+	ProtocolHTTPS = "HTTPS"
+
+	NamespacesFromAll  = "All"
+	NamespacesFromSame = "Same"
+
+	PathMatchExact             = "Exact"
+	PathMatchPathPrefix        = "PathPrefix"
+	PathMatchRegularExpression = "RegularExpression"
+
+	MatchExact             = "Exact"
+	MatchRegularExpression = "RegularExpression"
+
+	FilterRequestHeaderModifier  = "RequestHeaderModifier"
+	FilterResponseHeaderModifier = "ResponseHeaderModifier"
+	FilterRequestRedirect        = "RequestRedirect"
+	FilterURLRewrite             = "URLRewrite"
+
+	FullPathHTTPPathModifier    = "ReplaceFullPath"
+	PrefixMatchHTTPPathModifier = "ReplacePrefixMatch"
+
+	ConditionAccepted     = "Accepted"
+	ConditionResolvedRefs = "ResolvedRefs"
+
+	ReasonAccepted           = "Accepted"
+	ReasonResolvedRefs       = "ResolvedRefs"
+	ReasonNoMatchingListener = "NoMatchingListenerHostname"
+	ReasonNotAllowed         = "NotAllowedByListeners"
+	ReasonUnsupportedValue   = "UnsupportedValue"
+	ReasonBackendNotFound    = "BackendNotFound"
+	ReasonInvalidKind        = "InvalidKind"
+	ReasonRefNotPermitted    = "RefNotPermitted"
+
+	defaultRequestRedirectCode    = 302
+	defaultBackendWeight          = int32(1)
+	defaultHTTPRouteMatchPath     = "/"
+	defaultHTTPRouteMatchPathType = PathMatchPathPrefix
+)
+
+var (
+	GatewayClassesResource = schema.GroupVersionResource{Group: Group, Version: "v1", Resource: "gatewayclasses"}
+	GatewaysResource       = schema.GroupVersionResource{Group: Group, Version: "v1", Resource: "gateways"}
+	HTTPRoutesResource     = schema.GroupVersionResource{Group: Group, Version: "v1", Resource: "httproutes"}
+)
+
+type GatewayClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GatewayClassSpec `json:"spec"`
+}
+
+type GatewayClassSpec struct {
+	ControllerName string `json:"controllerName"`
+}
+
+type Gateway struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GatewaySpec `json:"spec"`
+}
+
+type GatewaySpec struct {
+	GatewayClassName string     `json:"gatewayClassName"`
+	Listeners        []Listener `json:"listeners,omitempty"`
+}
+
+type Listener struct {
+	Name          string         `json:"name"`
+	Hostname      *string        `json:"hostname,omitempty"`
+	Port          int32          `json:"port"`
+	Protocol      string         `json:"protocol"`
+	AllowedRoutes *AllowedRoutes `json:"allowedRoutes,omitempty"`
+}
+
+// IsHttp reports whether this listener accepts HTTPRoutes at all.
+func (this Listener) IsHttp() bool {
+	return this.Protocol == ProtocolHTTP || this.Protocol == ProtocolHTTPS
+}
+
+// AllowsNamespace reports whether routes of the given namespace are allowed to
+// attach to this listener of a Gateway inside gatewayNamespace. Selector based
+// namespace restrictions are not supported and will never allow any route.
+func (this Listener) AllowsNamespace(gatewayNamespace, routeNamespace string) bool {
+	from := NamespacesFromSame
+	if v := this.AllowedRoutes; v != nil && v.Namespaces != nil {
+		from = stringOr(v.Namespaces.From, NamespacesFromSame)
+	}
+	switch from {
+	case NamespacesFromAll:
+		return true
+	case NamespacesFromSame:
+		return gatewayNamespace == routeNamespace
+	default:
+		return false
+	}
+}
+
+type AllowedRoutes struct {
+	Namespaces *RouteNamespaces `json:"namespaces,omitempty"`
+}
+
+type RouteNamespaces struct {
+	From *string `json:"from,omitempty"`
+}
+
+type HTTPRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HTTPRouteSpec   `json:"spec"`
+	Status HTTPRouteStatus `json:"status,omitempty"`
+}
+
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []HTTPRouteRule   `json:"rules,omitempty"`
+}
+
+type ParentReference struct {
+	Group       *string `json:"group,omitempty"`
+	Kind        *string `json:"kind,omitempty"`
+	Namespace   *string `json:"namespace,omitempty"`
+	Name        string  `json:"name"`
+	SectionName *string `json:"sectionName,omitempty"`
+	Port        *int32  `json:"port,omitempty"`
+}
+
+// IsGateway reports whether this reference points to a Gateway (which is the
+// default if neither group nor kind are specified).
+func (this ParentReference) IsGateway() bool {
+	return stringOr(this.Group, Group) == Group &&
+		stringOr(this.Kind, KindGateway) == KindGateway
+}
+
+// NamespaceOr returns the namespace of this reference or def if it is not
+// explicitly specified.
+func (this ParentReference) NamespaceOr(def string) string {
+	return stringOr(this.Namespace, def)
+}
+
+type HTTPRouteRule struct {
+	Matches     []HTTPRouteMatch  `json:"matches,omitempty"`
+	Filters     []HTTPRouteFilter `json:"filters,omitempty"`
+	BackendRefs []HTTPBackendRef  `json:"backendRefs,omitempty"`
+}
+
+type HTTPRouteMatch struct {
+	Path        *HTTPPathMatch        `json:"path,omitempty"`
+	Headers     []HTTPHeaderMatch     `json:"headers,omitempty"`
+	QueryParams []HTTPQueryParamMatch `json:"queryParams,omitempty"`
+	Method      *string               `json:"method,omitempty"`
+}
+
+type HTTPPathMatch struct {
+	Type  *string `json:"type,omitempty"`
+	Value *string `json:"value,omitempty"`
+}
+
+// TypeOrDefault returns the type of this match; PathPrefix if not specified.
+func (this *HTTPPathMatch) TypeOrDefault() string {
+	if this == nil {
+		return defaultHTTPRouteMatchPathType
+	}
+	return stringOr(this.Type, defaultHTTPRouteMatchPathType)
+}
+
+// ValueOrDefault returns the value of this match; / if not specified.
+func (this *HTTPPathMatch) ValueOrDefault() string {
+	if this == nil {
+		return defaultHTTPRouteMatchPath
+	}
+	return stringOr(this.Value, defaultHTTPRouteMatchPath)
+}
+
+type HTTPHeaderMatch struct {
+	Type  *string `json:"type,omitempty"`
+	Name  string  `json:"name"`
+	Value string  `json:"value"`
+}
+
+type HTTPQueryParamMatch struct {
+	Type  *string `json:"type,omitempty"`
+	Name  string  `json:"name"`
+	Value string  `json:"value"`
+}
+
+type HTTPRouteFilter struct {
+	Type                   string                     `json:"type"`
+	RequestHeaderModifier  *HTTPHeaderFilter          `json:"requestHeaderModifier,omitempty"`
+	ResponseHeaderModifier *HTTPHeaderFilter          `json:"responseHeaderModifier,omitempty"`
+	RequestRedirect        *HTTPRequestRedirectFilter `json:"requestRedirect,omitempty"`
+	URLRewrite             *HTTPURLRewriteFilter      `json:"urlRewrite,omitempty"`
+}
+
+type HTTPHeaderFilter struct {
+	Set    []HTTPHeader `json:"set,omitempty"`
+	Add    []HTTPHeader `json:"add,omitempty"`
+	Remove []string     `json:"remove,omitempty"`
+}
+
+type HTTPHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HTTPRequestRedirectFilter struct {
+	Scheme     *string           `json:"scheme,omitempty"`
+	Hostname   *string           `json:"hostname,omitempty"`
+	Path       *HTTPPathModifier `json:"path,omitempty"`
+	Port       *int32            `json:"port,omitempty"`
+	StatusCode *int              `json:"statusCode,omitempty"`
+}
+
+// StatusCodeOrDefault returns the status code of this redirect; 302 if not
+// specified.
+func (this HTTPRequestRedirectFilter) StatusCodeOrDefault() int {
+	if v := this.StatusCode; v != nil {
+		return *v
+	}
+	return defaultRequestRedirectCode
+}
+
+type HTTPURLRewriteFilter struct {
+	Hostname *string           `json:"hostname,omitempty"`
+	Path     *HTTPPathModifier `json:"path,omitempty"`
+}
+
+type HTTPPathModifier struct {
+	Type               string  `json:"type"`
+	ReplaceFullPath    *string `json:"replaceFullPath,omitempty"`
+	ReplacePrefixMatch *string `json:"replacePrefixMatch,omitempty"`
+}
+
+type HTTPBackendRef struct {
+	Group     *string           `json:"group,omitempty"`
+	Kind      *string           `json:"kind,omitempty"`
+	Name      string            `json:"name"`
+	Namespace *string           `json:"namespace,omitempty"`
+	Port      *int32            `json:"port,omitempty"`
+	Weight    *int32            `json:"weight,omitempty"`
+	Filters   []HTTPRouteFilter `json:"filters,omitempty"`
+}
+
+// IsService reports whether this reference points to a Service (which is the
+// default if neither group nor kind are specified).
+func (this HTTPBackendRef) IsService() bool {
+	return stringOr(this.Group, "") == "" &&
+		stringOr(this.Kind, KindService) == KindService
+}
+
+// WeightOrDefault returns the weight of this reference; 1 if not specified.
+func (this HTTPBackendRef) WeightOrDefault() int32 {
+	if v := this.Weight; v != nil {
+		return *v
+	}
+	return defaultBackendWeight
+}
+
+type HTTPRouteStatus struct {
+	Parents []RouteParentStatus `json:"parents,omitempty"`
+}
+
+type RouteParentStatus struct {
+	ParentRef      ParentReference    `json:"parentRef"`
+	ControllerName string             `json:"controllerName"`
+	Conditions     []metav1.Condition `json:"conditions,omitempty"`
+}
+
+func GatewayClassOf(in metav1.Object) (*GatewayClass, error) {
+	result := &GatewayClass{}
+	return result, fromUnstructured(in, result)
+}
+
+func GatewayOf(in metav1.Object) (*Gateway, error) {
+	result := &Gateway{}
+	return result, fromUnstructured(in, result)
+}
+
+func HTTPRouteOf(in metav1.Object) (*HTTPRoute, error) {
+	result := &HTTPRoute{}
+	return result, fromUnstructured(in, result)
+}
+
+func fromUnstructured(in metav1.Object, target any) error {
+	u, ok := in.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("expected an unstructured object but got %T", in)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, target); err != nil {
+		return fmt.Errorf("cannot convert %s %s/%s: %w", u.GetKind(), u.GetNamespace(), u.GetName(), err)
+	}
+	return nil
+}
+
+func stringOr(v *string, def string) string {
+	if v != nil && *v != "" {
+		return *v
+	}
+	return def
+}
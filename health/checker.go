@@ -0,0 +1,251 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/support"
+	ltls "github.com/echocat/lingress/tls"
+	"github.com/echocat/slf4g"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Checker actively probes all endpoints of rules which are having a
+// health-check path configured and marks them as unhealthy (or healthy again)
+// based on the configured thresholds. Endpoints are probed using the backend
+// protocol of the rule; rules which are using the service as upstream are
+// not probed because their endpoints do not receive any requests.
+type Checker struct {
+	Rules          rules.Repository
+	Transport      http.RoundTripper
+	Http2Transport http.RoundTripper
+	Logger         log.Logger
+
+	// Resolution is the interval in which it will be checked which of the
+	// endpoints are due to be probed.
+	Resolution time.Duration
+
+	targets      map[targetKey]*target
+	targetsMutex sync.Mutex
+}
+
+type targetKey struct {
+	endpoint *rules.Endpoint
+	path     string
+	protocol value.BackendProtocol
+}
+
+type target struct {
+	endpoint *rules.Endpoint
+	protocol value.BackendProtocol
+	options  rules.OptionsHealthCheck
+	next     time.Time
+
+	running   int32
+	successes uint32
+	failures  uint32
+	// state is the result of the probes of this target according to the
+	// thresholds; see targetState*.
+	state int32
+}
+
+const (
+	// targetStateUnknown means there were not enough probes yet.
+	targetStateUnknown int32 = iota
+	targetStateHealthy
+	targetStateUnhealthy
+)
+
+func New(rulesRepository rules.Repository, logger log.Logger) (*Checker, error) {
+	http2Transport := &http.Transport{
+		DisableKeepAlives: true,
+		TLSClientConfig: &tls.Config{
+			RootCAs: ltls.Pool,
+		},
+		Protocols: new(http.Protocols),
+	}
+	http2Transport.Protocols.SetUnencryptedHTTP2(true)
+	http2Transport.Protocols.SetHTTP2(true)
+	return &Checker{
+		Rules: rulesRepository,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig: &tls.Config{
+				RootCAs: ltls.Pool,
+			},
+		},
+		Http2Transport: http2Transport,
+		Logger:         logger,
+		Resolution:     time.Second,
+		targets:        map[targetKey]*target{},
+	}, nil
+}
+
+func (this *Checker) Init(stop support.Channel) error {
+	go this.run(stop)
+	return nil
+}
+
+func (this *Checker) run(stop support.Channel) {
+	ticker := time.NewTicker(this.Resolution)
+	defer ticker.Stop()
+
+	stopCh := support.ToChan(stop)
+	for {
+		select {
+		case now := <-ticker.C:
+			this.check(now)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (this *Checker) check(now time.Time) {
+	this.targetsMutex.Lock()
+	defer this.targetsMutex.Unlock()
+
+	seen := map[targetKey]bool{}
+	seenEndpoints := map[*rules.Endpoint]bool{}
+	if err := this.Rules.All(func(r rules.Rule) error {
+		opts := rules.OptionsHealthCheckOf(r)
+		if !opts.IsActive() || rules.OptionsLoadBalancerOf(r).ServiceUpstream.GetOr(false) {
+			return nil
+		}
+		protocol := rules.OptionsUpstreamOf(r).Protocol.Get()
+		for _, endpoint := range r.Endpoints().All() {
+			key := targetKey{endpoint, opts.Path, protocol}
+			seen[key] = true
+			seenEndpoints[endpoint] = true
+			if existing, ok := this.targets[key]; ok {
+				existing.options = *opts
+			} else {
+				this.targets[key] = &target{
+					endpoint: endpoint,
+					protocol: protocol,
+					options:  *opts,
+					next:     now,
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		this.Logger.
+			WithError(err).
+			Warn("Cannot evaluate rules for health checks; ignoring...")
+		return
+	}
+
+	for key, candidate := range this.targets {
+		if !seen[key] {
+			delete(this.targets, key)
+			if !seenEndpoints[key.endpoint] {
+				// Nobody is checking this endpoint anymore.
+				key.endpoint.SetHealthy(true)
+			} else {
+				// The remaining checks of this endpoint decide alone.
+				this.evaluateEndpoint(key.endpoint)
+			}
+			continue
+		}
+		if candidate.next.After(now) || !atomic.CompareAndSwapInt32(&candidate.running, 0, 1) {
+			continue
+		}
+		candidate.next = now.Add(candidate.options.GetInterval())
+		go this.probe(candidate, candidate.options)
+	}
+}
+
+func (this *Checker) probe(t *target, opts rules.OptionsHealthCheck) {
+	defer atomic.StoreInt32(&t.running, 0)
+
+	l := this.Logger.
+		With("endpoint", t.endpoint.Address).
+		With("path", opts.Path).
+		With("protocol", t.protocol)
+
+	err := this.execute(t.endpoint, t.protocol, opts)
+	if err == nil {
+		t.failures = 0
+		t.successes++
+		if t.successes >= opts.GetHealthyThreshold() {
+			atomic.StoreInt32(&t.state, targetStateHealthy)
+		}
+	} else {
+		t.successes = 0
+		t.failures++
+		if t.failures >= opts.GetUnhealthyThreshold() {
+			atomic.StoreInt32(&t.state, targetStateUnhealthy)
+		}
+	}
+
+	this.targetsMutex.Lock()
+	healthy, changed := this.evaluateEndpoint(t.endpoint)
+	this.targetsMutex.Unlock()
+
+	if changed && healthy {
+		l.Info("Endpoint is healthy again.")
+	} else if changed {
+		l.WithError(err).Warn("Endpoint is unhealthy and will not receive requests anymore.")
+	} else if err != nil {
+		l.WithError(err).Debug("Health check of endpoint failed.")
+	}
+}
+
+// evaluateEndpoint combines the states of all targets of the given endpoint,
+// which exist if several rules are sharing the same endpoints but are using
+// different health checks: it is unhealthy if any of them is unhealthy. If
+// none of them is decided yet, the endpoint stays as it is. It returns the
+// resulting health and whether it was changed. targetsMutex has to be held.
+func (this *Checker) evaluateEndpoint(endpoint *rules.Endpoint) (healthy bool, changed bool) {
+	decided := false
+	for key, candidate := range this.targets {
+		if key.endpoint != endpoint {
+			continue
+		}
+		switch atomic.LoadInt32(&candidate.state) {
+		case targetStateUnhealthy:
+			return false, endpoint.SetHealthy(false)
+		case targetStateHealthy:
+			decided = true
+		}
+	}
+	if !decided {
+		return endpoint.IsHealthy(), false
+	}
+	return true, endpoint.SetHealthy(true)
+}
+
+func (this *Checker) execute(endpoint *rules.Endpoint, protocol value.BackendProtocol, opts rules.OptionsHealthCheck) error {
+	ctx, cancel := context.WithTimeout(context.Background(), opts.GetTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, protocol.Scheme()+"://"+endpoint.Address.String()+opts.Path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "lingress-health-check")
+
+	transport := this.Transport
+	if protocol.IsHttp2() {
+		transport = this.Http2Transport
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	//noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
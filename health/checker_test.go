@@ -0,0 +1,129 @@
+package health
+
+import (
+	"github.com/echocat/lingress/rules"
+	rvalue "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Checker_check(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var probes int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		resp.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ruleFor := func(protocol rvalue.BackendProtocol, serviceUpstream bool) (rules.Rule, *rules.Endpoint) {
+		endpoints := rules.NewEndpoints(server.Listener.Addr().(*net.TCPAddr))
+		r := rules.NewRule("app.example.org", []string{}, rules.PathTypePrefix, support.NewObjectReference("networking.k8s.io/v1", "Ingress", "foo", "app"), server.Listener.Addr(), endpoints, rules.DefaultOptionsFactory())
+		rules.OptionsHealthCheckOf(r).Path = "/health"
+		rules.OptionsHealthCheckOf(r).HealthyThreshold = value.NewUint32(1)
+		rules.OptionsUpstreamOf(r).Protocol = protocol
+		if serviceUpstream {
+			rules.OptionsLoadBalancerOf(r).ServiceUpstream = value.True()
+		}
+		endpoint := endpoints.All()[0]
+		endpoint.SetHealthy(false)
+		return r, endpoint
+	}
+
+	instance, err := New(&staticRepository{}, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	instance.Transport = server.Client().Transport
+
+	// Endpoints of the rule are probed using the scheme of its backend protocol.
+	r, endpoint := ruleFor(rvalue.BackendProtocolHttps, false)
+	instance.Rules = &staticRepository{r}
+	instance.check(time.Now())
+	g.Eventually(endpoint.IsHealthy).Should(BeTrue())
+	g.Expect(atomic.LoadInt32(&probes)).To(Equal(int32(1)))
+
+	// Via plain http the TLS server responds with 400.
+	r, endpoint = ruleFor(rvalue.BackendProtocolHttp, false)
+	instance.Rules = &staticRepository{r}
+	instance.check(time.Now())
+	g.Expect(instance.targets).To(HaveLen(1))
+	g.Consistently(endpoint.IsHealthy, 200*time.Millisecond).Should(BeFalse())
+
+	// The service is the upstream; its endpoints are not probed.
+	r, _ = ruleFor(rvalue.BackendProtocolHttps, true)
+	instance.Rules = &staticRepository{r}
+	instance.check(time.Now())
+	g.Expect(instance.targets).To(BeEmpty())
+	g.Consistently(func() int32 { return atomic.LoadInt32(&probes) }, 200*time.Millisecond).Should(Equal(int32(1)))
+}
+
+func Test_Checker_check_combinesChecksOfSharedEndpoints(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/bad" {
+			resp.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// Both rules are pointing to the same endpoints but are checking them
+	// using different paths.
+	endpoints := rules.NewEndpoints(server.Listener.Addr().(*net.TCPAddr))
+	ruleFor := func(name, path string) rules.Rule {
+		r := rules.NewRule(value.WildcardSupportingFqdn(name+".example.org"), []string{}, rules.PathTypePrefix, support.NewObjectReference("networking.k8s.io/v1", "Ingress", "foo", name), server.Listener.Addr(), endpoints, rules.DefaultOptionsFactory())
+		rules.OptionsHealthCheckOf(r).Path = path
+		rules.OptionsHealthCheckOf(r).HealthyThreshold = value.NewUint32(1)
+		rules.OptionsHealthCheckOf(r).UnhealthyThreshold = value.NewUint32(1)
+		return r
+	}
+	endpoint := endpoints.All()[0]
+
+	instance, err := New(&staticRepository{ruleFor("good", "/good"), ruleFor("bad", "/bad")}, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		instance.check(now.Add(time.Duration(i) * time.Hour))
+		g.Expect(instance.targets).To(HaveLen(2))
+		for _, candidate := range instance.targets {
+			g.Eventually(func() int32 { return atomic.LoadInt32(&candidate.running) }).Should(Equal(int32(0)))
+		}
+		g.Expect(endpoint.IsHealthy()).To(BeFalse())
+	}
+
+	// Without the failing check the remaining one decides alone.
+	instance.Rules = &staticRepository{ruleFor("good", "/good")}
+	instance.check(now.Add(10 * time.Hour))
+	g.Expect(instance.targets).To(HaveLen(1))
+	g.Expect(endpoint.IsHealthy()).To(BeTrue())
+}
+
+type staticRepository []rules.Rule
+
+func (this staticRepository) Init(support.Channel) error {
+	return nil
+}
+
+func (this staticRepository) All(consumer func(rules.Rule) error) error {
+	for _, r := range this {
+		if err := consumer(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this staticRepository) FindBy(rules.Query) (rules.Rules, error) {
+	return nil, nil
+}
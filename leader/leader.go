@@ -0,0 +1,192 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"github.com/echocat/lingress/kubernetes"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/slf4g"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Election elects the only instance (of all replicas) which should write back
+// into the cluster. If it is disabled this instance is always the leader.
+type Election struct {
+	settings *settings.Settings
+
+	Environment *kubernetes.Environment
+	Logger      log.Logger
+
+	identity string
+	leading  atomic.Bool
+	elector  *leaderelection.LeaderElector
+
+	subscribers      []Subscriber
+	subscribersMutex sync.Mutex
+}
+
+// Subscriber is notified about each transition of the leadership of this
+// instance. Both functions are optional.
+type Subscriber struct {
+	OnStartedLeading func()
+	OnStoppedLeading func()
+}
+
+func New(s *settings.Settings, environment *kubernetes.Environment, logger log.Logger) (*Election, error) {
+	identity, err := os.Hostname()
+	if err != nil || identity == "" {
+		identity = string(uuid.NewUUID())
+	}
+	return &Election{
+		settings:    s,
+		Environment: environment,
+		Logger:      logger,
+		identity:    identity,
+	}, nil
+}
+
+func (this *Election) IsEnabled() bool {
+	return this.settings.LeaderElection.IsEnabled()
+}
+
+// IsLeader reports whether this instance is currently the leader.
+func (this *Election) IsLeader() bool {
+	return this.leading.Load()
+}
+
+// Identity returns the identity this instance is elected with.
+func (this *Election) Identity() string {
+	return this.identity
+}
+
+// Leader returns the identity of the current leader; empty if unknown.
+func (this *Election) Leader() string {
+	if this.elector != nil {
+		return this.elector.GetLeader()
+	}
+	if this.IsLeader() {
+		return this.identity
+	}
+	return ""
+}
+
+// Subscribe registers the given Subscriber. If this instance is already the
+// leader, OnStartedLeading is called immediately.
+func (this *Election) Subscribe(subscriber Subscriber) {
+	this.subscribersMutex.Lock()
+	this.subscribers = append(this.subscribers, subscriber)
+	leading := this.IsLeader()
+	this.subscribersMutex.Unlock()
+
+	if leading && subscriber.OnStartedLeading != nil {
+		subscriber.OnStartedLeading()
+	}
+}
+
+// setLeading notifies the subscribers if the leadership changed. They are
+// called without holding subscribersMutex; so they are free to subscribe or
+// to do expensive work without blocking other transitions.
+func (this *Election) setLeading(leading bool) {
+	this.subscribersMutex.Lock()
+	if this.leading.Swap(leading) == leading {
+		this.subscribersMutex.Unlock()
+		return
+	}
+	subscribers := slices.Clone(this.subscribers)
+	this.subscribersMutex.Unlock()
+
+	for _, subscriber := range subscribers {
+		if leading && subscriber.OnStartedLeading != nil {
+			subscriber.OnStartedLeading()
+		} else if !leading && subscriber.OnStoppedLeading != nil {
+			subscriber.OnStoppedLeading()
+		}
+	}
+}
+
+func (this *Election) Init(stop support.Channel) error {
+	if !this.IsEnabled() {
+		this.setLeading(true)
+		return nil
+	}
+
+	s := this.settings.LeaderElection
+	client, err := this.Environment.NewClient()
+	if err == nil && this.settings.Kubernetes.Namespace == "" {
+		err = fmt.Errorf("the namespace of lingress is unknown; kubernetes.namespace has to be set to elect a leader")
+	}
+	if err != nil {
+		if s.Enabled.IsPresent() {
+			return err
+		}
+		// Only enabled by default; so we are usually not running inside a
+		// cluster (like while developing) where other instances could exist.
+		this.Logger.
+			WithError(err).
+			Warn("Cannot elect a leader; this instance acts as leader. Set leaderElection.enabled explicitly to prevent this.")
+		this.setLeading(true)
+		return nil
+	}
+	namespace := this.settings.Kubernetes.Namespace
+
+	leaseName := s.LeaseName
+
+	l := this.Logger.
+		With("identity", this.identity).
+		With("lease", namespace+"/"+leaseName)
+
+	this.elector, err = leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      leaseName,
+			},
+			Client: client.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: this.identity,
+			},
+		},
+		LeaseDuration:   s.LeaseDuration,
+		RenewDeadline:   s.RenewDeadline,
+		RetryPeriod:     s.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            leaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				l.Info("Became leader.")
+				this.setLeading(true)
+			},
+			OnStoppedLeading: func() {
+				this.setLeading(false)
+				l.Info("Lost leadership.")
+			},
+			OnNewLeader: func(identity string) {
+				l.With("leader", identity).Debug("Leader elected.")
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create leader election: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	support.ChannelDoOnEvent(stop, cancel)
+	go func() {
+		// Run returns as soon as the leadership is lost; so we try again until
+		// we should stop.
+		for ctx.Err() == nil {
+			this.elector.Run(ctx)
+		}
+	}()
+
+	l.Info("Participating in leader election...")
+	return nil
+}
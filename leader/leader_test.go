@@ -0,0 +1,96 @@
+package leader
+
+import (
+	"github.com/echocat/lingress/kubernetes"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func Test_Election_Subscribe(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	s := settings.MustNew()
+	s.LeaderElection.Enabled = value.False()
+	instance, err := New(&s, nil, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	var transitions []string
+	subscriber := Subscriber{
+		OnStartedLeading: func() { transitions = append(transitions, "started") },
+		OnStoppedLeading: func() { transitions = append(transitions, "stopped") },
+	}
+
+	instance.Subscribe(subscriber)
+	g.Expect(instance.IsLeader()).To(BeFalse())
+	g.Expect(transitions).To(BeEmpty())
+
+	// Without leader election every instance is the leader.
+	g.Expect(instance.Init(stop)).To(Succeed())
+	g.Expect(instance.IsLeader()).To(BeTrue())
+	g.Expect(instance.Leader()).To(Equal(instance.Identity()))
+	g.Expect(transitions).To(Equal([]string{"started"}))
+
+	instance.setLeading(true)
+	instance.setLeading(false)
+	g.Expect(transitions).To(Equal([]string{"started", "stopped"}))
+
+	instance.setLeading(true)
+	instance.Subscribe(Subscriber{OnStartedLeading: func() { transitions = append(transitions, "late") }})
+	g.Expect(transitions).To(Equal([]string{"started", "stopped", "started", "late"}))
+}
+
+func Test_Election_setLeading_callsSubscribersWithoutLock(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	instance, err := New(&s, nil, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	// A subscriber which subscribes another one must not deadlock.
+	late := make(chan struct{})
+	instance.Subscribe(Subscriber{OnStartedLeading: func() {
+		instance.Subscribe(Subscriber{OnStartedLeading: func() { close(late) }})
+	}})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		instance.setLeading(true)
+	}()
+	g.Eventually(done).Should(BeClosed())
+	g.Expect(late).To(BeClosed())
+}
+
+func Test_Election_Init_withoutCluster(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	s := settings.MustNew()
+	g.Expect(s.LeaderElection.IsEnabled()).To(BeTrue())
+
+	g.Expect(s.Kubernetes.Config.Set(settings.KubeconfigMock)).To(Succeed())
+	environment, err := kubernetes.NewEnvironment(&s)
+	g.Expect(err).To(BeNil())
+
+	// Only enabled by default: acts as leader if no Lease could be used.
+	instance, err := New(&s, environment, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(instance.Init(stop)).To(Succeed())
+	g.Expect(instance.IsLeader()).To(BeTrue())
+
+	// Enabled explicitly: fails.
+	s.LeaderElection.Enabled = value.True()
+	instance, err = New(&s, environment, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(instance.Init(stop)).To(HaveOccurred())
+	g.Expect(instance.IsLeader()).To(BeFalse())
+}
@@ -3,9 +3,15 @@ package lingress
 import (
 	"crypto/tls"
 	"fmt"
+	"github.com/echocat/lingress/acme"
+	"github.com/echocat/lingress/admission"
+	"github.com/echocat/lingress/certificates"
 	lctx "github.com/echocat/lingress/context"
 	"github.com/echocat/lingress/fallback"
 	"github.com/echocat/lingress/file/providers"
+	"github.com/echocat/lingress/health"
+	"github.com/echocat/lingress/kubernetes"
+	"github.com/echocat/lingress/leader"
 	"github.com/echocat/lingress/management"
 	"github.com/echocat/lingress/proxy"
 	"github.com/echocat/lingress/rules"
@@ -24,14 +30,21 @@ import (
 type Lingress struct {
 	settings *settings.Settings
 
+	LeaderElection  *leader.Election
 	RulesRepository rules.CombinedRepository
+	Acme            *acme.Acme
+	Admission       *admission.Admission
+	Certificates    *certificates.Monitor
+	HealthChecker   *health.Checker
 	Proxy           *proxy.Proxy
 	Fallback        *fallback.Fallback
 	Management      *management.Management
 	Http            *server.HttpConnector
 	Https           *server.HttpConnector
+	Http3           *server.Http3Connector
 
 	accessLogQueue chan *accessLogEntry
+	altSvc         string
 
 	unprocessableConnectionDocumented map[reflect.Type]bool
 
@@ -50,7 +63,35 @@ func New(s *settings.Settings, fps providers.FileProviders) (*Lingress, error) {
 	logProvider := log.GetProvider()
 
 	connectorIds := []server.ConnectorId{server.DefaultConnectorIdHttp, server.DefaultConnectorIdHttps}
-	r, err := rules.NewRepository(s, logProvider.GetLogger("rules"))
+	if s.Server.Http3.Enabled.Get() {
+		connectorIds = append(connectorIds, server.DefaultConnectorIdHttp3)
+	}
+	environment, err := kubernetes.NewEnvironment(s)
+	if err != nil {
+		return nil, err
+	}
+	le, err := leader.New(s, environment, logProvider.GetLogger("leader"))
+	if err != nil {
+		return nil, err
+	}
+	r, err := rules.NewRepository(s, environment, le, logProvider.GetLogger("rules"))
+	if err != nil {
+		return nil, err
+	}
+	a, err := acme.New(s, r, le, logProvider.GetLogger("acme"))
+	if err != nil {
+		return nil, err
+	}
+	validator, _ := r.(rules.IngressValidator)
+	ad, err := admission.New(s, validator, logProvider.GetLogger("admission"))
+	if err != nil {
+		return nil, err
+	}
+	cm, err := certificates.New(s, r, logProvider.GetLogger("certificates"))
+	if err != nil {
+		return nil, err
+	}
+	hc, err := health.New(r, logProvider.GetLogger("health"))
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +103,7 @@ func New(s *settings.Settings, fps providers.FileProviders) (*Lingress, error) {
 	if err != nil {
 		return nil, err
 	}
-	m, err := management.New(s, connectorIds, r, logProvider.GetLogger("management"))
+	m, err := management.New(s, connectorIds, r, cm, le, logProvider.GetLogger("management"))
 	if err != nil {
 		return nil, err
 	}
@@ -74,16 +115,26 @@ func New(s *settings.Settings, fps providers.FileProviders) (*Lingress, error) {
 	if err != nil {
 		return nil, err
 	}
+	hHttp3, err := server.NewHttp3Connector(s, server.DefaultConnectorIdHttp3, logProvider.GetLogger(string(server.DefaultConnectorIdHttp3)))
+	if err != nil {
+		return nil, err
+	}
 
 	result := &Lingress{
 		settings: s,
 
+		LeaderElection:  le,
 		RulesRepository: r,
+		Acme:            a,
+		Admission:       ad,
+		Certificates:    cm,
+		HealthChecker:   hc,
 		Proxy:           p,
 		Fallback:        f,
 		Management:      m,
 		Http:            hHttp,
 		Https:           hHttps,
+		Http3:           hHttp3,
 
 		accessLogger: logProvider.GetLogger("accessLog"),
 		logger:       logProvider.GetLogger("core"),
@@ -92,6 +143,7 @@ func New(s *settings.Settings, fps providers.FileProviders) (*Lingress, error) {
 	result.accessLoggerMessageKey = result.accessLogger.GetProvider().GetFieldKeysSpec().GetMessage()
 	result.Http.Handler = result
 	result.Https.Handler = result
+	result.Http3.Handler = result
 
 	p.ResultHandler = result.onResult
 	p.AccessLogger = result.onAccessLog
@@ -104,6 +156,14 @@ func (this *Lingress) ServeHTTP(connector server.Connector, resp http.ResponseWr
 	finalize := this.Management.CollectClientStarted(connector.GetId())
 	defer finalize()
 
+	if connector.GetId() == server.DefaultConnectorIdHttp && this.Acme.IsChallenge(req) {
+		this.Acme.ServeHTTP(resp, req)
+		return
+	}
+	if connector.GetId() == server.DefaultConnectorIdHttps && this.altSvc != "" {
+		resp.Header().Set("Alt-Svc", this.altSvc)
+	}
+
 	this.Proxy.ServeHTTP(connector, resp, req)
 }
 
@@ -166,6 +226,18 @@ func (this *Lingress) Init(stop support.Channel) error {
 	if err := this.RulesRepository.Init(stop); err != nil {
 		return err
 	}
+	if err := this.LeaderElection.Init(stop); err != nil {
+		return err
+	}
+	if err := this.Acme.Init(stop); err != nil {
+		return err
+	}
+	if err := this.Certificates.Init(stop); err != nil {
+		return err
+	}
+	if err := this.HealthChecker.Init(stop); err != nil {
+		return err
+	}
 	if err := this.Proxy.Init(stop); err != nil {
 		return err
 	}
@@ -186,6 +258,15 @@ func (this *Lingress) Init(stop support.Channel) error {
 		return err
 	} else {
 		this.Https.Server.TLSConfig = tlsConfig
+		this.Http3.Server.TLSConfig = tlsConfig
+	}
+
+	if this.Http3.IsEnabled() {
+		altSvc, err := this.Http3.AltSvc()
+		if err != nil {
+			return err
+		}
+		this.altSvc = altSvc
 	}
 
 	if err := this.Http.Serve(stop); err != nil {
@@ -194,10 +275,16 @@ func (this *Lingress) Init(stop support.Channel) error {
 	if err := this.Https.Serve(stop); err != nil {
 		return err
 	}
+	if err := this.Http3.Serve(stop); err != nil {
+		return err
+	}
 
 	if err := this.Management.Init(stop); err != nil {
 		return err
 	}
+	if err := this.Admission.Init(stop); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -218,9 +305,19 @@ func (this *Lingress) createTlsConfig() (*tls.Config, error) {
 			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
 			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
 		},
+		// http.Server.ServeTLS adds these only to its own clone of this
+		// config; the configs returned by resolveConfigForClient are cloned
+		// from this one and would otherwise lose them. HTTP/3 replaces them
+		// with h3 on each of the configs it serves.
+		NextProtos:     []string{"h2", "http/1.1"},
 		Certificates:   []tls.Certificate{},
 		GetCertificate: this.resolveCertificate,
 	}
+	result.GetConfigForClient = func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+		return this.resolveConfigForClient(&result, info)
+	}
+
+	this.Acme.ApplyToTlsConfig(&result)
 
 	if this.settings.Tls.FallbackCertificate.Get() {
 		v, err := support.CreateDummyCertificate()
@@ -234,6 +331,10 @@ func (this *Lingress) createTlsConfig() (*tls.Config, error) {
 }
 
 func (this *Lingress) resolveCertificate(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if this.Acme.IsTlsAlpnChallenge(info) {
+		return this.Acme.GetCertificate(info)
+	}
+
 	var query rules.CertificateQuery
 	if err := query.Host.Set(info.ServerName); err != nil {
 		return nil, nil
@@ -246,17 +347,59 @@ func (this *Lingress) resolveCertificate(info *tls.ClientHelloInfo) (*tls.Certif
 
 	for _, certificate := range certificates {
 		if err := info.SupportsCertificate(certificate); err == nil {
-			return certificate, nil
+			return this.Certificates.Staple(certificate), nil
+		}
+	}
+
+	if this.Acme.IsEnabled() {
+		certificate, err := this.Acme.GetCertificate(info)
+		if err != nil {
+			this.logger.
+				WithError(err).
+				With("host", info.ServerName).
+				Debug("Cannot get certificate using ACME.")
+			return nil, nil
 		}
+		return this.Certificates.Staple(certificate), nil
 	}
 
 	return nil, nil
 }
 
+// resolveConfigForClient returns a config which requests client certificates
+// if any rule of the requested host requires them; otherwise nil to use the
+// base config. TLS-ALPN-01 challenges are always answered with the base
+// config as the CA does not present any client certificate.
+func (this *Lingress) resolveConfigForClient(base *tls.Config, info *tls.ClientHelloInfo) (*tls.Config, error) {
+	if this.Acme.IsTlsAlpnChallenge(info) {
+		return nil, nil
+	}
+
+	var query rules.CertificateQuery
+	if err := query.Host.Set(info.ServerName); err != nil {
+		return nil, nil
+	}
+
+	requirements, err := this.RulesRepository.FindClientCertificateRequirementsBy(query)
+	if err != nil {
+		return nil, err
+	}
+	if !requirements.IsEnabled() {
+		return nil, nil
+	}
+
+	result := base.Clone()
+	result.GetConfigForClient = nil
+	result.ClientAuth = requirements.ClientAuth
+	result.ClientCAs = requirements.ClientCAs
+	return result, nil
+}
+
 func (this *Lingress) shutdownListener(stop support.Channel) {
 	stop.Wait()
 	this.Http.Shutdown()
 	this.Https.Shutdown()
+	this.Http3.Shutdown()
 }
 
 func (this *Lingress) accessLogQueueWorker(stop support.Channel, queue chan *accessLogEntry) {
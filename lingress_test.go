@@ -0,0 +1,171 @@
+package lingress
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/echocat/lingress/acme"
+	"github.com/echocat/lingress/certificates"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/crypto/acme/autocert"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_Lingress_createTlsConfig_negotiatesApplicationProtocols(t *testing.T) {
+	cases := []struct {
+		name              string
+		acme              bool
+		clientCertificate tls.ClientAuthType
+	}{
+		{"plain", false, tls.NoClientCert},
+		{"clientCertificate", false, tls.VerifyClientCertIfGiven},
+		{"plainWithAcme", true, tls.NoClientCert},
+		{"clientCertificateWithAcme", true, tls.VerifyClientCertIfGiven},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			certificate := newCertificateFor(t, "example.org")
+
+			s := settings.MustNew()
+			s.Acme.Enabled = value.False()
+			if c.acme {
+				s.Acme.Enabled = value.True()
+			}
+			repository := &staticRepository{
+				certificate: &certificate,
+				requirements: rules.ClientCertificateRequirements{
+					ClientAuth: c.clientCertificate,
+					ClientCAs:  x509.NewCertPool(),
+				},
+			}
+			instance := &Lingress{
+				settings:        &s,
+				RulesRepository: repository,
+				logger:          log.GetRootLogger(),
+			}
+			var err error
+			instance.Acme, err = acme.New(&s, repository, nil, log.GetRootLogger())
+			g.Expect(err).To(BeNil())
+			if c.acme {
+				instance.Acme.Manager = &autocert.Manager{}
+			}
+			instance.Certificates, err = certificates.New(&s, repository, log.GetRootLogger())
+			g.Expect(err).To(BeNil())
+
+			config, err := instance.createTlsConfig()
+			g.Expect(err).To(BeNil())
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			g.Expect(err).To(BeNil())
+			server := &http.Server{
+				Handler:   http.NotFoundHandler(),
+				TLSConfig: config,
+			}
+			go func() {
+				_ = server.ServeTLS(ln, "", "")
+			}()
+			//noinspection GoUnhandledErrorResult
+			defer server.Close()
+
+			for _, protocol := range []string{"h2", "http/1.1"} {
+				conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+					ServerName:         "example.org",
+					NextProtos:         []string{protocol},
+					InsecureSkipVerify: true,
+				})
+				g.Expect(err).To(BeNil())
+				g.Expect(conn.ConnectionState().NegotiatedProtocol).To(Equal(protocol))
+				g.Expect(conn.Close()).To(Succeed())
+			}
+
+			// HTTP/3 serves each config it is handed (also the ones of
+			// GetConfigForClient) with h3 only.
+			forClient, err := http3.ConfigureTLSConfig(config).GetConfigForClient(&tls.ClientHelloInfo{
+				ServerName:      "example.org",
+				SupportedProtos: []string{http3.NextProtoH3},
+			})
+			g.Expect(err).To(BeNil())
+			if c.clientCertificate == tls.NoClientCert {
+				g.Expect(forClient).To(BeNil())
+			} else {
+				g.Expect(forClient.NextProtos).To(Equal([]string{http3.NextProtoH3}))
+				g.Expect(forClient.ClientAuth).To(Equal(c.clientCertificate))
+			}
+		})
+	}
+}
+
+func newCertificateFor(t *testing.T, host string) tls.Certificate {
+	g := NewGomegaWithT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).To(BeNil())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	g.Expect(err).To(BeNil())
+	leaf, err := x509.ParseCertificate(der)
+	g.Expect(err).To(BeNil())
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+}
+
+type staticRepository struct {
+	certificate  *tls.Certificate
+	requirements rules.ClientCertificateRequirements
+}
+
+func (this *staticRepository) Init(support.Channel) error {
+	return nil
+}
+
+func (this *staticRepository) All(func(rules.Rule) error) error {
+	return nil
+}
+
+func (this *staticRepository) FindBy(rules.Query) (rules.Rules, error) {
+	return nil, nil
+}
+
+func (this *staticRepository) FindCertificatesBy(rules.CertificateQuery) (rules.Certificates, error) {
+	return rules.Certificates{this.certificate}, nil
+}
+
+func (this *staticRepository) AllCertificates() ([]rules.LoadedCertificate, error) {
+	return nil, nil
+}
+
+func (this *staticRepository) FindClientCertificateRequirementsBy(rules.CertificateQuery) (rules.ClientCertificateRequirements, error) {
+	return this.requirements, nil
+}
+
+func (this *staticRepository) HasHost(value.Fqdn) (bool, error) {
+	return true, nil
+}
@@ -2,7 +2,9 @@ package management
 
 import (
 	"context"
+	"fmt"
 	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/leader"
 	"github.com/echocat/lingress/rules"
 	"github.com/echocat/lingress/server"
 	"github.com/echocat/lingress/settings"
@@ -23,16 +25,20 @@ type Management struct {
 	Metrics *Metrics
 	Logger  log.Logger
 
-	server http.Server
-	rules  rules.Repository
+	server       http.Server
+	rules        rules.CombinedRepository
+	certificates rules.CertificateRepository
+	election     *leader.Election
 }
 
-func New(s *settings.Settings, connectorIds []server.ConnectorId, rulesRepository rules.Repository, logger log.Logger) (*Management, error) {
+func New(s *settings.Settings, connectorIds []server.ConnectorId, rulesRepository rules.CombinedRepository, certificates rules.CertificateRepository, election *leader.Election, logger log.Logger) (*Management, error) {
 	result := &Management{
-		settings: s,
-		Metrics:  NewMetrics(connectorIds, rulesRepository),
-		Logger:   logger,
-		rules:    rulesRepository,
+		settings:     s,
+		Metrics:      NewMetrics(connectorIds, rulesRepository, certificates, election, s.Tls.ExpiryWarning),
+		Logger:       logger,
+		rules:        rulesRepository,
+		certificates: certificates,
+		election:     election,
 		server: http.Server{
 			ErrorLog: sdk.NewWrapper(logger, level.Debug),
 		},
@@ -65,12 +71,16 @@ func (this *Management) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 		return
 	}
 	isPprof := this.settings.Management.Pprof.Get()
-	if req.URL.Path == "/health" {
+	if req.URL.Path == "/health" || req.URL.Path == "/livez" {
 		this.handleHealth(resp, req)
+	} else if req.URL.Path == "/readyz" {
+		this.handleReadiness(resp, req)
 	} else if req.URL.Path == "/status" {
 		this.handleStatus(resp, req)
 	} else if req.URL.Path == "/metrics" {
 		this.handleMetrics(resp, req)
+	} else if req.URL.Path == "/certificates" {
+		this.handleCertificates(resp, req)
 	} else if req.URL.Path == "/rules" {
 		this.handleRules(resp, req, "")
 	} else if strings.HasPrefix(req.URL.Path, "/rules/") {
@@ -98,6 +108,55 @@ func (this *Management) handleHealth(resp http.ResponseWriter, req *http.Request
 		StreamJsonTo(resp, req, this.getLogger)
 }
 
+// handleReadiness reports not ready (503) if the rules are not synchronized,
+// the last error while synchronizing them is more recent than
+// management.readinessErrorPeriod or (if management.readinessStrict is
+// enabled) no rules are loaded at all.
+func (this *Management) handleReadiness(resp http.ResponseWriter, req *http.Request) {
+	problems := this.readinessProblems()
+	if len(problems) > 0 {
+		support.NewGenericResponse(http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable), req).
+			WithData(map[string]interface{}{
+				"ready":    false,
+				"problems": problems,
+			}).
+			StreamJsonTo(resp, req, this.getLogger)
+		return
+	}
+	support.NewGenericResponse(http.StatusOK, http.StatusText(http.StatusOK), req).
+		WithData(map[string]interface{}{
+			"ready": true,
+		}).
+		StreamJsonTo(resp, req, this.getLogger)
+}
+
+func (this *Management) readinessProblems() (result []string) {
+	if candidate, ok := this.rules.(rules.SyncStateRepository); ok {
+		if !candidate.HasSynced() {
+			result = append(result, "rules are not synchronized yet")
+		}
+		if period := this.settings.Management.ReadinessErrorPeriod; period > 0 {
+			if at, err := candidate.LastError(); err != nil && time.Since(at) < period {
+				result = append(result, fmt.Sprintf("last synchronization of rules failed at %s: %v", at.Format(time.RFC3339), err))
+			}
+		}
+	}
+
+	if this.settings.Management.ReadinessStrict.Get() {
+		var numberOfRules uint
+		if err := this.rules.All(func(rules.Rule) error {
+			numberOfRules++
+			return nil
+		}); err != nil {
+			result = append(result, fmt.Sprintf("cannot read rules: %v", err))
+		} else if numberOfRules == 0 {
+			result = append(result, "no rules are loaded")
+		}
+	}
+
+	return
+}
+
 func (this *Management) handleStatus(resp http.ResponseWriter, req *http.Request) {
 	var numberOfRules uint
 	var numberOfRequests uint64
@@ -125,6 +184,14 @@ func (this *Management) handleStatus(resp http.ResponseWriter, req *http.Request
 			"totalDuration":    totalDuration / time.Microsecond,
 		},
 	}
+	if e := this.election; e != nil {
+		data["leaderElection"] = map[string]interface{}{
+			"enabled":  e.IsEnabled(),
+			"identity": e.Identity(),
+			"leading":  e.IsLeader(),
+			"leader":   e.Leader(),
+		}
+	}
 	support.NewGenericResponse(http.StatusOK, http.StatusText(http.StatusOK), req).
 		WithData(data).
 		StreamJsonTo(resp, req, this.getLogger)
@@ -156,6 +223,9 @@ func (this *Management) handleRules(resp http.ResponseWriter, req *http.Request,
 			if b := rule.Backend(); b != nil {
 				entry["backend"] = b.String()
 			}
+			if e := rule.Endpoints(); e != nil {
+				entry["endpoints"] = e.All()
+			}
 
 			if entries, ok := result[source]; ok {
 				result[source] = append(entries, entry)
@@ -189,6 +259,38 @@ func (this *Management) handleRules(resp http.ResponseWriter, req *http.Request,
 		StreamJsonTo(resp, req, this.getLogger)
 }
 
+func (this *Management) handleCertificates(resp http.ResponseWriter, req *http.Request) {
+	all, err := this.certificates.AllCertificates()
+	if err != nil {
+		this.Logger.
+			WithError(err).
+			Error("Unable to read certificates.")
+		support.NewGenericResponse(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), req).
+			StreamJsonTo(resp, req, this.getLogger)
+		return
+	}
+
+	data := make([]map[string]interface{}, 0, len(all))
+	for _, candidate := range all {
+		entry := map[string]interface{}{
+			"hosts":   candidate.Hosts,
+			"sources": candidate.Sources,
+		}
+		if leaf := candidate.Certificate.Leaf; leaf != nil {
+			entry["subject"] = leaf.Subject.String()
+			entry["issuer"] = leaf.Issuer.String()
+			entry["serial"] = leaf.SerialNumber.Text(16)
+			entry["notBefore"] = leaf.NotBefore
+			entry["notAfter"] = leaf.NotAfter
+		}
+		data = append(data, entry)
+	}
+
+	support.NewGenericResponse(http.StatusOK, http.StatusText(http.StatusOK), req).
+		WithData(data).
+		StreamJsonTo(resp, req, this.getLogger)
+}
+
 func (this *Management) Init(stop support.Channel) error {
 	go this.shutdownListener(stop)
 
@@ -0,0 +1,161 @@
+package management
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type syncStateRepository struct {
+	rules.CombinedRepository
+
+	synced      bool
+	lastErrorAt time.Time
+	rules       []rules.Rule
+}
+
+func (this *syncStateRepository) HasSynced() bool {
+	return this.synced
+}
+
+func (this *syncStateRepository) LastError() (time.Time, error) {
+	if this.lastErrorAt.IsZero() {
+		return time.Time{}, nil
+	}
+	return this.lastErrorAt, errors.New("expected")
+}
+
+func (this *syncStateRepository) All(consumer func(rules.Rule) error) error {
+	for _, rule := range this.rules {
+		if err := consumer(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func Test_Management_handleReadiness(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	repository := &syncStateRepository{}
+	instance := &Management{
+		settings: &s,
+		Logger:   log.GetRootLogger(),
+		rules:    repository,
+	}
+
+	readiness := func() (int, map[string]interface{}) {
+		resp := httptest.NewRecorder()
+		instance.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var body map[string]interface{}
+		g.Expect(json.Unmarshal(resp.Body.Bytes(), &body)).To(Succeed())
+		return resp.Code, body
+	}
+
+	code, body := readiness()
+	g.Expect(code).To(Equal(http.StatusServiceUnavailable))
+	g.Expect(body["problems"]).To(ConsistOf("rules are not synchronized yet"))
+
+	repository.synced = true
+	code, body = readiness()
+	g.Expect(code).To(Equal(http.StatusOK))
+	g.Expect(body["ready"]).To(BeTrue())
+
+	repository.lastErrorAt = time.Now()
+	code, body = readiness()
+	g.Expect(code).To(Equal(http.StatusServiceUnavailable))
+	g.Expect(body["problems"]).To(ConsistOf(ContainSubstring("last synchronization of rules failed at")))
+
+	repository.lastErrorAt = time.Now().Add(-2 * s.Management.ReadinessErrorPeriod)
+	code, _ = readiness()
+	g.Expect(code).To(Equal(http.StatusOK))
+
+	s.Management.ReadinessStrict = value.True()
+	code, body = readiness()
+	g.Expect(code).To(Equal(http.StatusServiceUnavailable))
+	g.Expect(body["problems"]).To(ConsistOf("no rules are loaded"))
+
+	repository.rules = []rules.Rule{rules.NewRule("", nil, rules.PathTypePrefix, nil, nil, nil, rules.DefaultOptionsFactory())}
+	code, _ = readiness()
+	g.Expect(code).To(Equal(http.StatusOK))
+
+	resp := httptest.NewRecorder()
+	instance.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	g.Expect(resp.Code).To(Equal(http.StatusOK))
+}
+
+type loadedCertificates []rules.LoadedCertificate
+
+func (this loadedCertificates) FindCertificatesBy(rules.CertificateQuery) (rules.Certificates, error) {
+	return nil, nil
+}
+
+func (this loadedCertificates) AllCertificates() ([]rules.LoadedCertificate, error) {
+	return this, nil
+}
+
+func Test_CertificatesMetrics_Collect(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).To(BeNil())
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(666),
+		Subject:      pkix.Name{CommonName: "*.example.org"},
+		DNSNames:     []string{"*.example.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(1)}, &key.PublicKey, key)
+	g.Expect(err).To(BeNil())
+	// The same secret parsed twice; like referenced by two entries of spec.tls.
+	certificateOf := func() *tls.Certificate {
+		leaf, err := x509.ParseCertificate(der)
+		g.Expect(err).To(BeNil())
+		return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+	}
+
+	registry := prometheus.NewRegistry()
+	NewCertificatesMetrics(registry, loadedCertificates{{
+		Certificate: certificateOf(),
+		Hosts:       value.WildcardSupportingFqdns{"a.example.org"},
+		Sources:     []string{"Ingress/foo/a"},
+	}, {
+		Certificate: certificateOf(),
+		Hosts:       value.WildcardSupportingFqdns{"b.example.org"},
+		Sources:     []string{"Ingress/foo/a", "Ingress/foo/b"},
+	}}, time.Hour*2)
+
+	families, err := registry.Gather()
+	g.Expect(err).To(BeNil())
+	g.Expect(families).To(HaveLen(2))
+	g.Expect(families[0].GetName()).To(Equal("lingress_certificates_expiring"))
+	g.Expect(families[0].GetMetric()[0].GetGauge().GetValue()).To(Equal(float64(1)))
+	g.Expect(families[1].GetName()).To(Equal("lingress_certificates_not_after_seconds"))
+	g.Expect(families[1].GetMetric()).To(HaveLen(1))
+	labels := map[string]string{}
+	for _, label := range families[1].GetMetric()[0].GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	g.Expect(labels).To(Equal(map[string]string{
+		"common_name": "*.example.org",
+		"serial":      "29a",
+		"sources":     "Ingress/foo/a,Ingress/foo/b",
+	}))
+}
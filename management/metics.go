@@ -2,6 +2,7 @@ package management
 
 import (
 	"github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/leader"
 	"github.com/echocat/lingress/rules"
 	"github.com/echocat/lingress/server"
 	"github.com/echocat/lingress/support"
@@ -11,7 +12,9 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync/atomic"
+	"time"
 )
 
 var (
@@ -25,9 +28,12 @@ var (
 )
 
 type Metrics struct {
-	Client   ConnectorEnabledClientMetrics
-	Upstream *UpstreamMetrics
-	Rules    *RulesMetrics
+	Client       ConnectorEnabledClientMetrics
+	Upstream     *UpstreamMetrics
+	Rules        *RulesMetrics
+	Endpoints    *EndpointsMetrics
+	Certificates *CertificatesMetrics
+	Leader       *LeaderMetrics
 
 	Registry *prometheus.Registry
 	Handler  http.Handler
@@ -42,6 +48,7 @@ type ConnectorEnabledClientMetrics map[server.ConnectorId]*ClientMetrics
 
 type UpstreamMetrics struct {
 	Request *RequestMetrics
+	Retries *prometheus.CounterVec
 }
 
 type RulesMetrics struct {
@@ -51,6 +58,34 @@ type RulesMetrics struct {
 	rules rules.Repository
 }
 
+type EndpointsMetrics struct {
+	Total     prometheus.GaugeFunc
+	Unhealthy prometheus.GaugeFunc
+	Ejected   prometheus.GaugeFunc
+
+	rules rules.Repository
+}
+
+// CertificatesMetrics exports the expiry of every served certificate. The
+// values are evaluated at the time they are collected.
+type CertificatesMetrics struct {
+	NotAfter *prometheus.Desc
+	Expiring *prometheus.Desc
+
+	// ExpiryWarning is the amount of time before the expiry of a certificate
+	// it is counted as expiring.
+	ExpiryWarning time.Duration
+
+	certificates rules.CertificateRepository
+}
+
+// LeaderMetrics exports whether this instance is the elected leader.
+type LeaderMetrics struct {
+	Leading prometheus.GaugeFunc
+
+	election *leader.Election
+}
+
 type RequestMetrics struct {
 	DurationSeconds *prometheus.HistogramVec
 	Total           *prometheus.CounterVec
@@ -85,15 +120,18 @@ type ConnectionStates struct {
 	Max     uint64
 }
 
-func NewMetrics(connectorIds []server.ConnectorId, rulesRepository rules.Repository) *Metrics {
+func NewMetrics(connectorIds []server.ConnectorId, rulesRepository rules.CombinedRepository, certificates rules.CertificateRepository, election *leader.Election, expiryWarning time.Duration) *Metrics {
 	registry := prometheus.NewRegistry()
 	registry.MustRegister(collectors.NewGoCollector())
 	registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 
 	return &Metrics{
-		Client:   NewConnectorEnabledClientMetrics(connectorIds, registry),
-		Upstream: NewUpstreamMetrics(registry),
-		Rules:    NewRulesMetrics(registry, rulesRepository),
+		Client:       NewConnectorEnabledClientMetrics(connectorIds, registry),
+		Upstream:     NewUpstreamMetrics(registry),
+		Rules:        NewRulesMetrics(registry, rulesRepository),
+		Endpoints:    NewEndpointsMetrics(registry, rulesRepository),
+		Certificates: NewCertificatesMetrics(registry, certificates, expiryWarning),
+		Leader:       NewLeaderMetrics(registry, election),
 
 		Registry: registry,
 		Handler:  promhttp.InstrumentMetricHandler(registry, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})),
@@ -212,6 +250,12 @@ func NewUpstreamMetrics(registerer prometheus.Registerer) *UpstreamMetrics {
 			1,
 			10,
 		}),
+		Retries: promauto.With(registerer).NewCounterVec(prometheus.CounterOpts{
+			Namespace: "lingress",
+			Subsystem: "upstream_requests",
+			Name:      "retries_total",
+			Help:      "Amount of attempts to upstreams which were retried.",
+		}, []string{"rule", "reason"}),
 	}
 }
 
@@ -237,6 +281,62 @@ func NewRulesMetrics(registerer prometheus.Registerer, rulesRepository rules.Rep
 	return result
 }
 
+func NewEndpointsMetrics(registerer prometheus.Registerer, rulesRepository rules.Repository) *EndpointsMetrics {
+	result := &EndpointsMetrics{
+		rules: rulesRepository,
+	}
+
+	result.Total = promauto.With(registerer).NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "lingress",
+		Subsystem: "upstream_endpoints",
+		Name:      "total",
+		Help:      "Total amount of endpoints of all upstreams.",
+	}, result.count(func(*rules.Endpoint, time.Time) bool {
+		return true
+	}))
+
+	result.Unhealthy = promauto.With(registerer).NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "lingress",
+		Subsystem: "upstream_endpoints",
+		Name:      "unhealthy",
+		Help:      "Amount of endpoints of all upstreams which are marked as unhealthy by active health checks.",
+	}, result.count(func(candidate *rules.Endpoint, _ time.Time) bool {
+		return !candidate.IsHealthy()
+	}))
+
+	result.Ejected = promauto.With(registerer).NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "lingress",
+		Subsystem: "upstream_endpoints",
+		Name:      "ejected",
+		Help:      "Amount of endpoints of all upstreams which are currently ejected by passive health checks.",
+	}, result.count(func(candidate *rules.Endpoint, now time.Time) bool {
+		return candidate.IsEjected(now)
+	}))
+
+	return result
+}
+
+func NewCertificatesMetrics(registerer prometheus.Registerer, certificates rules.CertificateRepository, expiryWarning time.Duration) *CertificatesMetrics {
+	result := &CertificatesMetrics{
+		NotAfter: prometheus.NewDesc(
+			prometheus.BuildFQName("lingress", "certificates", "not_after_seconds"),
+			"Time (as unix timestamp in seconds) after which a served certificate expires.",
+			[]string{"common_name", "serial", "sources"},
+			nil,
+		),
+		Expiring: prometheus.NewDesc(
+			prometheus.BuildFQName("lingress", "certificates", "expiring"),
+			"Amount of served certificates which are expired or will expire soon.",
+			nil,
+			nil,
+		),
+		ExpiryWarning: expiryWarning,
+		certificates:  certificates,
+	}
+	registerer.MustRegister(result)
+	return result
+}
+
 func (this *Metrics) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
 	this.Handler.ServeHTTP(resp, req)
 }
@@ -248,6 +348,14 @@ func (this *Metrics) CollectContext(ctx *context.Context) {
 		this.Upstream.Request.DurationSeconds.With(labels).Observe(v.Seconds())
 		this.Upstream.Request.Total.With(labels).Inc()
 	}
+	for _, attempt := range ctx.Upstream.Attempts {
+		if reason := attempt.RetriedBecause; reason != "" {
+			this.Upstream.Retries.With(prometheus.Labels{
+				"rule":   labels["rule"],
+				"reason": reason,
+			}).Inc()
+		}
+	}
 }
 
 func (this *Metrics) CollectClientStarted(connector server.ConnectorId) func() {
@@ -319,6 +427,54 @@ func (this *RulesMetrics) sources() float64 {
 	return float64(len(result))
 }
 
+func (this *EndpointsMetrics) count(predicate func(*rules.Endpoint, time.Time) bool) func() float64 {
+	return func() float64 {
+		now := time.Now()
+		seen := map[*rules.Endpoint]bool{}
+		_ = this.rules.All(func(r rules.Rule) error {
+			for _, candidate := range r.Endpoints().All() {
+				if !seen[candidate] && predicate(candidate, now) {
+					seen[candidate] = true
+				}
+			}
+			return nil
+		})
+		return float64(len(seen))
+	}
+}
+
+func (this *CertificatesMetrics) Describe(ch chan<- *prometheus.Desc) {
+	ch <- this.NotAfter
+	ch <- this.Expiring
+}
+
+func (this *CertificatesMetrics) Collect(ch chan<- prometheus.Metric) {
+	all, err := this.certificates.AllCertificates()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(this.NotAfter, err)
+		return
+	}
+
+	threshold := time.Now().Add(this.ExpiryWarning)
+	var expiring float64
+	for _, candidate := range rules.MergeLoadedCertificates(all) {
+		leaf := candidate.Certificate.Leaf
+		if leaf == nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(this.NotAfter, prometheus.GaugeValue,
+			float64(leaf.NotAfter.Unix()),
+			leaf.Subject.CommonName,
+			leaf.SerialNumber.Text(16),
+			strings.Join(candidate.Sources, ","),
+		)
+		if threshold.After(leaf.NotAfter) {
+			expiring++
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(this.Expiring, prometheus.GaugeValue, expiring)
+}
+
 func (this ConnectorEnabledClientMetrics) collectContext(labels prometheus.Labels, ctx *context.Context) {
 	if this == nil {
 		return
@@ -344,3 +500,24 @@ func (this ConnectorEnabledClientMetrics) collectClientStarted(connector server.
 	}
 	return func() {}
 }
+
+func NewLeaderMetrics(registerer prometheus.Registerer, election *leader.Election) *LeaderMetrics {
+	result := &LeaderMetrics{
+		election: election,
+	}
+
+	result.Leading = promauto.With(registerer).NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "lingress",
+		Name:      "leader",
+		Help:      "1 if this instance is the elected leader (which writes back into the cluster); otherwise 0.",
+	}, result.leading)
+
+	return result
+}
+
+func (this *LeaderMetrics) leading() float64 {
+	if this.election != nil && this.election.IsLeader() {
+		return 1
+	}
+	return 0
+}
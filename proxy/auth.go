@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	gocontext "context"
+	"crypto/tls"
+	"fmt"
+	"github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	ltls "github.com/echocat/lingress/tls"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+const (
+	maxDrainedAuthResponseBytes = 64 * 1024
+)
+
+func init() {
+	DefaultInterceptors.Add(NewForwardAuthInterceptor())
+}
+
+// ForwardAuthInterceptor asks the service referenced by the annotation
+// lingress.echocat.org/auth-url whether a request is allowed to pass.
+type ForwardAuthInterceptor struct {
+	Client *http.Client
+}
+
+func NewForwardAuthInterceptor() *ForwardAuthInterceptor {
+	return &ForwardAuthInterceptor{
+		Client: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					RootCAs: ltls.Pool,
+				},
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (this *ForwardAuthInterceptor) Name() string {
+	return "forwardAuth"
+}
+
+func (this *ForwardAuthInterceptor) Priority() int {
+	return PriorityForwardAuth
+}
+
+func (this *ForwardAuthInterceptor) HandlesStages() []context.Stage {
+	return []context.Stage{context.StageEvaluateClientRequest}
+}
+
+func (this *ForwardAuthInterceptor) Handle(ctx *context.Context) (proceed bool, err error) {
+	opts := rules.OptionsAuthOf(ctx.Rule)
+	authUrl := opts.Url.Get()
+	if authUrl == "" {
+		return true, nil
+	}
+
+	// Never trust headers which should be provided by the auth service.
+	for _, name := range opts.ResponseHeaders {
+		ctx.Client.Request.Header.Del(name.String())
+	}
+
+	reqCtx := ctx.Client.Request.Context()
+	if t := ctx.Settings.Auth.Timeout; t > 0 {
+		var cancel gocontext.CancelFunc
+		reqCtx, cancel = gocontext.WithTimeout(reqCtx, t)
+		defer cancel()
+	}
+
+	resp, err := this.ask(ctx, reqCtx, authUrl)
+	if err != nil {
+		return false, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainedAuthResponseBytes))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		for _, name := range opts.ResponseHeaders {
+			for _, v := range resp.Header.Values(name.String()) {
+				ctx.Client.Request.Header.Add(name.String(), v)
+			}
+		}
+		return true, nil
+	case resp.StatusCode == http.StatusUnauthorized:
+		if signin := opts.Signin.Get(); signin != "" {
+			target, err := this.signinTargetOf(ctx, signin)
+			if err != nil {
+				return false, err
+			}
+			ctx.Client.Response.Header().Set("X-Reason", "auth-signin")
+			ctx.Result = context.RedirectResult{
+				StatusCode: http.StatusFound,
+				Target:     target,
+			}
+			return false, nil
+		}
+		for _, v := range resp.Header.Values("WWW-Authenticate") {
+			ctx.Client.Response.Header().Add("WWW-Authenticate", v)
+		}
+		ctx.Client.Response.Header().Set("X-Reason", "auth-unauthorized")
+		ctx.Result = context.ResultFailedWithUnauthorized
+		return false, nil
+	case resp.StatusCode == http.StatusForbidden:
+		ctx.Client.Response.Header().Set("X-Reason", "auth-access-denied")
+		ctx.Result = context.ResultFailedWithAccessDenied
+		return false, nil
+	default:
+		return false, fmt.Errorf("auth service %s responded with unexpected status %d", authUrl, resp.StatusCode)
+	}
+}
+
+func (this *ForwardAuthInterceptor) ask(ctx *context.Context, reqCtx gocontext.Context, authUrl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, authUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = cloneHeader(ctx.Client.Request.Header)
+	removeConnectionHeaders(req.Header)
+	removeHopReqHeaders(req.Header)
+	req.Header.Del("Content-Length")
+	req.Header.Del("Content-Type")
+
+	if u, err := ctx.Client.RequestedUrl(); err != nil {
+		return nil, err
+	} else if u != nil {
+		req.Header.Set("X-Original-Url", u.String())
+		req.Header.Set("X-Forwarded-Host", u.Host)
+		req.Header.Set("X-Forwarded-Proto", u.Scheme)
+		req.Header.Set("X-Forwarded-Uri", u.RequestURI())
+	}
+	req.Header.Set("X-Original-Method", ctx.Client.Request.Method)
+	req.Header.Set("X-Forwarded-Method", ctx.Client.Request.Method)
+	if address, err := ctx.Client.Address(); err == nil {
+		req.Header.Set("X-Forwarded-For", address)
+	}
+	req.Header.Set("X-Request-Id", ctx.Id.String())
+	req.Header.Set("X-Correlation-Id", ctx.CorrelationId.String())
+
+	resp, err := this.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot ask auth service %s: %w", authUrl, err)
+	}
+	return resp, nil
+}
+
+func (this *ForwardAuthInterceptor) signinTargetOf(ctx *context.Context, signin string) (string, error) {
+	target, err := url.Parse(signin)
+	if err != nil {
+		return "", err
+	}
+	u, err := ctx.Client.RequestedUrl()
+	if err != nil {
+		return "", err
+	}
+	if u != nil {
+		q := target.Query()
+		q.Set("rd", u.String())
+		target.RawQuery = q.Encode()
+	}
+	return target.String(), nil
+}
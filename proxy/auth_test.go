@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ForwardAuthInterceptor_Handle(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	authService := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		switch req.Header.Get("Authorization") {
+		case "Bearer valid":
+			resp.Header().Set("X-User", "foo")
+			resp.WriteHeader(http.StatusNoContent)
+		case "Bearer forbidden":
+			resp.WriteHeader(http.StatusForbidden)
+		default:
+			resp.Header().Set("WWW-Authenticate", "Bearer")
+			resp.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer authService.Close()
+
+	r := newTestRule(t, "auth", rules.Annotations{
+		"lingress.echocat.org/auth-url":              authService.URL,
+		"lingress.echocat.org/auth-response-headers": "X-User",
+	})
+	instance := NewForwardAuthInterceptor()
+
+	handle := func(authorization string) (*lctx.Context, bool) {
+		s := settings.MustNew()
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/foo", nil)
+		req.Header.Set("X-User", "spoofed")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		ctx, _, err := lctx.AcquireContext(&s, "test", false, httptest.NewRecorder(), req, log.GetRootLogger())
+		g.Expect(err).To(BeNil())
+		ctx.Rule = r
+		proceed, err := instance.Handle(ctx)
+		g.Expect(err).To(BeNil())
+		return ctx, proceed
+	}
+
+	ctx, proceed := handle("Bearer valid")
+	g.Expect(proceed).To(BeTrue())
+	g.Expect(ctx.Client.Request.Header.Values("X-User")).To(Equal([]string{"foo"}))
+
+	ctx, proceed = handle("")
+	g.Expect(proceed).To(BeFalse())
+	g.Expect(ctx.Result).To(Equal(lctx.ResultFailedWithUnauthorized))
+	g.Expect(ctx.Client.Response.Header().Get("WWW-Authenticate")).To(Equal("Bearer"))
+
+	ctx, proceed = handle("Bearer forbidden")
+	g.Expect(proceed).To(BeFalse())
+	g.Expect(ctx.Result).To(Equal(lctx.ResultFailedWithAccessDenied))
+}
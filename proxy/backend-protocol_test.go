@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_transportFor_h2c_forwards_trailers(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("X-Proto", req.Proto)
+		_, _ = resp.Write([]byte("hello"))
+		resp.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+	}))
+	upstream.Config.Protocols = new(http.Protocols)
+	upstream.Config.Protocols.SetUnencryptedHTTP2(true)
+	upstream.Start()
+	defer upstream.Close()
+
+	s := settings.MustNew()
+	instance, err := New(&s, nil, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	options := rules.DefaultOptionsFactory()
+	g.Expect(options.Set(rules.Annotations{"lingress.echocat.org/backend-protocol": "h2c"})).To(Succeed())
+	rule := rules.NewRule("", nil, rules.PathTypePrefix, nil, nil, nil, options)
+
+	req, err := http.NewRequest(http.MethodPost, upstream.URL, nil)
+	g.Expect(err).To(BeNil())
+	resp, err := instance.transportFor(rule).RoundTrip(req)
+	g.Expect(err).To(BeNil())
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(body)).To(Equal("hello"))
+	g.Expect(resp.Header.Get("X-Proto")).To(Equal("HTTP/2.0"))
+	g.Expect(resp.Trailer.Get("Grpc-Status")).To(Equal("0"))
+}
+
+func Test_transportFor_h2_over_tls(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("X-Proto", req.Proto)
+		_, _ = resp.Write([]byte("hello"))
+	}))
+	upstream.EnableHTTP2 = true
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	s := settings.MustNew()
+	instance, err := New(&s, nil, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	instance.Http2Transport.TLSClientConfig.RootCAs = upstream.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	for _, protocol := range []string{"h2", "grpcs"} {
+		options := rules.DefaultOptionsFactory()
+		g.Expect(options.Set(rules.Annotations{"lingress.echocat.org/backend-protocol": protocol})).To(Succeed())
+		rule := rules.NewRule("", nil, rules.PathTypePrefix, nil, nil, nil, options)
+		g.Expect(rules.OptionsUpstreamOf(rule).Protocol.Scheme()).To(Equal("https"))
+
+		req, err := http.NewRequest(http.MethodGet, upstream.URL, nil)
+		g.Expect(err).To(BeNil())
+		resp, err := instance.transportFor(rule).RoundTrip(req)
+		g.Expect(err).To(BeNil())
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		g.Expect(err).To(BeNil())
+		g.Expect(string(body)).To(Equal("hello"))
+		g.Expect(resp.Header.Get("X-Proto")).To(Equal("HTTP/2.0"))
+	}
+}
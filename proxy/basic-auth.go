@@ -0,0 +1,28 @@
+package proxy
+
+import (
+	"github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+)
+
+func init() {
+	DefaultInterceptors.AddPrioritizedFunc("basicAuth", PriorityBasicAuth, BasicAuthInterceptor, context.StageEvaluateClientRequest)
+}
+
+func BasicAuthInterceptor(ctx *context.Context) (proceed bool, err error) {
+	opts := rules.OptionsBasicAuthOf(ctx.Rule)
+	if !opts.Secret.IsPresent() {
+		return true, nil
+	}
+
+	if username, password, ok := ctx.Client.Request.BasicAuth(); ok {
+		if c := opts.Credentials; c != nil && c.Verify(username, password) {
+			return true, nil
+		}
+	}
+
+	ctx.Client.Response.Header().Set("WWW-Authenticate", `Basic realm="`+opts.Realm.GetOr("Restricted")+`", charset="UTF-8"`)
+	ctx.Client.Response.Header().Set("X-Reason", "basic-auth")
+	ctx.Result = context.ResultFailedWithUnauthorized
+	return false, nil
+}
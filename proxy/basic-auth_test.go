@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_BasicAuthInterceptor(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	r := newTestRule(t, "basic-auth", rules.Annotations{
+		"lingress.echocat.org/basic-auth.secret": "auth",
+		"lingress.echocat.org/basic-auth.realm":  "Test",
+	})
+	credentials := rules.NewHtpasswd()
+	credentials.Set([]byte("user:$2y$05$qYnt4o7VMJ8ol4yrVoalkOFkgiZCjXtfb.5.3yB1haz9lflq8a7l2\n"))
+	rules.OptionsBasicAuthOf(r).Credentials = credentials
+
+	handle := func(username, password string) (*lctx.Context, bool) {
+		s := settings.MustNew()
+		req := httptest.NewRequest(http.MethodGet, "http://localhost/foo", nil)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		ctx, _, err := lctx.AcquireContext(&s, "test", false, httptest.NewRecorder(), req, log.GetRootLogger())
+		g.Expect(err).To(BeNil())
+		ctx.Rule = r
+		proceed, err := BasicAuthInterceptor(ctx)
+		g.Expect(err).To(BeNil())
+		return ctx, proceed
+	}
+
+	_, proceed := handle("user", "password")
+	g.Expect(proceed).To(BeTrue())
+
+	for _, candidate := range [][2]string{{"", ""}, {"user", "wrong"}, {"unknown", "password"}} {
+		ctx, proceed := handle(candidate[0], candidate[1])
+		g.Expect(proceed).To(BeFalse())
+		g.Expect(ctx.Result).To(Equal(lctx.ResultFailedWithUnauthorized))
+		g.Expect(ctx.Client.Response.Header().Get("WWW-Authenticate")).To(Equal(`Basic realm="Test", charset="UTF-8"`))
+	}
+
+	// Replaced credentials are respected immediately (even if the old ones
+	// were verified before).
+	credentials.Set([]byte("other:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"))
+	_, proceed = handle("user", "password")
+	g.Expect(proceed).To(BeFalse())
+	_, proceed = handle("other", "password")
+	g.Expect(proceed).To(BeTrue())
+}
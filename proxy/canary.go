@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	canaryAlways = "always"
+	canaryNever  = "never"
+)
+
+type canaryOverride uint8
+
+const (
+	canaryOverrideNone canaryOverride = iota
+	canaryOverrideAlways
+	canaryOverrideNever
+)
+
+// findRule finds the rule which serves the request. If none of the rules of
+// the most specific path is matching the request (see rules.OptionsMatch),
+// the rules of the less specific paths are tried; as it is required by the
+// Gateway API for HTTPRoutes. If no rule is found at all nil is returned.
+func (this *Proxy) findRule(ctx *lctx.Context, query rules.Query) (rules.Rule, error) {
+	return findRuleFor(this.RulesRepository, ctx.Client.Request, query, rand.Float64)
+}
+
+func findRuleFor(repository rules.Repository, req *http.Request, query rules.Query, random func() float64) (rules.Rule, error) {
+	path, err := rules.ParsePath(query.Path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		rs, err := repository.FindBy(query)
+		if err != nil {
+			return nil, err
+		}
+		if rs == nil || rs.Len() == 0 {
+			return nil, nil
+		}
+		if r := selectRuleFor(req, path, rs, random); r != nil {
+			return r, nil
+		}
+
+		depth := 0
+		for i := 0; i < rs.Len(); i++ {
+			depth = max(depth, len(rs.Get(i).Path()))
+		}
+		if depth == 0 || depth > len(path) {
+			return nil, nil
+		}
+		query.Path = "/" + strings.Join(path[:depth-1], "/")
+	}
+}
+
+// selectRuleFor selects one of the competing rules (which are all claiming the
+// same host and path). At first only the rules which are matching the request
+// (see rules.OptionsMatch) and the requested path are considered. Rules which are marked as canary are
+// preferred if the request explicitly asks for them (by header or cookie) or if
+// they win the weighted roll; otherwise the first primary rule is used. If no
+// rule matches the request nil is returned.
+func selectRuleFor(req *http.Request, path []string, in rules.Rules, random func() float64) rules.Rule {
+	candidates := matchingRulesFor(req, path, in)
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var primary rules.Rule
+	var canaries []rules.Rule
+	for _, candidate := range candidates {
+		if rules.OptionsCanaryOf(candidate).Enabled.GetOr(false) {
+			canaries = append(canaries, candidate)
+		} else if primary == nil {
+			primary = candidate
+		}
+	}
+
+	if len(canaries) == 0 {
+		return candidates[0]
+	}
+
+	weighted := make([]rules.Rule, 0, len(canaries))
+	for _, canary := range canaries {
+		switch evaluateCanaryOverride(req, rules.OptionsCanaryOf(canary)) {
+		case canaryOverrideAlways:
+			return canary
+		case canaryOverrideNever:
+			continue
+		}
+		weighted = append(weighted, canary)
+	}
+
+	if len(weighted) > 0 {
+		roll := random()
+		var cumulated float64
+		for _, canary := range weighted {
+			cumulated += rules.OptionsCanaryOf(canary).WeightRatio()
+			if roll < cumulated {
+				return canary
+			}
+		}
+	}
+
+	if primary != nil {
+		return primary
+	}
+	return candidates[0]
+}
+
+// matchingRulesFor returns all rules which are matching the given request and
+// have the highest specificity of all of them. Rules of path type Exact are
+// only matching exactly the requested path.
+func matchingRulesFor(req *http.Request, path []string, in rules.Rules) []rules.Rule {
+	var result []rules.Rule
+	specificity := -1
+	for i := 0; i < in.Len(); i++ {
+		candidate := in.Get(i)
+		if candidate.PathType() == rules.PathTypeExact && !slices.Equal(candidate.Path(), path) {
+			continue
+		}
+		opts := rules.OptionsMatchOf(candidate)
+		if !opts.Matches(req) {
+			continue
+		}
+		if v := opts.Specificity(); v > specificity {
+			result, specificity = []rules.Rule{candidate}, v
+		} else if v == specificity {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+func evaluateCanaryOverride(req *http.Request, opts *rules.OptionsCanary) canaryOverride {
+	if req == nil {
+		return canaryOverrideNone
+	}
+	if name := opts.ByHeader; name != "" {
+		if v := req.Header.Get(name); v != "" {
+			if expected := opts.ByHeaderValue; expected != "" {
+				if v == expected {
+					return canaryOverrideAlways
+				}
+			} else if v == canaryAlways {
+				return canaryOverrideAlways
+			} else if v == canaryNever {
+				return canaryOverrideNever
+			}
+		}
+	}
+	if name := opts.ByCookie; name != "" {
+		if c, err := req.Cookie(name); err == nil {
+			switch c.Value {
+			case canaryAlways:
+				return canaryOverrideAlways
+			case canaryNever:
+				return canaryOverrideNever
+			}
+		}
+	}
+	return canaryOverrideNone
+}
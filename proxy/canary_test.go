@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"github.com/echocat/lingress/rules"
+	"github.com/echocat/lingress/support"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"testing"
+)
+
+func Test_selectRuleFor_without_canaries_selects_first(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	a, b := newTestRule(t, "a", nil), newTestRule(t, "b", nil)
+
+	g.Expect(selectRuleFor(newTestRequest(), nil, testRules{a, b}, fixedRandom(0))).To(BeIdenticalTo(a))
+}
+
+func Test_selectRuleFor_respects_weight(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	primary := newTestRule(t, "primary", nil)
+	canary := newTestRule(t, "canary", rules.Annotations{
+		"lingress.echocat.org/canary":        "true",
+		"lingress.echocat.org/canary-weight": "5",
+	})
+	rs := testRules{canary, primary}
+
+	g.Expect(selectRuleFor(newTestRequest(), nil, rs, fixedRandom(0.04))).To(BeIdenticalTo(canary))
+	g.Expect(selectRuleFor(newTestRequest(), nil, rs, fixedRandom(0.05))).To(BeIdenticalTo(primary))
+	g.Expect(selectRuleFor(newTestRequest(), nil, rs, fixedRandom(0.99))).To(BeIdenticalTo(primary))
+}
+
+func Test_selectRuleFor_respects_header_and_cookie(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	primary := newTestRule(t, "primary", nil)
+	canary := newTestRule(t, "canary", rules.Annotations{
+		"lingress.echocat.org/canary":           "true",
+		"lingress.echocat.org/canary-weight":    "100",
+		"lingress.echocat.org/canary-by-header": "X-Canary",
+		"lingress.echocat.org/canary-by-cookie": "canary",
+	})
+	rs := testRules{primary, canary}
+
+	never := newTestRequest()
+	never.Header.Set("X-Canary", "never")
+	g.Expect(selectRuleFor(never, nil, rs, fixedRandom(0))).To(BeIdenticalTo(primary))
+
+	neverByCookie := newTestRequest()
+	neverByCookie.AddCookie(&http.Cookie{Name: "canary", Value: "never"})
+	g.Expect(selectRuleFor(neverByCookie, nil, rs, fixedRandom(0))).To(BeIdenticalTo(primary))
+
+	g.Expect(selectRuleFor(newTestRequest(), nil, rs, fixedRandom(0))).To(BeIdenticalTo(canary))
+}
+
+func Test_selectRuleFor_respects_header_value(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	primary := newTestRule(t, "primary", nil)
+	canary := newTestRule(t, "canary", rules.Annotations{
+		"lingress.echocat.org/canary":                 "true",
+		"lingress.echocat.org/canary-by-header":       "X-Version",
+		"lingress.echocat.org/canary-by-header-value": "v2",
+	})
+	rs := testRules{primary, canary}
+
+	matching := newTestRequest()
+	matching.Header.Set("X-Version", "v2")
+	g.Expect(selectRuleFor(matching, nil, rs, fixedRandom(0.5))).To(BeIdenticalTo(canary))
+
+	other := newTestRequest()
+	other.Header.Set("X-Version", "v1")
+	g.Expect(selectRuleFor(other, nil, rs, fixedRandom(0.5))).To(BeIdenticalTo(primary))
+}
+
+func Test_selectRuleFor_respects_matches(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	fallback := newTestRule(t, "fallback", nil)
+	byHeader := newTestRule(t, "byHeader", rules.Annotations{
+		"lingress.echocat.org/match-headers": "X-Version: v2",
+	})
+	byMethod := newTestRule(t, "byMethod", rules.Annotations{
+		"lingress.echocat.org/match-methods": "POST",
+	})
+	rs := testRules{fallback, byHeader, byMethod}
+
+	matching := newTestRequest()
+	matching.Header.Set("X-Version", "v2")
+	g.Expect(selectRuleFor(matching, nil, rs, fixedRandom(0))).To(BeIdenticalTo(byHeader))
+
+	g.Expect(selectRuleFor(newTestRequest(), nil, rs, fixedRandom(0))).To(BeIdenticalTo(fallback))
+	g.Expect(selectRuleFor(newTestRequest(), nil, testRules{byHeader, byMethod}, fixedRandom(0))).To(BeNil())
+}
+
+type testRules []rules.Rule
+
+func (this testRules) Get(i int) rules.Rule {
+	return this[i]
+}
+
+func (this testRules) Len() int {
+	return len(this)
+}
+
+func (this testRules) Any() rules.Rule {
+	if len(this) > 0 {
+		return this[0]
+	}
+	return nil
+}
+
+func (this testRules) AnyFilteredBy([]string) rules.Rule {
+	return this.Any()
+}
+
+func newTestRule(t *testing.T, name string, annotations rules.Annotations) rules.Rule {
+	options := rules.DefaultOptionsFactory()
+	if err := options.Set(annotations); err != nil {
+		t.Fatal(err)
+	}
+	source, err := support.NewObjectReferenceOf(&networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: name},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules.NewRule("", []string{}, rules.PathTypePrefix, source, nil, nil, options)
+}
+
+func newTestRequest() *http.Request {
+	return &http.Request{Header: http.Header{}}
+}
+
+func fixedRandom(v float64) func() float64 {
+	return func() float64 {
+		return v
+	}
+}
+
+func Test_findRuleFor_falls_back_to_less_specific_paths(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ruleOf := func(name string, path []string, pathType rules.PathType, annotations rules.Annotations) rules.Rule {
+		options := rules.DefaultOptionsFactory()
+		g.Expect(options.Set(annotations)).To(Succeed())
+		source := support.NewObjectReference("", "HTTPRoute", "test", name)
+		return rules.NewRule("app.example.org", path, pathType, source, nil, nil, options)
+	}
+	fallback := ruleOf("fallback", []string{}, rules.PathTypePrefix, nil)
+	api := ruleOf("api", []string{"api"}, rules.PathTypePrefix, nil)
+	v2 := ruleOf("v2", []string{"api", "v2"}, rules.PathTypePrefix, rules.Annotations{
+		"lingress.echocat.org/match-headers": "X-Version: v2",
+	})
+	exact := ruleOf("exact", []string{"static"}, rules.PathTypeExact, nil)
+
+	repository := testRepository{rules.NewByHost(func([]string, rules.Rule) {}, func([]string, rules.Rule) {})}
+	for _, r := range []rules.Rule{fallback, api, v2, exact} {
+		g.Expect(repository.Put(r)).To(Succeed())
+	}
+	find := func(path string, header http.Header) rules.Rule {
+		req := newTestRequest()
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		actual, err := findRuleFor(repository, req, rules.Query{Host: "app.example.org", Path: path}, fixedRandom(0))
+		g.Expect(err).To(BeNil())
+		return actual
+	}
+
+	g.Expect(find("/api/v2/foo", http.Header{"X-Version": {"v2"}})).To(BeIdenticalTo(v2))
+	g.Expect(find("/api/v2/foo", nil)).To(BeIdenticalTo(api))
+	g.Expect(find("/static", nil)).To(BeIdenticalTo(exact))
+
+	g.Expect(repository.Remove(func(_ []string, r rules.Rule) bool { return r == fallback || r == api })).To(Succeed())
+	g.Expect(find("/api/v2/foo", nil)).To(BeNil())
+}
+
+type testRepository struct {
+	*rules.ByHost
+}
+
+func (this testRepository) Init(support.Channel) error {
+	return nil
+}
+
+func (this testRepository) FindBy(q rules.Query) (rules.Rules, error) {
+	path, err := rules.ParsePath(q.Path, true)
+	if err != nil {
+		return nil, err
+	}
+	return this.Find(q.Host, path)
+}
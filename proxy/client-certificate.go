@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/support"
+)
+
+const (
+	headerClientCertificateSubject     = "X-Client-Certificate-Subject"
+	headerClientCertificateIssuer      = "X-Client-Certificate-Issuer"
+	headerClientCertificateFingerprint = "X-Client-Certificate-Fingerprint"
+)
+
+func init() {
+	DefaultInterceptors.AddPrioritizedFunc("clientCertificate", PriorityClientCertificate, ClientCertificateInterceptor, context.StageEvaluateClientRequest)
+}
+
+func ClientCertificateInterceptor(ctx *context.Context) (proceed bool, err error) {
+	h := ctx.Client.Request.Header
+	// Never trust headers which should be provided by us.
+	h.Del(headerClientCertificateSubject)
+	h.Del(headerClientCertificateIssuer)
+	h.Del(headerClientCertificateFingerprint)
+
+	opts := rules.OptionsClientCertificateOf(ctx.Rule)
+	mode := opts.Mode.Get()
+	if !mode.IsEnabled() {
+		return true, nil
+	}
+
+	// The handshake verified the certificate against the authorities of all
+	// rules of this host; so we have to verify it again against the
+	// authorities of this rule.
+	if c := ctx.Client.Certificate(); c != nil && opts.Authorities != nil && opts.Authorities.Verify(ctx.Client.Request.TLS.PeerCertificates) {
+		h.Set(headerClientCertificateSubject, c.Subject.String())
+		h.Set(headerClientCertificateIssuer, c.Issuer.String())
+		h.Set(headerClientCertificateFingerprint, support.CertificateFingerprint(c))
+		return true, nil
+	}
+
+	if mode != value2.ClientCertificateModeRequired {
+		return true, nil
+	}
+
+	ctx.Client.Response.Header().Set("X-Reason", "client-certificate-required")
+	ctx.Result = context.ResultFailedWithAccessDenied
+	return false, nil
+}
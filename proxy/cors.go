@@ -29,6 +29,10 @@ func (this *CorsInterceptor) Name() string {
 	return "cors"
 }
 
+func (this *CorsInterceptor) Priority() int {
+	return PriorityCors
+}
+
 func (this *CorsInterceptor) HandlesStages() []context.Stage {
 	return []context.Stage{context.StageEvaluateClientRequest, context.StagePrepareClientResponse}
 }
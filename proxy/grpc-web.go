@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	grpcContentType        = "application/grpc"
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+
+	// grpcWebTrailerFlag marks a frame of a gRPC-Web response which contains
+	// the trailers instead of a message.
+	grpcWebTrailerFlag = byte(0x80)
+)
+
+func init() {
+	DefaultInterceptors.Add(NewGrpcWebInterceptor())
+}
+
+// GrpcWebInterceptor translates gRPC-Web requests (as sent by browsers) into
+// regular gRPC requests to the upstream and the responses back into gRPC-Web
+// responses, which contain the trailers inside the body.
+type GrpcWebInterceptor struct{}
+
+func NewGrpcWebInterceptor() *GrpcWebInterceptor {
+	return &GrpcWebInterceptor{}
+}
+
+func (this *GrpcWebInterceptor) Name() string {
+	return "grpcWeb"
+}
+
+func (this *GrpcWebInterceptor) HandlesStages() []context.Stage {
+	return []context.Stage{context.StagePrepareUpstreamRequest, context.StagePrepareClientResponse}
+}
+
+func (this *GrpcWebInterceptor) Handle(ctx *context.Context) (proceed bool, err error) {
+	if !rules.OptionsGrpcWebOf(ctx.Rule).Enabled.GetOr(false) {
+		return true, nil
+	}
+	contentType := ctx.Client.Request.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, grpcWebContentType) {
+		return true, nil
+	}
+	text := strings.HasPrefix(contentType, grpcWebTextContentType)
+
+	switch ctx.Stage {
+	case context.StagePrepareUpstreamRequest:
+		this.prepareRequest(ctx.Upstream.Request, contentType, text)
+	case context.StagePrepareClientResponse:
+		if resp := ctx.Upstream.Response; resp != nil {
+			this.prepareResponse(ctx.Client.Response.Header(), resp, text)
+		}
+	}
+	return true, nil
+}
+
+func (this *GrpcWebInterceptor) prepareRequest(req *http.Request, contentType string, text bool) {
+	if req == nil {
+		return
+	}
+	if text {
+		contentType = strings.TrimPrefix(contentType, grpcWebTextContentType)
+		if body := req.Body; body != nil && body != http.NoBody {
+			req.Body = struct {
+				io.Reader
+				io.Closer
+			}{base64.NewDecoder(base64.StdEncoding, body), body}
+			req.ContentLength = -1
+			req.Header.Del("Content-Length")
+		}
+	} else {
+		contentType = strings.TrimPrefix(contentType, grpcWebContentType)
+	}
+	req.Header.Set("Content-Type", grpcContentType+contentType)
+	req.Header.Set("Te", "trailers")
+	req.Header.Del("X-Grpc-Web")
+}
+
+func (this *GrpcWebInterceptor) prepareResponse(header http.Header, resp *http.Response, text bool) {
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, grpcContentType) {
+		// This is not a gRPC response (maybe an error page of the upstream);
+		// so leave it as it is.
+		return
+	}
+	if text {
+		contentType = grpcWebTextContentType + strings.TrimPrefix(contentType, grpcContentType)
+	} else {
+		contentType = grpcWebContentType + strings.TrimPrefix(contentType, grpcContentType)
+	}
+	header.Set("Content-Type", contentType)
+	header.Del("Content-Length")
+	header.Del("Trailer")
+	resp.Body = newGrpcWebResponseBody(resp, text)
+}
+
+// grpcWebResponseBody passes the body of an upstream gRPC response through
+// and appends the trailers as gRPC-Web trailer frame. In text mode everything
+// is base64 encoded.
+type grpcWebResponseBody struct {
+	response *http.Response
+	source   io.ReadCloser
+	chunk    []byte
+	buffer   bytes.Buffer
+	target   io.Writer
+	encoder  io.WriteCloser
+	done     bool
+}
+
+func newGrpcWebResponseBody(response *http.Response, text bool) *grpcWebResponseBody {
+	result := &grpcWebResponseBody{
+		response: response,
+		source:   response.Body,
+		chunk:    make([]byte, 32*1024),
+	}
+	result.target = &result.buffer
+	if text {
+		result.encoder = base64.NewEncoder(base64.StdEncoding, &result.buffer)
+		result.target = result.encoder
+	}
+	return result
+}
+
+func (this *grpcWebResponseBody) Read(p []byte) (int, error) {
+	for this.buffer.Len() == 0 && !this.done {
+		if err := this.fill(); err != nil {
+			return 0, err
+		}
+	}
+	if this.buffer.Len() == 0 {
+		return 0, io.EOF
+	}
+	return this.buffer.Read(p)
+}
+
+func (this *grpcWebResponseBody) fill() error {
+	n, err := this.source.Read(this.chunk)
+	if n > 0 {
+		_, _ = this.target.Write(this.chunk[:n])
+	}
+	if err == io.EOF {
+		this.done = true
+		this.writeTrailers()
+		if this.encoder != nil {
+			_ = this.encoder.Close()
+		}
+		return nil
+	}
+	return err
+}
+
+func (this *grpcWebResponseBody) writeTrailers() {
+	trailer := this.response.Trailer
+	// The trailers are part of the body now; so they must not be sent as
+	// HTTP trailers to the client, too.
+	this.response.Trailer = nil
+	if len(trailer) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(trailer))
+	for key := range trailer {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var content bytes.Buffer
+	for _, key := range keys {
+		for _, v := range trailer[key] {
+			content.WriteString(strings.ToLower(key))
+			content.WriteString(": ")
+			content.WriteString(v)
+			content.WriteString("\r\n")
+		}
+	}
+
+	var frameHeader [5]byte
+	frameHeader[0] = grpcWebTrailerFlag
+	binary.BigEndian.PutUint32(frameHeader[1:], uint32(content.Len()))
+	_, _ = this.target.Write(frameHeader[:])
+	_, _ = this.target.Write(content.Bytes())
+}
+
+func (this *grpcWebResponseBody) Close() error {
+	return this.source.Close()
+}
@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"encoding/base64"
+	. "github.com/onsi/gomega"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_grpcWebResponseBody_appends_trailers(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newResponse := func() *http.Response {
+		return &http.Response{
+			Body: io.NopCloser(strings.NewReader("message")),
+			Trailer: http.Header{
+				"Grpc-Status":  {"0"},
+				"Grpc-Message": {"ok"},
+			},
+		}
+	}
+	expected := "message\x80\x00\x00\x00\x22grpc-message: ok\r\ngrpc-status: 0\r\n"
+
+	resp := newResponse()
+	actual, err := io.ReadAll(newGrpcWebResponseBody(resp, false))
+	g.Expect(err).To(BeNil())
+	g.Expect(string(actual)).To(Equal(expected))
+	g.Expect(resp.Trailer).To(BeEmpty())
+
+	actual, err = io.ReadAll(newGrpcWebResponseBody(newResponse(), true))
+	g.Expect(err).To(BeNil())
+	g.Expect(string(actual)).To(Equal(base64.StdEncoding.EncodeToString([]byte(expected))))
+}
+
+func Test_GrpcWebInterceptor_prepareRequest(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost/foo.Bar/Baz", strings.NewReader(base64.StdEncoding.EncodeToString([]byte("message"))))
+	g.Expect(err).To(BeNil())
+
+	NewGrpcWebInterceptor().prepareRequest(req, "application/grpc-web-text+proto", true)
+
+	g.Expect(req.Header.Get("Content-Type")).To(Equal("application/grpc+proto"))
+	g.Expect(req.Header.Get("Te")).To(Equal("trailers"))
+	g.Expect(req.ContentLength).To(Equal(int64(-1)))
+	body, err := io.ReadAll(req.Body)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(body)).To(Equal("message"))
+}
@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+)
+
+// reportUpstreamHealth reports the outcome of the upstream request to the
+// passive health checks (outlier detection) of the endpoint. Dial errors and
+// 5xx responses are counted as failures.
+func (this *Proxy) reportUpstreamHealth(ctx *lctx.Context, r rules.Rule, endpoint *rules.Endpoint, status int, err error) {
+	opts := rules.OptionsHealthCheckOf(r)
+	if !opts.IsPassive() {
+		return
+	}
+
+	if isDialError(err) || status >= 500 {
+		if endpoint.ReportFailure(opts.OutlierConsecutiveErrors.Get(), opts.GetOutlierEjectionTime()) {
+			ctx.Log().
+				With("endpoint", endpoint.Address).
+				With("ejectedUntil", endpoint.EjectedUntil()).
+				Warn("Endpoint ejected because of too many consecutive errors.")
+		}
+	} else if err == nil {
+		endpoint.ReportSuccess()
+	}
+}
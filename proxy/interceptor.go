@@ -1,28 +1,66 @@
 package proxy
 
 import (
+	"cmp"
 	"github.com/echocat/lingress/context"
+	"slices"
+	"strings"
 )
 
 var (
 	DefaultInterceptors = make(Interceptors)
 )
 
+// Priorities of the interceptors of context.StageEvaluateClientRequest. Lower
+// ones are handled first: A request has to be secure and from an allowed
+// remote before CORS preflights are answered; these are answered before any
+// limit is counted or any credential is challenged. Redirects are only
+// answered for requests which passed all of them.
+const (
+	PriorityForceSecure        = 100
+	PriorityWhitelistedRemotes = 200
+	PriorityCors               = 300
+	PriorityRateLimit          = 400
+	PriorityClientCertificate  = 500
+	PriorityBasicAuth          = 600
+	PriorityForwardAuth        = 700
+	PriorityRedirect           = 800
+
+	// DefaultPriority is used for all interceptors which are not
+	// implementing PrioritizedInterceptor.
+	DefaultPriority = 1000
+)
+
 type Interceptor interface {
 	Name() string
 	Handle(ctx *context.Context) (proceed bool, err error)
 	HandlesStages() []context.Stage
 }
 
-type Interceptors map[context.Stage]map[string]Interceptor
+// PrioritizedInterceptor is an Interceptor which defines its position inside
+// of its stages. Interceptors with a lower priority are handled first; equal
+// priorities are ordered by name.
+type PrioritizedInterceptor interface {
+	Interceptor
+	Priority() int
+}
+
+func PriorityOf(i Interceptor) int {
+	if pi, ok := i.(PrioritizedInterceptor); ok {
+		return pi.Priority()
+	}
+	return DefaultPriority
+}
+
+// Interceptors holds the interceptors of each stage in the order they are
+// handled.
+type Interceptors map[context.Stage][]Interceptor
 
 func (this Interceptors) Handle(ctx *context.Context) (proceed bool, err error) {
 	proceed = true
-	if candidates, ok := this[ctx.Stage]; ok {
-		for _, candidate := range candidates {
-			if proceed, err = candidate.Handle(ctx); !proceed || err != nil {
-				return
-			}
+	for _, candidate := range this[ctx.Stage] {
+		if proceed, err = candidate.Handle(ctx); !proceed || err != nil {
+			return
 		}
 	}
 	return
@@ -31,20 +69,33 @@ func (this Interceptors) Handle(ctx *context.Context) (proceed bool, err error)
 func (this Interceptors) Add(i Interceptor) Interceptors {
 	name := i.Name()
 	for _, stage := range i.HandlesStages() {
-		if ofStage, found := this[stage]; found {
-			ofStage[name] = i
-		} else {
-			this[stage] = map[string]Interceptor{name: i}
-		}
+		candidates := slices.DeleteFunc(this[stage], func(candidate Interceptor) bool {
+			return candidate.Name() == name
+		})
+		candidates = append(candidates, i)
+		slices.SortStableFunc(candidates, compareInterceptors)
+		this[stage] = candidates
 	}
 	return this
 }
 
+func compareInterceptors(a, b Interceptor) int {
+	if c := cmp.Compare(PriorityOf(a), PriorityOf(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Name(), b.Name())
+}
+
 func (this Interceptors) AddFunc(name string, i InterceptorFunc, stages ...context.Stage) Interceptors {
+	return this.AddPrioritizedFunc(name, DefaultPriority, i, stages...)
+}
+
+func (this Interceptors) AddPrioritizedFunc(name string, priority int, i InterceptorFunc, stages ...context.Stage) Interceptors {
 	return this.Add(&interceptorFunc{
-		name:    name,
-		handler: i,
-		stages:  stages,
+		name:     name,
+		priority: priority,
+		handler:  i,
+		stages:   stages,
 	})
 }
 
@@ -54,9 +105,13 @@ func (this Interceptors) Remove(i Interceptor) Interceptors {
 
 func (this Interceptors) RemoveByName(name string) Interceptors {
 	for stage, candidates := range this {
-		delete(candidates, name)
+		candidates = slices.DeleteFunc(candidates, func(candidate Interceptor) bool {
+			return candidate.Name() == name
+		})
 		if len(candidates) <= 0 {
 			delete(this, stage)
+		} else {
+			this[stage] = candidates
 		}
 	}
 	return this
@@ -65,11 +120,7 @@ func (this Interceptors) RemoveByName(name string) Interceptors {
 func (this Interceptors) Clone() Interceptors {
 	result := make(Interceptors)
 	for stage, candidates := range this {
-		resultCandidates := make(map[string]Interceptor)
-		for name, candidate := range candidates {
-			resultCandidates[name] = candidate
-		}
-		result[stage] = resultCandidates
+		result[stage] = slices.Clone(candidates)
 	}
 	return result
 }
@@ -77,15 +128,20 @@ func (this Interceptors) Clone() Interceptors {
 type InterceptorFunc func(ctx *context.Context) (proceed bool, err error)
 
 type interceptorFunc struct {
-	name    string
-	handler InterceptorFunc
-	stages  []context.Stage
+	name     string
+	priority int
+	handler  InterceptorFunc
+	stages   []context.Stage
 }
 
 func (this *interceptorFunc) Name() string {
 	return this.name
 }
 
+func (this *interceptorFunc) Priority() int {
+	return this.priority
+}
+
 func (this *interceptorFunc) Handle(ctx *context.Context) (proceed bool, err error) {
 	return this.handler(ctx)
 }
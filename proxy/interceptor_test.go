@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"github.com/echocat/lingress/context"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func namesOf(in []Interceptor) []string {
+	result := make([]string, len(in))
+	for i, candidate := range in {
+		result[i] = candidate.Name()
+	}
+	return result
+}
+
+func Test_DefaultInterceptors_order(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(namesOf(DefaultInterceptors[context.StageEvaluateClientRequest])).To(Equal([]string{
+		"forceSecure",
+		"whitelistedRemotes",
+		"cors",
+		"rateLimit",
+		"clientCertificate",
+		"basicAuth",
+		"forwardAuth",
+		"redirect",
+	}))
+}
+
+func Test_Interceptors_Add(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	noop := func(*context.Context) (bool, error) { return true, nil }
+	instance := make(Interceptors).
+		AddFunc("b", noop, context.StageEvaluateClientRequest).
+		AddFunc("a", noop, context.StageEvaluateClientRequest).
+		AddPrioritizedFunc("c", 1, noop, context.StageEvaluateClientRequest, context.StageDone)
+	g.Expect(namesOf(instance[context.StageEvaluateClientRequest])).To(Equal([]string{"c", "a", "b"}))
+
+	// Adding one with the same name again replaces it.
+	instance.AddPrioritizedFunc("b", 0, noop, context.StageEvaluateClientRequest)
+	g.Expect(namesOf(instance[context.StageEvaluateClientRequest])).To(Equal([]string{"b", "c", "a"}))
+
+	clone := instance.Clone()
+	instance.RemoveByName("c")
+	g.Expect(namesOf(instance[context.StageEvaluateClientRequest])).To(Equal([]string{"b", "a"}))
+	g.Expect(instance).NotTo(HaveKey(context.StageDone))
+	g.Expect(namesOf(clone[context.StageEvaluateClientRequest])).To(Equal([]string{"b", "c", "a"}))
+}
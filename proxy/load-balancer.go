@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+)
+
+// selectUpstreamEndpoint selects the endpoint the upstream request should be
+// sent to. If the rule does carry endpoints (see --discovery.endpoints) and
+// is not configured to use the service itself, one of these endpoints is
+// selected using the configured load balancer; otherwise the backend of the
+// rule (clusterIP of the service) is used. The returned release function has
+// to be called after the upstream request was finished. If the endpoint is
+// nil there is no available endpoint.
+func (this *Proxy) selectUpstreamEndpoint(ctx *lctx.Context, r rules.Rule) (*rules.Endpoint, func(), error) {
+	endpoints := r.Endpoints()
+	opts := rules.OptionsLoadBalancerOf(r)
+	if endpoints == nil || opts.ServiceUpstream.GetOr(false) {
+		return &rules.Endpoint{Address: r.Backend()}, func() {}, nil
+	}
+
+	lb := opts.LoadBalancer
+	if lb == rules.LoadBalancerDefault {
+		var err error
+		if lb, err = rules.ParseLoadBalancer(this.settings.Upstream.LoadBalancer); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var hashKey string
+	if lb == rules.LoadBalancerConsistentHash {
+		hashKey = hashKeyOf(ctx, opts.HashBy)
+	}
+
+	endpoint, release := endpoints.Select(lb, hashKey)
+	return endpoint, release, nil
+}
+
+func hashKeyOf(ctx *lctx.Context, by rules.HashBy) string {
+	req := ctx.Client.Request
+	switch by.Kind {
+	case rules.HashByPath:
+		if u := req.URL; u != nil {
+			return u.Path
+		}
+		return req.RequestURI
+	case rules.HashByHeader:
+		return req.Header.Get(by.Name)
+	case rules.HashByCookie:
+		if c, err := req.Cookie(by.Name); err == nil {
+			return c.Value
+		}
+		return ""
+	default:
+		address, _ := ctx.Client.Address()
+		return address
+	}
+}
@@ -27,6 +27,7 @@ type Proxy struct {
 
 	Dialer          net.Dialer
 	Transport       http.Transport
+	Http2Transport  http.Transport
 	RulesRepository rules.Repository
 	Logger          log.Logger
 
@@ -54,6 +55,11 @@ func New(s *settings.Settings, rules rules.Repository, logger log.Logger) (*Prox
 		Logger:          logger,
 	}
 	result.Transport.DialContext = result.Dialer.DialContext
+	result.Http2Transport.DialContext = result.Dialer.DialContext
+	result.Http2Transport.TLSClientConfig = result.Transport.TLSClientConfig.Clone()
+	result.Http2Transport.Protocols = new(http.Protocols)
+	result.Http2Transport.Protocols.SetUnencryptedHTTP2(true)
+	result.Http2Transport.Protocols.SetHTTP2(true)
 	result.bufferPool.New = result.createBuffer
 	return result, nil
 }
@@ -65,6 +71,9 @@ func (this *Proxy) Init(support.Channel) error {
 	if err := this.settings.Upstream.ApplyToHttpTransport(&this.Transport); err != nil {
 		return err
 	}
+	if err := this.settings.Upstream.ApplyToHttpTransport(&this.Http2Transport); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -134,18 +143,13 @@ func (this *Proxy) ServeHTTP(connector server.Connector, resp http.ResponseWrite
 		query.Path = u.Path
 	}
 
-	rs, err := this.RulesRepository.FindBy(query)
+	r, err := this.findRule(ctx, query)
 	if err != nil {
 		this.markDone(lctx.ResultFailedWithUnexpectedError, ctx, err)
 		return
-	} else if rs == nil || rs.Len() == 0 {
-		this.markDone(lctx.ResultFailedWithRuleNotFound, ctx, err)
-		return
 	}
-
-	r, err := this.selectRule(rs)
-	if err != nil {
-		this.markDone(lctx.ResultFailedWithUnexpectedError, ctx, err)
+	if r == nil {
+		this.markDone(lctx.ResultFailedWithRuleNotFound, ctx, err)
 		return
 	}
 
@@ -158,9 +162,22 @@ func (this *Proxy) ServeHTTP(connector server.Connector, resp http.ResponseWrite
 		return
 	}
 
-	ctx.Upstream.Address = r.Backend()
+	endpoint, release, err := this.selectUpstreamEndpoint(ctx, r)
+	if err != nil {
+		this.markDone(lctx.ResultFailedWithUnexpectedError, ctx, err)
+		return
+	}
+	// execute releases the endpoint as soon as a retry abandons it; so it must
+	// not be released twice.
+	release = sync.OnceFunc(release)
+	defer release()
+	if endpoint == nil {
+		this.markDone(lctx.ResultFailedWithUpstreamUnavailable, ctx, fmt.Errorf("there are no available endpoints for rule %v", r))
+		return
+	}
+	ctx.Upstream.Address = endpoint.Address
 
-	if proceed, err := this.createBackendRequestFor(ctx, r); err != nil {
+	if proceed, err := this.createBackendRequestFor(ctx); err != nil {
 		this.markDone(lctx.ResultFailedWithUnexpectedError, ctx, err)
 		return
 	} else if !proceed {
@@ -170,7 +187,10 @@ func (this *Proxy) ServeHTTP(connector server.Connector, resp http.ResponseWrite
 		defer cancel()
 	}
 
-	if err := this.execute(ctx); isDialError(err) {
+	if err := this.execute(ctx, r, endpoint, release); isUpstreamTimeoutError(ctx, err) && ctx.Client.Status <= 0 {
+		this.markDone(lctx.ResultFailedWithUpstreamTimeout, ctx, err)
+		return
+	} else if isDialError(err) {
 		this.markDone(lctx.ResultFailedWithUpstreamUnavailable, ctx, err)
 		return
 	} else if isClientGoneError(err) {
@@ -207,11 +227,7 @@ func (this *Proxy) markDone(result lctx.Result, ctx *lctx.Context, err ...error)
 	ctx.Done(result, err...)
 }
 
-func (this *Proxy) selectRule(in rules.Rules) (out rules.Rule, err error) {
-	return in.Any(), nil
-}
-
-func (this *Proxy) createBackendRequestFor(ctx *lctx.Context, r rules.Rule) (proceed bool, err error) {
+func (this *Proxy) createBackendRequestFor(ctx *lctx.Context) (proceed bool, err error) {
 	ctx.Stage = lctx.StagePrepareUpstreamRequest
 	fReq := ctx.Client.Request
 	u, err := url.Parse(fReq.URL.String())
@@ -222,37 +238,29 @@ func (this *Proxy) createBackendRequestFor(ctx *lctx.Context, r rules.Rule) (pro
 	if v := this.settings.Upstream.OverrideHost; v != "" {
 		u.Host = v
 	} else {
-		u.Host = r.Backend().String()
+		u.Host = ctx.Upstream.Address.String()
 	}
+	protocol := rules.OptionsUpstreamOf(ctx.Rule).Protocol
 	if v := this.settings.Upstream.OverrideScheme; v != "" {
 		u.Scheme = v
 	} else {
-		u.Scheme = "http"
+		u.Scheme = protocol.Scheme()
 	}
 
-	bCtx := fReq.Context()
-	var cancel context.CancelFunc
-	bCtx, ctx.Upstream.Cancel = context.WithCancel(bCtx)
+	bCtx, cancel := this.createBackendContextFor(ctx, fReq.Context())
 	ctx.Upstream.Cancel = cancel
-	go func(cancel context.CancelFunc) {
-		if cancel != nil {
-			select {
-			case <-ctx.Client.Request.Context().Done():
-				cancel()
-			case <-bCtx.Done():
-			}
-		}
-	}(cancel)
 
 	bReq := (&http.Request{
-		Host:             u.Host,
-		Method:           fReq.Method,
-		URL:              u,
-		Proto:            "HTTP/1.1",
-		ProtoMajor:       1,
-		ProtoMinor:       1,
-		Header:           cloneHeader(fReq.Header),
-		Trailer:          cloneHeader(fReq.Trailer),
+		Host:       u.Host,
+		Method:     fReq.Method,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     cloneHeader(fReq.Header),
+		// The values of the trailers are only known after the body was read
+		// completely; so the map has to be shared instead of being cloned.
+		Trailer:          fReq.Trailer,
 		Close:            false,
 		Body:             fReq.Body,
 		ContentLength:    fReq.ContentLength,
@@ -262,7 +270,10 @@ func (this *Proxy) createBackendRequestFor(ctx *lctx.Context, r rules.Rule) (pro
 	if fReq.ContentLength == 0 {
 		bReq.Body = nil // Issue 16036: nil Body for http.Transport retries
 	}
-	if fReq.ContentLength < 0 && len(bReq.TransferEncoding) <= 0 {
+	if protocol.IsHttp2() {
+		bReq.Proto, bReq.ProtoMajor, bReq.ProtoMinor = "HTTP/2.0", 2, 0
+		bReq.TransferEncoding = nil
+	} else if fReq.ContentLength < 0 && len(bReq.TransferEncoding) <= 0 {
 		bReq.TransferEncoding = []string{"chunked"}
 	}
 	reqUpType := retrieveUpgradeType(bReq.Header)
@@ -275,7 +286,16 @@ func (this *Proxy) createBackendRequestFor(ctx *lctx.Context, r rules.Rule) (pro
 	return this.callInterceptors(ctx)
 }
 
-func (this *Proxy) execute(ctx *lctx.Context) error {
+// transportFor returns the transport which speaks the backend protocol of
+// the given rule.
+func (this *Proxy) transportFor(r rules.Rule) http.RoundTripper {
+	if rules.OptionsUpstreamOf(r).Protocol.IsHttp2() {
+		return &this.Http2Transport
+	}
+	return &this.Transport
+}
+
+func (this *Proxy) execute(ctx *lctx.Context, r rules.Rule, endpoint *rules.Endpoint, releaseEndpoint func()) error {
 	if mc := this.MetricsCollector; mc != nil {
 		finalize := mc.CollectUpstreamStarted()
 		defer finalize()
@@ -289,7 +309,8 @@ func (this *Proxy) execute(ctx *lctx.Context) error {
 	} else if !proceed {
 		return nil
 	}
-	bResp, err := this.Transport.RoundTrip(ctx.Upstream.Request)
+	bResp, release, err := this.roundTrip(ctx, r, endpoint, releaseEndpoint)
+	defer release()
 	ctx.Upstream.Duration = time.Now().Sub(ctx.Upstream.Started)
 	if err != nil {
 		return err
@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"container/list"
+	"github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	value2 "github.com/echocat/lingress/rules/value"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+func init() {
+	DefaultInterceptors.Add(NewRateLimitInterceptor())
+}
+
+// RateLimitInterceptor limits the requests per key using token buckets. The
+// buckets are kept in memory and bounded by --rateLimit.maxKeys; if exceeded
+// the least recently used buckets are forgotten.
+type RateLimitInterceptor struct {
+	mutex   sync.Mutex
+	buckets map[string]*list.Element
+	lru     *list.List
+
+	now func() time.Time
+}
+
+type rateLimitBucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+type rateLimitState struct {
+	allowed   bool
+	remaining float64
+	retryIn   time.Duration
+	resetIn   time.Duration
+}
+
+func NewRateLimitInterceptor() *RateLimitInterceptor {
+	return &RateLimitInterceptor{
+		buckets: map[string]*list.Element{},
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+func (this *RateLimitInterceptor) Name() string {
+	return "rateLimit"
+}
+
+func (this *RateLimitInterceptor) Priority() int {
+	return PriorityRateLimit
+}
+
+func (this *RateLimitInterceptor) HandlesStages() []context.Stage {
+	return []context.Stage{context.StageEvaluateClientRequest}
+}
+
+func (this *RateLimitInterceptor) Handle(ctx *context.Context) (proceed bool, err error) {
+	r := ctx.Rule
+	if r == nil {
+		return true, nil
+	}
+	opts := rules.OptionsRateLimitOf(r)
+	s := ctx.Settings.RateLimit
+
+	requests := s.Requests.Evaluate(opts.Requests).Get()
+	if requests == 0 {
+		return true, nil
+	}
+	period := s.Period.Evaluate(opts.Period).GetOr(time.Second)
+	if period <= 0 {
+		return true, nil
+	}
+	burst := s.Burst.Evaluate(opts.Burst).GetOr(requests)
+	if burst == 0 {
+		burst = requests
+	}
+
+	key, err := this.keyOf(ctx, s.By.Evaluate(opts.By).Get())
+	if err != nil {
+		return false, err
+	}
+
+	rate := float64(requests) / period.Seconds()
+	state := this.take(key, rate, float64(burst), int(s.MaxKeys))
+
+	h := ctx.Client.Response.Header()
+	h.Set("RateLimit-Limit", strconv.FormatUint(uint64(burst), 10))
+	h.Set("RateLimit-Remaining", strconv.FormatInt(int64(math.Floor(state.remaining)), 10))
+	h.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(state.resetIn), 10))
+
+	if state.allowed {
+		return true, nil
+	}
+
+	h.Set("Retry-After", strconv.FormatInt(ceilSeconds(state.retryIn), 10))
+	h.Set("X-Reason", "rate-limited")
+	ctx.Result = context.ResultFailedWithTooManyRequests
+	return false, nil
+}
+
+func (this *RateLimitInterceptor) keyOf(ctx *context.Context, by value2.RateLimitBy) (string, error) {
+	prefix := ctx.Rule.Source().String() + "\n"
+	switch by.Kind() {
+	case value2.RateLimitByRule:
+		return prefix, nil
+	case value2.RateLimitByHeader:
+		if v := ctx.Client.Request.Header.Get(by.HeaderName()); v != "" {
+			return prefix + "header:" + v, nil
+		}
+	}
+	address, err := ctx.Client.Address()
+	if err != nil {
+		return "", err
+	}
+	return prefix + "remote:" + address, nil
+}
+
+func (this *RateLimitInterceptor) take(key string, rate, burst float64, maxKeys int) rateLimitState {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	now := this.now()
+	var bucket *rateLimitBucket
+	if element, ok := this.buckets[key]; ok {
+		this.lru.MoveToFront(element)
+		bucket = element.Value.(*rateLimitBucket)
+		bucket.tokens = math.Min(burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+		bucket.updated = now
+	} else {
+		bucket = &rateLimitBucket{
+			key:     key,
+			tokens:  burst,
+			updated: now,
+		}
+		this.buckets[key] = this.lru.PushFront(bucket)
+		for this.lru.Len() > maxKeys {
+			oldest := this.lru.Back()
+			this.lru.Remove(oldest)
+			delete(this.buckets, oldest.Value.(*rateLimitBucket).key)
+		}
+	}
+
+	result := rateLimitState{}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.allowed = true
+	} else {
+		result.retryIn = secondsToDuration((1 - bucket.tokens) / rate)
+	}
+	result.remaining = bucket.tokens
+	result.resetIn = secondsToDuration((burst - bucket.tokens) / rate)
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
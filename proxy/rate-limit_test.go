@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func Test_RateLimitInterceptor_take_refills_tokens(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	now := time.Unix(0, 0)
+	instance := NewRateLimitInterceptor()
+	instance.now = func() time.Time { return now }
+
+	g.Expect(instance.take("a", 1, 2, 10).allowed).To(BeTrue())
+	g.Expect(instance.take("a", 1, 2, 10).allowed).To(BeTrue())
+
+	rejected := instance.take("a", 1, 2, 10)
+	g.Expect(rejected.allowed).To(BeFalse())
+	g.Expect(rejected.retryIn).To(Equal(time.Second))
+	g.Expect(rejected.resetIn).To(Equal(2 * time.Second))
+
+	now = now.Add(time.Second)
+	g.Expect(instance.take("a", 1, 2, 10).allowed).To(BeTrue())
+	g.Expect(instance.take("a", 1, 2, 10).allowed).To(BeFalse())
+}
+
+func Test_RateLimitInterceptor_take_evicts_least_recently_used(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	instance := NewRateLimitInterceptor()
+	instance.now = func() time.Time { return time.Unix(0, 0) }
+
+	g.Expect(instance.take("a", 1, 1, 2).allowed).To(BeTrue())
+	g.Expect(instance.take("b", 1, 1, 2).allowed).To(BeTrue())
+	g.Expect(instance.take("a", 1, 1, 2).allowed).To(BeFalse())
+	g.Expect(instance.take("c", 1, 1, 2).allowed).To(BeTrue())
+
+	g.Expect(instance.buckets).To(HaveLen(2))
+	g.Expect(instance.buckets).To(HaveKey("a"))
+	g.Expect(instance.buckets).To(HaveKey("c"))
+	g.Expect(instance.take("b", 1, 1, 2).allowed).To(BeTrue())
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	DefaultInterceptors.AddPrioritizedFunc("redirect", PriorityRedirect, RedirectInterceptor, context.StageEvaluateClientRequest)
+}
+
+func RedirectInterceptor(ctx *context.Context) (proceed bool, err error) {
+	r := ctx.Rule
+	opts := rules.OptionsRedirectOf(r)
+	if r == nil || !opts.IsRelevant() {
+		return true, nil
+	}
+
+	u, err := ctx.Client.RequestedUrl()
+	if err != nil || u == nil {
+		return true, err
+	}
+
+	target, err := redirectTargetOf(u, r, opts)
+	if err != nil {
+		return false, err
+	}
+
+	ctx.Client.Response.Header().Set("X-Reason", "redirect")
+	ctx.Result = context.RedirectResult{
+		StatusCode: opts.GetStatusCode(),
+		Target:     target.String(),
+	}
+
+	return false, nil
+}
+
+func redirectTargetOf(u *url.URL, r rules.Rule, opts *rules.OptionsRedirect) (*url.URL, error) {
+	cu := *u
+	result := &cu
+
+	host, port := result.Hostname(), result.Port()
+	if v := opts.Scheme; v != "" && v != result.Scheme {
+		result.Scheme = v
+		// The port of the original request does not make sense for another scheme.
+		port = ""
+	}
+	if v := opts.Host; v != "" {
+		host = v
+	}
+	if v := opts.Port; v.IsPresent() {
+		port = strconv.FormatUint(uint64(v.Get()), 10)
+	}
+	if (result.Scheme == "http" && port == "80") || (result.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		result.Host = net.JoinHostPort(host, port)
+	} else {
+		result.Host = host
+	}
+
+	if opts.Path != nil {
+		result.Path = "/" + strings.Join(opts.Path, "/")
+		result.RawPath = ""
+	} else if opts.PathPrefix != nil {
+		path, err := rules.ParsePath(result.Path, true)
+		if err != nil {
+			return nil, err
+		}
+		path = append(append([]string{}, opts.PathPrefix...), stripPrefix(path, r.Path())...)
+		result.Path = "/" + strings.Join(path, "/")
+		result.RawPath = ""
+	}
+
+	return result, nil
+}
@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"github.com/echocat/lingress/rules"
+	. "github.com/onsi/gomega"
+	"net/url"
+	"testing"
+)
+
+func Test_redirectTargetOf(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	options := rules.DefaultOptionsFactory()
+	g.Expect(options.Set(rules.Annotations{
+		"lingress.echocat.org/redirect-scheme":      "https",
+		"lingress.echocat.org/redirect-path-prefix": "/new",
+	})).To(Succeed())
+	r := rules.NewRule("", []string{"old"}, rules.PathTypePrefix, nil, nil, nil, options)
+
+	u, err := url.Parse("http://example.org:8080/old/foo?bar=1")
+	g.Expect(err).To(BeNil())
+
+	actual, err := redirectTargetOf(u, r, rules.OptionsRedirectOf(r))
+	g.Expect(err).To(BeNil())
+	g.Expect(actual.String()).To(Equal("https://example.org/new/foo?bar=1"))
+	g.Expect(rules.OptionsRedirectOf(r).GetStatusCode()).To(Equal(302))
+}
@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	value2 "github.com/echocat/lingress/rules/value"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"syscall"
+	"time"
+)
+
+const (
+	// retryDrainBytes is the maximum amount of bytes read from the body of a
+	// failed response before a retry. Smaller bodies allow the connection to
+	// be reused; larger ones are not worth the time.
+	retryDrainBytes = 4 << 10
+)
+
+var (
+	errPerTryTimeout = errors.New("per try timeout reached")
+)
+
+type retryPolicy struct {
+	attempts      uint32
+	on            value2.RetryOn
+	backoff       time.Duration
+	perTryTimeout time.Duration
+}
+
+func (this *Proxy) retryPolicyFor(r rules.Rule) retryPolicy {
+	opts := rules.OptionsRetryOf(r)
+	s := this.settings.Upstream
+	return retryPolicy{
+		attempts:      min(s.RetryAttempts.Evaluate(opts.Attempts).GetOr(1), value2.MaxRetryAttempts),
+		on:            s.RetryOn.Evaluate(opts.On).Get(),
+		backoff:       min(s.RetryBackoff.Evaluate(opts.Backoff).Get(), value2.MaxRetryBackoff),
+		perTryTimeout: s.RetryPerTryTimeout.Evaluate(opts.PerTryTimeout).Get(),
+	}
+}
+
+// backoffAfter returns the time to wait after the given attempt failed. It is
+// doubled with each attempt up to value2.MaxRetryBackoff.
+func (this retryPolicy) backoffAfter(attempt uint32) time.Duration {
+	result := this.backoff
+	limit := value2.MaxRetryBackoff
+	for i := uint32(1); i < attempt && result > 0 && result < limit; i++ {
+		result <<= 1
+	}
+	return min(result, limit)
+}
+
+// roundTrip sends the upstream request to the given endpoint which was
+// selected together with the given release function. If this fails with one
+// of the conditions of the retry policy of the rule, the request will be sent
+// again to another selected endpoint; the abandoned endpoint is released
+// immediately. The returned release function has to be called after the
+// response was consumed.
+func (this *Proxy) roundTrip(ctx *lctx.Context, r rules.Rule, endpoint *rules.Endpoint, release func()) (*http.Response, func(), error) {
+	policy := this.retryPolicyFor(r)
+	req := ctx.Upstream.Request
+	replayable := policy.attempts > 1 && this.prepareRequestForRetries(req)
+	transport := this.transportFor(r)
+	responseHeaderTimeout := this.responseHeaderTimeoutFor(r)
+
+	for attempt := uint32(1); ; attempt++ {
+		started := time.Now()
+		resp, done, err := this.roundTripOnce(transport, req, policy.perTryTimeout, responseHeaderTimeout)
+		releaseAttempt := func() {
+			done()
+			release()
+		}
+		current := lctx.UpstreamAttempt{
+			Address:  endpoint.Address,
+			Duration: time.Since(started),
+			Error:    err,
+		}
+		if resp != nil {
+			current.Status = resp.StatusCode
+		}
+		this.reportUpstreamHealth(ctx, r, endpoint, current.Status, err)
+
+		condition, retryable := retryConditionOf(resp, err)
+		if !replayable || !retryable || attempt >= policy.attempts || !policy.on.Contains(condition) || req.Context().Err() != nil {
+			ctx.Upstream.Attempts = append(ctx.Upstream.Attempts, current)
+			return resp, releaseAttempt, err
+		}
+
+		if !sleepContext(req.Context(), policy.backoffAfter(attempt)) {
+			ctx.Upstream.Attempts = append(ctx.Upstream.Attempts, current)
+			return resp, releaseAttempt, err
+		}
+
+		next, nextRelease, sErr := this.selectUpstreamEndpoint(ctx, r)
+		if sErr != nil || next == nil {
+			// There is nothing left to retry with; so we respond with what we have.
+			ctx.Upstream.Attempts = append(ctx.Upstream.Attempts, current)
+			return resp, releaseAttempt, err
+		}
+
+		current.RetriedBecause = condition.String()
+		ctx.Upstream.Attempts = append(ctx.Upstream.Attempts, current)
+		if resp != nil {
+			_, _ = io.CopyN(io.Discard, resp.Body, retryDrainBytes)
+			_ = resp.Body.Close()
+		}
+		releaseAttempt()
+
+		endpoint, release = next, nextRelease
+		ctx.Upstream.Address = endpoint.Address
+		// Only the address to connect to changes; the Host header has to stay
+		// the virtual host the client requested (or the overridden one).
+		if this.settings.Upstream.OverrideHost == "" {
+			req.URL.Host = endpoint.Address.String()
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, release, err
+			}
+		}
+		ctx.Log().
+			With("attempt", attempt+1).
+			With("reason", condition).
+			Debug("Retrying upstream request.")
+	}
+}
+
+// roundTripOnce sends the given request once. The per try timeout limits the
+// time until the response headers are received including connecting; the
+// response header timeout limits the time after the request was sent. Both
+// are armed for each attempt again.
+func (this *Proxy) roundTripOnce(transport http.RoundTripper, req *http.Request, perTryTimeout, responseHeaderTimeout time.Duration) (*http.Response, func(), error) {
+	if perTryTimeout <= 0 && responseHeaderTimeout <= 0 {
+		resp, err := transport.RoundTrip(req)
+		return resp, func() {}, err
+	}
+
+	// The context must not be canceled after the response headers were
+	// received; otherwise the body cannot be read anymore. So it is only
+	// canceled by the returned function after the response was consumed.
+	attemptCtx, cancel := context.WithCancelCause(req.Context())
+	var timers []*time.Timer
+	if perTryTimeout > 0 {
+		timers = append(timers, time.AfterFunc(perTryTimeout, func() {
+			cancel(errPerTryTimeout)
+		}))
+	}
+	if responseHeaderTimeout > 0 {
+		timer := time.AfterFunc(responseHeaderTimeout, func() {
+			cancel(ErrUpstreamResponseHeaderTimeout)
+		})
+		timers = append(timers, timer)
+		attemptCtx = httptrace.WithClientTrace(attemptCtx, &httptrace.ClientTrace{
+			GotFirstResponseByte: func() {
+				timer.Stop()
+			},
+		})
+	}
+	resp, err := transport.RoundTrip(req.WithContext(attemptCtx))
+	for _, timer := range timers {
+		timer.Stop()
+	}
+	done := func() {
+		cancel(context.Canceled)
+	}
+	if err != nil {
+		cause := context.Cause(attemptCtx)
+		done()
+		if errors.Is(cause, errPerTryTimeout) {
+			return nil, done, fmt.Errorf("%w after %v: %v", errPerTryTimeout, perTryTimeout, err)
+		}
+		if errors.Is(cause, ErrUpstreamResponseHeaderTimeout) {
+			return nil, done, fmt.Errorf("%w after %v: %v", ErrUpstreamResponseHeaderTimeout, responseHeaderTimeout, err)
+		}
+	}
+	return resp, done, err
+}
+
+// prepareRequestForRetries returns true if the given request can be sent
+// more than once. This is only the case for idempotent methods without body
+// or for requests which body was buffered.
+func (this *Proxy) prepareRequestForRetries(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody {
+		return isIdempotentMethod(req.Method)
+	}
+
+	limit := int64(this.settings.Upstream.RetryMaxBufferBytes)
+	if limit <= 0 || req.ContentLength <= 0 || req.ContentLength > limit {
+		return false
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(req.Body, req.ContentLength))
+	if err != nil {
+		// Hand over what we already read together with the rest; the request
+		// itself will fail at the upstream.
+		req.Body = io.NopCloser(io.MultiReader(bytes.NewReader(buf), req.Body))
+		return false
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return true
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func retryConditionOf(resp *http.Response, err error) (value2.RetryCondition, bool) {
+	if err != nil {
+		var ne net.Error
+		if isDialError(err) {
+			return value2.RetryOnConnectFailure, true
+		} else if errors.Is(err, errPerTryTimeout) || (errors.As(err, &ne) && ne.Timeout()) {
+			return value2.RetryOnTimeout, true
+		} else if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return value2.RetryOnReset, true
+		}
+		return "", false
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway:
+		return value2.RetryOn502, true
+	case http.StatusServiceUnavailable:
+		return value2.RetryOn503, true
+	case http.StatusGatewayTimeout:
+		return value2.RetryOn504, true
+	}
+	return "", false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,192 @@
+package proxy
+
+import (
+	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_retryConditionOf(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	dialErr := &net.OpError{Op: "dial", Err: io.EOF}
+	condition, ok := retryConditionOf(nil, dialErr)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(condition).To(Equal(value2.RetryOnConnectFailure))
+
+	condition, ok = retryConditionOf(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(condition).To(Equal(value2.RetryOn503))
+
+	_, ok = retryConditionOf(&http.Response{StatusCode: http.StatusInternalServerError}, nil)
+	g.Expect(ok).To(BeFalse())
+}
+
+func Test_retryPolicy_backoffAfter(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	policy := retryPolicy{backoff: 25 * time.Millisecond}
+	g.Expect(policy.backoffAfter(1)).To(Equal(25 * time.Millisecond))
+	g.Expect(policy.backoffAfter(3)).To(Equal(100 * time.Millisecond))
+	g.Expect(policy.backoffAfter(10)).To(Equal(value2.MaxRetryBackoff))
+	g.Expect(policy.backoffAfter(100)).To(Equal(value2.MaxRetryBackoff))
+
+	policy = retryPolicy{backoff: time.Minute}
+	g.Expect(policy.backoffAfter(1)).To(Equal(value2.MaxRetryBackoff))
+	g.Expect(policy.backoffAfter(5)).To(Equal(value2.MaxRetryBackoff))
+
+	policy = retryPolicy{}
+	g.Expect(policy.backoffAfter(5)).To(Equal(time.Duration(0)))
+}
+
+func Test_retryPolicyFor_limits_attempts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	g.Expect(s.Upstream.RetryAttempts.Set("!100")).To(Succeed())
+	instance := &Proxy{settings: &s}
+	g.Expect(instance.retryPolicyFor(nil).attempts).To(Equal(uint32(value2.MaxRetryAttempts)))
+
+	options := rules.DefaultOptionsFactory()
+	g.Expect(options.Set(rules.Annotations{"lingress.echocat.org/retry.attempts": "11"})).To(MatchError(ContainSubstring("has to be between 1 and 10")))
+}
+
+func Test_retryPolicyFor_limits_backoff(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	g.Expect(s.Upstream.RetryBackoff.Set("!1h")).To(Succeed())
+	instance := &Proxy{settings: &s}
+	g.Expect(instance.retryPolicyFor(nil).backoff).To(Equal(value2.MaxRetryBackoff))
+
+	options := rules.DefaultOptionsFactory()
+	g.Expect(options.Set(rules.Annotations{"lingress.echocat.org/retry.backoff": "1h"})).To(MatchError(ContainSubstring("has to be between 0s and 5s")))
+	g.Expect(options.Set(rules.Annotations{"lingress.echocat.org/retry.backoff": "5s"})).To(Succeed())
+}
+
+func Test_prepareRequestForRetries(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	s.Upstream.RetryMaxBufferBytes = 10
+	instance := &Proxy{settings: &s}
+
+	newRequest := func(method, body string) *http.Request {
+		req, err := http.NewRequest(method, "http://localhost/", strings.NewReader(body))
+		g.Expect(err).To(BeNil())
+		if body == "" {
+			req.Body = http.NoBody
+		}
+		return req
+	}
+
+	g.Expect(instance.prepareRequestForRetries(newRequest(http.MethodGet, ""))).To(BeTrue())
+	g.Expect(instance.prepareRequestForRetries(newRequest(http.MethodPost, ""))).To(BeFalse())
+	g.Expect(instance.prepareRequestForRetries(newRequest(http.MethodPost, "01234567890"))).To(BeFalse())
+
+	buffered := newRequest(http.MethodPost, "0123")
+	g.Expect(instance.prepareRequestForRetries(buffered)).To(BeTrue())
+	first, _ := io.ReadAll(buffered.Body)
+	buffered.Body, _ = buffered.GetBody()
+	second, _ := io.ReadAll(buffered.Body)
+	g.Expect(string(first)).To(Equal("0123"))
+	g.Expect(string(second)).To(Equal("0123"))
+}
+
+func Test_roundTrip_keeps_host_header_on_retries(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	var hosts []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		hosts = append(hosts, req.Host)
+		if len(hosts) == 1 {
+			resp.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		resp.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	address, err := net.ResolveTCPAddr("tcp", upstream.Listener.Addr().String())
+	g.Expect(err).To(BeNil())
+
+	s := settings.MustNew()
+	s.Upstream.RetryAttempts = value.NewForcibleUint32(value.NewUint32(2), false)
+	s.Upstream.RetryOn = value2.NewForcibleRetryOn(value2.RetryOn{value2.RetryOn503}, false)
+	s.Upstream.RetryBackoff = value.NewForcibleDuration(value.NewDuration(time.Millisecond), false)
+	instance := &Proxy{settings: &s}
+
+	endpoints := rules.NewEndpoints(address)
+	r := rules.NewRule("app.example.org", nil, rules.PathTypePrefix, nil, address, endpoints, rules.DefaultOptionsFactory())
+	req, err := http.NewRequest(http.MethodGet, "http://"+address.String()+"/", nil)
+	g.Expect(err).To(BeNil())
+	req.Host = "app.example.org"
+	ctx := &lctx.Context{Logger: log.GetRootLogger(), Result: lctx.ResultUnknown}
+	ctx.Client.Request = httptest.NewRequest(http.MethodGet, "http://app.example.org/", nil)
+	ctx.Upstream.Request = req
+
+	resp, release, err := instance.roundTrip(ctx, r, endpoints.All()[0], func() {})
+	defer release()
+	g.Expect(err).To(BeNil())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	_ = resp.Body.Close()
+	g.Expect(hosts).To(Equal([]string{"app.example.org", "app.example.org"}))
+}
+
+func Test_roundTrip_releases_abandoned_endpoint(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		resp.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = resp.Write([]byte(strings.Repeat("x", 2*retryDrainBytes)))
+	}))
+	defer failing.Close()
+	succeeding := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, _ *http.Request) {
+		resp.WriteHeader(http.StatusOK)
+	}))
+	defer succeeding.Close()
+	failingAddress, err := net.ResolveTCPAddr("tcp", failing.Listener.Addr().String())
+	g.Expect(err).To(BeNil())
+	succeedingAddress, err := net.ResolveTCPAddr("tcp", succeeding.Listener.Addr().String())
+	g.Expect(err).To(BeNil())
+
+	s := settings.MustNew()
+	s.Upstream.LoadBalancer = "round-robin"
+	s.Upstream.RetryAttempts = value.NewForcibleUint32(value.NewUint32(2), false)
+	s.Upstream.RetryOn = value2.NewForcibleRetryOn(value2.RetryOn{value2.RetryOn503}, false)
+	instance := &Proxy{settings: &s}
+
+	endpoints := rules.NewEndpoints(failingAddress, succeedingAddress)
+	r := rules.NewRule("app.example.org", nil, rules.PathTypePrefix, nil, failingAddress, endpoints, rules.DefaultOptionsFactory())
+	req, err := http.NewRequest(http.MethodGet, "http://"+failingAddress.String()+"/", nil)
+	g.Expect(err).To(BeNil())
+	ctx := &lctx.Context{Logger: log.GetRootLogger(), Result: lctx.ResultUnknown}
+	ctx.Client.Request = httptest.NewRequest(http.MethodGet, "http://app.example.org/", nil)
+	ctx.Upstream.Request = req
+
+	endpoint, release := endpoints.Select(rules.LoadBalancerRoundRobin, "")
+	g.Expect(endpoint.Address).To(Equal(failingAddress))
+
+	resp, release, err := instance.roundTrip(ctx, r, endpoint, release)
+	g.Expect(err).To(BeNil())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	_ = resp.Body.Close()
+
+	// The failed endpoint is not in flight anymore while the response of the
+	// retry is still consumed.
+	all := endpoints.All()
+	g.Expect(all[0].InFlight()).To(Equal(int64(0)))
+	g.Expect(all[1].InFlight()).To(Equal(int64(1)))
+	release()
+	g.Expect(all[1].InFlight()).To(Equal(int64(0)))
+}
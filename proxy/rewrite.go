@@ -0,0 +1,29 @@
+package proxy
+
+import (
+	"github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	"strings"
+)
+
+func init() {
+	DefaultInterceptors.AddFunc("rewrite", RewriteInterceptor, context.StagePrepareUpstreamRequest)
+}
+
+func RewriteInterceptor(ctx *context.Context) (proceed bool, err error) {
+	opts := rules.OptionsRewriteOf(ctx.Rule)
+	req := ctx.Upstream.Request
+	if req == nil || !opts.IsRelevant() {
+		return true, nil
+	}
+
+	if v := opts.Host; v != "" {
+		req.Host = v
+	}
+	if v := opts.Path; v != nil {
+		req.URL.Path = "/" + strings.Join(v, "/")
+		req.URL.RawPath = ""
+	}
+
+	return true, nil
+}
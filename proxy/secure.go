@@ -9,7 +9,7 @@ import (
 
 func init() {
 	DefaultInterceptors.Add(&ForceSecureInterceptor{})
-	DefaultInterceptors.AddFunc("whitelistedRemotes", WhitelistedRemotesInterceptor, context.StageEvaluateClientRequest)
+	DefaultInterceptors.AddPrioritizedFunc("whitelistedRemotes", PriorityWhitelistedRemotes, WhitelistedRemotesInterceptor, context.StageEvaluateClientRequest)
 	DefaultInterceptors.AddFunc("removeServerHeader", RemoveServerHeader, context.StagePrepareClientResponse)
 }
 
@@ -19,6 +19,10 @@ func (this *ForceSecureInterceptor) Name() string {
 	return "forceSecure"
 }
 
+func (this *ForceSecureInterceptor) Priority() int {
+	return PriorityForceSecure
+}
+
 func (this *ForceSecureInterceptor) HandlesStages() []context.Stage {
 	return []context.Stage{context.StageEvaluateClientRequest}
 }
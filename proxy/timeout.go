@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	"time"
+)
+
+var (
+	ErrUpstreamRequestTimeout        = errors.New("upstream request timeout reached")
+	ErrUpstreamResponseHeaderTimeout = errors.New("upstream response header timeout reached")
+)
+
+// createBackendContextFor creates the context of the upstream request which
+// respects the request timeout of the current rule. The response header
+// timeout is applied to each attempt by roundTripOnce.
+func (this *Proxy) createBackendContextFor(ctx *lctx.Context, parent context.Context) (context.Context, context.CancelFunc) {
+	opts := rules.OptionsUpstreamOf(ctx.Rule)
+	s := this.settings.Upstream
+
+	result, cancel := context.WithCancelCause(parent)
+	var timer *time.Timer
+
+	if v := s.RequestTimeout.Evaluate(opts.RequestTimeout).Get(); v > 0 {
+		timer = time.AfterFunc(v, func() {
+			cancel(ErrUpstreamRequestTimeout)
+		})
+	}
+
+	return result, func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		cancel(context.Canceled)
+	}
+}
+
+// responseHeaderTimeoutFor returns the maximum time to wait for the response
+// headers of each attempt of the given rule; 0 means no limit.
+func (this *Proxy) responseHeaderTimeoutFor(r rules.Rule) time.Duration {
+	opts := rules.OptionsUpstreamOf(r)
+	return this.settings.Upstream.ResponseHeaderTimeout.Evaluate(opts.ResponseHeaderTimeout).Get()
+}
+
+func isUpstreamTimeoutError(ctx *lctx.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, errPerTryTimeout) || errors.Is(err, ErrUpstreamResponseHeaderTimeout) {
+		return true
+	}
+	if req := ctx.Upstream.Request; req != nil {
+		cause := context.Cause(req.Context())
+		return errors.Is(cause, ErrUpstreamRequestTimeout)
+	}
+	return false
+}
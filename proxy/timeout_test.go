@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	lctx "github.com/echocat/lingress/context"
+	"github.com/echocat/lingress/rules"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_createBackendContextFor_respects_request_timeout(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	s.Upstream.RequestTimeout = value.NewForcibleDuration(value.NewDuration(10*time.Millisecond), false)
+	instance := &Proxy{settings: &s}
+
+	ctx := &lctx.Context{}
+	bCtx, cancel := instance.createBackendContextFor(ctx, context.Background())
+	defer cancel()
+
+	<-bCtx.Done()
+	ctx.Upstream.Request = (&http.Request{}).WithContext(bCtx)
+
+	g.Expect(isUpstreamTimeoutError(ctx, bCtx.Err())).To(BeTrue())
+}
+
+func Test_createBackendContextFor_without_timeouts(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	instance := &Proxy{settings: &s}
+
+	ctx := &lctx.Context{}
+	bCtx, cancel := instance.createBackendContextFor(ctx, context.Background())
+	ctx.Upstream.Request = (&http.Request{}).WithContext(bCtx)
+	cancel()
+
+	g.Expect(isUpstreamTimeoutError(ctx, bCtx.Err())).To(BeFalse())
+}
+
+func Test_roundTrip_respects_response_header_timeout_of_each_attempt(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	hang := make(chan struct{})
+	var requests int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			resp.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case <-hang:
+		case <-req.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+	defer close(hang)
+	address, err := net.ResolveTCPAddr("tcp", upstream.Listener.Addr().String())
+	g.Expect(err).To(BeNil())
+
+	s := settings.MustNew()
+	s.Upstream.ResponseHeaderTimeout = value.NewForcibleDuration(value.NewDuration(50*time.Millisecond), false)
+	s.Upstream.RetryAttempts = value.NewForcibleUint32(value.NewUint32(2), false)
+	s.Upstream.RetryOn = value2.NewForcibleRetryOn(value2.RetryOn{value2.RetryOn503}, false)
+	s.Upstream.RetryBackoff = value.NewForcibleDuration(value.NewDuration(time.Millisecond), false)
+	instance := &Proxy{settings: &s}
+
+	endpoints := rules.NewEndpoints(address)
+	r := rules.NewRule("app.example.org", nil, rules.PathTypePrefix, nil, address, endpoints, rules.DefaultOptionsFactory())
+	ctx := &lctx.Context{Logger: log.GetRootLogger(), Result: lctx.ResultUnknown}
+	ctx.Client.Request = httptest.NewRequest(http.MethodGet, "http://app.example.org/", nil)
+	// Only to not wait forever if the timeout is not respected.
+	parent, cancelParent := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelParent()
+	bCtx, cancel := instance.createBackendContextFor(ctx, parent)
+	defer cancel()
+	req, err := http.NewRequestWithContext(bCtx, http.MethodGet, "http://"+address.String()+"/", nil)
+	g.Expect(err).To(BeNil())
+	ctx.Upstream.Request = req
+
+	started := time.Now()
+	_, release, err := instance.roundTrip(ctx, r, endpoints.All()[0], func() {})
+	defer release()
+	g.Expect(err).To(MatchError(ErrUpstreamResponseHeaderTimeout))
+	g.Expect(isUpstreamTimeoutError(ctx, err)).To(BeTrue())
+	g.Expect(time.Since(started)).To(BeNumerically("<", time.Second))
+	g.Expect(atomic.LoadInt32(&requests)).To(Equal(int32(2)))
+	g.Expect(ctx.Upstream.Attempts).To(HaveLen(2))
+}
@@ -174,3 +174,50 @@ func (this *ByHost) Clone() *ByHost {
 
 	return result
 }
+
+// AllOfHost calls the consumer for each rule which could match the given host
+// regardless of its path.
+func (this *ByHost) AllOfHost(host value.Fqdn, consumer func(Rule) error) error {
+	if v, ok := this.hostFullMatch[host]; ok {
+		if err := v.All(consumer); err != nil {
+			return err
+		}
+	}
+	if v, ok := this.hostPrefixWildcardMatch[host.Parent()]; ok {
+		if err := v.All(consumer); err != nil {
+			return err
+		}
+	}
+	return this.allHostsMatching.All(consumer)
+}
+
+// HasHost returns true if there is at least one rule which explicitly
+// matches the given host (without wildcards).
+func (this *ByHost) HasHost(host value.Fqdn) bool {
+	v, ok := this.hostFullMatch[host]
+	return ok && v.HasContent()
+}
+
+// AllOfExactHost calls the consumer for each rule which was registered for
+// exactly the given host (including its wildcard, if any) regardless of its
+// path. An empty host addresses the rules which are matching all hosts.
+func (this *ByHost) AllOfExactHost(host value.WildcardSupportingFqdn, consumer func(Rule) error) error {
+	if host == "" {
+		return this.allHostsMatching.All(consumer)
+	}
+
+	hadWildcard, plain, err := host.WithoutWildcard()
+	if err != nil {
+		return err
+	}
+
+	target := this.hostFullMatch
+	if hadWildcard {
+		target = this.hostPrefixWildcardMatch
+	}
+
+	if v, ok := target[plain]; ok {
+		return v.All(consumer)
+	}
+	return nil
+}
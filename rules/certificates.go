@@ -8,12 +8,64 @@ import (
 	"github.com/echocat/lingress/support"
 	"github.com/echocat/lingress/value"
 	"slices"
+	"sort"
 	"strings"
 	"sync"
 )
 
 type CertificateRepository interface {
 	FindCertificatesBy(CertificateQuery) (Certificates, error)
+	AllCertificates() ([]LoadedCertificate, error)
+}
+
+// LoadedCertificate describes a certificate which is currently served for the
+// contained hosts.
+type LoadedCertificate struct {
+	Certificate *tls.Certificate
+	Hosts       value.WildcardSupportingFqdns
+	Sources     []string
+}
+
+// MergeLoadedCertificates combines all entries of the same certificate (by
+// its fingerprint) into one, containing the hosts and sources of all of them.
+// The same certificate could be loaded several times; like a secret which is
+// referenced by several tls entries or rules sources serving the same one.
+func MergeLoadedCertificates(in []LoadedCertificate) []LoadedCertificate {
+	byFingerprint := map[string]int{}
+	result := make([]LoadedCertificate, 0, len(in))
+	for _, candidate := range in {
+		if candidate.Certificate == nil || candidate.Certificate.Leaf == nil {
+			result = append(result, candidate)
+			continue
+		}
+		key := support.CertificateFingerprint(candidate.Certificate.Leaf)
+		i, ok := byFingerprint[key]
+		if !ok {
+			byFingerprint[key] = len(result)
+			result = append(result, LoadedCertificate{
+				Certificate: candidate.Certificate,
+				Hosts:       slices.Clone(candidate.Hosts),
+				Sources:     slices.Clone(candidate.Sources),
+			})
+			continue
+		}
+		entry := &result[i]
+		for _, host := range candidate.Hosts {
+			if !slices.Contains(entry.Hosts, host) {
+				entry.Hosts = append(entry.Hosts, host)
+			}
+		}
+		for _, source := range candidate.Sources {
+			if !slices.Contains(entry.Sources, source) {
+				entry.Sources = append(entry.Sources, source)
+			}
+		}
+		sort.Slice(entry.Hosts, func(a, b int) bool {
+			return entry.Hosts[a] < entry.Hosts[b]
+		})
+		sort.Strings(entry.Sources)
+	}
+	return result
 }
 
 type Certificates []*tls.Certificate
@@ -25,7 +77,9 @@ func (this *Certificates) add(in *tls.Certificate) bool {
 			return false
 		}
 	}
-	*this = append(*this, in)
+	// Find hands out the slices without holding the lock while they are
+	// read; so they are never modified in place but copied on write.
+	*this = append((*this)[:len(*this):len(*this)], in)
 	return true
 }
 
@@ -51,18 +105,20 @@ func (this certificates) hasContent() bool {
 func (this *certificates) add(source support.ObjectReference, ins ...*tls.Certificate) bool {
 	if this.sourceToCertificates == nil {
 		this.sourceToCertificates = map[string]*Certificates{
-			source.ShortString(): (*Certificates)(&ins),
+			source.String(): (*Certificates)(&ins),
 		}
 		this.all = ins
 		return true
 	}
 
-	ofSource, ok := this.sourceToCertificates[source.ShortString()]
+	ofSource, ok := this.sourceToCertificates[source.String()]
 	if !ok {
 		ofSource = &Certificates{}
-		this.sourceToCertificates[source.ShortString()] = ofSource
+		this.sourceToCertificates[source.String()] = ofSource
 	}
-	return ofSource.addAll(ins) || this.all.addAll(ins)
+	addedToSource := ofSource.addAll(ins)
+	addedToAll := this.all.addAll(ins)
+	return addedToSource || addedToAll
 }
 
 func (this *certificates) remove(source support.ObjectReference) bool {
@@ -70,15 +126,15 @@ func (this *certificates) remove(source support.ObjectReference) bool {
 		return false
 	}
 
-	_, ok := this.sourceToCertificates[source.ShortString()]
+	_, ok := this.sourceToCertificates[source.String()]
 	if !ok {
 		return false
 	}
-	delete(this.sourceToCertificates, source.ShortString())
+	delete(this.sourceToCertificates, source.String())
 
 	anyRemoved := false
 
-	this.all = slices.DeleteFunc(this.all, func(candidate *tls.Certificate) bool {
+	this.all = slices.DeleteFunc(slices.Clone(this.all), func(candidate *tls.Certificate) bool {
 		anyOtherContains := false
 		for _, others := range this.sourceToCertificates {
 			for _, other := range *others {
@@ -108,7 +164,9 @@ type certificatesByHostValue struct {
 }
 
 func (this *certificatesByHostValue) remove(source support.ObjectReference) bool {
-	return this.direct.remove(source) || this.wildcard.remove(source)
+	removedDirect := this.direct.remove(source)
+	removedWildcard := this.wildcard.remove(source)
+	return removedDirect || removedWildcard
 }
 
 func (this *certificatesByHostValue) hasContent() bool {
@@ -128,8 +186,7 @@ func (this *CertificatesByHost) Find(host value.WildcardSupportingFqdn) Certific
 	if values == nil {
 		return nil
 	}
-	direct := values[host]
-	if len(direct.direct.all) > 0 {
+	if direct := values[host]; direct != nil && len(direct.direct.all) > 0 {
 		return direct.direct.all
 	}
 
@@ -138,13 +195,72 @@ func (this *CertificatesByHost) Find(host value.WildcardSupportingFqdn) Certific
 		return nil
 	}
 	shortHost := host[i+1:]
-	return values[shortHost].wildcard.all
+	if wildcard := values[shortHost]; wildcard != nil {
+		return wildcard.wildcard.all
+	}
+	return nil
+}
+
+// Replace replaces all certificates with the ones of the given instance at
+// once; so nobody will find a certificate missing while they are replaced.
+// with must not be used anymore afterward.
+func (this *CertificatesByHost) Replace(with *CertificatesByHost) {
+	with.mutex.Lock()
+	values := with.values
+	with.values = nil
+	with.mutex.Unlock()
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.values = values
+}
+
+// ReplaceBySource replaces all certificates of the given source with the ones
+// of the given instance at once; so nobody will find a certificate missing
+// while they are replaced. with must contain only certificates of source and
+// must not be used anymore afterward.
+func (this *CertificatesByHost) ReplaceBySource(source support.ObjectReference, with *CertificatesByHost) (removed, added value.WildcardSupportingFqdns, err error) {
+	with.mutex.Lock()
+	values := with.values
+	with.values = nil
+	with.mutex.Unlock()
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if removed, err = this.removeBySource(source); err != nil {
+		return nil, nil, err
+	}
+
+	addedM := map[value.WildcardSupportingFqdn]struct{}{}
+	for host, candidate := range values {
+		for _, certificate := range candidate.direct.all {
+			if this.add(source, host, certificate) {
+				addedM[host] = struct{}{}
+			}
+		}
+		for _, certificate := range candidate.wildcard.all {
+			if this.add(source, "*."+host, certificate) {
+				addedM["*."+host] = struct{}{}
+			}
+		}
+	}
+
+	added = make(value.WildcardSupportingFqdns, 0, len(addedM))
+	for host := range addedM {
+		added = append(added, host)
+	}
+	return removed, added, nil
 }
 
 func (this *CertificatesByHost) RemoveBySource(source support.ObjectReference) (value.WildcardSupportingFqdns, error) {
 	this.mutex.Lock()
 	defer this.mutex.Unlock()
 
+	return this.removeBySource(source)
+}
+
+func (this *CertificatesByHost) removeBySource(source support.ObjectReference) (value.WildcardSupportingFqdns, error) {
 	removed := map[value.WildcardSupportingFqdn]struct{}{}
 	toPurge := map[value.WildcardSupportingFqdn]struct{}{}
 
@@ -216,6 +332,54 @@ func (this *CertificatesByHost) Add(source support.ObjectReference, certificate
 	return addedS, nil
 }
 
+func (this *CertificatesByHost) AddForHosts(source support.ObjectReference, certificate tls.Certificate, hosts value.WildcardSupportingFqdns) (added, skipped value.WildcardSupportingFqdns, err error) {
+	if len(hosts) == 0 {
+		added, err = this.Add(source, certificate)
+		return
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	if len(certificate.Certificate) <= 0 {
+		return nil, nil, errors.New("empty certificate")
+	}
+	if certificate.PrivateKey == nil {
+		return nil, nil, errors.New("certificate without privateKey")
+	}
+	if certificate.Leaf == nil {
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		certificate.Leaf = leaf
+	}
+
+	for _, host := range hosts {
+		if !certificateCoversHost(certificate.Leaf, host) {
+			skipped = append(skipped, host)
+			continue
+		}
+		if this.add(source, host, &certificate) {
+			added = append(added, host)
+		}
+	}
+
+	return added, skipped, nil
+}
+
+func certificateCoversHost(leaf *x509.Certificate, host value.WildcardSupportingFqdn) bool {
+	if strings.HasPrefix(string(host), "*.") {
+		for _, dns := range leaf.DNSNames {
+			if strings.EqualFold(dns, string(host)) {
+				return true
+			}
+		}
+		return false
+	}
+	return leaf.VerifyHostname(string(host)) == nil
+}
+
 func (this *CertificatesByHost) add(source support.ObjectReference, host value.WildcardSupportingFqdn, certificate *tls.Certificate) bool {
 	wildcarded := false
 	if strings.HasPrefix(string(host), "*.") {
@@ -244,3 +408,56 @@ func (this *CertificatesByHost) AddBytes(source support.ObjectReference, certifi
 	}
 	return this.Add(source, cert)
 }
+
+func (this *CertificatesByHost) AddBytesForHosts(source support.ObjectReference, certificate, privateKey []byte, hosts value.WildcardSupportingFqdns) (added, skipped value.WildcardSupportingFqdns, err error) {
+	cert, err := tls.X509KeyPair(certificate, privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return this.AddForHosts(source, cert, hosts)
+}
+
+// All returns all loaded certificates together with the hosts they are
+// served for and the sources they are loaded from.
+func (this *CertificatesByHost) All() []LoadedCertificate {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	byCertificate := map[*tls.Certificate]*LoadedCertificate{}
+	var ordered []*LoadedCertificate
+	visit := func(host value.WildcardSupportingFqdn, of certificates) {
+		for source, ofSource := range of.sourceToCertificates {
+			for _, certificate := range *ofSource {
+				entry, ok := byCertificate[certificate]
+				if !ok {
+					entry = &LoadedCertificate{Certificate: certificate}
+					byCertificate[certificate] = entry
+					ordered = append(ordered, entry)
+				}
+				if !slices.Contains(entry.Hosts, host) {
+					entry.Hosts = append(entry.Hosts, host)
+				}
+				if !slices.Contains(entry.Sources, source) {
+					entry.Sources = append(entry.Sources, source)
+				}
+			}
+		}
+	}
+	for host, v := range this.values {
+		visit(host, v.direct)
+		visit("*."+host, v.wildcard)
+	}
+
+	result := make([]LoadedCertificate, len(ordered))
+	for i, entry := range ordered {
+		sort.Slice(entry.Hosts, func(a, b int) bool {
+			return entry.Hosts[a] < entry.Hosts[b]
+		})
+		sort.Strings(entry.Sources)
+		result[i] = *entry
+	}
+	sort.Slice(result, func(a, b int) bool {
+		return result[a].Hosts[0] < result[b].Hosts[0]
+	})
+	return MergeLoadedCertificates(result)
+}
@@ -0,0 +1,198 @@
+package rules
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func Test_CertificatesByHost_All(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).To(BeNil())
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(666),
+		Subject:      pkix.Name{CommonName: "a.example.org"},
+		DNSNames:     []string{"a.example.org", "*.b.example.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(1)}, &key.PublicKey, key)
+	g.Expect(err).To(BeNil())
+
+	source, err := support.NewObjectReferenceOf(&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"}})
+	g.Expect(err).To(BeNil())
+
+	var instance CertificatesByHost
+	g.Expect(instance.All()).To(BeEmpty())
+
+	_, err = instance.Add(source, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key})
+	g.Expect(err).To(BeNil())
+
+	actual := instance.All()
+	g.Expect(actual).To(HaveLen(1))
+	g.Expect(actual[0].Hosts).To(Equal(value.WildcardSupportingFqdns{"*.b.example.org", "a.example.org"}))
+	g.Expect(actual[0].Sources).To(Equal([]string{source.String()}))
+	g.Expect(actual[0].Certificate.Leaf.SerialNumber.Int64()).To(Equal(int64(666)))
+
+	_, err = instance.RemoveBySource(source)
+	g.Expect(err).To(BeNil())
+	g.Expect(instance.All()).To(BeEmpty())
+}
+
+func Test_CertificatesByHost_All_secretReferencedTwice(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).To(BeNil())
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(666),
+		Subject:      pkix.Name{CommonName: "*.example.org"},
+		DNSNames:     []string{"*.example.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(1)}, &key.PublicKey, key)
+	g.Expect(err).To(BeNil())
+
+	source, err := support.NewObjectReferenceOf(&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"}})
+	g.Expect(err).To(BeNil())
+
+	// Like two entries of spec.tls referencing the same secret: it is parsed
+	// once for each of them.
+	var instance CertificatesByHost
+	_, _, err = instance.AddForHosts(source, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, value.WildcardSupportingFqdns{"a.example.org"})
+	g.Expect(err).To(BeNil())
+	_, _, err = instance.AddForHosts(source, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, value.WildcardSupportingFqdns{"b.example.org"})
+	g.Expect(err).To(BeNil())
+
+	actual := instance.All()
+	g.Expect(actual).To(HaveLen(1))
+	g.Expect(actual[0].Hosts).To(Equal(value.WildcardSupportingFqdns{"a.example.org", "b.example.org"}))
+	g.Expect(actual[0].Sources).To(Equal([]string{source.String()}))
+}
+
+func Test_CertificatesByHost_Find_whileReplaceBySource(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	certificateOf := func(serial int64) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		g.Expect(err).To(BeNil())
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "app.example.org"},
+			DNSNames:     []string{"app.example.org"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}, &x509.Certificate{SerialNumber: big.NewInt(1)}, &key.PublicKey, key)
+		g.Expect(err).To(BeNil())
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	sourceOf := func(name string) support.ObjectReference {
+		result, err := support.NewObjectReferenceOf(&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: name}})
+		g.Expect(err).To(BeNil())
+		return result
+	}
+	a, b := sourceOf("a"), sourceOf("b")
+	certificatesOfA := []tls.Certificate{certificateOf(1), certificateOf(2)}
+	certificateOfB := certificateOf(3)
+
+	var instance CertificatesByHost
+	_, err := instance.Add(b, certificateOfB)
+	g.Expect(err).To(BeNil())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			var with CertificatesByHost
+			_, err := with.Add(a, certificatesOfA[i%2])
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, _, err := instance.ReplaceBySource(a, &with); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		found := instance.Find("app.example.org")
+		g.Expect(found).NotTo(BeEmpty())
+		for _, candidate := range found {
+			g.Expect(candidate).NotTo(BeNil())
+			g.Expect(candidate.Leaf.DNSNames).To(ConsistOf("app.example.org"))
+		}
+	}
+}
+
+func Test_CertificatesByHost_rotateHostAndWildcard(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	certificateOf := func(serial int64) tls.Certificate {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		g.Expect(err).To(BeNil())
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "example.org"},
+			DNSNames:     []string{"example.org", "*.example.org"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}, &x509.Certificate{SerialNumber: big.NewInt(1)}, &key.PublicKey, key)
+		g.Expect(err).To(BeNil())
+		return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	}
+	serialsOf := func(instance *CertificatesByHost, host value.WildcardSupportingFqdn) []int64 {
+		var result []int64
+		for _, candidate := range instance.Find(host) {
+			leaf, err := x509.ParseCertificate(candidate.Certificate[0])
+			g.Expect(err).To(BeNil())
+			result = append(result, leaf.SerialNumber.Int64())
+		}
+		return result
+	}
+
+	source := support.NewObjectReference("networking.k8s.io/v1", "Ingress", "foo", "bar")
+	other := support.NewObjectReference("networking.k8s.io/v1", "Ingress", "foo", "other")
+
+	var instance CertificatesByHost
+	_, err := instance.Add(source, certificateOf(1))
+	g.Expect(err).To(BeNil())
+	_, _, err = instance.AddForHosts(other, certificateOf(2), value.WildcardSupportingFqdns{"example.org"})
+	g.Expect(err).To(BeNil())
+	g.Expect(serialsOf(&instance, "example.org")).To(Equal([]int64{1, 2}))
+	g.Expect(serialsOf(&instance, "app.example.org")).To(Equal([]int64{1}))
+
+	// Rotation: the old certificate is neither served for the host nor for
+	// its wildcard anymore.
+	removed, err := instance.RemoveBySource(source)
+	g.Expect(err).To(BeNil())
+	g.Expect(removed).To(ConsistOf(value.WildcardSupportingFqdn("example.org")))
+	_, err = instance.Add(source, certificateOf(3))
+	g.Expect(err).To(BeNil())
+	g.Expect(serialsOf(&instance, "example.org")).To(Equal([]int64{2, 3}))
+	g.Expect(serialsOf(&instance, "app.example.org")).To(Equal([]int64{3}))
+
+	// Deletion
+	_, err = instance.RemoveBySource(source)
+	g.Expect(err).To(BeNil())
+	g.Expect(serialsOf(&instance, "example.org")).To(Equal([]int64{2}))
+	g.Expect(serialsOf(&instance, "app.example.org")).To(BeEmpty())
+}
@@ -0,0 +1,286 @@
+package rules
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/value"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type ClientCertificateRepository interface {
+	FindClientCertificateRequirementsBy(CertificateQuery) (ClientCertificateRequirements, error)
+}
+
+// ClientCertificateRequirements describes how client certificates has to be
+// requested while the TLS handshake for one host.
+type ClientCertificateRequirements struct {
+	ClientAuth tls.ClientAuthType
+	ClientCAs  *x509.CertPool
+}
+
+// IsEnabled returns true if client certificates should be requested.
+func (this ClientCertificateRequirements) IsEnabled() bool {
+	return this.ClientAuth != tls.NoClientCert
+}
+
+// ClientCertificateRequirementsOf aggregates the requirements of the given
+// rules of one host. Client certificates are requested if at least one rule
+// enables them; they are only required while the handshake if all rules
+// require them. The certificates are verified against the authorities of all
+// rules; each rule has to verify again against its own authorities. The pool
+// of the authorities is taken from pools (if provided) to not create a new
+// one for each handshake.
+func ClientCertificateRequirementsOf(rules []Rule, pools *ClientCertificatePools) ClientCertificateRequirements {
+	var result ClientCertificateRequirements
+	var authorities []*ClientCertificateAuthorities
+	allRequired := len(rules) > 0
+	for _, r := range rules {
+		opts := OptionsClientCertificateOf(r)
+		mode := opts.Mode.Get()
+		if mode != value2.ClientCertificateModeRequired {
+			allRequired = false
+		}
+		if !mode.IsEnabled() || opts.Authorities == nil {
+			continue
+		}
+		if !slices.Contains(authorities, opts.Authorities) {
+			authorities = append(authorities, opts.Authorities)
+		}
+	}
+
+	if len(authorities) == 0 {
+		result.ClientAuth = tls.NoClientCert
+		return result
+	}
+
+	result.ClientCAs = pools.Get(authorities)
+	if allRequired {
+		result.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		result.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return result
+}
+
+// ClientCertificatePools caches the pools which are combining the
+// authorities of several rules of one host. The zero value is ready to use.
+type ClientCertificatePools struct {
+	mutex sync.Mutex
+	pools map[string]clientCertificatePool
+}
+
+type clientCertificatePool struct {
+	pool        *x509.CertPool
+	authorities []*ClientCertificateAuthorities
+}
+
+// Get returns the pool containing the certificates of all given authorities.
+func (this *ClientCertificatePools) Get(authorities []*ClientCertificateAuthorities) *x509.CertPool {
+	if len(authorities) == 1 {
+		return authorities[0].Pool()
+	}
+	if this == nil {
+		return combineClientCertificateAuthorities(authorities)
+	}
+
+	keys := make([]string, len(authorities))
+	for i, candidate := range authorities {
+		keys[i] = fmt.Sprintf("%p", candidate)
+	}
+	slices.Sort(keys)
+	key := strings.Join(keys, ",")
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if existing, ok := this.pools[key]; ok {
+		return existing.pool
+	}
+	if this.pools == nil {
+		this.pools = map[string]clientCertificatePool{}
+	}
+	result := combineClientCertificateAuthorities(authorities)
+	this.pools[key] = clientCertificatePool{result, authorities}
+	return result
+}
+
+// Evict forgets all pools which are containing the given authorities.
+func (this *ClientCertificatePools) Evict(authorities *ClientCertificateAuthorities) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	for key, candidate := range this.pools {
+		if slices.Contains(candidate.authorities, authorities) {
+			delete(this.pools, key)
+		}
+	}
+}
+
+// Clear forgets all pools.
+func (this *ClientCertificatePools) Clear() {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.pools = nil
+}
+
+func (this *ClientCertificatePools) len() int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return len(this.pools)
+}
+
+func combineClientCertificateAuthorities(authorities []*ClientCertificateAuthorities) *x509.CertPool {
+	result := x509.NewCertPool()
+	for _, candidate := range authorities {
+		for _, certificate := range candidate.Certificates() {
+			result.AddCert(certificate)
+		}
+	}
+	return result
+}
+
+// hostRulesRepository provides all rules of a host regardless of their
+// paths; these are the ones which are relevant for the TLS handshake.
+type hostRulesRepository interface {
+	rulesOfHost(value.Fqdn) ([]Rule, error)
+}
+
+func rulesOfHost(byHost *ByHost, host value.Fqdn) ([]Rule, error) {
+	var result []Rule
+	if err := byHost.AllOfHost(host, func(r Rule) error {
+		result = append(result, r)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (this *KubernetesBasedRepository) rulesOfHost(host value.Fqdn) ([]Rule, error) {
+	return rulesOfHost(this.ByHostRules(), host)
+}
+
+func (this *KubernetesBasedRepository) FindClientCertificateRequirementsBy(q CertificateQuery) (ClientCertificateRequirements, error) {
+	var host value.Fqdn
+	if err := host.Set(q.Host.String()); err != nil {
+		return ClientCertificateRequirements{}, nil
+	}
+	candidates, err := this.rulesOfHost(host)
+	if err != nil {
+		return ClientCertificateRequirements{}, err
+	}
+	return ClientCertificateRequirementsOf(candidates, &this.clientCertificatePools), nil
+}
+
+// ClientCertificateAuthorities holds the CA certificates which are used to
+// verify client certificates. One instance is shared between all rules
+// referencing the same secret and is updated in place.
+type ClientCertificateAuthorities struct {
+	mutex        sync.RWMutex
+	certificates []*x509.Certificate
+	pool         *x509.CertPool
+	version      string
+}
+
+func NewClientCertificateAuthorities() *ClientCertificateAuthorities {
+	return &ClientCertificateAuthorities{
+		pool: x509.NewCertPool(),
+	}
+}
+
+// Set replaces all authorities with the PEM encoded certificates of the given
+// content.
+func (this *ClientCertificateAuthorities) Set(content []byte) error {
+	_, err := this.SetVersioned("", content)
+	return err
+}
+
+// SetVersioned is like Set but does nothing if the given version (like the
+// resourceVersion of the secret) is not empty and equal to the version of
+// the current content. It reports whether the authorities were replaced.
+func (this *ClientCertificateAuthorities) SetVersioned(version string, content []byte) (bool, error) {
+	if version != "" && this.Version() == version {
+		return false, nil
+	}
+
+	var certificates []*x509.Certificate
+	pool := x509.NewCertPool()
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return false, err
+		}
+		certificates = append(certificates, certificate)
+		pool.AddCert(certificate)
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.certificates = certificates
+	this.pool = pool
+	this.version = version
+	return true, nil
+}
+
+// Version returns the version of the current content; empty if unknown.
+func (this *ClientCertificateAuthorities) Version() string {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.version
+}
+
+// Pool returns the pool of all authorities. It has to be treated as
+// read-only; it is replaced (not modified) by Set.
+func (this *ClientCertificateAuthorities) Pool() *x509.CertPool {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.pool
+}
+
+func (this *ClientCertificateAuthorities) Certificates() []*x509.Certificate {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return this.certificates
+}
+
+func (this *ClientCertificateAuthorities) Len() int {
+	return len(this.Certificates())
+}
+
+// Verify returns true if the given chain (leaf first) was issued by one of
+// the authorities.
+func (this *ClientCertificateAuthorities) Verify(chain []*x509.Certificate) bool {
+	if len(chain) == 0 {
+		return false
+	}
+	pool := this.Pool()
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range chain[1:] {
+		intermediates.AddCert(certificate)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+func (this *ClientCertificateAuthorities) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"certificates": this.Len(),
+	})
+}
@@ -0,0 +1,127 @@
+package rules
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	. "github.com/onsi/gomega"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func newTestCertificate(t *testing.T, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result, key
+}
+
+func Test_ClientCertificateAuthorities_Verify(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca, caKey := newTestCertificate(t, "ca", nil, nil)
+	otherCa, otherCaKey := newTestCertificate(t, "other-ca", nil, nil)
+	client, _ := newTestCertificate(t, "client", ca, caKey)
+	otherClient, _ := newTestCertificate(t, "other-client", otherCa, otherCaKey)
+
+	instance := NewClientCertificateAuthorities()
+	g.Expect(instance.Set(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))).To(Succeed())
+	g.Expect(instance.Len()).To(Equal(1))
+
+	g.Expect(instance.Verify([]*x509.Certificate{client})).To(BeTrue())
+	g.Expect(instance.Verify([]*x509.Certificate{otherClient})).To(BeFalse())
+	g.Expect(instance.Verify(nil)).To(BeFalse())
+}
+
+func Test_ClientCertificateRequirementsOf(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	newRule := func(mode string) Rule {
+		options := DefaultOptionsFactory()
+		annotations := Annotations{}
+		if mode != "" {
+			annotations[annotationClientCertificateCaSecret] = "ca"
+			annotations[annotationClientCertificateVerify] = mode
+		}
+		g.Expect(options.Set(annotations)).To(Succeed())
+		if opts := options[optionsClientCertificateKey].(*OptionsClientCertificate); opts.CaSecret.IsPresent() {
+			opts.Authorities = NewClientCertificateAuthorities()
+		}
+		return NewRule("", nil, PathTypePrefix, nil, nil, nil, options)
+	}
+
+	g.Expect(ClientCertificateRequirementsOf([]Rule{newRule("")}, nil).ClientAuth).To(Equal(tls.NoClientCert))
+	g.Expect(ClientCertificateRequirementsOf([]Rule{newRule("off")}, nil).ClientAuth).To(Equal(tls.NoClientCert))
+	g.Expect(ClientCertificateRequirementsOf([]Rule{newRule("required"), newRule("")}, nil).ClientAuth).To(Equal(tls.VerifyClientCertIfGiven))
+	g.Expect(ClientCertificateRequirementsOf([]Rule{newRule("optional")}, nil).ClientAuth).To(Equal(tls.VerifyClientCertIfGiven))
+	g.Expect(ClientCertificateRequirementsOf([]Rule{newRule("required"), newRule("required")}, nil).ClientAuth).To(Equal(tls.RequireAndVerifyClientCert))
+}
+
+func Test_ClientCertificatePools(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca, _ := newTestCertificate(t, "ca", nil, nil)
+	otherCa, _ := newTestCertificate(t, "other-ca", nil, nil)
+	a := NewClientCertificateAuthorities()
+	g.Expect(a.Set(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))).To(Succeed())
+	b := NewClientCertificateAuthorities()
+	g.Expect(b.Set(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: otherCa.Raw}))).To(Succeed())
+
+	var instance ClientCertificatePools
+
+	// A single authority is served by its own pool.
+	g.Expect(instance.Get([]*ClientCertificateAuthorities{a})).To(BeIdenticalTo(a.Pool()))
+	g.Expect(instance.len()).To(Equal(0))
+
+	// Combined pools are built once regardless of the order...
+	combined := instance.Get([]*ClientCertificateAuthorities{a, b})
+	g.Expect(instance.Get([]*ClientCertificateAuthorities{b, a})).To(BeIdenticalTo(combined))
+	g.Expect(instance.len()).To(Equal(1))
+
+	// ... until one of the authorities is evicted.
+	instance.Evict(b)
+	g.Expect(instance.len()).To(Equal(0))
+	g.Expect(instance.Get([]*ClientCertificateAuthorities{a, b})).NotTo(BeIdenticalTo(combined))
+}
+
+func Test_ClientCertificateAuthorities_SetVersioned(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca, _ := newTestCertificate(t, "ca", nil, nil)
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	instance := NewClientCertificateAuthorities()
+
+	g.Expect(instance.SetVersioned("1", content)).To(BeTrue())
+	pool := instance.Pool()
+	g.Expect(instance.SetVersioned("1", content)).To(BeFalse())
+	g.Expect(instance.Pool()).To(BeIdenticalTo(pool))
+	g.Expect(instance.SetVersioned("2", content)).To(BeTrue())
+	g.Expect(instance.Pool()).NotTo(BeIdenticalTo(pool))
+}
@@ -0,0 +1,312 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type LoadBalancer uint8
+
+const (
+	LoadBalancerDefault LoadBalancer = iota
+	LoadBalancerRoundRobin
+	LoadBalancerLeastRequests
+	LoadBalancerConsistentHash
+)
+
+func ParseLoadBalancer(plain string) (LoadBalancer, error) {
+	var result LoadBalancer
+	if err := result.Set(plain); err != nil {
+		return LoadBalancerDefault, err
+	}
+	return result, nil
+}
+
+func (this LoadBalancer) String() string {
+	switch this {
+	case LoadBalancerDefault:
+		return ""
+	case LoadBalancerRoundRobin:
+		return "round-robin"
+	case LoadBalancerLeastRequests:
+		return "least-requests"
+	case LoadBalancerConsistentHash:
+		return "consistent-hash"
+	default:
+		return fmt.Sprintf("Unknown-%d", this)
+	}
+}
+
+func (this *LoadBalancer) Set(plain string) error {
+	switch strings.ToLower(strings.TrimSpace(plain)) {
+	case "":
+		*this = LoadBalancerDefault
+	case "round-robin", "roundrobin":
+		*this = LoadBalancerRoundRobin
+	case "least-requests", "leastrequests":
+		*this = LoadBalancerLeastRequests
+	case "consistent-hash", "consistenthash":
+		*this = LoadBalancerConsistentHash
+	default:
+		return fmt.Errorf("illegal load balancer: %s", plain)
+	}
+	return nil
+}
+
+func (this LoadBalancer) MarshalText() ([]byte, error) {
+	return []byte(this.String()), nil
+}
+
+// Endpoints holds all ready endpoints of one port of a service. It is shared
+// between all rules which are pointing to the same service port and will be
+// updated in place if the EndpointSlices of the service are changing.
+type Endpoints struct {
+	mutex     sync.RWMutex
+	endpoints []*Endpoint
+	next      uint64
+}
+
+type Endpoint struct {
+	Address net.Addr
+
+	key      string
+	inFlight int64
+
+	// unhealthy is set to 1 by active health checks.
+	unhealthy int32
+	// consecutiveFailures counts failures reported by passive health checks.
+	consecutiveFailures uint32
+	// ejectedUntil contains the unix nanos until this endpoint is ejected by
+	// passive health checks.
+	ejectedUntil int64
+}
+
+func NewEndpoints(addresses ...net.Addr) *Endpoints {
+	result := &Endpoints{}
+	result.Set(addresses)
+	return result
+}
+
+// Set replaces the current endpoints with the given addresses. The state of
+// endpoints which were already known before (like the amount of requests in
+// flight) will be preserved.
+func (this *Endpoints) Set(addresses []net.Addr) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	existing := make(map[string]*Endpoint, len(this.endpoints))
+	for _, candidate := range this.endpoints {
+		existing[candidate.key] = candidate
+	}
+
+	endpoints := make([]*Endpoint, len(addresses))
+	for i, address := range addresses {
+		key := address.String()
+		if v, ok := existing[key]; ok {
+			endpoints[i] = v
+		} else {
+			endpoints[i] = &Endpoint{
+				Address: address,
+				key:     key,
+			}
+		}
+	}
+	this.endpoints = endpoints
+}
+
+func (this *Endpoints) Len() int {
+	if this == nil {
+		return 0
+	}
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return len(this.endpoints)
+}
+
+func (this *Endpoints) Addresses() []net.Addr {
+	if this == nil {
+		return nil
+	}
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	result := make([]net.Addr, len(this.endpoints))
+	for i, candidate := range this.endpoints {
+		result[i] = candidate.Address
+	}
+	return result
+}
+
+// Select selects one endpoint using the given LoadBalancer. hashKey is only
+// used by LoadBalancerConsistentHash. The returned release function has to
+// be called after the request to the selected endpoint was done. If there is
+// no endpoint at all nil is returned. If every endpoint is unhealthy or
+// ejected, it is selected from all of them; failing health checks (like of a
+// shared dependency) should not turn into an outage of the whole service.
+func (this *Endpoints) Select(lb LoadBalancer, hashKey string) (*Endpoint, func()) {
+	if this == nil {
+		return nil, func() {}
+	}
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+
+	if len(this.endpoints) == 0 {
+		return nil, func() {}
+	}
+
+	now := time.Now()
+	available := make([]*Endpoint, 0, len(this.endpoints))
+	for _, candidate := range this.endpoints {
+		if candidate.IsAvailable(now) {
+			available = append(available, candidate)
+		}
+	}
+	if len(available) == 0 {
+		available = this.endpoints
+	}
+
+	var result *Endpoint
+	switch lb {
+	case LoadBalancerLeastRequests:
+		result = this.selectLeastRequests(available)
+	case LoadBalancerConsistentHash:
+		result = this.selectConsistentHash(available, hashKey)
+	default:
+		result = this.selectRoundRobin(available)
+	}
+
+	atomic.AddInt64(&result.inFlight, 1)
+	return result, func() {
+		atomic.AddInt64(&result.inFlight, -1)
+	}
+}
+
+func (this *Endpoints) selectRoundRobin(available []*Endpoint) *Endpoint {
+	i := atomic.AddUint64(&this.next, 1) - 1
+	return available[i%uint64(len(available))]
+}
+
+func (this *Endpoints) selectLeastRequests(available []*Endpoint) *Endpoint {
+	// Start at a rotating offset to not always prefer the first endpoints
+	// if all of them have the same amount of requests in flight.
+	offset := atomic.AddUint64(&this.next, 1) - 1
+	var result *Endpoint
+	for i := range available {
+		candidate := available[(offset+uint64(i))%uint64(len(available))]
+		if result == nil || atomic.LoadInt64(&candidate.inFlight) < atomic.LoadInt64(&result.inFlight) {
+			result = candidate
+		}
+	}
+	return result
+}
+
+// selectConsistentHash uses rendezvous hashing which ensures that only the
+// keys of a removed endpoint will be moved to other endpoints.
+func (this *Endpoints) selectConsistentHash(available []*Endpoint, hashKey string) *Endpoint {
+	var result *Endpoint
+	var resultScore uint64
+	for _, candidate := range available {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(candidate.key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(hashKey))
+		if score := h.Sum64(); result == nil || score > resultScore {
+			result, resultScore = candidate, score
+		}
+	}
+	return result
+}
+
+// All returns all endpoints regardless of their health.
+func (this *Endpoints) All() []*Endpoint {
+	if this == nil {
+		return nil
+	}
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	result := make([]*Endpoint, len(this.endpoints))
+	copy(result, this.endpoints)
+	return result
+}
+
+func (this *Endpoints) String() string {
+	addresses := this.Addresses()
+	result := make([]string, len(addresses))
+	for i, address := range addresses {
+		result[i] = address.String()
+	}
+	return strings.Join(result, ",")
+}
+
+func (this *Endpoint) InFlight() int64 {
+	return atomic.LoadInt64(&this.inFlight)
+}
+
+// IsAvailable returns true if this endpoint is neither marked as unhealthy by
+// active health checks nor ejected by passive health checks.
+func (this *Endpoint) IsAvailable(now time.Time) bool {
+	return this.IsHealthy() && !this.IsEjected(now)
+}
+
+func (this *Endpoint) IsHealthy() bool {
+	return atomic.LoadInt32(&this.unhealthy) == 0
+}
+
+// SetHealthy is used by active health checks and returns true if the state
+// was changed.
+func (this *Endpoint) SetHealthy(healthy bool) bool {
+	var v int32
+	if !healthy {
+		v = 1
+	}
+	return atomic.SwapInt32(&this.unhealthy, v) != v
+}
+
+func (this *Endpoint) IsEjected(now time.Time) bool {
+	return atomic.LoadInt64(&this.ejectedUntil) > now.UnixNano()
+}
+
+func (this *Endpoint) EjectedUntil() time.Time {
+	if v := atomic.LoadInt64(&this.ejectedUntil); v > 0 {
+		return time.Unix(0, v)
+	}
+	return time.Time{}
+}
+
+// ReportSuccess is used by passive health checks and resets the consecutive
+// failures.
+func (this *Endpoint) ReportSuccess() {
+	atomic.StoreUint32(&this.consecutiveFailures, 0)
+}
+
+// ReportFailure is used by passive health checks. If the consecutive failures
+// reaching maxFailures the endpoint will be ejected for ejectionTime and true
+// is returned.
+func (this *Endpoint) ReportFailure(maxFailures uint32, ejectionTime time.Duration) bool {
+	if maxFailures == 0 {
+		return false
+	}
+	if atomic.AddUint32(&this.consecutiveFailures, 1) < maxFailures {
+		return false
+	}
+	atomic.StoreUint32(&this.consecutiveFailures, 0)
+	atomic.StoreInt64(&this.ejectedUntil, time.Now().Add(ejectionTime).UnixNano())
+	return true
+}
+
+func (this *Endpoint) MarshalJSON() ([]byte, error) {
+	buf := map[string]interface{}{
+		"address":  this.Address.String(),
+		"healthy":  this.IsHealthy(),
+		"inFlight": this.InFlight(),
+	}
+	if v := this.EjectedUntil(); v.After(time.Now()) {
+		buf["ejectedUntil"] = v
+	}
+	return json.Marshal(buf)
+}
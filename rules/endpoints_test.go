@@ -0,0 +1,106 @@
+package rules
+
+import (
+	. "github.com/onsi/gomega"
+	"net"
+	"testing"
+	"time"
+)
+
+func newTestAddresses(ports ...int) []net.Addr {
+	result := make([]net.Addr, len(ports))
+	for i, port := range ports {
+		result[i] = &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: port}
+	}
+	return result
+}
+
+func Test_Endpoints_Select_roundRobin(t *testing.T) {
+	g := NewGomegaWithT(t)
+	instance := NewEndpoints(newTestAddresses(1, 2, 3)...)
+
+	var actual []string
+	for i := 0; i < 4; i++ {
+		endpoint, release := instance.Select(LoadBalancerRoundRobin, "")
+		actual = append(actual, endpoint.Address.String())
+		release()
+	}
+
+	g.Expect(actual).To(Equal([]string{"10.0.0.1:1", "10.0.0.1:2", "10.0.0.1:3", "10.0.0.1:1"}))
+}
+
+func Test_Endpoints_Select_leastRequests(t *testing.T) {
+	g := NewGomegaWithT(t)
+	instance := NewEndpoints(newTestAddresses(1, 2)...)
+
+	first, releaseFirst := instance.Select(LoadBalancerLeastRequests, "")
+	second, releaseSecond := instance.Select(LoadBalancerLeastRequests, "")
+	g.Expect(second).NotTo(BeIdenticalTo(first))
+
+	releaseFirst()
+	third, releaseThird := instance.Select(LoadBalancerLeastRequests, "")
+	g.Expect(third).To(BeIdenticalTo(first))
+
+	releaseSecond()
+	releaseThird()
+	g.Expect(first.InFlight()).To(Equal(int64(0)))
+	g.Expect(second.InFlight()).To(Equal(int64(0)))
+}
+
+func Test_Endpoints_Select_consistentHash(t *testing.T) {
+	g := NewGomegaWithT(t)
+	instance := NewEndpoints(newTestAddresses(1, 2, 3, 4)...)
+
+	selected, _ := instance.Select(LoadBalancerConsistentHash, "foo")
+	for i := 0; i < 10; i++ {
+		again, _ := instance.Select(LoadBalancerConsistentHash, "foo")
+		g.Expect(again).To(BeIdenticalTo(selected))
+	}
+
+	var remaining []net.Addr
+	for _, candidate := range instance.Addresses() {
+		if candidate.String() != selected.Address.String() {
+			remaining = append(remaining, candidate)
+		}
+	}
+	remaining = append(remaining, selected.Address)
+	instance.Set(remaining)
+	afterSet, _ := instance.Select(LoadBalancerConsistentHash, "foo")
+	g.Expect(afterSet).To(BeIdenticalTo(selected))
+}
+
+func Test_Endpoints_Select_empty(t *testing.T) {
+	g := NewGomegaWithT(t)
+	instance := NewEndpoints()
+
+	actual, release := instance.Select(LoadBalancerRoundRobin, "")
+	release()
+
+	g.Expect(actual).To(BeNil())
+}
+
+func Test_Endpoints_Select_skipsUnavailable(t *testing.T) {
+	g := NewGomegaWithT(t)
+	instance := NewEndpoints(newTestAddresses(1, 2)...)
+	all := instance.All()
+
+	all[0].SetHealthy(false)
+	for i := 0; i < 3; i++ {
+		actual, _ := instance.Select(LoadBalancerRoundRobin, "")
+		g.Expect(actual).To(BeIdenticalTo(all[1]))
+	}
+
+	g.Expect(all[1].ReportFailure(2, time.Minute)).To(BeFalse())
+	g.Expect(all[1].ReportFailure(2, time.Minute)).To(BeTrue())
+	// None is available; so all of them are used again.
+	selected := map[*Endpoint]bool{}
+	for i := 0; i < 2; i++ {
+		actual, _ := instance.Select(LoadBalancerRoundRobin, "")
+		selected[actual] = true
+	}
+	g.Expect(selected).To(HaveLen(2))
+
+	all[0].SetHealthy(true)
+	actual, _ := instance.Select(LoadBalancerRoundRobin, "")
+	g.Expect(actual).To(BeIdenticalTo(all[0]))
+}
@@ -0,0 +1,119 @@
+package rules
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"sync"
+)
+
+// Htpasswd holds credentials in the format of htpasswd files. Supported are
+// bcrypt ($2a$, $2b$ and $2y$) and SHA-1 ({SHA}) hashes. One instance is shared
+// between all rules referencing the same secret and is updated in place.
+type Htpasswd struct {
+	mutex   sync.RWMutex
+	entries map[string]string
+
+	// verified contains the hashes of all credentials which were verified
+	// successfully; so the expensive bcrypt is only evaluated once per
+	// credentials instead of once per request.
+	verified map[[sha256.Size]byte]struct{}
+}
+
+func NewHtpasswd() *Htpasswd {
+	return &Htpasswd{
+		entries:  map[string]string{},
+		verified: map[[sha256.Size]byte]struct{}{},
+	}
+}
+
+// Set replaces all credentials with the given content and returns the names
+// of the users which were ignored because of unsupported hashes.
+func (this *Htpasswd) Set(content []byte) (ignored []string) {
+	entries := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		username, hash, ok := strings.Cut(line, ":")
+		if !ok || username == "" {
+			continue
+		}
+		if !isSupportedHtpasswdHash(hash) {
+			ignored = append(ignored, username)
+			continue
+		}
+		entries[username] = hash
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.entries = entries
+	this.verified = map[[sha256.Size]byte]struct{}{}
+	return
+}
+
+func (this *Htpasswd) Verify(username, password string) bool {
+	// A username cannot contain a colon; so this is unambiguous.
+	key := sha256.Sum256([]byte(username + ":" + password))
+
+	this.mutex.RLock()
+	hash, ok := this.entries[username]
+	_, verified := this.verified[key]
+	this.mutex.RUnlock()
+	if !ok {
+		return false
+	}
+	if verified {
+		return true
+	}
+
+	if !verifyHtpasswdHash(hash, password) {
+		return false
+	}
+
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	// Only remember it if the credentials were not replaced meanwhile.
+	if this.entries[username] == hash {
+		this.verified[key] = struct{}{}
+	}
+	return true
+}
+
+func verifyHtpasswdHash(hash, password string) bool {
+	if strings.HasPrefix(hash, "{SHA}") {
+		sum := sha1.Sum([]byte(password))
+		expected := []byte(hash[5:])
+		actual := []byte(base64.StdEncoding.EncodeToString(sum[:]))
+		return subtle.ConstantTimeCompare(expected, actual) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+func (this *Htpasswd) Len() int {
+	this.mutex.RLock()
+	defer this.mutex.RUnlock()
+	return len(this.entries)
+}
+
+func (this *Htpasswd) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"users": this.Len(),
+	})
+}
+
+func isSupportedHtpasswdHash(hash string) bool {
+	return strings.HasPrefix(hash, "{SHA}") ||
+		strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") ||
+		strings.HasPrefix(hash, "$2y$")
+}
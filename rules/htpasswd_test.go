@@ -0,0 +1,34 @@
+package rules
+
+import (
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func Test_Htpasswd_Verify(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	instance := NewHtpasswd()
+	ignored := instance.Set([]byte("# comment\n" +
+		"bcrypt:$2y$05$qYnt4o7VMJ8ol4yrVoalkOFkgiZCjXtfb.5.3yB1haz9lflq8a7l2\n" +
+		"sha:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n" +
+		"md5:$apr1$Ao3jFrLd$Tn8HcEvsNFcvwQ8HSR0A1/\n"))
+
+	g.Expect(ignored).To(Equal([]string{"md5"}))
+	g.Expect(instance.Len()).To(Equal(2))
+
+	g.Expect(instance.Verify("bcrypt", "password")).To(BeTrue())
+	g.Expect(instance.Verify("bcrypt", "wrong")).To(BeFalse())
+	g.Expect(instance.Verify("sha", "password")).To(BeTrue())
+	g.Expect(instance.Verify("sha", "wrong")).To(BeFalse())
+	g.Expect(instance.Verify("md5", "password")).To(BeFalse())
+	g.Expect(instance.Verify("unknown", "password")).To(BeFalse())
+
+	// Successful verifications are remembered.
+	g.Expect(instance.verified).To(HaveLen(2))
+	g.Expect(instance.Verify("bcrypt", "password")).To(BeTrue())
+	g.Expect(instance.verified).To(HaveLen(2))
+
+	instance.Set(nil)
+	g.Expect(instance.Verify("sha", "password")).To(BeFalse())
+}
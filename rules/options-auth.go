@@ -0,0 +1,77 @@
+package rules
+
+import (
+	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/value"
+	"net/url"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsAuth{})
+
+const (
+	optionsAuthKey = "auth"
+
+	annotationAuthUrl             = "lingress.echocat.org/auth-url"
+	annotationAuthResponseHeaders = "lingress.echocat.org/auth-response-headers"
+	annotationAuthSignin          = "lingress.echocat.org/auth-signin"
+)
+
+func OptionsAuthOf(rule Rule) *OptionsAuth {
+	if rule == nil {
+		return &OptionsAuth{}
+	}
+	if v, ok := rule.Options()[optionsAuthKey].(*OptionsAuth); ok {
+		return v
+	}
+	return &OptionsAuth{}
+}
+
+type OptionsAuth struct {
+	Url             value.String       `json:"url,omitempty"`
+	ResponseHeaders value2.HeaderNames `json:"responseHeaders,omitempty"`
+	Signin          value.String       `json:"signin,omitempty"`
+}
+
+func (this OptionsAuth) Name() string {
+	return optionsAuthKey
+}
+
+func (this OptionsAuth) IsRelevant() bool {
+	return this.Url.IsPresent() ||
+		this.ResponseHeaders.IsPresent() ||
+		this.Signin.IsPresent()
+}
+
+func (this *OptionsAuth) Set(annotations Annotations) (err error) {
+	if this.Url, err = evaluateOptionUrl(annotations, annotationAuthUrl); err != nil {
+		return
+	}
+	if v, ok := annotations[annotationAuthResponseHeaders]; ok {
+		if this.ResponseHeaders, err = value2.ParseHeaderNames(v); err != nil {
+			return fmt.Errorf("illegal value for annotation %s: %w", annotationAuthResponseHeaders, err)
+		}
+	} else {
+		this.ResponseHeaders = nil
+	}
+	if this.Signin, err = evaluateOptionUrl(annotations, annotationAuthSignin); err != nil {
+		return
+	}
+	return
+}
+
+func evaluateOptionUrl(annotations map[string]string, name string) (result value.String, err error) {
+	v, ok := annotations[name]
+	if !ok || v == "" {
+		return value.String{}, nil
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return value.String{}, fmt.Errorf("illegal value for annotation %s: %w", name, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return value.String{}, fmt.Errorf("illegal value for annotation %s: has to be an absolute http or https URL", name)
+	}
+	err = result.Set(v)
+	return
+}
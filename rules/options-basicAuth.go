@@ -0,0 +1,66 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/value"
+	"strings"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsBasicAuth{})
+
+const (
+	optionsBasicAuthKey = "basicAuth"
+
+	annotationBasicAuthSecret = "lingress.echocat.org/basic-auth.secret"
+	annotationBasicAuthRealm  = "lingress.echocat.org/basic-auth.realm"
+
+	basicAuthSecretKey = "auth"
+)
+
+func OptionsBasicAuthOf(rule Rule) *OptionsBasicAuth {
+	if rule == nil {
+		return &OptionsBasicAuth{}
+	}
+	if v, ok := rule.Options()[optionsBasicAuthKey].(*OptionsBasicAuth); ok {
+		return v
+	}
+	return &OptionsBasicAuth{}
+}
+
+type OptionsBasicAuth struct {
+	Secret value.String `json:"secret,omitempty"`
+	Realm  value.String `json:"realm,omitempty"`
+
+	// Credentials are provided by the Repository from the referenced Secret.
+	Credentials *Htpasswd `json:"credentials,omitempty"`
+}
+
+func (this OptionsBasicAuth) Name() string {
+	return optionsBasicAuthKey
+}
+
+func (this OptionsBasicAuth) IsRelevant() bool {
+	return this.Secret.IsPresent() ||
+		this.Realm.IsPresent()
+}
+
+func (this *OptionsBasicAuth) Set(annotations Annotations) (err error) {
+	this.Secret, this.Realm, this.Credentials = value.String{}, value.String{}, nil
+	if v := annotations[annotationBasicAuthSecret]; v != "" {
+		if strings.Contains(v, "/") {
+			return fmt.Errorf("illegal value for annotation %s: the secret has to be in the same namespace as the ingress", annotationBasicAuthSecret)
+		}
+		if err = this.Secret.Set(v); err != nil {
+			return
+		}
+	}
+	if v := annotations[annotationBasicAuthRealm]; v != "" {
+		if strings.ContainsAny(v, "\"\r\n") {
+			return fmt.Errorf("illegal value for annotation %s: must not contain quotes or line breaks", annotationBasicAuthRealm)
+		}
+		if err = this.Realm.Set(v); err != nil {
+			return
+		}
+	}
+	return
+}
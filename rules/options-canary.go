@@ -0,0 +1,103 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/value"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsCanary{})
+
+const (
+	optionsCanaryKey = "canary"
+
+	annotationCanaryEnabled       = "lingress.echocat.org/canary"
+	annotationCanaryWeight        = "lingress.echocat.org/canary-weight"
+	annotationCanaryWeightTotal   = "lingress.echocat.org/canary-weight-total"
+	annotationCanaryByHeader      = "lingress.echocat.org/canary-by-header"
+	annotationCanaryByHeaderValue = "lingress.echocat.org/canary-by-header-value"
+	annotationCanaryByCookie      = "lingress.echocat.org/canary-by-cookie"
+
+	defaultCanaryWeightTotal = uint32(100)
+)
+
+func OptionsCanaryOf(rule Rule) *OptionsCanary {
+	if rule == nil {
+		return &OptionsCanary{}
+	}
+	if v, ok := rule.Options()[optionsCanaryKey].(*OptionsCanary); ok {
+		return v
+	}
+	return &OptionsCanary{}
+}
+
+type OptionsCanary struct {
+	Enabled       value.Bool   `json:"enabled,omitempty"`
+	Weight        value.Uint32 `json:"weight,omitempty"`
+	WeightTotal   value.Uint32 `json:"weightTotal,omitempty"`
+	ByHeader      string       `json:"byHeader,omitempty"`
+	ByHeaderValue string       `json:"byHeaderValue,omitempty"`
+	ByCookie      string       `json:"byCookie,omitempty"`
+}
+
+func (this OptionsCanary) Name() string {
+	return optionsCanaryKey
+}
+
+func (this OptionsCanary) IsRelevant() bool {
+	return this.Enabled.IsPresent() ||
+		this.Weight.IsPresent() ||
+		this.WeightTotal.IsPresent() ||
+		this.ByHeader != "" ||
+		this.ByHeaderValue != "" ||
+		this.ByCookie != ""
+}
+
+// WeightRatio returns the share (between 0 and 1) of the traffic which should
+// be routed to this rule.
+func (this OptionsCanary) WeightRatio() float64 {
+	total := this.WeightTotal.GetOr(defaultCanaryWeightTotal)
+	if total == 0 {
+		return 0
+	}
+	return float64(this.Weight.Get()) / float64(total)
+}
+
+func (this *OptionsCanary) Set(annotations Annotations) (err error) {
+	if this.Enabled, err = evaluateOptionCanaryEnabled(annotations); err != nil {
+		return
+	}
+	if this.Weight, err = evaluateOptionUint32(annotations, annotationCanaryWeight); err != nil {
+		return
+	}
+	if this.WeightTotal, err = evaluateOptionUint32(annotations, annotationCanaryWeightTotal); err != nil {
+		return
+	}
+	if this.WeightTotal.IsPresent() && this.WeightTotal.Get() == 0 {
+		return fmt.Errorf("illegal value for annotation %s: has to be greater than 0", annotationCanaryWeightTotal)
+	}
+	if this.Weight.Get() > this.WeightTotal.GetOr(defaultCanaryWeightTotal) {
+		return fmt.Errorf("illegal value for annotation %s: %v is greater than %v", annotationCanaryWeight, this.Weight, this.WeightTotal.GetOr(defaultCanaryWeightTotal))
+	}
+	this.ByHeader = annotations[annotationCanaryByHeader]
+	this.ByHeaderValue = annotations[annotationCanaryByHeaderValue]
+	this.ByCookie = annotations[annotationCanaryByCookie]
+	return
+}
+
+func evaluateOptionCanaryEnabled(annotations map[string]string) (value.Bool, error) {
+	if v, ok := annotations[annotationCanaryEnabled]; ok {
+		return AnnotationIsBool(annotationCanaryEnabled, v)
+	}
+	return value.UndefinedBool(), nil
+}
+
+func evaluateOptionUint32(annotations map[string]string, name string) (value.Uint32, error) {
+	if v, ok := annotations[name]; ok {
+		result, err := value.ParseUint32(v)
+		if err != nil {
+			return value.Uint32{}, fmt.Errorf("illegal numeric value for annotation %s: %s", name, v)
+		}
+		return result, nil
+	}
+	return value.Uint32{}, nil
+}
@@ -0,0 +1,68 @@
+package rules
+
+import (
+	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/value"
+	"strings"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsClientCertificate{})
+
+const (
+	optionsClientCertificateKey = "clientCertificate"
+
+	annotationClientCertificateCaSecret = "lingress.echocat.org/client-certificate.ca-secret"
+	annotationClientCertificateVerify   = "lingress.echocat.org/client-certificate.verify"
+
+	clientCertificateCaSecretKey = "ca.crt"
+)
+
+func OptionsClientCertificateOf(rule Rule) *OptionsClientCertificate {
+	if rule == nil {
+		return &OptionsClientCertificate{}
+	}
+	if v, ok := rule.Options()[optionsClientCertificateKey].(*OptionsClientCertificate); ok {
+		return v
+	}
+	return &OptionsClientCertificate{}
+}
+
+type OptionsClientCertificate struct {
+	CaSecret value.String                 `json:"caSecret,omitempty"`
+	Mode     value2.ClientCertificateMode `json:"mode,omitempty"`
+
+	// Authorities are provided by the Repository from the referenced Secret.
+	Authorities *ClientCertificateAuthorities `json:"authorities,omitempty"`
+}
+
+func (this OptionsClientCertificate) Name() string {
+	return optionsClientCertificateKey
+}
+
+func (this OptionsClientCertificate) IsRelevant() bool {
+	return this.CaSecret.IsPresent() ||
+		this.Mode.IsPresent()
+}
+
+func (this *OptionsClientCertificate) Set(annotations Annotations) (err error) {
+	this.CaSecret, this.Authorities = value.String{}, nil
+	if v := annotations[annotationClientCertificateCaSecret]; v != "" {
+		if strings.Contains(v, "/") {
+			return fmt.Errorf("illegal value for annotation %s: the secret has to be in the same namespace as the ingress", annotationClientCertificateCaSecret)
+		}
+		if err = this.CaSecret.Set(v); err != nil {
+			return
+		}
+	}
+	if this.Mode, err = value2.ParseClientCertificateMode(annotations[annotationClientCertificateVerify]); err != nil {
+		return fmt.Errorf("illegal value for annotation %s: %w", annotationClientCertificateVerify, err)
+	}
+	if this.CaSecret.IsPresent() && !this.Mode.IsPresent() {
+		this.Mode = value2.ClientCertificateModeRequired
+	}
+	if this.Mode.IsEnabled() && !this.CaSecret.IsPresent() {
+		return fmt.Errorf("annotation %s requires annotation %s", annotationClientCertificateVerify, annotationClientCertificateCaSecret)
+	}
+	return
+}
@@ -0,0 +1,45 @@
+package rules
+
+import (
+	"github.com/echocat/lingress/value"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsGrpcWeb{})
+
+const (
+	optionsGrpcWebKey = "grpcWeb"
+
+	annotationGrpcWebEnabled = "lingress.echocat.org/grpc-web"
+)
+
+func OptionsGrpcWebOf(rule Rule) *OptionsGrpcWeb {
+	if rule == nil {
+		return &OptionsGrpcWeb{}
+	}
+	if v, ok := rule.Options()[optionsGrpcWebKey].(*OptionsGrpcWeb); ok {
+		return v
+	}
+	return &OptionsGrpcWeb{}
+}
+
+type OptionsGrpcWeb struct {
+	Enabled value.Bool `json:"enabled,omitempty"`
+}
+
+func (this OptionsGrpcWeb) Name() string {
+	return optionsGrpcWebKey
+}
+
+func (this OptionsGrpcWeb) IsRelevant() bool {
+	return this.Enabled.IsPresent()
+}
+
+func (this *OptionsGrpcWeb) Set(annotations Annotations) (err error) {
+	this.Enabled = value.UndefinedBool()
+	if v, ok := annotations[annotationGrpcWebEnabled]; ok {
+		if this.Enabled, err = AnnotationIsBool(annotationGrpcWebEnabled, v); err != nil {
+			return
+		}
+	}
+	return
+}
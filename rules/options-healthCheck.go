@@ -0,0 +1,143 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/value"
+	"strings"
+	"time"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsHealthCheck{})
+
+const (
+	optionsHealthCheckKey = "healthCheck"
+
+	annotationHealthCheckPath               = "lingress.echocat.org/health-check.path"
+	annotationHealthCheckInterval           = "lingress.echocat.org/health-check.interval"
+	annotationHealthCheckTimeout            = "lingress.echocat.org/health-check.timeout"
+	annotationHealthCheckHealthyThreshold   = "lingress.echocat.org/health-check.healthy-threshold"
+	annotationHealthCheckUnhealthyThreshold = "lingress.echocat.org/health-check.unhealthy-threshold"
+	annotationOutlierConsecutiveErrors      = "lingress.echocat.org/outlier-detection.consecutive-errors"
+	annotationOutlierEjectionTime           = "lingress.echocat.org/outlier-detection.ejection-time"
+
+	defaultHealthCheckInterval           = 10 * time.Second
+	defaultHealthCheckTimeout            = 2 * time.Second
+	defaultHealthCheckHealthyThreshold   = uint32(2)
+	defaultHealthCheckUnhealthyThreshold = uint32(3)
+	defaultOutlierEjectionTime           = 30 * time.Second
+)
+
+func OptionsHealthCheckOf(rule Rule) *OptionsHealthCheck {
+	if rule == nil {
+		return &OptionsHealthCheck{}
+	}
+	if v, ok := rule.Options()[optionsHealthCheckKey].(*OptionsHealthCheck); ok {
+		return v
+	}
+	return &OptionsHealthCheck{}
+}
+
+type OptionsHealthCheck struct {
+	Path               string         `json:"path,omitempty"`
+	Interval           value.Duration `json:"interval,omitempty"`
+	Timeout            value.Duration `json:"timeout,omitempty"`
+	HealthyThreshold   value.Uint32   `json:"healthyThreshold,omitempty"`
+	UnhealthyThreshold value.Uint32   `json:"unhealthyThreshold,omitempty"`
+
+	OutlierConsecutiveErrors value.Uint32   `json:"outlierConsecutiveErrors,omitempty"`
+	OutlierEjectionTime      value.Duration `json:"outlierEjectionTime,omitempty"`
+}
+
+func (this OptionsHealthCheck) Name() string {
+	return optionsHealthCheckKey
+}
+
+func (this OptionsHealthCheck) IsRelevant() bool {
+	return this.Path != "" ||
+		this.Interval.IsPresent() ||
+		this.Timeout.IsPresent() ||
+		this.HealthyThreshold.IsPresent() ||
+		this.UnhealthyThreshold.IsPresent() ||
+		this.OutlierConsecutiveErrors.IsPresent() ||
+		this.OutlierEjectionTime.IsPresent()
+}
+
+// IsActive returns true if the endpoints should be actively probed.
+func (this OptionsHealthCheck) IsActive() bool {
+	return this.Path != ""
+}
+
+// IsPassive returns true if endpoints should be ejected after consecutive
+// errors.
+func (this OptionsHealthCheck) IsPassive() bool {
+	return this.OutlierConsecutiveErrors.Get() > 0
+}
+
+func (this OptionsHealthCheck) GetInterval() time.Duration {
+	return this.Interval.GetOr(defaultHealthCheckInterval)
+}
+
+func (this OptionsHealthCheck) GetTimeout() time.Duration {
+	return this.Timeout.GetOr(defaultHealthCheckTimeout)
+}
+
+func (this OptionsHealthCheck) GetHealthyThreshold() uint32 {
+	return this.HealthyThreshold.GetOr(defaultHealthCheckHealthyThreshold)
+}
+
+func (this OptionsHealthCheck) GetUnhealthyThreshold() uint32 {
+	return this.UnhealthyThreshold.GetOr(defaultHealthCheckUnhealthyThreshold)
+}
+
+func (this OptionsHealthCheck) GetOutlierEjectionTime() time.Duration {
+	return this.OutlierEjectionTime.GetOr(defaultOutlierEjectionTime)
+}
+
+func (this *OptionsHealthCheck) Set(annotations Annotations) (err error) {
+	if this.Path, err = evaluateOptionHealthCheckPath(annotations); err != nil {
+		return
+	}
+	if this.Interval, err = evaluateOptionDuration(annotations, annotationHealthCheckInterval); err != nil {
+		return
+	}
+	if this.Timeout, err = evaluateOptionDuration(annotations, annotationHealthCheckTimeout); err != nil {
+		return
+	}
+	if this.HealthyThreshold, err = evaluateOptionUint32(annotations, annotationHealthCheckHealthyThreshold); err != nil {
+		return
+	}
+	if this.UnhealthyThreshold, err = evaluateOptionUint32(annotations, annotationHealthCheckUnhealthyThreshold); err != nil {
+		return
+	}
+	if this.OutlierConsecutiveErrors, err = evaluateOptionUint32(annotations, annotationOutlierConsecutiveErrors); err != nil {
+		return
+	}
+	if this.OutlierEjectionTime, err = evaluateOptionDuration(annotations, annotationOutlierEjectionTime); err != nil {
+		return
+	}
+	if this.Interval.IsPresent() && this.Interval.Get() <= 0 {
+		return fmt.Errorf("illegal value for annotation %s: has to be greater than 0", annotationHealthCheckInterval)
+	}
+	return
+}
+
+func evaluateOptionHealthCheckPath(annotations map[string]string) (string, error) {
+	if v, ok := annotations[annotationHealthCheckPath]; ok && v != "" {
+		if !strings.HasPrefix(v, "/") {
+			return "", fmt.Errorf("illegal value for annotation %s: has to start with /", annotationHealthCheckPath)
+		}
+		return v, nil
+	}
+	return "", nil
+}
+
+func evaluateOptionDuration(annotations map[string]string, name string) (value.Duration, error) {
+	if v, ok := annotations[name]; ok {
+		result, err := value.ParseDuration(v)
+		if err != nil {
+			return value.Duration{}, fmt.Errorf("illegal duration value for annotation %s: %s", name, v)
+		}
+		return result, nil
+	}
+	return value.Duration{}, nil
+}
@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/value"
+	"strings"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsLoadBalancer{})
+
+const (
+	optionsLoadBalancerKey = "loadBalancer"
+
+	annotationLoadBalancer       = "lingress.echocat.org/load-balancer"
+	annotationLoadBalancerHashBy = "lingress.echocat.org/load-balancer.hash-by"
+	annotationServiceUpstream    = "lingress.echocat.org/service-upstream"
+)
+
+func OptionsLoadBalancerOf(rule Rule) *OptionsLoadBalancer {
+	if rule == nil {
+		return &OptionsLoadBalancer{}
+	}
+	if v, ok := rule.Options()[optionsLoadBalancerKey].(*OptionsLoadBalancer); ok {
+		return v
+	}
+	return &OptionsLoadBalancer{}
+}
+
+type OptionsLoadBalancer struct {
+	LoadBalancer    LoadBalancer `json:"loadBalancer,omitempty"`
+	HashBy          HashBy       `json:"hashBy,omitempty"`
+	ServiceUpstream value.Bool   `json:"serviceUpstream,omitempty"`
+}
+
+func (this OptionsLoadBalancer) Name() string {
+	return optionsLoadBalancerKey
+}
+
+func (this OptionsLoadBalancer) IsRelevant() bool {
+	return this.LoadBalancer != LoadBalancerDefault ||
+		this.HashBy.IsPresent() ||
+		this.ServiceUpstream.IsPresent()
+}
+
+func (this *OptionsLoadBalancer) Set(annotations Annotations) (err error) {
+	if err = this.LoadBalancer.Set(annotations[annotationLoadBalancer]); err != nil {
+		return fmt.Errorf("illegal value for annotation %s: %w", annotationLoadBalancer, err)
+	}
+	if err = this.HashBy.Set(annotations[annotationLoadBalancerHashBy]); err != nil {
+		return fmt.Errorf("illegal value for annotation %s: %w", annotationLoadBalancerHashBy, err)
+	}
+	if this.ServiceUpstream, err = AnnotationIsBool(annotationServiceUpstream, annotations[annotationServiceUpstream]); err != nil {
+		return
+	}
+	return
+}
+
+type HashByKind uint8
+
+const (
+	HashByRemote HashByKind = iota
+	HashByPath
+	HashByHeader
+	HashByCookie
+)
+
+// HashBy describes which part of a request is used as key by
+// LoadBalancerConsistentHash. Possible values are: remote, path,
+// header:<name> and cookie:<name>. If absent remote is used.
+type HashBy struct {
+	Kind HashByKind
+	Name string
+
+	present bool
+}
+
+func (this HashBy) IsPresent() bool {
+	return this.present
+}
+
+func (this HashBy) String() string {
+	switch this.Kind {
+	case HashByRemote:
+		return "remote"
+	case HashByPath:
+		return "path"
+	case HashByHeader:
+		return "header:" + this.Name
+	case HashByCookie:
+		return "cookie:" + this.Name
+	default:
+		return fmt.Sprintf("Unknown-%d", this.Kind)
+	}
+}
+
+func (this *HashBy) Set(plain string) error {
+	plain = strings.TrimSpace(plain)
+	if plain == "" {
+		*this = HashBy{}
+		return nil
+	}
+	kind, name, _ := strings.Cut(plain, ":")
+	switch strings.ToLower(kind) {
+	case "remote":
+		*this = HashBy{Kind: HashByRemote, present: true}
+	case "path":
+		*this = HashBy{Kind: HashByPath, present: true}
+	case "header":
+		if name == "" {
+			return fmt.Errorf("header name expected: %s", plain)
+		}
+		*this = HashBy{Kind: HashByHeader, Name: name, present: true}
+	case "cookie":
+		if name == "" {
+			return fmt.Errorf("cookie name expected: %s", plain)
+		}
+		*this = HashBy{Kind: HashByCookie, Name: name, present: true}
+	default:
+		return fmt.Errorf("illegal hash by: %s", plain)
+	}
+	return nil
+}
+
+func (this HashBy) MarshalText() ([]byte, error) {
+	return []byte(this.String()), nil
+}
@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"fmt"
+	rvalue "github.com/echocat/lingress/rules/value"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsMatch{})
+
+const (
+	optionsMatchKey = "match"
+
+	annotationMatchMethods = "lingress.echocat.org/match-methods"
+	annotationMatchHeaders = "lingress.echocat.org/match-headers"
+	annotationMatchQueries = "lingress.echocat.org/match-queries"
+)
+
+func OptionsMatchOf(rule Rule) *OptionsMatch {
+	if rule == nil {
+		return &OptionsMatch{}
+	}
+	if v, ok := rule.Options()[optionsMatchKey].(*OptionsMatch); ok {
+		return v
+	}
+	return &OptionsMatch{}
+}
+
+// OptionsMatch restricts a rule to requests which are matching additional
+// conditions beside host and path. If several rules are competing for the same
+// host and path, only the matching ones with the most conditions are
+// considered.
+type OptionsMatch struct {
+	Methods rvalue.Methods `json:"methods,omitempty"`
+	Headers []ValueMatch   `json:"headers,omitempty"`
+	Queries []ValueMatch   `json:"queries,omitempty"`
+}
+
+func (this OptionsMatch) Name() string {
+	return optionsMatchKey
+}
+
+func (this OptionsMatch) IsRelevant() bool {
+	return len(this.Methods) > 0 ||
+		len(this.Headers) > 0 ||
+		len(this.Queries) > 0
+}
+
+func (this *OptionsMatch) Set(annotations Annotations) (err error) {
+	if this.Methods, err = evaluateOptionMatchMethods(annotations); err != nil {
+		return
+	}
+	if this.Headers, err = evaluateOptionValueMatches(annotations, annotationMatchHeaders, ":", textproto.CanonicalMIMEHeaderKey); err != nil {
+		return
+	}
+	if this.Queries, err = evaluateOptionValueMatches(annotations, annotationMatchQueries, "=", nil); err != nil {
+		return
+	}
+	return
+}
+
+// Matches reports whether the given request fulfills all conditions.
+func (this OptionsMatch) Matches(req *http.Request) bool {
+	if !this.IsRelevant() {
+		return true
+	}
+	if req == nil {
+		return false
+	}
+	if !this.Methods.Matches(rvalue.Method(req.Method)) {
+		return false
+	}
+	for _, candidate := range this.Headers {
+		if !candidate.Matches(req.Header.Values(candidate.Name)) {
+			return false
+		}
+	}
+	if len(this.Queries) > 0 {
+		var query map[string][]string
+		if u := req.URL; u != nil {
+			query = u.Query()
+		}
+		for _, candidate := range this.Queries {
+			if !candidate.Matches(query[candidate.Name]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Specificity returns the amount of conditions of this match; rules with a
+// higher specificity are preferred.
+func (this OptionsMatch) Specificity() int {
+	result := len(this.Headers) + len(this.Queries)
+	if len(this.Methods) > 0 {
+		result++
+	}
+	return result
+}
+
+// ValueMatch describes the requirement of a request to provide a header or
+// query parameter with the given Name which is either equal to Value or (if
+// Pattern is set) matches Pattern; in this case Value holds the expression.
+type ValueMatch struct {
+	Name    string         `json:"name"`
+	Value   string         `json:"value,omitempty"`
+	Pattern *regexp.Regexp `json:"-"`
+}
+
+func (this ValueMatch) Matches(values []string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	if this.Pattern != nil {
+		return this.Pattern.MatchString(values[0])
+	}
+	return values[0] == this.Value
+}
+
+func (this ValueMatch) String() string {
+	if this.Pattern != nil {
+		return this.Name + "~" + this.Value
+	}
+	return this.Name + "=" + this.Value
+}
+
+func evaluateOptionMatchMethods(annotations map[string]string) (rvalue.Methods, error) {
+	if v, ok := annotations[annotationMatchMethods]; ok {
+		result, err := rvalue.ParseMethods(v)
+		if err != nil {
+			return nil, fmt.Errorf("illegal value for annotation %s: %w", annotationMatchMethods, err)
+		}
+		return result, nil
+	}
+	return nil, nil
+}
+
+func evaluateOptionValueMatches(annotations map[string]string, name string, separator string, normalizeName func(string) string) ([]ValueMatch, error) {
+	pvs, ok := annotations[name]
+	if !ok {
+		return nil, nil
+	}
+	var result []ValueMatch
+	for _, v := range strings.Split(strings.ReplaceAll(pvs, "\r", ""), "\n") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(v, separator)
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("illegal value for annotation %s: %s", name, v)
+		}
+		if normalizeName != nil {
+			key = normalizeName(key)
+		}
+		result = append(result, ValueMatch{
+			Name:  key,
+			Value: strings.TrimSpace(val),
+		})
+	}
+	return result, nil
+}
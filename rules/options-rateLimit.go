@@ -0,0 +1,65 @@
+package rules
+
+import (
+	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/value"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsRateLimit{})
+
+const (
+	optionsRateLimitKey = "rateLimit"
+
+	annotationRateLimitRequests = "lingress.echocat.org/rate-limit.requests"
+	annotationRateLimitPeriod   = "lingress.echocat.org/rate-limit.period"
+	annotationRateLimitBurst    = "lingress.echocat.org/rate-limit.burst"
+	annotationRateLimitBy       = "lingress.echocat.org/rate-limit.by"
+)
+
+func OptionsRateLimitOf(rule Rule) *OptionsRateLimit {
+	if rule == nil {
+		return &OptionsRateLimit{}
+	}
+	if v, ok := rule.Options()[optionsRateLimitKey].(*OptionsRateLimit); ok {
+		return v
+	}
+	return &OptionsRateLimit{}
+}
+
+type OptionsRateLimit struct {
+	Requests value.Uint32       `json:"requests,omitempty"`
+	Period   value.Duration     `json:"period,omitempty"`
+	Burst    value.Uint32       `json:"burst,omitempty"`
+	By       value2.RateLimitBy `json:"by,omitempty"`
+}
+
+func (this OptionsRateLimit) Name() string {
+	return optionsRateLimitKey
+}
+
+func (this OptionsRateLimit) IsRelevant() bool {
+	return this.Requests.IsPresent() ||
+		this.Period.IsPresent() ||
+		this.Burst.IsPresent() ||
+		this.By.IsPresent()
+}
+
+func (this *OptionsRateLimit) Set(annotations Annotations) (err error) {
+	if this.Requests, err = evaluateOptionUint32(annotations, annotationRateLimitRequests); err != nil {
+		return
+	}
+	if this.Period, err = evaluateOptionDuration(annotations, annotationRateLimitPeriod); err != nil {
+		return
+	}
+	if this.Period.IsPresent() && this.Period.Get() <= 0 {
+		return fmt.Errorf("illegal value for annotation %s: has to be greater than 0", annotationRateLimitPeriod)
+	}
+	if this.Burst, err = evaluateOptionUint32(annotations, annotationRateLimitBurst); err != nil {
+		return
+	}
+	if this.By, err = value2.ParseRateLimitBy(annotations[annotationRateLimitBy]); err != nil {
+		return fmt.Errorf("illegal value for annotation %s: %w", annotationRateLimitBy, err)
+	}
+	return
+}
@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/value"
+	"net/http"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsRedirect{})
+
+const (
+	optionsRedirectKey = "redirect"
+
+	annotationRedirectScheme     = "lingress.echocat.org/redirect-scheme"
+	annotationRedirectHost       = "lingress.echocat.org/redirect-host"
+	annotationRedirectPort       = "lingress.echocat.org/redirect-port"
+	annotationRedirectPath       = "lingress.echocat.org/redirect-path"
+	annotationRedirectPathPrefix = "lingress.echocat.org/redirect-path-prefix"
+	annotationRedirectStatusCode = "lingress.echocat.org/redirect-status-code"
+
+	defaultRedirectStatusCode = uint32(http.StatusFound)
+)
+
+func OptionsRedirectOf(rule Rule) *OptionsRedirect {
+	if rule == nil {
+		return &OptionsRedirect{}
+	}
+	if v, ok := rule.Options()[optionsRedirectKey].(*OptionsRedirect); ok {
+		return v
+	}
+	return &OptionsRedirect{}
+}
+
+// OptionsRedirect answers all requests of a rule with a redirect instead of
+// forwarding them to the upstream. Every part of the target URL which is not
+// configured is taken from the original request.
+type OptionsRedirect struct {
+	Scheme     string       `json:"scheme,omitempty"`
+	Host       string       `json:"host,omitempty"`
+	Port       value.Uint32 `json:"port,omitempty"`
+	Path       []string     `json:"path,omitempty"`
+	PathPrefix []string     `json:"pathPrefix,omitempty"`
+	StatusCode value.Uint32 `json:"statusCode,omitempty"`
+}
+
+func (this OptionsRedirect) Name() string {
+	return optionsRedirectKey
+}
+
+func (this OptionsRedirect) IsRelevant() bool {
+	return this.Scheme != "" ||
+		this.Host != "" ||
+		this.Port.IsPresent() ||
+		this.Path != nil ||
+		this.PathPrefix != nil ||
+		this.StatusCode.IsPresent()
+}
+
+func (this OptionsRedirect) GetStatusCode() int {
+	return int(this.StatusCode.GetOr(defaultRedirectStatusCode))
+}
+
+func (this *OptionsRedirect) Set(annotations Annotations) (err error) {
+	this.Scheme = annotations[annotationRedirectScheme]
+	if this.Scheme != "" && this.Scheme != "http" && this.Scheme != "https" {
+		return fmt.Errorf("illegal value for annotation %s: %s", annotationRedirectScheme, this.Scheme)
+	}
+	this.Host = annotations[annotationRedirectHost]
+	if this.Port, err = evaluateOptionUint32(annotations, annotationRedirectPort); err != nil {
+		return
+	}
+	if this.Port.Get() > 65535 {
+		return fmt.Errorf("illegal value for annotation %s: %v", annotationRedirectPort, this.Port)
+	}
+	if this.Path, err = evaluateOptionOptionalPath(annotations, annotationRedirectPath); err != nil {
+		return
+	}
+	if this.PathPrefix, err = evaluateOptionOptionalPath(annotations, annotationRedirectPathPrefix); err != nil {
+		return
+	}
+	if this.Path != nil && this.PathPrefix != nil {
+		return fmt.Errorf("annotations %s and %s cannot be combined", annotationRedirectPath, annotationRedirectPathPrefix)
+	}
+	if this.StatusCode, err = evaluateOptionUint32(annotations, annotationRedirectStatusCode); err != nil {
+		return
+	}
+	if v := this.StatusCode; v.IsPresent() && (v.Get() < 300 || v.Get() > 399) {
+		return fmt.Errorf("illegal value for annotation %s: %v is not a redirect status code", annotationRedirectStatusCode, v)
+	}
+	return
+}
+
+func evaluateOptionOptionalPath(annotations map[string]string, name string) ([]string, error) {
+	if v, ok := annotations[name]; ok {
+		result, err := ParsePath(v, false)
+		if err != nil {
+			return nil, fmt.Errorf("illegal value for annotation %s: %w", name, err)
+		}
+		if result == nil {
+			result = []string{}
+		}
+		return result, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,79 @@
+package rules
+
+import (
+	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/value"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsRetry{})
+
+const (
+	optionsRetryKey = "retry"
+
+	annotationRetryAttempts      = "lingress.echocat.org/retry.attempts"
+	annotationRetryOn            = "lingress.echocat.org/retry.on"
+	annotationRetryBackoff       = "lingress.echocat.org/retry.backoff"
+	annotationRetryPerTryTimeout = "lingress.echocat.org/retry.per-try-timeout"
+)
+
+func OptionsRetryOf(rule Rule) *OptionsRetry {
+	if rule == nil {
+		return &OptionsRetry{}
+	}
+	if v, ok := rule.Options()[optionsRetryKey].(*OptionsRetry); ok {
+		return v
+	}
+	return &OptionsRetry{}
+}
+
+type OptionsRetry struct {
+	Attempts      value.Uint32   `json:"attempts,omitempty"`
+	On            value2.RetryOn `json:"on,omitempty"`
+	Backoff       value.Duration `json:"backoff,omitempty"`
+	PerTryTimeout value.Duration `json:"perTryTimeout,omitempty"`
+}
+
+func (this OptionsRetry) Name() string {
+	return optionsRetryKey
+}
+
+func (this OptionsRetry) IsRelevant() bool {
+	return this.Attempts.IsPresent() ||
+		this.On.IsPresent() ||
+		this.Backoff.IsPresent() ||
+		this.PerTryTimeout.IsPresent()
+}
+
+func (this *OptionsRetry) Set(annotations Annotations) (err error) {
+	if this.Attempts, err = evaluateOptionUint32(annotations, annotationRetryAttempts); err != nil {
+		return
+	}
+	if v := this.Attempts.Get(); this.Attempts.IsPresent() && (v == 0 || v > value2.MaxRetryAttempts) {
+		return fmt.Errorf("illegal value for annotation %s: has to be between 1 and %d", annotationRetryAttempts, value2.MaxRetryAttempts)
+	}
+	if this.On, err = evaluateOptionRetryOn(annotations); err != nil {
+		return
+	}
+	if this.Backoff, err = evaluateOptionDuration(annotations, annotationRetryBackoff); err != nil {
+		return
+	}
+	if v := this.Backoff.Get(); this.Backoff.IsPresent() && (v < 0 || v > value2.MaxRetryBackoff) {
+		return fmt.Errorf("illegal value for annotation %s: has to be between 0s and %v", annotationRetryBackoff, value2.MaxRetryBackoff)
+	}
+	if this.PerTryTimeout, err = evaluateOptionDuration(annotations, annotationRetryPerTryTimeout); err != nil {
+		return
+	}
+	return
+}
+
+func evaluateOptionRetryOn(annotations map[string]string) (value2.RetryOn, error) {
+	if v, ok := annotations[annotationRetryOn]; ok {
+		result, err := value2.ParseRetryOn(v)
+		if err != nil {
+			return nil, fmt.Errorf("illegal value for annotation %s: %w", annotationRetryOn, err)
+		}
+		return result, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+package rules
+
+var _ = RegisterDefaultOptionsPart(&OptionsRewrite{})
+
+const (
+	optionsRewriteKey = "rewrite"
+
+	annotationRewriteHost = "lingress.echocat.org/rewrite-host"
+	annotationRewritePath = "lingress.echocat.org/rewrite-path"
+)
+
+func OptionsRewriteOf(rule Rule) *OptionsRewrite {
+	if rule == nil {
+		return &OptionsRewrite{}
+	}
+	if v, ok := rule.Options()[optionsRewriteKey].(*OptionsRewrite); ok {
+		return v
+	}
+	return &OptionsRewrite{}
+}
+
+// OptionsRewrite replaces the host and/or the whole path of requests before
+// they are sent to the upstream. For replacing only the prefix of the path see
+// OptionsPrefix.
+type OptionsRewrite struct {
+	Host string   `json:"host,omitempty"`
+	Path []string `json:"path,omitempty"`
+}
+
+func (this OptionsRewrite) Name() string {
+	return optionsRewriteKey
+}
+
+func (this OptionsRewrite) IsRelevant() bool {
+	return this.Host != "" ||
+		this.Path != nil
+}
+
+func (this *OptionsRewrite) Set(annotations Annotations) (err error) {
+	this.Host = annotations[annotationRewriteHost]
+	if this.Path, err = evaluateOptionOptionalPath(annotations, annotationRewritePath); err != nil {
+		return
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package rules
+
+import (
+	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/value"
+)
+
+var _ = RegisterDefaultOptionsPart(&OptionsUpstream{})
+
+const (
+	optionsUpstreamKey = "upstream"
+
+	annotationUpstreamRequestTimeout        = "lingress.echocat.org/upstream.request-timeout"
+	annotationUpstreamResponseHeaderTimeout = "lingress.echocat.org/upstream.response-header-timeout"
+	annotationUpstreamBackendProtocol       = "lingress.echocat.org/backend-protocol"
+)
+
+func OptionsUpstreamOf(rule Rule) *OptionsUpstream {
+	if rule == nil {
+		return &OptionsUpstream{}
+	}
+	if v, ok := rule.Options()[optionsUpstreamKey].(*OptionsUpstream); ok {
+		return v
+	}
+	return &OptionsUpstream{}
+}
+
+type OptionsUpstream struct {
+	RequestTimeout        value.Duration         `json:"requestTimeout,omitempty"`
+	ResponseHeaderTimeout value.Duration         `json:"responseHeaderTimeout,omitempty"`
+	Protocol              value2.BackendProtocol `json:"protocol,omitempty"`
+}
+
+func (this OptionsUpstream) Name() string {
+	return optionsUpstreamKey
+}
+
+func (this OptionsUpstream) IsRelevant() bool {
+	return this.RequestTimeout.IsPresent() ||
+		this.ResponseHeaderTimeout.IsPresent() ||
+		this.Protocol.IsPresent()
+}
+
+func (this *OptionsUpstream) Set(annotations Annotations) (err error) {
+	if this.RequestTimeout, err = evaluateOptionDuration(annotations, annotationUpstreamRequestTimeout); err != nil {
+		return
+	}
+	if this.ResponseHeaderTimeout, err = evaluateOptionDuration(annotations, annotationUpstreamResponseHeaderTimeout); err != nil {
+		return
+	}
+	if this.Protocol, err = value2.ParseBackendProtocol(annotations[annotationUpstreamBackendProtocol]); err != nil {
+		return fmt.Errorf("illegal value for annotation %s: %w", annotationUpstreamBackendProtocol, err)
+	}
+	return
+}
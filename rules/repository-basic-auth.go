@@ -0,0 +1,105 @@
+package rules
+
+import (
+	"github.com/echocat/lingress/support"
+)
+
+type basicAuthCredentials struct {
+	secret      support.ObjectReference
+	credentials *Htpasswd
+}
+
+// applyBasicAuthCredentials provides the shared credentials of the secret
+// referenced by the options of the given ingress.
+func (this *repositoryImplState) applyBasicAuthCredentials(ref support.ObjectReference, options Options) {
+	opts, ok := options[optionsBasicAuthKey].(*OptionsBasicAuth)
+	if !ok || !opts.Secret.IsPresent() {
+		return
+	}
+
+	secret := ref.WithApiVersionAndKind("v1", "Secret").WithName(opts.Secret.Get())
+
+	this.basicAuthMutex.Lock()
+	defer this.basicAuthMutex.Unlock()
+
+	if existing, ok := this.basicAuth[secret.String()]; ok {
+		opts.Credentials = existing.credentials
+		return
+	}
+
+	entry := basicAuthCredentials{
+		secret:      secret,
+		credentials: NewHtpasswd(),
+	}
+	this.refreshBasicAuthCredentials(entry)
+	this.basicAuth[secret.String()] = entry
+	opts.Credentials = entry.credentials
+}
+
+func (this *repositoryImplState) refreshBasicAuthCredentials(target basicAuthCredentials) {
+	l := this.Logger.
+		With("secret", target.secret)
+
+	secret, err := this.secretOf(target.secret)
+	if err != nil {
+		l.WithError(err).
+			Warn("Cannot read secret of basic authentication; all requests will be rejected...")
+		target.credentials.Set(nil)
+		return
+	}
+	if secret == nil {
+		l.Warn("Secret referenced by basic authentication not found; all requests will be rejected...")
+		target.credentials.Set(nil)
+		return
+	}
+
+	if ignored := target.credentials.Set(secret.Data[basicAuthSecretKey]); len(ignored) > 0 {
+		l.With("users", ignored).
+			Warn("Secret of basic authentication contains users with unsupported hashes; ignoring those users...")
+	}
+	l.With("users", target.credentials.Len()).
+		Debug("Basic authentication credentials refreshed.")
+}
+
+func (this *repositoryImplState) onBasicAuthSecretChanged(ref support.ObjectReference) error {
+	this.basicAuthMutex.Lock()
+	defer this.basicAuthMutex.Unlock()
+
+	if candidate, ok := this.basicAuth[ref.String()]; ok {
+		this.refreshBasicAuthCredentials(candidate)
+	}
+
+	return nil
+}
+
+// pruneBasicAuthCredentials forgets all credentials which are not used by any
+// served rule anymore (because the Ingress was removed or references another
+// secret now). It has to be called while holding byHostRulesMutex.
+func (this *repositoryImplState) pruneBasicAuthCredentials() error {
+	this.basicAuthMutex.Lock()
+	defer this.basicAuthMutex.Unlock()
+
+	if len(this.basicAuth) == 0 {
+		return nil
+	}
+
+	inUse := map[*Htpasswd]struct{}{}
+	if err := this.ByHostRules().All(func(r Rule) error {
+		if v := OptionsBasicAuthOf(r).Credentials; v != nil {
+			inUse[v] = struct{}{}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for key, candidate := range this.basicAuth {
+		if _, ok := inUse[candidate.credentials]; !ok {
+			delete(this.basicAuth, key)
+			this.Logger.
+				With("secret", candidate.secret).
+				Debug("Basic authentication credentials pruned.")
+		}
+	}
+	return nil
+}
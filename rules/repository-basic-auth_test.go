@@ -0,0 +1,120 @@
+package rules
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func Test_basicAuthCredentials_hotReload(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	htpasswdOf := func(username, password string) []byte {
+		sum := sha1.Sum([]byte(password))
+		return []byte(username + ":{SHA}" + base64.StdEncoding.EncodeToString(sum[:]) + "\n")
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "auth"},
+		Data:       map[string][]byte{basicAuthSecretKey: htpasswdOf("user", "password")},
+	}
+	client := fake.NewClientset(secret)
+	secrets := client.CoreV1().Secrets("foo")
+
+	s := settings.MustNew()
+	definitions := &definition.Definitions{}
+	var err error
+	definitions.ServiceSecrets, err = definition.NewServiceSecrets(&s, client, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.Secret, err = definition.NewSecret(&s, client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	repository := &KubernetesBasedRepository{
+		settings:       &s,
+		Logger:         log.GetRootLogger(),
+		OptionsFactory: DefaultOptionsFactory,
+	}
+	repository.byHostRules.Store(NewByHost(repository.onRuleAdded, repository.onRuleRemoved))
+	state := &repositoryImplState{
+		KubernetesBasedRepository: repository,
+		definitions:               definitions,
+		ingressTls:                map[string]ingressTls{},
+		basicAuth:                 map[string]basicAuthCredentials{},
+
+		clientCertificateAuthorities: map[string]clientCertificateAuthorities{},
+	}
+	definitions.Secret.OnElementAdded = state.onSecretElementAdded
+	definitions.Secret.OnElementUpdated = state.onSecretElementUpdated
+	definitions.Secret.OnElementRemoved = state.onSecretElementRemoved
+	g.Expect(definitions.ServiceSecrets.Init(stop)).To(Succeed())
+	g.Expect(definitions.Secret.Init(stop)).To(Succeed())
+
+	ingress := support.NewObjectReference("networking.k8s.io/v1", "Ingress", "foo", "app")
+	options := DefaultOptionsFactory()
+	opts := options[optionsBasicAuthKey].(*OptionsBasicAuth)
+	g.Expect(opts.Secret.Set("auth")).To(Succeed())
+	state.applyBasicAuthCredentials(ingress, options)
+	g.Expect(opts.Credentials.Verify("user", "password")).To(BeTrue())
+
+	rule := NewRule("app.example.org", nil, PathTypePrefix, ingress, nil, nil, options)
+	g.Expect(repository.ByHostRules().Put(rule)).To(Succeed())
+
+	// Changed credentials are applied to the rules which are already served.
+	secret.Data[basicAuthSecretKey] = htpasswdOf("user", "changed")
+	_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+	g.Expect(err).To(BeNil())
+	g.Eventually(func() bool { return opts.Credentials.Verify("user", "changed") }).Should(BeTrue())
+	g.Expect(opts.Credentials.Verify("user", "password")).To(BeFalse())
+
+	// Credentials of a removed secret are rejected but kept while in use...
+	g.Expect(secrets.Delete(context.Background(), "auth", metav1.DeleteOptions{})).To(Succeed())
+	g.Eventually(func() bool { return opts.Credentials.Verify("user", "changed") }).Should(BeFalse())
+	g.Expect(state.basicAuthCredentialsSecrets()).To(ConsistOf("foo/auth"))
+
+	// ... so they are applied again if the secret is recreated...
+	secret.ResourceVersion = ""
+	_, err = secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+	g.Expect(err).To(BeNil())
+	g.Eventually(func() bool { return opts.Credentials.Verify("user", "changed") }).Should(BeTrue())
+
+	// ... but pruned as soon as no rule uses them anymore, because the rule
+	// references another secret...
+	state.initiated.Store(true)
+	otherOptions := DefaultOptionsFactory()
+	g.Expect(otherOptions[optionsBasicAuthKey].(*OptionsBasicAuth).Secret.Set("other")).To(Succeed())
+	g.Expect(state.updateByHostRules(func(target *ByHost) error {
+		if err := target.Remove(PredicateByObjectReference(ingress)); err != nil {
+			return err
+		}
+		state.applyBasicAuthCredentials(ingress, otherOptions)
+		return target.Put(NewRule("app.example.org", nil, PathTypePrefix, ingress, nil, nil, otherOptions))
+	})).To(Succeed())
+	g.Expect(state.basicAuthCredentialsSecrets()).To(ConsistOf("foo/other"))
+
+	// ... or was removed.
+	g.Expect(state.updateByHostRules(func(target *ByHost) error {
+		return target.Remove(PredicateByObjectReference(ingress))
+	})).To(Succeed())
+	g.Expect(state.basicAuthCredentialsSecrets()).To(BeEmpty())
+}
+
+func (this *repositoryImplState) basicAuthCredentialsSecrets() []string {
+	this.basicAuthMutex.Lock()
+	defer this.basicAuthMutex.Unlock()
+	var result []string
+	for _, candidate := range this.basicAuth {
+		result = append(result, candidate.secret.ShortString())
+	}
+	return result
+}
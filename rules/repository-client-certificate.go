@@ -0,0 +1,122 @@
+package rules
+
+import (
+	"github.com/echocat/lingress/support"
+)
+
+type clientCertificateAuthorities struct {
+	secret      support.ObjectReference
+	authorities *ClientCertificateAuthorities
+}
+
+// applyClientCertificateAuthorities provides the shared authorities of the
+// secret referenced by the options of the given ingress.
+func (this *repositoryImplState) applyClientCertificateAuthorities(ref support.ObjectReference, options Options) {
+	opts, ok := options[optionsClientCertificateKey].(*OptionsClientCertificate)
+	if !ok || !opts.CaSecret.IsPresent() {
+		return
+	}
+
+	secret := ref.WithApiVersionAndKind("v1", "Secret").WithName(opts.CaSecret.Get())
+
+	this.clientCertificateAuthoritiesMutex.Lock()
+	defer this.clientCertificateAuthoritiesMutex.Unlock()
+
+	if existing, ok := this.clientCertificateAuthorities[secret.String()]; ok {
+		opts.Authorities = existing.authorities
+		return
+	}
+
+	entry := clientCertificateAuthorities{
+		secret:      secret,
+		authorities: NewClientCertificateAuthorities(),
+	}
+	this.refreshClientCertificateAuthorities(entry)
+	this.clientCertificateAuthorities[secret.String()] = entry
+	opts.Authorities = entry.authorities
+}
+
+func (this *repositoryImplState) refreshClientCertificateAuthorities(target clientCertificateAuthorities) {
+	l := this.Logger.
+		With("secret", target.secret)
+
+	changed := true
+	defer func() {
+		if changed {
+			this.clientCertificatePools.Evict(target.authorities)
+		}
+	}()
+
+	secret, err := this.secretOf(target.secret)
+	if err != nil {
+		l.WithError(err).
+			Warn("Cannot read secret of client certificate authorities; all client certificates will be rejected...")
+		_ = target.authorities.Set(nil)
+		return
+	}
+	if secret == nil {
+		l.Warn("Secret referenced by client certificate authorities not found; all client certificates will be rejected...")
+		_ = target.authorities.Set(nil)
+		return
+	}
+
+	if changed, err = target.authorities.SetVersioned(secret.ResourceVersion, secret.Data[clientCertificateCaSecretKey]); err != nil {
+		l.WithError(err).
+			Warn("Secret of client certificate authorities contains illegal certificates; all client certificates will be rejected...")
+		_ = target.authorities.Set(nil)
+		changed = true
+		return
+	} else if !changed {
+		return
+	}
+	if target.authorities.Len() == 0 {
+		l.Warn("Secret of client certificate authorities does not contain any certificate in key " + clientCertificateCaSecretKey + "; all client certificates will be rejected...")
+	}
+	l.With("certificates", target.authorities.Len()).
+		Debug("Client certificate authorities refreshed.")
+}
+
+func (this *repositoryImplState) onClientCertificateSecretChanged(ref support.ObjectReference) error {
+	this.clientCertificateAuthoritiesMutex.Lock()
+	defer this.clientCertificateAuthoritiesMutex.Unlock()
+
+	if candidate, ok := this.clientCertificateAuthorities[ref.String()]; ok {
+		this.refreshClientCertificateAuthorities(candidate)
+	}
+
+	return nil
+}
+
+// pruneClientCertificateAuthorities forgets all authorities (and the pools
+// containing them) which are not used by any served rule anymore (because
+// the Ingress was removed or references another secret now). It has to be
+// called while holding byHostRulesMutex.
+func (this *repositoryImplState) pruneClientCertificateAuthorities() error {
+	this.clientCertificateAuthoritiesMutex.Lock()
+	defer this.clientCertificateAuthoritiesMutex.Unlock()
+
+	if len(this.clientCertificateAuthorities) == 0 {
+		return nil
+	}
+
+	inUse := map[*ClientCertificateAuthorities]struct{}{}
+	if err := this.ByHostRules().All(func(r Rule) error {
+		if v := OptionsClientCertificateOf(r).Authorities; v != nil {
+			inUse[v] = struct{}{}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for key, candidate := range this.clientCertificateAuthorities {
+		if _, ok := inUse[candidate.authorities]; !ok {
+			delete(this.clientCertificateAuthorities, key)
+			this.clientCertificatePools.Evict(candidate.authorities)
+			this.Logger.
+				With("secret", candidate.secret).
+				Debug("Client certificate authorities pruned.")
+		}
+	}
+	return nil
+}
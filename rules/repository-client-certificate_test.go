@@ -0,0 +1,118 @@
+package rules
+
+import (
+	"context"
+	"encoding/pem"
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func Test_clientCertificateAuthorities_hotReload(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	ca, _ := newTestCertificate(t, "ca", nil, nil)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "ca", ResourceVersion: "1"},
+		Data:       map[string][]byte{clientCertificateCaSecretKey: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})},
+	}
+	client := fake.NewClientset(secret)
+	secrets := client.CoreV1().Secrets("foo")
+
+	s := settings.MustNew()
+	definitions := &definition.Definitions{}
+	var err error
+	definitions.ServiceSecrets, err = definition.NewServiceSecrets(&s, client, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.Secret, err = definition.NewSecret(&s, client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	repository := &KubernetesBasedRepository{
+		settings:       &s,
+		Logger:         log.GetRootLogger(),
+		OptionsFactory: DefaultOptionsFactory,
+	}
+	repository.byHostRules.Store(NewByHost(repository.onRuleAdded, repository.onRuleRemoved))
+	state := &repositoryImplState{
+		KubernetesBasedRepository: repository,
+		definitions:               definitions,
+		ingressTls:                map[string]ingressTls{},
+		basicAuth:                 map[string]basicAuthCredentials{},
+
+		clientCertificateAuthorities: map[string]clientCertificateAuthorities{},
+	}
+	definitions.Secret.OnElementAdded = state.onSecretElementAdded
+	definitions.Secret.OnElementUpdated = state.onSecretElementUpdated
+	definitions.Secret.OnElementRemoved = state.onSecretElementRemoved
+	g.Expect(definitions.ServiceSecrets.Init(stop)).To(Succeed())
+	g.Expect(definitions.Secret.Init(stop)).To(Succeed())
+
+	ingress := support.NewObjectReference("networking.k8s.io/v1", "Ingress", "foo", "app")
+	options := DefaultOptionsFactory()
+	g.Expect(options.Set(Annotations{
+		annotationClientCertificateCaSecret: "ca",
+		annotationClientCertificateVerify:   "required",
+	})).To(Succeed())
+	state.applyClientCertificateAuthorities(ingress, options)
+	opts := OptionsClientCertificateOf(NewRule("", nil, PathTypePrefix, nil, nil, nil, options))
+	g.Expect(opts.Authorities.Len()).To(Equal(1))
+	g.Expect(repository.ByHostRules().Put(NewRule("app.example.org", nil, PathTypePrefix, ingress, nil, nil, options))).To(Succeed())
+
+	// The pool is kept as long as the secret is not changed...
+	pool := opts.Authorities.Pool()
+	g.Expect(state.onClientCertificateSecretChanged(support.NewObjectReference("v1", "Secret", "foo", "ca"))).To(Succeed())
+	g.Expect(opts.Authorities.Pool()).To(BeIdenticalTo(pool))
+
+	// ... and replaced if it changes.
+	secret.ResourceVersion = "2"
+	_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+	g.Expect(err).To(BeNil())
+	g.Eventually(opts.Authorities.Pool).ShouldNot(BeIdenticalTo(pool))
+
+	// Authorities of a removed secret are kept (empty) while in use...
+	g.Expect(secrets.Delete(context.Background(), "ca", metav1.DeleteOptions{})).To(Succeed())
+	g.Eventually(opts.Authorities.Len).Should(Equal(0))
+	g.Expect(state.clientCertificateAuthoritiesSecrets()).To(ConsistOf("foo/ca"))
+
+	// ... but pruned as soon as no rule uses them anymore, because the rule
+	// references another secret...
+	state.initiated.Store(true)
+	otherOptions := DefaultOptionsFactory()
+	g.Expect(otherOptions.Set(Annotations{
+		annotationClientCertificateCaSecret: "other",
+		annotationClientCertificateVerify:   "required",
+	})).To(Succeed())
+	g.Expect(state.updateByHostRules(func(target *ByHost) error {
+		if err := target.Remove(PredicateByObjectReference(ingress)); err != nil {
+			return err
+		}
+		state.applyClientCertificateAuthorities(ingress, otherOptions)
+		return target.Put(NewRule("app.example.org", nil, PathTypePrefix, ingress, nil, nil, otherOptions))
+	})).To(Succeed())
+	g.Expect(state.clientCertificateAuthoritiesSecrets()).To(ConsistOf("foo/other"))
+
+	// ... or was removed.
+	g.Expect(state.updateByHostRules(func(target *ByHost) error {
+		return target.Remove(PredicateByObjectReference(ingress))
+	})).To(Succeed())
+	g.Expect(state.clientCertificateAuthoritiesSecrets()).To(BeEmpty())
+}
+
+func (this *repositoryImplState) clientCertificateAuthoritiesSecrets() []string {
+	this.clientCertificateAuthoritiesMutex.Lock()
+	defer this.clientCertificateAuthoritiesMutex.Unlock()
+	var result []string
+	for _, candidate := range this.clientCertificateAuthorities {
+		result = append(result, candidate.secret.ShortString())
+	}
+	return result
+}
@@ -0,0 +1,304 @@
+package rules
+
+import (
+	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	networkingv1 "k8s.io/api/networking/v1"
+	"strings"
+	"time"
+)
+
+// CompositeRepository combines several child repositories. Their order is the
+// precedence of them: Which rules are used if more than one child serves the
+// same request is decided by the ConflictStrategy. Each rule still reports by
+// Rule.Source() which child (and object of it) it came from.
+type CompositeRepository struct {
+	Children         []CombinedRepository
+	ConflictStrategy value2.ConflictStrategy
+}
+
+func NewCompositeRepository(strategy value2.ConflictStrategy, children ...CombinedRepository) (*CompositeRepository, error) {
+	if len(children) == 0 {
+		return nil, fmt.Errorf("no child repositories provided")
+	}
+	return &CompositeRepository{
+		Children:         children,
+		ConflictStrategy: strategy,
+	}, nil
+}
+
+// ConflictError is returned by CompositeRepository.FindBy if more than one
+// child serves the same request and value2.ConflictStrategyError is used.
+type ConflictError struct {
+	Query   Query
+	Sources []support.ObjectReference
+}
+
+func (this *ConflictError) Error() string {
+	sources := make([]string, len(this.Sources))
+	for i, source := range this.Sources {
+		sources[i] = source.String()
+	}
+	return fmt.Sprintf("%s%s is served by several sources: %s", this.Query.Host, this.Query.Path, strings.Join(sources, ", "))
+}
+
+func (this *CompositeRepository) Init(stop support.Channel) error {
+	for i, child := range this.Children {
+		if err := child.Init(stop); err != nil {
+			return fmt.Errorf("cannot initialize rules source #%d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (this *CompositeRepository) All(consumer func(Rule) error) error {
+	for _, child := range this.Children {
+		if err := child.All(consumer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *CompositeRepository) FindBy(q Query) (Rules, error) {
+	path, err := ParsePath(q.Path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Rules
+	var resultAny Rule
+	var conflicting []support.ObjectReference
+	strategy := this.ConflictStrategy.Get()
+	for _, child := range this.Children {
+		candidate, err := child.FindBy(q)
+		if err != nil {
+			return nil, err
+		}
+		if candidate == nil || candidate.Len() == 0 {
+			continue
+		}
+		candidateAny := candidate.AnyFilteredBy(path)
+		if candidateAny == nil {
+			continue
+		}
+
+		switch strategy {
+		case value2.ConflictStrategyFirstWins:
+			return candidate, nil
+		case value2.ConflictStrategyError:
+			conflicting = append(conflicting, candidateAny.Source())
+		}
+		if resultAny == nil || isMoreSpecificRule(candidateAny, resultAny) {
+			result, resultAny = candidate, candidateAny
+		}
+	}
+
+	if len(conflicting) > 1 {
+		return nil, &ConflictError{Query: q, Sources: conflicting}
+	}
+	return result, nil
+}
+
+// isMoreSpecificRule reports whether candidate matches more specific than
+// current: The longer path wins, then an exact host before a wildcard host
+// before all hosts and then the path type Exact before Prefix.
+func isMoreSpecificRule(candidate, current Rule) bool {
+	if cl, rl := len(candidate.Path()), len(current.Path()); cl != rl {
+		return cl > rl
+	}
+	if ch, rh := hostSpecificityOf(candidate.Host()), hostSpecificityOf(current.Host()); ch != rh {
+		return ch > rh
+	}
+	return candidate.PathType() == PathTypeExact && current.PathType() != PathTypeExact
+}
+
+func hostSpecificityOf(host value.WildcardSupportingFqdn) int {
+	if host == "" {
+		return 0
+	}
+	if strings.HasPrefix(string(host), "*.") {
+		return 1
+	}
+	return 2
+}
+
+func (this *CompositeRepository) HasHost(host value.Fqdn) (bool, error) {
+	for _, child := range this.Children {
+		if ok, err := child.HasHost(host); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// CertificatesConflictError is returned by
+// CompositeRepository.FindCertificatesBy if more than one child has
+// certificates for the same host and value2.ConflictStrategyError is used.
+type CertificatesConflictError struct {
+	Query CertificateQuery
+	// Children are the indexes of the children inside of
+	// CompositeRepository.Children having certificates for the host.
+	Children []int
+}
+
+func (this *CertificatesConflictError) Error() string {
+	children := make([]string, len(this.Children))
+	for i, child := range this.Children {
+		children[i] = fmt.Sprintf("#%d", child)
+	}
+	return fmt.Sprintf("certificates for %s are provided by several rules sources: %s", this.Query.Host, strings.Join(children, ", "))
+}
+
+// FindCertificatesBy returns the certificates of the child which is selected
+// by the ConflictStrategy: The first child having any for the requested host,
+// the child having the most specific ones (exact host before wildcard host;
+// on a tie the first one) or an error if several children are having any.
+func (this *CompositeRepository) FindCertificatesBy(q CertificateQuery) (Certificates, error) {
+	var result Certificates
+	resultSpecificity := -1
+	var conflicting []int
+	strategy := this.ConflictStrategy.Get()
+	for i, child := range this.Children {
+		candidate, err := child.FindCertificatesBy(q)
+		if err != nil {
+			return nil, err
+		}
+		if len(candidate) == 0 {
+			continue
+		}
+
+		switch strategy {
+		case value2.ConflictStrategyFirstWins:
+			return candidate, nil
+		case value2.ConflictStrategyError:
+			conflicting = append(conflicting, i)
+		}
+		if specificity := certificatesSpecificityOf(candidate, q.Host); specificity > resultSpecificity {
+			result, resultSpecificity = candidate, specificity
+		}
+	}
+
+	if len(conflicting) > 1 {
+		return nil, &CertificatesConflictError{Query: q, Children: conflicting}
+	}
+	return result, nil
+}
+
+// certificatesSpecificityOf returns 2 if any of the given certificates is
+// issued for exactly the given host, otherwise 1 (they are matching it by
+// wildcard).
+func certificatesSpecificityOf(certificates Certificates, host value.WildcardSupportingFqdn) int {
+	for _, certificate := range certificates {
+		if leaf := certificate.Leaf; leaf != nil {
+			if strings.EqualFold(leaf.Subject.CommonName, string(host)) {
+				return 2
+			}
+			for _, dns := range leaf.DNSNames {
+				if strings.EqualFold(dns, string(host)) {
+					return 2
+				}
+			}
+		}
+	}
+	return 1
+}
+
+func (this *CompositeRepository) AllCertificates() ([]LoadedCertificate, error) {
+	var result []LoadedCertificate
+	for _, child := range this.Children {
+		candidates, err := child.AllCertificates()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, candidates...)
+	}
+	return MergeLoadedCertificates(result), nil
+}
+
+// FindClientCertificateRequirementsBy returns the requirements of the rules
+// of all children which have the requested host: Which child serves a request
+// is decided by its path and the ConflictStrategy, which are both unknown
+// while the TLS handshake. So client certificates are verified against the
+// authorities of all of them; they are only required if all rules require
+// them (see ClientCertificateRequirementsOf).
+func (this *CompositeRepository) FindClientCertificateRequirementsBy(q CertificateQuery) (ClientCertificateRequirements, error) {
+	var host value.Fqdn
+	if err := host.Set(q.Host.String()); err != nil {
+		return ClientCertificateRequirements{}, nil
+	}
+
+	var matching []int
+	for i, child := range this.Children {
+		if ok, err := child.HasHost(host); err != nil {
+			return ClientCertificateRequirements{}, err
+		} else if ok {
+			matching = append(matching, i)
+		}
+	}
+	if len(matching) == 0 {
+		return ClientCertificateRequirements{}, nil
+	}
+	if len(matching) == 1 {
+		return this.Children[matching[0]].FindClientCertificateRequirementsBy(q)
+	}
+
+	var candidates []Rule
+	for _, i := range matching {
+		child, ok := this.Children[i].(hostRulesRepository)
+		if !ok {
+			return ClientCertificateRequirements{}, fmt.Errorf("rules source #%d cannot provide the rules of host %v", i, host)
+		}
+		rules, err := child.rulesOfHost(host)
+		if err != nil {
+			return ClientCertificateRequirements{}, err
+		}
+		candidates = append(candidates, rules...)
+	}
+	// The combined pool is not cached; only the children know when their
+	// authorities are changing.
+	return ClientCertificateRequirementsOf(candidates, nil), nil
+}
+
+// HasSynced reports whether all children which are providing their
+// synchronization state have synced.
+func (this *CompositeRepository) HasSynced() bool {
+	for _, child := range this.Children {
+		if candidate, ok := child.(SyncStateRepository); ok && !candidate.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// LastError returns the most recent error of all children which are providing
+// their synchronization state.
+func (this *CompositeRepository) LastError() (time.Time, error) {
+	var candidates []func() (time.Time, error)
+	for _, child := range this.Children {
+		if candidate, ok := child.(SyncStateRepository); ok {
+			candidates = append(candidates, candidate.LastError)
+		}
+	}
+	return support.LatestError(candidates...)
+}
+
+// ValidateIngress delegates to all children which are able to validate
+// Ingresses.
+func (this *CompositeRepository) ValidateIngress(ingress *networkingv1.Ingress) ([]string, error) {
+	var warnings []string
+	for _, child := range this.Children {
+		validator, ok := child.(IngressValidator)
+		if !ok {
+			continue
+		}
+		candidate, err := validator.ValidateIngress(ingress)
+		warnings = append(warnings, candidate...)
+		if err != nil {
+			return warnings, err
+		}
+	}
+	return warnings, nil
+}
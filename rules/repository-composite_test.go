@@ -0,0 +1,136 @@
+package rules
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func Test_CompositeRepository_FindBy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	childOf := func(name string, host value.WildcardSupportingFqdn, path ...string) (CombinedRepository, support.ObjectReference) {
+		result := &KubernetesBasedRepository{Logger: log.GetRootLogger()}
+		result.byHostRules.Store(NewByHost(result.onRuleAdded, result.onRuleRemoved))
+		source := support.NewObjectReference("", fileSourceKind, "", name)
+		g.Expect(result.ByHostRules().Put(NewRule(host, path, PathTypePrefix, source, nil, nil, DefaultOptionsFactory()))).To(Succeed())
+		return result, source
+	}
+	override, overrideSource := childOf("override", "*.example.org")
+	cluster, clusterSource := childOf("cluster", "app.example.org", "api")
+
+	sourceOf := func(instance *CompositeRepository, q Query) support.ObjectReference {
+		actual, err := instance.FindBy(q)
+		g.Expect(err).To(BeNil())
+		g.Expect(actual.Len()).To(Equal(1))
+		return actual.Get(0).Source()
+	}
+
+	instance, err := NewCompositeRepository(value2.ConflictStrategyFirstWins, override, cluster)
+	g.Expect(err).To(BeNil())
+	g.Expect(sourceOf(instance, Query{Host: "app.example.org", Path: "/api"})).To(Equal(overrideSource))
+
+	instance.ConflictStrategy = value2.ConflictStrategyMostSpecificWins
+	g.Expect(sourceOf(instance, Query{Host: "app.example.org", Path: "/api"})).To(Equal(clusterSource))
+	g.Expect(sourceOf(instance, Query{Host: "app.example.org", Path: "/other"})).To(Equal(overrideSource))
+
+	instance.ConflictStrategy = value2.ConflictStrategyError
+	g.Expect(sourceOf(instance, Query{Host: "other.example.org", Path: "/api"})).To(Equal(overrideSource))
+	_, err = instance.FindBy(Query{Host: "app.example.org", Path: "/api"})
+	g.Expect(err).To(MatchError("app.example.org/api is served by several sources: File:override, File:cluster"))
+}
+
+func Test_CompositeRepository_FindCertificatesBy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	childOf := func(name string, host string) (CombinedRepository, int64) {
+		result := &KubernetesBasedRepository{Logger: log.GetRootLogger()}
+		result.byHostRules.Store(NewByHost(result.onRuleAdded, result.onRuleRemoved))
+		certificate, key := newTestCertificate(t, host, nil, nil)
+		source := support.NewObjectReference("", fileSourceKind, "", name)
+		_, err := result.CertificatesByHost.Add(source, tls.Certificate{Certificate: [][]byte{certificate.Raw}, PrivateKey: key})
+		g.Expect(err).To(BeNil())
+		return result, certificate.SerialNumber.Int64()
+	}
+	override, overrideSerial := childOf("override", "*.example.org")
+	cluster, clusterSerial := childOf("cluster", "app.example.org")
+
+	serialOf := func(instance *CompositeRepository, host value.WildcardSupportingFqdn) int64 {
+		actual, err := instance.FindCertificatesBy(CertificateQuery{Host: host})
+		g.Expect(err).To(BeNil())
+		g.Expect(actual).To(HaveLen(1))
+		return actual[0].Leaf.SerialNumber.Int64()
+	}
+
+	instance, err := NewCompositeRepository(value2.ConflictStrategyFirstWins, override, cluster)
+	g.Expect(err).To(BeNil())
+	g.Expect(serialOf(instance, "app.example.org")).To(Equal(overrideSerial))
+
+	instance.ConflictStrategy = value2.ConflictStrategyMostSpecificWins
+	g.Expect(serialOf(instance, "app.example.org")).To(Equal(clusterSerial))
+	g.Expect(serialOf(instance, "other.example.org")).To(Equal(overrideSerial))
+
+	instance.ConflictStrategy = value2.ConflictStrategyError
+	g.Expect(serialOf(instance, "other.example.org")).To(Equal(overrideSerial))
+	_, err = instance.FindCertificatesBy(CertificateQuery{Host: "app.example.org"})
+	g.Expect(err).To(MatchError("certificates for app.example.org are provided by several rules sources: #0, #1"))
+}
+
+func Test_CompositeRepository_FindClientCertificateRequirementsBy(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	ca, caKey := newTestCertificate(t, "ca", nil, nil)
+	otherCa, otherCaKey := newTestCertificate(t, "other-ca", nil, nil)
+	client, _ := newTestCertificate(t, "client", ca, caKey)
+	otherClient, _ := newTestCertificate(t, "other-client", otherCa, otherCaKey)
+
+	childOf := func(path, mode string, ca *x509.Certificate) CombinedRepository {
+		result := &KubernetesBasedRepository{Logger: log.GetRootLogger()}
+		result.byHostRules.Store(NewByHost(result.onRuleAdded, result.onRuleRemoved))
+		options := DefaultOptionsFactory()
+		g.Expect(options.Set(Annotations{
+			annotationClientCertificateCaSecret: "ca",
+			annotationClientCertificateVerify:   mode,
+		})).To(Succeed())
+		authorities := NewClientCertificateAuthorities()
+		g.Expect(authorities.Set(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))).To(Succeed())
+		options[optionsClientCertificateKey].(*OptionsClientCertificate).Authorities = authorities
+		source := support.NewObjectReference("", fileSourceKind, "", path)
+		g.Expect(result.ByHostRules().Put(NewRule("app.example.org", []string{path}, PathTypePrefix, source, nil, nil, options))).To(Succeed())
+		return result
+	}
+	requirementsOf := func(instance *CompositeRepository, host value.WildcardSupportingFqdn) ClientCertificateRequirements {
+		actual, err := instance.FindClientCertificateRequirementsBy(CertificateQuery{Host: host})
+		g.Expect(err).To(BeNil())
+		return actual
+	}
+	verifies := func(requirements ClientCertificateRequirements, client *x509.Certificate) bool {
+		_, err := client.Verify(x509.VerifyOptions{
+			Roots:     requirements.ClientCAs,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		return err == nil
+	}
+
+	// The request can be served by both children depending on its path; so
+	// the authorities of both are requested...
+	instance, err := NewCompositeRepository(value2.ConflictStrategyFirstWins, childOf("a", "required", ca), childOf("b", "optional", otherCa))
+	g.Expect(err).To(BeNil())
+	actual := requirementsOf(instance, "app.example.org")
+	g.Expect(actual.ClientAuth).To(Equal(tls.VerifyClientCertIfGiven))
+	g.Expect(verifies(actual, client)).To(BeTrue())
+	g.Expect(verifies(actual, otherClient)).To(BeTrue())
+
+	// ... and certificates are only required if both are requiring them.
+	instance, err = NewCompositeRepository(value2.ConflictStrategyMostSpecificWins, childOf("a", "required", ca), childOf("b", "required", otherCa))
+	g.Expect(err).To(BeNil())
+	g.Expect(requirementsOf(instance, "app.example.org").ClientAuth).To(Equal(tls.RequireAndVerifyClientCert))
+
+	g.Expect(requirementsOf(instance, "other.example.org").IsEnabled()).To(BeFalse())
+}
@@ -0,0 +1,208 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/slf4g"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+)
+
+type servicePortEndpoints struct {
+	namespace string
+	service   string
+	portName  string
+	endpoints *Endpoints
+	// static is true if the endpoints only containing the clusterIP of the
+	// service and will not be refreshed by EndpointSlices.
+	static bool
+}
+
+func servicePortEndpointsKey(namespace, service, portName string) string {
+	return namespace + "/" + service + ":" + portName
+}
+
+// ingressToEndpoints returns the shared Endpoints instance for the given
+// backend. If the discovery of endpoints is disabled (or the endpoints cannot
+// be resolved) the Endpoints will only contain the clusterIP based backend.
+func (this *repositoryImplState) ingressToEndpoints(service *v1.Service, in networkingv1.ServiceBackendPort, backend net.Addr, usingLogger log.Logger) *Endpoints {
+	if !this.definitions.EndpointSlice.IsEnabled() {
+		return this.ingressToBackendEndpoints(service, backend)
+	}
+
+	portName, err := this.evaluateServicePortName(in, service)
+	if err != nil {
+		usingLogger.
+			WithError(err).
+			Warn("Cannot resolve backend port for endpoints; clusterIP of service will be used...")
+		return this.ingressToBackendEndpoints(service, backend)
+	}
+
+	this.endpointsMutex.Lock()
+	defer this.endpointsMutex.Unlock()
+
+	key := servicePortEndpointsKey(service.Namespace, service.Name, portName)
+	if existing, ok := this.endpoints[key]; ok {
+		return existing.endpoints
+	}
+
+	entry := servicePortEndpoints{
+		namespace: service.Namespace,
+		service:   service.Name,
+		portName:  portName,
+		endpoints: NewEndpoints(),
+	}
+	this.refreshEndpoints(entry)
+	this.endpoints[key] = entry
+	return entry.endpoints
+}
+
+func (this *repositoryImplState) ingressToBackendEndpoints(service *v1.Service, backend net.Addr) *Endpoints {
+	this.endpointsMutex.Lock()
+	defer this.endpointsMutex.Unlock()
+
+	key := servicePortEndpointsKey(service.Namespace, service.Name, "@"+backend.String())
+	if existing, ok := this.endpoints[key]; ok {
+		return existing.endpoints
+	}
+
+	entry := servicePortEndpoints{
+		namespace: service.Namespace,
+		service:   service.Name,
+		endpoints: NewEndpoints(backend),
+		static:    true,
+	}
+	this.endpoints[key] = entry
+	return entry.endpoints
+}
+
+func (this *repositoryImplState) evaluateServicePortName(in networkingv1.ServiceBackendPort, service *v1.Service) (string, error) {
+	for _, candidate := range service.Spec.Ports {
+		if (in.Name != "" && candidate.Name == in.Name) || (in.Name == "" && candidate.Port == in.Number) {
+			return candidate.Name, nil
+		}
+	}
+	if v := in.Name; v != "" {
+		return "", fmt.Errorf("unknown service reference %s:%s", service.Name, v)
+	}
+	return "", fmt.Errorf("unknown service reference %s:%d", service.Name, in.Number)
+}
+
+func (this *repositoryImplState) refreshEndpoints(target servicePortEndpoints) {
+	slices, err := this.definitions.EndpointSlice.ByService(target.namespace, target.service)
+	if err != nil {
+		this.Logger.
+			WithError(err).
+			With("service", target.namespace+"/"+target.service).
+			With("port", target.portName).
+			Warn("Cannot refresh endpoints; previous endpoints will be kept.")
+		return
+	}
+
+	var addresses []net.Addr
+	for _, slice := range slices {
+		if slice.AddressType != discoveryv1.AddressTypeIPv4 && slice.AddressType != discoveryv1.AddressTypeIPv6 {
+			continue
+		}
+		port := endpointSlicePortByName(slice, target.portName)
+		if port == nil {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			if ready := endpoint.Conditions.Ready; ready != nil && !*ready {
+				continue
+			}
+			// All addresses of one endpoint are fungible; so the first one is enough.
+			if len(endpoint.Addresses) == 0 {
+				continue
+			}
+			if ip := net.ParseIP(endpoint.Addresses[0]); ip != nil {
+				addresses = append(addresses, &net.TCPAddr{
+					IP:   ip,
+					Port: int(*port),
+				})
+			}
+		}
+	}
+
+	target.endpoints.Set(addresses)
+	this.Logger.
+		With("service", target.namespace+"/"+target.service).
+		With("port", target.portName).
+		With("endpoints", target.endpoints).
+		Debug("Endpoints refreshed.")
+}
+
+// pruneEndpoints forgets all endpoints which are not used by any served rule
+// anymore (because the Ingress or its Service was removed or changed). It
+// has to be called while holding byHostRulesMutex.
+func (this *repositoryImplState) pruneEndpoints() error {
+	this.endpointsMutex.Lock()
+	defer this.endpointsMutex.Unlock()
+
+	if len(this.endpoints) == 0 {
+		return nil
+	}
+
+	inUse := map[*Endpoints]struct{}{}
+	if err := this.ByHostRules().All(func(r Rule) error {
+		if v := r.Endpoints(); v != nil {
+			inUse[v] = struct{}{}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for key, candidate := range this.endpoints {
+		if _, ok := inUse[candidate.endpoints]; !ok {
+			delete(this.endpoints, key)
+			this.Logger.
+				With("endpoints", key).
+				Debug("Endpoints pruned.")
+		}
+	}
+	return nil
+}
+
+func endpointSlicePortByName(slice *discoveryv1.EndpointSlice, name string) *int32 {
+	for _, candidate := range slice.Ports {
+		candidateName := ""
+		if candidate.Name != nil {
+			candidateName = *candidate.Name
+		}
+		if candidateName == name && candidate.Port != nil {
+			return candidate.Port
+		}
+	}
+	return nil
+}
+
+func (this *repositoryImplState) onEndpointSliceChanged(namespace, service string) error {
+	this.endpointsMutex.Lock()
+	defer this.endpointsMutex.Unlock()
+
+	for _, candidate := range this.endpoints {
+		if !candidate.static && candidate.namespace == namespace && (service == "" || candidate.service == service) {
+			this.refreshEndpoints(candidate)
+		}
+	}
+	return nil
+}
+
+func (this *repositoryImplState) onEndpointSliceElementAdded(_ support.ObjectReference, new metav1.Object) error {
+	return this.onEndpointSliceChanged(new.GetNamespace(), new.GetLabels()[discoveryv1.LabelServiceName])
+}
+
+func (this *repositoryImplState) onEndpointSliceElementUpdated(_ support.ObjectReference, _, new metav1.Object) error {
+	return this.onEndpointSliceChanged(new.GetNamespace(), new.GetLabels()[discoveryv1.LabelServiceName])
+}
+
+func (this *repositoryImplState) onEndpointSliceElementRemoved(ref support.ObjectReference) error {
+	// We do not know anymore to which service the slice belonged to; so we
+	// refresh all services of the namespace.
+	return this.onEndpointSliceChanged(ref.Namespace(), "")
+}
@@ -0,0 +1,124 @@
+package rules
+
+import (
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func Test_pruneEndpoints(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	portName, port := "http", int32(8080)
+	service := func(name, clusterIP string) *v1.Service {
+		return &v1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: name},
+			Spec: v1.ServiceSpec{
+				Type:      v1.ServiceTypeClusterIP,
+				ClusterIP: clusterIP,
+				Ports:     []v1.ServicePort{{Name: portName, Port: 80}},
+			},
+		}
+	}
+	client := fake.NewClientset(service("a", "10.0.0.1"), service("b", "10.0.0.2"), &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "foo",
+			Name:      "a-1",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "a"},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Ports:       []discoveryv1.EndpointPort{{Name: &portName, Port: &port}},
+		Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"10.1.0.1"}}},
+	})
+
+	s := settings.MustNew()
+	s.Discovery.Endpoints = value.True()
+	definitions := &definition.Definitions{}
+	var err error
+	definitions.Service, err = definition.NewService(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.EndpointSlice, err = definition.NewEndpointSlice(&s, client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(definitions.Service.Init(stop)).To(Succeed())
+	g.Expect(definitions.EndpointSlice.Init(stop)).To(Succeed())
+
+	repository := &KubernetesBasedRepository{
+		settings:           &s,
+		Logger:             log.GetRootLogger(),
+		OptionsFactory:     DefaultOptionsFactory,
+		CertificatesByHost: CertificatesByHost{},
+	}
+	repository.byHostRules.Store(NewByHost(repository.onRuleAdded, repository.onRuleRemoved))
+	state := &repositoryImplState{
+		KubernetesBasedRepository: repository,
+		definitions:               definitions,
+		ingressTls:                map[string]ingressTls{},
+		endpoints:                 map[string]servicePortEndpoints{},
+		basicAuth:                 map[string]basicAuthCredentials{},
+	}
+	state.initiated.Store(true)
+
+	pathType := networkingv1.PathTypePrefix
+	ingress := func(name, service string) *networkingv1.Ingress {
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: name},
+			Spec: networkingv1.IngressSpec{
+				Rules: []networkingv1.IngressRule{{
+					Host: name + ".example.org",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     "/",
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+								Name: service,
+								Port: networkingv1.ServiceBackendPort{Name: portName},
+							}},
+						}},
+					}},
+				}},
+			},
+		}
+	}
+	refOf := func(name string) support.ObjectReference {
+		return support.NewObjectReference("networking.k8s.io/v1", "Ingress", "foo", name)
+	}
+	keys := func() []string {
+		state.endpointsMutex.Lock()
+		defer state.endpointsMutex.Unlock()
+		var result []string
+		for key := range state.endpoints {
+			result = append(result, key)
+		}
+		return result
+	}
+
+	g.Expect(state.onIngressElementAdded(refOf("x"), ingress("x", "a"))).To(Succeed())
+	g.Expect(state.onIngressElementAdded(refOf("y"), ingress("y", "a"))).To(Succeed())
+	g.Expect(keys()).To(ConsistOf("foo/a:http"))
+	found, err := repository.FindBy(Query{Host: "x.example.org", Path: "/"})
+	g.Expect(err).To(BeNil())
+	g.Expect(found.Any().Endpoints().Addresses()).To(HaveLen(1))
+
+	// Still used by y.
+	g.Expect(state.onIngressElementRemoved(refOf("x"))).To(Succeed())
+	g.Expect(keys()).To(ConsistOf("foo/a:http"))
+
+	// Service changed: the endpoints of the previous one are pruned.
+	g.Expect(state.onIngressElementUpdated(refOf("y"), nil, ingress("y", "b"))).To(Succeed())
+	g.Expect(keys()).To(ConsistOf("foo/b:http"))
+
+	g.Expect(state.onIngressElementRemoved(refOf("y"))).To(Succeed())
+	g.Expect(keys()).To(BeEmpty())
+}
@@ -0,0 +1,129 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/leader"
+	"github.com/echocat/lingress/support"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientkubernetes "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"time"
+)
+
+const (
+	eventReasonInvalidAnnotations      = "InvalidAnnotations"
+	eventReasonIllegalHost             = "IllegalHost"
+	eventReasonIllegalPath             = "IllegalPath"
+	eventReasonIllegalPathType         = "IllegalPathType"
+	eventReasonIllegalBackend          = "IllegalBackend"
+	eventReasonUnsupportedBackend      = "UnsupportedBackend"
+	eventReasonServiceNotFound         = "ServiceNotFound"
+	eventReasonUnsupportedServiceType  = "UnsupportedServiceType"
+	eventReasonServiceWithoutClusterIp = "ServiceWithoutClusterIP"
+	eventReasonUnresolvablePort        = "UnresolvablePort"
+	eventReasonUnresolvableAddress     = "UnresolvableAddress"
+
+	// ingressEventRepeatAfter is the duration after which the same problem of
+	// an unchanged Ingress is published again. This ensures it does not vanish
+	// (Kubernetes removes Events after one hour by default) while it persists.
+	ingressEventRepeatAfter = 30 * time.Minute
+)
+
+// initEvents prepares the publishing of Kubernetes Events for problems found
+// inside of Ingresses. Beside the deduplication of recordIngressProblem, the
+// Events are aggregated and rate-limited per Ingress by the EventCorrelator of
+// client-go.
+func (this *repositoryImplState) initEvents(client clientkubernetes.Interface, stop support.Channel) {
+	if !this.settings.Ingress.Events.GetOr(true) {
+		return
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: client.CoreV1().Events(""),
+	})
+	support.ChannelDoOnEvent(stop, broadcaster.Shutdown)
+
+	this.eventRecorder = broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{
+		Component: "lingress",
+	})
+	this.ingressEvents = map[string]*ingressEvents{}
+
+	// Only the leader publishes Events; so the new leader has to publish the
+	// problems which are currently present.
+	this.subscribeLeadership(leader.Subscriber{
+		OnStartedLeading: func() {
+			go this.republishIngressProblems()
+		},
+	})
+}
+
+// republishIngressProblems evaluates all Ingresses again which records their
+// current problems.
+func (this *repositoryImplState) republishIngressProblems() {
+	if err := this.revisitIngresses(); err != nil {
+		this.Logger.
+			WithError(err).
+			Warn("Cannot publish problems of Ingresses; ignoring...")
+	}
+}
+
+type ingressEvents struct {
+	resourceVersion string
+	published       map[string]time.Time
+}
+
+// recordIngressProblem publishes a warning Event for the given object if this
+// instance is the leader. The same reason and message is only published once
+// per resourceVersion of the object (and repeated after
+// ingressEventRepeatAfter).
+func (this *repositoryImplState) recordIngressProblem(involved runtime.Object, reason string, messageFormat string, args ...any) {
+	recorder := this.eventRecorder
+	if recorder == nil || involved == nil || !this.isLeader() {
+		return
+	}
+	object, ok := involved.(metav1.Object)
+	if !ok {
+		return
+	}
+	message := fmt.Sprintf(messageFormat, args...)
+
+	if !this.shouldRecordIngressProblem(object, reason+"\n"+message) {
+		return
+	}
+
+	recorder.Event(involved, v1.EventTypeWarning, reason, message)
+}
+
+func (this *repositoryImplState) shouldRecordIngressProblem(object metav1.Object, key string) bool {
+	this.ingressEventsMutex.Lock()
+	defer this.ingressEventsMutex.Unlock()
+
+	objectKey := object.GetNamespace() + "/" + object.GetName()
+	entry, ok := this.ingressEvents[objectKey]
+	if !ok || entry.resourceVersion != object.GetResourceVersion() {
+		entry = &ingressEvents{
+			resourceVersion: object.GetResourceVersion(),
+			published:       map[string]time.Time{},
+		}
+		this.ingressEvents[objectKey] = entry
+	}
+
+	now := time.Now()
+	if last, ok := entry.published[key]; ok && now.Sub(last) < ingressEventRepeatAfter {
+		return false
+	}
+	entry.published[key] = now
+	return true
+}
+
+func (this *repositoryImplState) forgetIngressEvents(ref support.ObjectReference) {
+	this.ingressEventsMutex.Lock()
+	defer this.ingressEventsMutex.Unlock()
+
+	delete(this.ingressEvents, ref.ShortString())
+}
@@ -0,0 +1,119 @@
+package rules
+
+import (
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/leader"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	"testing"
+	"time"
+)
+
+func Test_recordIngressProblem(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	recorder := record.NewFakeRecorder(10)
+	state := &repositoryImplState{
+		KubernetesBasedRepository: &KubernetesBasedRepository{},
+		eventRecorder:             recorder,
+		ingressEvents:             map[string]*ingressEvents{},
+	}
+	ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar", ResourceVersion: "1"}}
+
+	state.recordIngressProblem(ingress, eventReasonServiceNotFound, "Service %s does not exist", "foo/app")
+	state.recordIngressProblem(ingress, eventReasonServiceNotFound, "Service %s does not exist", "foo/app")
+	state.recordIngressProblem(ingress, eventReasonIllegalPathType, "Path %q is ignored", "/")
+	g.Expect(recorder.Events).To(HaveLen(2))
+	g.Expect(<-recorder.Events).To(Equal("Warning ServiceNotFound Service foo/app does not exist"))
+	g.Expect(<-recorder.Events).To(Equal(`Warning IllegalPathType Path "/" is ignored`))
+
+	ingress.ResourceVersion = "2"
+	state.recordIngressProblem(ingress, eventReasonServiceNotFound, "Service %s does not exist", "foo/app")
+	g.Expect(recorder.Events).To(HaveLen(1))
+
+	state.recordIngressProblem(nil, eventReasonServiceNotFound, "Service %s does not exist", "foo/app")
+	g.Expect(recorder.Events).To(HaveLen(1))
+}
+
+func Test_recordIngressProblem_republishedOnLeadership(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	className := "lingress"
+	pathType := networkingv1.PathTypePrefix
+	client := fake.NewClientset(&networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "app", ResourceVersion: "1"},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: &className,
+			Rules: []networkingv1.IngressRule{{
+				Host: "app.example.org",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     "/",
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "missing",
+							Port: networkingv1.ServiceBackendPort{Number: 80},
+						}},
+					}},
+				}},
+			}},
+		},
+	})
+
+	s := settings.MustNew()
+	s.Ingress.Classes = []string{className}
+	s.LeaderElection.Enabled = value.False()
+	definitions := &definition.Definitions{}
+	var err error
+	definitions.IngressClass, err = definition.NewIngressClass(client, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.Service, err = definition.NewService(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.EndpointSlice, err = definition.NewEndpointSlice(&s, client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.Ingress, err = definition.NewIngress(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(definitions.IngressClass.Init(stop)).To(Succeed())
+	g.Expect(definitions.Service.Init(stop)).To(Succeed())
+	g.Expect(definitions.Ingress.Init(stop)).To(Succeed())
+
+	election, err := leader.New(&s, nil, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	repository := &KubernetesBasedRepository{
+		settings:           &s,
+		Logger:             log.GetRootLogger(),
+		LeaderElection:     election,
+		OptionsFactory:     DefaultOptionsFactory,
+		CertificatesByHost: CertificatesByHost{},
+	}
+	repository.byHostRules.Store(NewByHost(repository.onRuleAdded, repository.onRuleRemoved))
+	recorder := record.NewFakeRecorder(10)
+	state := &repositoryImplState{
+		KubernetesBasedRepository: repository,
+		definitions:               definitions,
+		ingressTls:                map[string]ingressTls{},
+		endpoints:                 map[string]servicePortEndpoints{},
+		basicAuth:                 map[string]basicAuthCredentials{},
+	}
+	state.initEvents(client, stop)
+	state.eventRecorder = recorder
+	state.initiated.Store(true)
+
+	// Not the leader: the problem is not published...
+	g.Expect(state.revisitIngresses()).To(Succeed())
+	g.Consistently(recorder.Events, 100*time.Millisecond).ShouldNot(Receive())
+
+	// ... until it becomes the leader.
+	g.Expect(election.Init(stop)).To(Succeed())
+	g.Eventually(recorder.Events).Should(Receive(Equal("Warning ServiceNotFound Service foo/missing does not exist; ignoring its routes until it exists")))
+}
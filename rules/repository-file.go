@@ -0,0 +1,526 @@
+package rules
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"github.com/echocat/slf4g"
+	"gopkg.in/yaml.v3"
+	"io"
+	networkingv1 "k8s.io/api/networking/v1"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	fileSourceKind = "File"
+)
+
+// FileBasedRepository provides rules and certificates which are defined inside
+// a YAML (or JSON) file instead of the Kubernetes cluster. The file and all
+// files referenced by it are checked periodically for changes and reloaded if
+// changed. If a reload fails the previous state remains active.
+type FileBasedRepository struct {
+	settings *settings.Settings
+
+	File   string
+	Logger log.Logger
+
+	CertificatesByHost CertificatesByHost
+	OptionsFactory     OptionsFactory
+
+	byHostRules            atomic.Pointer[ByHost]
+	clientCertificatePools ClientCertificatePools
+	watched                map[string]fileState
+	synced                 atomic.Bool
+	lastError              support.LastError
+	mutex                  sync.Mutex
+}
+
+func NewFileBasedRepository(s *settings.Settings, file string, logger log.Logger) (*FileBasedRepository, error) {
+	if file == "" {
+		return nil, fmt.Errorf("no file for rules provided")
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve rules file %s: %w", file, err)
+	}
+	result := &FileBasedRepository{
+		settings: s,
+		File:     abs,
+		Logger:   logger.With("file", abs),
+
+		OptionsFactory:     DefaultOptionsFactory,
+		CertificatesByHost: CertificatesByHost{},
+	}
+	result.byHostRules.Store(NewByHost(result.onRuleAdded, result.onRuleRemoved))
+	return result, nil
+}
+
+// RulesFile is the document a FileBasedRepository is read from.
+type RulesFile struct {
+	// Certificates are pairs of files which contain a PEM encoded certificate
+	// (chain) and its privateKey.
+	Certificates []RulesFileCertificate `yaml:"certificates,omitempty" json:"certificates,omitempty"`
+	// Secrets can be referenced by annotations (like
+	// lingress.echocat.org/basic-auth.secret) by their name. Each key of a
+	// secret refers to the file containing its content.
+	Secrets map[string]map[string]string `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+	Rules   []RulesFileRule              `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+type RulesFileCertificate struct {
+	Certificate string `yaml:"certificate" json:"certificate"`
+	PrivateKey  string `yaml:"privateKey" json:"privateKey"`
+}
+
+type RulesFileRule struct {
+	// Name identifies the rule as part of its source; defaults to rules[<index>].
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Host the rule is served for; empty means all hosts.
+	Host  string          `yaml:"host,omitempty" json:"host,omitempty"`
+	Paths []RulesFilePath `yaml:"paths,omitempty" json:"paths,omitempty"`
+	// Backend is the <host>:<port> where requests are sent to. Defaults to
+	// the first of Endpoints.
+	Backend string `yaml:"backend,omitempty" json:"backend,omitempty"`
+	// Endpoints are <host>:<port> pairs which are load balanced.
+	Endpoints []string `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
+	// Annotations are the same as for Ingresses.
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+}
+
+type RulesFilePath struct {
+	Path     string `yaml:"path,omitempty" json:"path,omitempty"`
+	PathType string `yaml:"pathType,omitempty" json:"pathType,omitempty"`
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(file string) fileState {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{
+		modTime: fi.ModTime(),
+		size:    fi.Size(),
+	}
+}
+
+func (this *FileBasedRepository) Init(stop support.Channel) error {
+	if err := this.Reload(); err != nil {
+		return err
+	}
+
+	go this.run(stop)
+	return nil
+}
+
+func (this *FileBasedRepository) run(stop support.Channel) {
+	interval := this.settings.Rules.FileReloadInterval
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	stopCh := support.ToChan(stop)
+	for {
+		select {
+		case <-ticker.C:
+			if !this.hasChanged() {
+				continue
+			}
+			if err := this.Reload(); err != nil {
+				this.Logger.
+					WithError(err).
+					Error("Cannot reload rules file; keeping previous rules...")
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (this *FileBasedRepository) hasChanged() bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	for file, state := range this.watched {
+		if statFile(file) != state {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload reads the file and all files referenced by it again and replaces the
+// current rules and certificates with them.
+func (this *FileBasedRepository) Reload() error {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	loader := &rulesFileLoader{
+		repository:  this,
+		dir:         filepath.Dir(this.File),
+		watched:     map[string]fileState{},
+		credentials: map[string]*Htpasswd{},
+		authorities: map[string]*ClientCertificateAuthorities{},
+	}
+	byHost, certificates, err := loader.load(this.File)
+	// Also if it fails we watch all files which were read (or tried to) to
+	// retry as soon as one of them changes.
+	this.watched = loader.watched
+	if err != nil {
+		err = fmt.Errorf("cannot load rules file %s: %w", this.File, err)
+		this.lastError.Store(err)
+		return err
+	}
+
+	// The new certificates are collected first and replace the previous ones
+	// at once; so no handshake happens without certificate meanwhile.
+	var loaded CertificatesByHost
+	for _, certificate := range certificates {
+		if added, err := loaded.AddBytes(certificate.source, certificate.certificate, certificate.privateKey); err != nil {
+			this.Logger.
+				WithError(err).
+				With("ref", certificate.source).
+				Warn("Cannot add certificate; ignoring...")
+		} else if len(added) > 0 {
+			this.Logger.With("ref", certificate.source).With("fqdns", added).Debug("Certificates for FQNDs added.")
+		}
+	}
+	this.CertificatesByHost.Replace(&loaded)
+
+	this.byHostRules.Store(byHost)
+	// All authorities were loaded again; so the pools of the previous ones are obsolete.
+	this.clientCertificatePools.Clear()
+	this.synced.Store(true)
+
+	this.Logger.
+		With("certificates", len(certificates)).
+		Info("Rules file loaded.")
+	return nil
+}
+
+// HasSynced reports whether the file was loaded successfully at least once.
+func (this *FileBasedRepository) HasSynced() bool {
+	return this.synced.Load()
+}
+
+func (this *FileBasedRepository) LastError() (time.Time, error) {
+	return this.lastError.Load()
+}
+
+func (this *FileBasedRepository) onRuleAdded(_ []string, r Rule) {
+	this.Logger.With("rule", r).Debug("Rule added.")
+}
+
+func (this *FileBasedRepository) onRuleRemoved(_ []string, r Rule) {
+	this.Logger.With("rule", r).Debug("Rule removed.")
+}
+
+func (this *FileBasedRepository) ByHostRules() *ByHost {
+	return this.byHostRules.Load()
+}
+
+func (this *FileBasedRepository) All(consumer func(Rule) error) error {
+	return this.ByHostRules().All(consumer)
+}
+
+func (this *FileBasedRepository) FindBy(q Query) (Rules, error) {
+	path, err := ParsePath(q.Path, true)
+	if err != nil {
+		return nil, err
+	}
+	return this.ByHostRules().Find(q.Host, path)
+}
+
+func (this *FileBasedRepository) HasHost(host value.Fqdn) (bool, error) {
+	return this.ByHostRules().HasHost(host), nil
+}
+
+func (this *FileBasedRepository) FindCertificatesBy(q CertificateQuery) (Certificates, error) {
+	return this.CertificatesByHost.Find(q.Host), nil
+}
+
+func (this *FileBasedRepository) AllCertificates() ([]LoadedCertificate, error) {
+	return this.CertificatesByHost.All(), nil
+}
+
+func (this *FileBasedRepository) rulesOfHost(host value.Fqdn) ([]Rule, error) {
+	return rulesOfHost(this.ByHostRules(), host)
+}
+
+func (this *FileBasedRepository) FindClientCertificateRequirementsBy(q CertificateQuery) (ClientCertificateRequirements, error) {
+	var host value.Fqdn
+	if err := host.Set(q.Host.String()); err != nil {
+		return ClientCertificateRequirements{}, nil
+	}
+	candidates, err := this.rulesOfHost(host)
+	if err != nil {
+		return ClientCertificateRequirements{}, err
+	}
+	return ClientCertificateRequirementsOf(candidates, &this.clientCertificatePools), nil
+}
+
+type rulesFileCertificateContent struct {
+	source      support.ObjectReference
+	certificate []byte
+	privateKey  []byte
+}
+
+// rulesFileLoader loads one version of a RulesFile and remembers all files it
+// has read.
+type rulesFileLoader struct {
+	repository *FileBasedRepository
+	dir        string
+	document   RulesFile
+	watched    map[string]fileState
+
+	credentials map[string]*Htpasswd
+	authorities map[string]*ClientCertificateAuthorities
+}
+
+func (this *rulesFileLoader) readFile(file string) ([]byte, string, error) {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(this.dir, file)
+	}
+	this.watched[file] = statFile(file)
+	content, err := os.ReadFile(file)
+	return content, file, err
+}
+
+func (this *rulesFileLoader) load(file string) (*ByHost, []rulesFileCertificateContent, error) {
+	content, _, err := this.readFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&this.document); err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, err
+	}
+
+	certificates := make([]rulesFileCertificateContent, len(this.document.Certificates))
+	for i, candidate := range this.document.Certificates {
+		if certificates[i], err = this.loadCertificate(candidate); err != nil {
+			return nil, nil, fmt.Errorf("certificates[%d]: %w", i, err)
+		}
+	}
+
+	result := NewByHost(this.repository.onRuleAdded, this.repository.onRuleRemoved)
+	names := map[string]bool{}
+	for i, candidate := range this.document.Rules {
+		name := candidate.Name
+		if name == "" {
+			name = "rules[" + strconv.Itoa(i) + "]"
+		}
+		if names[name] {
+			return nil, nil, fmt.Errorf("rules[%d]: duplicate name %q", i, name)
+		}
+		names[name] = true
+
+		source := support.NewObjectReference("", fileSourceKind, "", this.repository.File+"#"+name)
+		if err := this.loadRule(source, candidate, result); err != nil {
+			return nil, nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+
+	return result, certificates, nil
+}
+
+func (this *rulesFileLoader) loadCertificate(in RulesFileCertificate) (result rulesFileCertificateContent, err error) {
+	if in.Certificate == "" || in.PrivateKey == "" {
+		return result, fmt.Errorf("certificate and privateKey are required")
+	}
+	var file string
+	if result.certificate, file, err = this.readFile(in.Certificate); err != nil {
+		return result, err
+	}
+	if result.privateKey, _, err = this.readFile(in.PrivateKey); err != nil {
+		return result, err
+	}
+	if _, err = tls.X509KeyPair(result.certificate, result.privateKey); err != nil {
+		return result, err
+	}
+	result.source = support.NewObjectReference("", fileSourceKind, "", file)
+	return result, nil
+}
+
+func (this *rulesFileLoader) loadRule(source support.ObjectReference, in RulesFileRule, target *ByHost) error {
+	var host value.WildcardSupportingFqdn
+	if v := normalizeHostname(in.Host); v != "" {
+		if err := host.Set(v); err != nil {
+			return fmt.Errorf("illegal host %q: %w", v, err)
+		}
+	}
+
+	backend, endpoints, err := this.resolveBackend(in)
+	if err != nil {
+		return err
+	}
+
+	options := this.repository.OptionsFactory()
+	if err := options.Set(in.Annotations); err != nil {
+		return err
+	}
+	if err := this.applySecrets(options); err != nil {
+		return err
+	}
+
+	paths := in.Paths
+	if len(paths) == 0 {
+		paths = []RulesFilePath{{Path: "/"}}
+	}
+	for i, candidate := range paths {
+		path, err := ParsePath(candidate.Path, false)
+		if err != nil {
+			return fmt.Errorf("paths[%d]: %w", i, err)
+		}
+		pathType := PathTypePrefix
+		if v := candidate.PathType; v != "" {
+			if pathType, err = ParsePathType((*networkingv1.PathType)(&v)); err != nil {
+				return fmt.Errorf("paths[%d]: %w", i, err)
+			}
+		}
+		if err := target.Put(NewRule(host, path, pathType, source, backend, endpoints, options)); err != nil {
+			return fmt.Errorf("paths[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func (this *rulesFileLoader) resolveBackend(in RulesFileRule) (net.Addr, *Endpoints, error) {
+	var endpoints *Endpoints
+	if len(in.Endpoints) > 0 {
+		addresses := make([]net.Addr, len(in.Endpoints))
+		for i, candidate := range in.Endpoints {
+			addr, err := parseFileAddr(candidate)
+			if err != nil {
+				return nil, nil, fmt.Errorf("endpoints[%d]: illegal address %q: %w", i, candidate, err)
+			}
+			addresses[i] = addr
+		}
+		endpoints = NewEndpoints(addresses...)
+	}
+
+	if in.Backend == "" {
+		if endpoints == nil {
+			return nil, nil, fmt.Errorf("neither backend nor endpoints configured")
+		}
+		return endpoints.Addresses()[0], endpoints, nil
+	}
+	backend, err := parseFileAddr(in.Backend)
+	if err != nil {
+		return nil, nil, fmt.Errorf("illegal backend %q: %w", in.Backend, err)
+	}
+	return backend, endpoints, nil
+}
+
+// parseFileAddr parses the given <host>:<port>. Hosts which are not an IP
+// are kept as they are and resolved on each new connection (like the
+// clusterIP of a Service would be); so changes of their DNS records are
+// respected.
+func parseFileAddr(plain string) (net.Addr, error) {
+	host, port, err := net.SplitHostPort(plain)
+	if err != nil {
+		return nil, err
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil || portNumber == 0 {
+		return nil, fmt.Errorf("illegal port %q", port)
+	}
+	if host == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return &net.TCPAddr{IP: ip, Port: int(portNumber)}, nil
+	}
+	return fileHostAddr(net.JoinHostPort(host, port)), nil
+}
+
+// fileHostAddr is a <host>:<port> whose host is a DNS name.
+type fileHostAddr string
+
+func (this fileHostAddr) Network() string {
+	return "tcp"
+}
+
+func (this fileHostAddr) String() string {
+	return string(this)
+}
+
+// applySecrets provides the credentials and authorities of the secrets
+// referenced by the given options; like KubernetesBasedRepository does it with
+// the Secrets of the cluster.
+func (this *rulesFileLoader) applySecrets(options Options) error {
+	if opts, ok := options[optionsBasicAuthKey].(*OptionsBasicAuth); ok && opts.Secret.IsPresent() {
+		name := opts.Secret.Get()
+		credentials, ok := this.credentials[name]
+		if !ok {
+			content, err := this.readSecret(name, basicAuthSecretKey)
+			if err != nil {
+				return err
+			}
+			credentials = NewHtpasswd()
+			if ignored := credentials.Set(content); len(ignored) > 0 {
+				this.repository.Logger.
+					With("secret", name).
+					With("users", ignored).
+					Warn("Secret of basic authentication contains users with unsupported hashes; ignoring those users...")
+			}
+			this.credentials[name] = credentials
+		}
+		opts.Credentials = credentials
+	}
+
+	if opts, ok := options[optionsClientCertificateKey].(*OptionsClientCertificate); ok && opts.CaSecret.IsPresent() {
+		name := opts.CaSecret.Get()
+		authorities, ok := this.authorities[name]
+		if !ok {
+			content, err := this.readSecret(name, clientCertificateCaSecretKey)
+			if err != nil {
+				return err
+			}
+			authorities = NewClientCertificateAuthorities()
+			if err := authorities.Set(content); err != nil {
+				return fmt.Errorf("secret %s contains illegal certificates: %w", name, err)
+			}
+			this.authorities[name] = authorities
+		}
+		opts.Authorities = authorities
+	}
+
+	return nil
+}
+
+func (this *rulesFileLoader) readSecret(name, key string) ([]byte, error) {
+	secret, ok := this.document.Secrets[name]
+	if !ok {
+		return nil, fmt.Errorf("secret %s does not exist", name)
+	}
+	file, ok := secret[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s does not contain key %s", name, key)
+	}
+	content, _, err := this.readFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key %s of secret %s: %w", key, name, err)
+	}
+	return content, nil
+}
@@ -0,0 +1,157 @@
+package rules
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_FileBasedRepository(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rules.yaml")
+	g.Expect(os.WriteFile(filepath.Join(dir, "htpasswd"), []byte("sha:{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=\n"), 0600)).To(Succeed())
+	g.Expect(os.WriteFile(file, []byte(`
+secrets:
+  users:
+    auth: htpasswd
+rules:
+  - name: app
+    host: app.example.org
+    paths:
+      - path: /api
+        pathType: Exact
+    endpoints: [ "127.0.0.1:8081", "127.0.0.1:8082" ]
+    annotations:
+      lingress.echocat.org/basic-auth.secret: users
+  - host: "*.example.org"
+    backend: 127.0.0.1:8080
+`), 0600)).To(Succeed())
+
+	s := settings.MustNew()
+	s.Rules.FileReloadInterval = 10 * time.Millisecond
+	instance, err := NewFileBasedRepository(&s, file, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(instance.Init(stop)).To(Succeed())
+
+	actual, err := instance.FindBy(Query{Host: "app.example.org", Path: "/api"})
+	g.Expect(err).To(BeNil())
+	g.Expect(actual.Len()).To(Equal(1))
+	g.Expect(actual.Get(0).Source().String()).To(Equal("File:" + file + "#app"))
+	g.Expect(actual.Get(0).PathType()).To(Equal(PathTypeExact))
+	g.Expect(actual.Get(0).Backend().String()).To(Equal("127.0.0.1:8081"))
+	g.Expect(actual.Get(0).Endpoints().Len()).To(Equal(2))
+	g.Expect(OptionsBasicAuthOf(actual.Get(0)).Credentials.Verify("sha", "password")).To(BeTrue())
+
+	actual, err = instance.FindBy(Query{Host: "other.example.org", Path: "/foo"})
+	g.Expect(err).To(BeNil())
+	g.Expect(actual.Len()).To(Equal(1))
+	g.Expect(actual.Get(0).Source().String()).To(Equal("File:" + file + "#rules[1]"))
+
+	// A broken file keeps the previous rules...
+	g.Expect(os.WriteFile(file, []byte(`rules: [ { host: "app.example.org" } ]`), 0600)).To(Succeed())
+	g.Expect(instance.Reload()).To(MatchError(ContainSubstring("rules[0]: neither backend nor endpoints configured")))
+	g.Expect(instance.HasHost("app.example.org")).To(BeTrue())
+
+	// ... and a fixed one is picked up by itself.
+	g.Expect(os.WriteFile(file, []byte(`{"rules": [{"host": "new.example.org", "backend": "127.0.0.1:8080"}]}`), 0600)).To(Succeed())
+	g.Eventually(func() (bool, error) {
+		return instance.HasHost("new.example.org")
+	}).Should(BeTrue())
+	g.Expect(instance.HasHost("app.example.org")).To(BeFalse())
+}
+
+func Test_FileBasedRepository_Reload(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).To(BeNil())
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(666),
+		Subject:      pkix.Name{CommonName: "app.example.org"},
+		DNSNames:     []string{"app.example.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(1)}, &key.PublicKey, key)
+	g.Expect(err).To(BeNil())
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	g.Expect(err).To(BeNil())
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "rules.yaml")
+	g.Expect(os.WriteFile(filepath.Join(dir, "tls.crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "tls.key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)).To(Succeed())
+	g.Expect(os.WriteFile(file, []byte(`
+certificates:
+  - certificate: tls.crt
+    privateKey: tls.key
+rules:
+  - host: app.example.org
+    backend: backend.example.org:8080
+    endpoints: [ "backend-a.example.org:8080", "[::1]:8081" ]
+`), 0600)).To(Succeed())
+
+	s := settings.MustNew()
+	instance, err := NewFileBasedRepository(&s, file, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(instance.Reload()).To(Succeed())
+
+	// DNS names are resolved for each connection instead of once.
+	actual, err := instance.FindBy(Query{Host: "app.example.org", Path: "/"})
+	g.Expect(err).To(BeNil())
+	g.Expect(actual.Get(0).Backend().String()).To(Equal("backend.example.org:8080"))
+	g.Expect(actual.Get(0).Endpoints().Addresses()).To(ConsistOf(
+		fileHostAddr("backend-a.example.org:8080"),
+		&net.TCPAddr{IP: net.ParseIP("::1"), Port: 8081},
+	))
+
+	// The certificates are never missing while they are reloaded.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			if err := instance.Reload(); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			found, err := instance.FindCertificatesBy(CertificateQuery{Host: "app.example.org"})
+			g.Expect(err).To(BeNil())
+			g.Expect(found).To(HaveLen(1))
+		}
+	}
+}
+
+func Test_parseFileAddr(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(parseFileAddr("127.0.0.1:80")).To(Equal(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 80}))
+	g.Expect(parseFileAddr("backend:80")).To(Equal(fileHostAddr("backend:80")))
+
+	for _, illegal := range []string{"backend", ":80", "backend:0", "backend:http", "backend:65536"} {
+		_, err := parseFileAddr(illegal)
+		g.Expect(err).To(HaveOccurred(), illegal)
+	}
+}
@@ -0,0 +1,710 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"github.com/echocat/lingress/gateway"
+	"github.com/echocat/lingress/leader"
+	rvalue "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"github.com/echocat/slf4g"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"net/textproto"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+type httpRouteBackend struct {
+	addr      net.Addr
+	endpoints *Endpoints
+	weight    int32
+}
+
+type httpRouteVisit struct {
+	object  metav1.Object
+	route   *gateway.HTTPRoute
+	parents []gateway.RouteParentStatus
+}
+
+func (this *repositoryImplState) initGateway(stop support.Channel) {
+	if this.definitions.HttpRoute == nil {
+		return
+	}
+
+	this.httpRouteStatus = map[string]*httpRouteVisit{}
+	this.httpRouteStatusQueue = newStatusQueue("route", this.writeHttpRouteStatus, this.Logger)
+	this.httpRouteStatusQueue.run(stop)
+
+	this.definitions.GatewayClass.OnElementAdded = this.onGatewayElementAdded
+	this.definitions.GatewayClass.OnElementUpdated = this.onGatewayElementUpdated
+	this.definitions.GatewayClass.OnElementRemoved = this.onGatewayElementRemoved
+
+	this.definitions.Gateway.OnElementAdded = this.onGatewayElementAdded
+	this.definitions.Gateway.OnElementUpdated = this.onGatewayElementUpdated
+	this.definitions.Gateway.OnElementRemoved = this.onGatewayElementRemoved
+
+	this.definitions.HttpRoute.OnElementAdded = this.onHttpRouteElementAdded
+	this.definitions.HttpRoute.OnElementUpdated = this.onHttpRouteElementUpdated
+	this.definitions.HttpRoute.OnElementRemoved = this.onHttpRouteElementRemoved
+
+	// Only the leader writes the status of the HTTPRoutes; so the new leader
+	// has to ensure they are up-to-date.
+	this.subscribeLeadership(leader.Subscriber{
+		OnStartedLeading: func() {
+			if err := this.applyHttpRoutes(this.definitions.HttpRoute.All()...); err != nil {
+				this.Logger.
+					WithError(err).
+					Warn("Cannot update status of HTTPRoutes; ignoring...")
+			}
+		},
+	})
+}
+
+// onGatewayElementAdded is used for GatewayClasses and Gateways; each change
+// of them might change which HTTPRoutes are served (and how).
+func (this *repositoryImplState) onGatewayElementAdded(support.ObjectReference, metav1.Object) error {
+	return this.applyHttpRoutes(this.definitions.HttpRoute.All()...)
+}
+
+func (this *repositoryImplState) onGatewayElementUpdated(support.ObjectReference, metav1.Object, metav1.Object) error {
+	return this.applyHttpRoutes(this.definitions.HttpRoute.All()...)
+}
+
+func (this *repositoryImplState) onGatewayElementRemoved(support.ObjectReference) error {
+	return this.applyHttpRoutes(this.definitions.HttpRoute.All()...)
+}
+
+func (this *repositoryImplState) onHttpRouteElementAdded(_ support.ObjectReference, new metav1.Object) error {
+	return this.applyHttpRoutes(new)
+}
+
+func (this *repositoryImplState) onHttpRouteElementUpdated(_ support.ObjectReference, _, new metav1.Object) error {
+	return this.applyHttpRoutes(new)
+}
+
+func (this *repositoryImplState) onHttpRouteElementRemoved(ref support.ObjectReference) error {
+	this.forgetHttpRouteStatus(ref.ShortString())
+	return this.updateByHostRules(func(target *ByHost) error {
+		if err := target.Remove(PredicateByObjectReference(ref)); err != nil {
+			return fmt.Errorf("cannot remove previous element by source %v: %v", ref, err)
+		}
+		return nil
+	})
+}
+
+func (this *repositoryImplState) applyHttpRoutes(candidates ...metav1.Object) error {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	visits, err := this.visitHttpRoutes(candidates)
+	if err != nil {
+		return err
+	}
+
+	for _, visit := range visits {
+		this.enqueueHttpRouteStatus(visit)
+	}
+	return nil
+}
+
+func (this *repositoryImplState) visitHttpRoutes(candidates []metav1.Object) (visits []httpRouteVisit, err error) {
+	err = this.updateByHostRules(func(target *ByHost) error {
+		visits = make([]httpRouteVisit, 0, len(candidates))
+		for _, candidate := range candidates {
+			ref, err := support.NewObjectReferenceOf(candidate.(support.ObjectReferenceSource))
+			if err != nil {
+				return err
+			}
+			route, err := gateway.HTTPRouteOf(candidate)
+			if err != nil {
+				this.Logger.
+					WithError(err).
+					With("ref", ref).
+					Warn("Cannot read HTTPRoute; ignoring...")
+				continue
+			}
+			parents, err := this.visitHttpRoute(ref, route, target)
+			if err != nil {
+				return err
+			}
+			visits = append(visits, httpRouteVisit{
+				object:  candidate,
+				route:   route,
+				parents: parents,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return visits, nil
+}
+
+// visitHttpRoute registers the rules of the given route (if it is attached to
+// at least one Gateway handled by this instance) and returns the status which
+// should be reported for each of these Gateways.
+func (this *repositoryImplState) visitHttpRoute(ref support.ObjectReference, route *gateway.HTTPRoute, target *ByHost) ([]gateway.RouteParentStatus, error) {
+	if err := target.Remove(PredicateByObjectReference(ref)); err != nil {
+		return nil, err
+	}
+
+	l := this.Logger.
+		With("ref", ref)
+
+	var parents []gateway.RouteParentStatus
+	var hosts []string
+	for _, parentRef := range route.Spec.ParentRefs {
+		listeners, ok, err := this.httpRouteListenersOf(route, parentRef)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		parent := gateway.RouteParentStatus{
+			ParentRef:      parentRef,
+			ControllerName: this.settings.Gateway.ControllerName,
+		}
+		if len(listeners) == 0 {
+			parent.Conditions = append(parent.Conditions, newHttpRouteCondition(route, gateway.ConditionAccepted, false, gateway.ReasonNotAllowed, "No listener of the Gateway allows this route."))
+		} else if parentHosts := httpRouteHostnames(listeners, route.Spec.Hostnames); len(parentHosts) == 0 {
+			parent.Conditions = append(parent.Conditions, newHttpRouteCondition(route, gateway.ConditionAccepted, false, gateway.ReasonNoMatchingListener, "No hostname of this route matches a listener of the Gateway."))
+		} else {
+			for _, host := range parentHosts {
+				if !slices.Contains(hosts, host) {
+					hosts = append(hosts, host)
+				}
+			}
+		}
+		parents = append(parents, parent)
+	}
+
+	if len(parents) == 0 {
+		return nil, nil
+	}
+
+	accepted, resolvedRefs, err := this.visitHttpRouteRules(ref, route, hosts, target, l)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, parent := range parents {
+		if len(parent.Conditions) == 0 {
+			parents[i].Conditions = append(parent.Conditions, accepted)
+		}
+		parents[i].Conditions = append(parents[i].Conditions, resolvedRefs)
+	}
+
+	return parents, nil
+}
+
+func (this *repositoryImplState) visitHttpRouteRules(ref support.ObjectReference, route *gateway.HTTPRoute, plainHosts []string, target *ByHost, l log.Logger) (accepted, resolvedRefs metav1.Condition, err error) {
+	accepted = newHttpRouteCondition(route, gateway.ConditionAccepted, true, gateway.ReasonAccepted, "Route is accepted.")
+	resolvedRefs = newHttpRouteCondition(route, gateway.ConditionResolvedRefs, true, gateway.ReasonResolvedRefs, "All references are resolved.")
+
+	hosts := make([]value.WildcardSupportingFqdn, 0, len(plainHosts))
+	for _, plainHost := range plainHosts {
+		var host value.WildcardSupportingFqdn
+		if err := host.Set(normalizeHostname(plainHost)); err != nil {
+			l.With("host", plainHost).WithError(err).Warn("Illegal host in HTTPRoute; ignoring...")
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+
+	for ri, rule := range route.Spec.Rules {
+		l := l.With("rule", ri)
+
+		var backends []httpRouteBackend
+		var totalWeight int32
+		for _, backendRef := range rule.BackendRefs {
+			l := l.With("backend", backendRef.Name)
+			addr, endpoints, reason, err := this.httpRouteToBackend(ref, route, backendRef, l)
+			if err != nil {
+				return accepted, resolvedRefs, err
+			}
+			if reason != "" {
+				resolvedRefs = newHttpRouteCondition(route, gateway.ConditionResolvedRefs, false, reason, fmt.Sprintf("Backend %s cannot be resolved.", backendRef.Name))
+				continue
+			}
+			if weight := backendRef.WeightOrDefault(); weight > 0 {
+				backends = append(backends, httpRouteBackend{addr, endpoints, weight})
+				totalWeight += weight
+			}
+		}
+
+		if len(backends) == 0 {
+			if !slices.ContainsFunc(rule.Filters, func(candidate gateway.HTTPRouteFilter) bool {
+				return candidate.Type == gateway.FilterRequestRedirect
+			}) {
+				l.Warn("There is no usable backend configured for rule; ignoring...")
+				continue
+			}
+			// Redirects do not need any backend.
+			backends = []httpRouteBackend{{}}
+		}
+
+		matches := rule.Matches
+		if len(matches) == 0 {
+			matches = []gateway.HTTPRouteMatch{{}}
+		}
+
+		for _, match := range matches {
+			path, pathType, err := httpRoutePathOf(match.Path)
+			if err != nil {
+				l.WithError(err).Warn("Unsupported path match in HTTPRoute; ignoring...")
+				accepted = newHttpRouteCondition(route, gateway.ConditionAccepted, false, gateway.ReasonUnsupportedValue, err.Error())
+				continue
+			}
+
+			for bi, backend := range backends {
+				options, err := this.newHttpRouteOptions(ref, route, rule, match)
+				if err != nil {
+					l.WithError(err).Warn("Unsupported configuration in HTTPRoute; ignoring...")
+					accepted = newHttpRouteCondition(route, gateway.ConditionAccepted, false, gateway.ReasonUnsupportedValue, err.Error())
+					break
+				}
+				if bi > 0 {
+					options[optionsCanaryKey] = &OptionsCanary{
+						Enabled:     value.True(),
+						Weight:      value.NewUint32(uint32(backend.weight)),
+						WeightTotal: value.NewUint32(uint32(totalWeight)),
+					}
+				}
+				for _, host := range hosts {
+					r := NewRule(host, path, pathType, ref, backend.addr, backend.endpoints, options)
+					if err := target.Put(r); err != nil {
+						return accepted, resolvedRefs, err
+					}
+					l.With("host", host).
+						With("path", path).
+						Debug("Element registered.")
+				}
+			}
+		}
+	}
+
+	return accepted, resolvedRefs, nil
+}
+
+// httpRouteListenersOf returns the listeners which the given route could be
+// attached to by the given parentRef. If the referenced Gateway is not handled
+// by this instance, ok will be false.
+func (this *repositoryImplState) httpRouteListenersOf(route *gateway.HTTPRoute, parentRef gateway.ParentReference) (result []gateway.Listener, ok bool, err error) {
+	if !parentRef.IsGateway() {
+		return nil, false, nil
+	}
+	gatewayNamespace := parentRef.NamespaceOr(route.Namespace)
+	g, err := this.definitions.Gateway.Get(gatewayNamespace + "/" + parentRef.Name)
+	if err != nil || g == nil {
+		return nil, false, err
+	}
+	class, err := this.definitions.GatewayClass.Get(g.Spec.GatewayClassName)
+	if err != nil || class == nil {
+		return nil, false, err
+	}
+	if class.Spec.ControllerName != this.settings.Gateway.ControllerName {
+		return nil, false, nil
+	}
+
+	for _, listener := range g.Spec.Listeners {
+		if !listener.IsHttp() {
+			continue
+		}
+		if v := parentRef.SectionName; v != nil && *v != listener.Name {
+			continue
+		}
+		if v := parentRef.Port; v != nil && *v != listener.Port {
+			continue
+		}
+		if !listener.AllowsNamespace(gatewayNamespace, route.Namespace) {
+			continue
+		}
+		result = append(result, listener)
+	}
+	return result, true, nil
+}
+
+// httpRouteToBackend resolves the given backendRef. If it cannot be resolved,
+// the reason is returned.
+func (this *repositoryImplState) httpRouteToBackend(ref support.ObjectReference, route *gateway.HTTPRoute, backendRef gateway.HTTPBackendRef, l log.Logger) (net.Addr, *Endpoints, string, error) {
+	if !backendRef.IsService() {
+		l.Warn("Only services are supported as backend of HTTPRoutes; ignoring...")
+		return nil, nil, gateway.ReasonInvalidKind, nil
+	}
+	if v := backendRef.Namespace; v != nil && *v != route.Namespace {
+		l.Warn("Services of other namespaces are not supported as backend of HTTPRoutes; ignoring...")
+		return nil, nil, gateway.ReasonRefNotPermitted, nil
+	}
+	if backendRef.Port == nil {
+		l.Warn("There is no port configured for backend; ignoring...")
+		return nil, nil, gateway.ReasonBackendNotFound, nil
+	}
+
+	addr, endpoints, err := this.ingressToBackend(ref, &networkingv1.IngressBackend{
+		Service: &networkingv1.IngressServiceBackend{
+			Name: backendRef.Name,
+			Port: networkingv1.ServiceBackendPort{Number: *backendRef.Port},
+		},
+	}, nil, l)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if addr == nil {
+		return nil, nil, gateway.ReasonBackendNotFound, nil
+	}
+	return addr, endpoints, "", nil
+}
+
+func (this *repositoryImplState) newHttpRouteOptions(ref support.ObjectReference, route *gateway.HTTPRoute, rule gateway.HTTPRouteRule, match gateway.HTTPRouteMatch) (Options, error) {
+	result := this.OptionsFactory()
+	if err := result.Set(route.GetAnnotations()); err != nil {
+		return nil, err
+	}
+	this.applyBasicAuthCredentials(ref, result)
+	this.applyClientCertificateAuthorities(ref, result)
+
+	if err := applyHttpRouteFilters(result, rule.Filters); err != nil {
+		return nil, err
+	}
+	if err := applyHttpRouteMatch(result, match); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// enqueueHttpRouteStatus queues the status of the given visit for
+// writeHttpRouteStatus. It replaces a queued status of the same HTTPRoute
+// which is not written yet.
+func (this *repositoryImplState) enqueueHttpRouteStatus(visit httpRouteVisit) {
+	if this.httpRouteStatusQueue == nil || !this.isLeader() {
+		return
+	}
+	key := visit.route.Namespace + "/" + visit.route.Name
+
+	this.httpRouteStatusMutex.Lock()
+	this.httpRouteStatus[key] = &visit
+	this.httpRouteStatusMutex.Unlock()
+
+	this.httpRouteStatusQueue.enqueue(key)
+}
+
+func (this *repositoryImplState) forgetHttpRouteStatus(key string) {
+	if this.httpRouteStatusQueue == nil {
+		return
+	}
+
+	this.httpRouteStatusMutex.Lock()
+	defer this.httpRouteStatusMutex.Unlock()
+
+	delete(this.httpRouteStatus, key)
+}
+
+// writeHttpRouteStatus writes the latest queued status of the HTTPRoute with
+// the given key. It stays queued if it cannot be written; unless it was
+// replaced in the meantime.
+func (this *repositoryImplState) writeHttpRouteStatus(ctx context.Context, key string) error {
+	this.httpRouteStatusMutex.Lock()
+	visit, ok := this.httpRouteStatus[key]
+	this.httpRouteStatusMutex.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if err := this.updateHttpRouteStatus(ctx, *visit); err != nil {
+		return err
+	}
+
+	this.httpRouteStatusMutex.Lock()
+	defer this.httpRouteStatusMutex.Unlock()
+	if this.httpRouteStatus[key] == visit {
+		delete(this.httpRouteStatus, key)
+	}
+	return nil
+}
+
+func (this *repositoryImplState) updateHttpRouteStatus(ctx context.Context, visit httpRouteVisit) error {
+	if !this.isLeader() {
+		return nil
+	}
+
+	controllerName := this.settings.Gateway.ControllerName
+
+	var result []gateway.RouteParentStatus
+	var existingOfOurs int
+	for _, existing := range visit.route.Status.Parents {
+		if existing.ControllerName != controllerName {
+			result = append(result, existing)
+		} else {
+			existingOfOurs++
+		}
+	}
+
+	changed := existingOfOurs != len(visit.parents)
+	for _, parent := range visit.parents {
+		var conditions []metav1.Condition
+		if i := slices.IndexFunc(visit.route.Status.Parents, func(candidate gateway.RouteParentStatus) bool {
+			return candidate.ControllerName == controllerName && reflect.DeepEqual(candidate.ParentRef, parent.ParentRef)
+		}); i >= 0 {
+			conditions = slices.Clone(visit.route.Status.Parents[i].Conditions)
+		} else {
+			changed = true
+		}
+		for _, condition := range parent.Conditions {
+			if meta.SetStatusCondition(&conditions, condition) {
+				changed = true
+			}
+		}
+		parent.Conditions = conditions
+		result = append(result, parent)
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return this.definitions.HttpRoute.UpdateStatus(ctx, visit.object, result)
+}
+
+func newHttpRouteCondition(route *gateway.HTTPRoute, conditionType string, status bool, reason, message string) metav1.Condition {
+	result := metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: route.Generation,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+	if status {
+		result.Status = metav1.ConditionTrue
+	}
+	return result
+}
+
+// httpRouteHostnames returns the hosts which are served by a route with the
+// given hostnames if it is attached to the given listeners. An empty host
+// represents all hosts.
+func httpRouteHostnames(listeners []gateway.Listener, hostnames []string) []string {
+	var result []string
+	add := func(v string) {
+		if !slices.Contains(result, v) {
+			result = append(result, v)
+		}
+	}
+	for _, listener := range listeners {
+		var listenerHost string
+		if v := listener.Hostname; v != nil {
+			listenerHost = *v
+		}
+		if len(hostnames) == 0 {
+			add(listenerHost)
+			continue
+		}
+		for _, hostname := range hostnames {
+			if listenerHost == "" || hostnameMatches(listenerHost, hostname) {
+				add(hostname)
+			} else if hostnameMatches(hostname, listenerHost) {
+				add(listenerHost)
+			}
+		}
+	}
+	return result
+}
+
+// hostnameMatches reports whether pattern (which might be a wildcard like
+// *.example.org) covers the given host.
+func hostnameMatches(pattern, host string) bool {
+	if pattern == host {
+		return true
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok && strings.HasPrefix(suffix, ".") {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return false
+}
+
+func httpRoutePathOf(in *gateway.HTTPPathMatch) ([]string, PathType, error) {
+	var pathType PathType
+	switch in.TypeOrDefault() {
+	case gateway.PathMatchExact:
+		pathType = PathTypeExact
+	case gateway.PathMatchPathPrefix:
+		pathType = PathTypePrefix
+	default:
+		return nil, 0, fmt.Errorf("unsupported path match type: %s", in.TypeOrDefault())
+	}
+	path, err := ParsePath(in.ValueOrDefault(), false)
+	if err != nil {
+		return nil, 0, err
+	}
+	return path, pathType, nil
+}
+
+func applyHttpRouteMatch(options Options, in gateway.HTTPRouteMatch) error {
+	opts, ok := options[optionsMatchKey].(*OptionsMatch)
+	if !ok {
+		return nil
+	}
+	if v := in.Method; v != nil {
+		method, err := rvalue.ParseMethod(*v)
+		if err != nil {
+			return err
+		}
+		opts.Methods = append(opts.Methods, method)
+	}
+	for _, header := range in.Headers {
+		candidate, err := newValueMatch(textproto.CanonicalMIMEHeaderKey(header.Name), header.Value, header.Type)
+		if err != nil {
+			return err
+		}
+		opts.Headers = append(opts.Headers, candidate)
+	}
+	for _, query := range in.QueryParams {
+		candidate, err := newValueMatch(query.Name, query.Value, query.Type)
+		if err != nil {
+			return err
+		}
+		opts.Queries = append(opts.Queries, candidate)
+	}
+	return nil
+}
+
+func newValueMatch(name, v string, matchType *string) (ValueMatch, error) {
+	result := ValueMatch{Name: name, Value: v}
+	if matchType == nil || *matchType == gateway.MatchExact {
+		return result, nil
+	}
+	if *matchType != gateway.MatchRegularExpression {
+		return ValueMatch{}, fmt.Errorf("unsupported match type for %s: %s", name, *matchType)
+	}
+	pattern, err := regexp.Compile(v)
+	if err != nil {
+		return ValueMatch{}, fmt.Errorf("illegal regular expression for %s: %w", name, err)
+	}
+	result.Pattern = pattern
+	return result, nil
+}
+
+func applyHttpRouteFilters(options Options, filters []gateway.HTTPRouteFilter) error {
+	for _, filter := range filters {
+		switch filter.Type {
+		case gateway.FilterRequestHeaderModifier:
+			if opts, ok := options[optionsCustomerHeadersKey].(*OptionsCustomHeaders); ok {
+				opts.RequestHeaders = append(opts.RequestHeaders, httpHeaderFilterToHeaders(filter.RequestHeaderModifier)...)
+			}
+		case gateway.FilterResponseHeaderModifier:
+			if opts, ok := options[optionsCustomerHeadersKey].(*OptionsCustomHeaders); ok {
+				opts.ResponseHeaders = append(opts.ResponseHeaders, httpHeaderFilterToHeaders(filter.ResponseHeaderModifier)...)
+			}
+		case gateway.FilterRequestRedirect:
+			if err := applyHttpRouteRedirect(options, filter.RequestRedirect); err != nil {
+				return err
+			}
+		case gateway.FilterURLRewrite:
+			if err := applyHttpRouteRewrite(options, filter.URLRewrite); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported filter type: %s", filter.Type)
+		}
+	}
+	return nil
+}
+
+func httpHeaderFilterToHeaders(in *gateway.HTTPHeaderFilter) (result value.Headers) {
+	if in == nil {
+		return nil
+	}
+	for _, v := range in.Set {
+		result = append(result, value.Header{Key: textproto.CanonicalMIMEHeaderKey(v.Name), Value: v.Value, Forced: true})
+	}
+	for _, v := range in.Add {
+		result = append(result, value.Header{Key: textproto.CanonicalMIMEHeaderKey(v.Name), Value: v.Value, Add: true})
+	}
+	for _, v := range in.Remove {
+		result = append(result, value.Header{Key: textproto.CanonicalMIMEHeaderKey(v), Del: true})
+	}
+	return result
+}
+
+func applyHttpRouteRedirect(options Options, in *gateway.HTTPRequestRedirectFilter) error {
+	opts, ok := options[optionsRedirectKey].(*OptionsRedirect)
+	if !ok || in == nil {
+		return nil
+	}
+	if v := in.Scheme; v != nil {
+		opts.Scheme = *v
+	}
+	if v := in.Hostname; v != nil {
+		opts.Host = *v
+	}
+	if v := in.Port; v != nil {
+		opts.Port = value.NewUint32(uint32(*v))
+	}
+	opts.StatusCode = value.NewUint32(uint32(in.StatusCodeOrDefault()))
+	if v := in.Path; v != nil {
+		full, prefix, err := httpPathModifierToPaths(v)
+		if err != nil {
+			return err
+		}
+		opts.Path, opts.PathPrefix = full, prefix
+	}
+	return nil
+}
+
+func applyHttpRouteRewrite(options Options, in *gateway.HTTPURLRewriteFilter) error {
+	opts, ok := options[optionsRewriteKey].(*OptionsRewrite)
+	if !ok || in == nil {
+		return nil
+	}
+	if v := in.Hostname; v != nil {
+		opts.Host = *v
+	}
+	if v := in.Path; v != nil {
+		full, prefix, err := httpPathModifierToPaths(v)
+		if err != nil {
+			return err
+		}
+		opts.Path = full
+		if prefix != nil {
+			if prefixOpts, ok := options[optionsPrefixKey].(*OptionsPrefix); ok {
+				prefixOpts.StripRulePathPrefix = value.True()
+				prefixOpts.PathPrefix = prefix
+			}
+		}
+	}
+	return nil
+}
+
+func httpPathModifierToPaths(in *gateway.HTTPPathModifier) (full, prefix []string, err error) {
+	parse := func(v *string) ([]string, error) {
+		if v == nil {
+			return nil, fmt.Errorf("path modifier of type %s without value", in.Type)
+		}
+		result, err := ParsePath(*v, false)
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			result = []string{}
+		}
+		return result, nil
+	}
+	switch in.Type {
+	case gateway.FullPathHTTPPathModifier:
+		full, err = parse(in.ReplaceFullPath)
+	case gateway.PrefixMatchHTTPPathModifier:
+		prefix, err = parse(in.ReplacePrefixMatch)
+	default:
+		err = fmt.Errorf("unsupported path modifier type: %s", in.Type)
+	}
+	return
+}
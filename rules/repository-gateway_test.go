@@ -0,0 +1,327 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/gateway"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func Test_httpRouteHostnames(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	host := func(v string) *string { return &v }
+	all := []gateway.Listener{{Name: "all"}}
+	wildcard := []gateway.Listener{{Name: "wildcard", Hostname: host("*.example.org")}}
+
+	g.Expect(httpRouteHostnames(all, nil)).To(Equal([]string{""}))
+	g.Expect(httpRouteHostnames(all, []string{"foo.example.org"})).To(Equal([]string{"foo.example.org"}))
+	g.Expect(httpRouteHostnames(wildcard, nil)).To(Equal([]string{"*.example.org"}))
+	g.Expect(httpRouteHostnames(wildcard, []string{"foo.example.org", "foo.example.com"})).To(Equal([]string{"foo.example.org"}))
+	g.Expect(httpRouteHostnames([]gateway.Listener{{Hostname: host("foo.example.org")}}, []string{"*.example.org"})).To(Equal([]string{"foo.example.org"}))
+	g.Expect(httpRouteHostnames(wildcard, []string{"example.org"})).To(BeEmpty())
+}
+
+func Test_applyHttpRouteFilters(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	prefix := "/v2"
+	options := DefaultOptionsFactory()
+	g.Expect(applyHttpRouteFilters(options, []gateway.HTTPRouteFilter{{
+		Type: gateway.FilterRequestHeaderModifier,
+		RequestHeaderModifier: &gateway.HTTPHeaderFilter{
+			Set:    []gateway.HTTPHeader{{Name: "x-foo", Value: "bar"}},
+			Remove: []string{"x-removed"},
+		},
+	}, {
+		Type: gateway.FilterURLRewrite,
+		URLRewrite: &gateway.HTTPURLRewriteFilter{
+			Path: &gateway.HTTPPathModifier{Type: gateway.PrefixMatchHTTPPathModifier, ReplacePrefixMatch: &prefix},
+		},
+	}})).To(Succeed())
+
+	g.Expect(options[optionsCustomerHeadersKey].(*OptionsCustomHeaders).RequestHeaders.String()).To(Equal("!X-Foo:bar\n-X-Removed"))
+	g.Expect(options[optionsPrefixKey].(*OptionsPrefix).StripRulePathPrefix.GetOr(false)).To(BeTrue())
+	g.Expect(options[optionsPrefixKey].(*OptionsPrefix).PathPrefix).To(Equal([]string{"v2"}))
+
+	g.Expect(applyHttpRouteFilters(DefaultOptionsFactory(), []gateway.HTTPRouteFilter{{Type: "ExtensionRef"}})).
+		To(MatchError("unsupported filter type: ExtensionRef"))
+}
+
+func Test_applyHttpRouteMatch(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	method, regularExpression := "POST", gateway.MatchRegularExpression
+	options := DefaultOptionsFactory()
+	g.Expect(applyHttpRouteMatch(options, gateway.HTTPRouteMatch{
+		Method:      &method,
+		Headers:     []gateway.HTTPHeaderMatch{{Name: "x-version", Value: "v2"}},
+		QueryParams: []gateway.HTTPQueryParamMatch{{Name: "id", Value: "^[0-9]+$", Type: &regularExpression}},
+	})).To(Succeed())
+	opts := options[optionsMatchKey].(*OptionsMatch)
+	g.Expect(opts.Specificity()).To(Equal(3))
+
+	req := &http.Request{Method: "POST", Header: http.Header{"X-Version": {"v2"}}, URL: &url.URL{RawQuery: "id=123"}}
+	g.Expect(opts.Matches(req)).To(BeTrue())
+
+	req.URL.RawQuery = "id=abc"
+	g.Expect(opts.Matches(req)).To(BeFalse())
+}
+
+func Test_updateByHostRules_serializes_all_writers(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	repository := &KubernetesBasedRepository{Logger: log.GetRootLogger()}
+	repository.byHostRules.Store(NewByHost(repository.onRuleAdded, repository.onRuleRemoved))
+	state := &repositoryImplState{KubernetesBasedRepository: repository}
+	state.initiated.Store(true)
+
+	// Ingresses and HTTPRoutes are reported by different informers; none of
+	// their concurrent updates is allowed to get lost.
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			host := value.WildcardSupportingFqdn(fmt.Sprintf("app%d.example.org", i))
+			source := support.NewObjectReference("", "Ingress", "foo", string(host))
+			g.Expect(state.updateByHostRules(func(target *ByHost) error {
+				return target.Put(NewRule(host, nil, PathTypePrefix, source, nil, nil, DefaultOptionsFactory()))
+			})).To(Succeed())
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 50; i++ {
+		g.Expect(repository.HasHost(value.Fqdn(fmt.Sprintf("app%d.example.org", i)))).To(BeTrue())
+	}
+}
+
+func Test_visitHttpRoute_weightedBackends(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	state := newHttpRouteTestState(t)
+	route := newTestHttpRoute([]string{"app.example.org", "app.example.com"},
+		gateway.HTTPBackendRef{Name: "a", Port: int32Of(80), Weight: int32Of(3)},
+		gateway.HTTPBackendRef{Name: "b", Port: int32Of(80), Weight: int32Of(1)},
+		gateway.HTTPBackendRef{Name: "c", Port: int32Of(80), Weight: int32Of(0)},
+	)
+
+	target := NewByHost(nil, nil)
+	parents, err := state.visitHttpRoute(testHttpRouteReference, route, target)
+	g.Expect(err).To(BeNil())
+	g.Expect(parents).To(HaveLen(1))
+	g.Expect(parents[0].ControllerName).To(Equal(settings.DefaultGatewayControllerName))
+	g.Expect(conditionsOf(parents[0])).To(Equal(map[string]string{
+		gateway.ConditionAccepted:     gateway.ReasonAccepted,
+		gateway.ConditionResolvedRefs: gateway.ReasonResolvedRefs,
+	}))
+
+	var actual []Rule
+	g.Expect(target.All(func(candidate Rule) error {
+		actual = append(actual, candidate)
+		return nil
+	})).To(Succeed())
+	g.Expect(actual).To(HaveLen(2))
+
+	// Only the hostname which is covered by the listener is served; the first
+	// backend is the primary one, the others are canaries with their share of
+	// the total weight. Backends with weight 0 are not served at all.
+	g.Expect(actual[0].Host()).To(Equal(value.WildcardSupportingFqdn("app.example.org")))
+	g.Expect(actual[0].Backend().String()).To(Equal("10.0.0.1:80"))
+	g.Expect(actual[0].Options()[optionsCanaryKey].(*OptionsCanary).Enabled.GetOr(false)).To(BeFalse())
+	g.Expect(actual[1].Host()).To(Equal(value.WildcardSupportingFqdn("app.example.org")))
+	g.Expect(actual[1].Backend().String()).To(Equal("10.0.0.2:80"))
+	canary := actual[1].Options()[optionsCanaryKey].(*OptionsCanary)
+	g.Expect(canary.Enabled.GetOr(false)).To(BeTrue())
+	g.Expect(canary.Weight.Get()).To(Equal(uint32(1)))
+	g.Expect(canary.WeightTotal.Get()).To(Equal(uint32(4)))
+}
+
+func Test_visitHttpRoute_listenerHostnames(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	state := newHttpRouteTestState(t)
+
+	target := NewByHost(nil, nil)
+	parents, err := state.visitHttpRoute(testHttpRouteReference, newTestHttpRoute(nil,
+		gateway.HTTPBackendRef{Name: "a", Port: int32Of(80)},
+	), target)
+	g.Expect(err).To(BeNil())
+	g.Expect(conditionsOf(parents[0])[gateway.ConditionAccepted]).To(Equal(gateway.ReasonAccepted))
+	// Without hostnames the route serves the ones of the listener.
+	g.Expect(rulesOfExactHost(t, target, "*.example.org")).To(HaveLen(1))
+
+	target = NewByHost(nil, nil)
+	parents, err = state.visitHttpRoute(testHttpRouteReference, newTestHttpRoute([]string{"app.example.com"},
+		gateway.HTTPBackendRef{Name: "a", Port: int32Of(80)},
+	), target)
+	g.Expect(err).To(BeNil())
+	g.Expect(conditionsOf(parents[0])[gateway.ConditionAccepted]).To(Equal(gateway.ReasonNoMatchingListener))
+	g.Expect(rulesOfExactHost(t, target, "*.example.org")).To(BeEmpty())
+	g.Expect(rulesOfExactHost(t, target, "app.example.com")).To(BeEmpty())
+}
+
+func Test_visitHttpRoute_conditions(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	state := newHttpRouteTestState(t)
+
+	parents, err := state.visitHttpRoute(testHttpRouteReference, newTestHttpRoute([]string{"app.example.org"},
+		gateway.HTTPBackendRef{Name: "a", Port: int32Of(80)},
+		gateway.HTTPBackendRef{Name: "missing", Port: int32Of(80)},
+	), NewByHost(nil, nil))
+	g.Expect(err).To(BeNil())
+	g.Expect(conditionsOf(parents[0])).To(Equal(map[string]string{
+		gateway.ConditionAccepted:     gateway.ReasonAccepted,
+		gateway.ConditionResolvedRefs: gateway.ReasonBackendNotFound,
+	}))
+
+	otherNamespace := "bar"
+	parents, err = state.visitHttpRoute(testHttpRouteReference, newTestHttpRoute([]string{"app.example.org"},
+		gateway.HTTPBackendRef{Name: "a", Port: int32Of(80), Namespace: &otherNamespace},
+	), NewByHost(nil, nil))
+	g.Expect(err).To(BeNil())
+	g.Expect(conditionsOf(parents[0])[gateway.ConditionResolvedRefs]).To(Equal(gateway.ReasonRefNotPermitted))
+
+	route := newTestHttpRoute([]string{"app.example.org"}, gateway.HTTPBackendRef{Name: "a", Port: int32Of(80)})
+	route.Spec.ParentRefs = []gateway.ParentReference{{Name: "foreign"}}
+	parents, err = state.visitHttpRoute(testHttpRouteReference, route, NewByHost(nil, nil))
+	g.Expect(err).To(BeNil())
+	g.Expect(parents).To(BeEmpty())
+}
+
+var testHttpRouteReference = support.NewObjectReference(gateway.Group+"/v1", "HTTPRoute", "foo", "app")
+
+func newTestHttpRoute(hostnames []string, backendRefs ...gateway.HTTPBackendRef) *gateway.HTTPRoute {
+	return &gateway.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "app", Generation: 1},
+		Spec: gateway.HTTPRouteSpec{
+			ParentRefs: []gateway.ParentReference{{Name: "lingress"}},
+			Hostnames:  hostnames,
+			Rules:      []gateway.HTTPRouteRule{{BackendRefs: backendRefs}},
+		},
+	}
+}
+
+// newHttpRouteTestState creates a state which knows the Gateway foo/lingress
+// (serving *.example.org) of this controller, the Gateway foo/foreign of
+// another controller and the Services foo/a and foo/b.
+func newHttpRouteTestState(t *testing.T) *repositoryImplState {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	t.Cleanup(stop.Broadcast)
+
+	hostname := "*.example.org"
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		gateway.GatewayClassesResource: "GatewayClassList",
+		gateway.GatewaysResource:       "GatewayList",
+	})
+	// The resources are given explicitly as the ones guessed by the kinds
+	// (like "gatewaies") do not match.
+	create := func(resource schema.GroupVersionResource, kind string, in any) {
+		object := toUnstructured(t, kind, in)
+		g.Expect(dynamicClient.Tracker().Create(resource, object, object.GetNamespace())).To(Succeed())
+	}
+	create(gateway.GatewayClassesResource, "GatewayClass", &gateway.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "lingress"},
+		Spec:       gateway.GatewayClassSpec{ControllerName: settings.DefaultGatewayControllerName},
+	})
+	create(gateway.GatewayClassesResource, "GatewayClass", &gateway.GatewayClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "foreign"},
+		Spec:       gateway.GatewayClassSpec{ControllerName: "example.org/foreign"},
+	})
+	create(gateway.GatewaysResource, "Gateway", &gateway.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "lingress"},
+		Spec: gateway.GatewaySpec{GatewayClassName: "lingress", Listeners: []gateway.Listener{{
+			Name: "http", Hostname: &hostname, Port: 80, Protocol: gateway.ProtocolHTTP,
+		}}},
+	})
+	create(gateway.GatewaysResource, "Gateway", &gateway.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "foreign"},
+		Spec: gateway.GatewaySpec{GatewayClassName: "foreign", Listeners: []gateway.Listener{{
+			Name: "http", Port: 80, Protocol: gateway.ProtocolHTTP,
+		}}},
+	})
+	client := fake.NewClientset(
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "a"}, Spec: v1.ServiceSpec{ClusterIP: "10.0.0.1"}},
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "b"}, Spec: v1.ServiceSpec{ClusterIP: "10.0.0.2"}},
+		&v1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "c"}, Spec: v1.ServiceSpec{ClusterIP: "10.0.0.3"}},
+	)
+
+	s := settings.MustNew()
+	s.Discovery.Endpoints = value.False()
+	definitions := &definition.Definitions{}
+	var err error
+	definitions.GatewayClass, err = definition.NewGatewayClass(dynamicClient, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.Gateway, err = definition.NewGateway(dynamicClient, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.Service, err = definition.NewService(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.EndpointSlice, err = definition.NewEndpointSlice(&s, client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(definitions.GatewayClass.Init(stop)).To(Succeed())
+	g.Expect(definitions.Gateway.Init(stop)).To(Succeed())
+	g.Expect(definitions.Service.Init(stop)).To(Succeed())
+
+	return &repositoryImplState{
+		KubernetesBasedRepository: &KubernetesBasedRepository{
+			settings:       &s,
+			Logger:         log.GetRootLogger(),
+			OptionsFactory: DefaultOptionsFactory,
+		},
+		definitions: definitions,
+		endpoints:   map[string]servicePortEndpoints{},
+	}
+}
+
+func toUnstructured(t *testing.T, kind string, in any) *unstructured.Unstructured {
+	g := NewGomegaWithT(t)
+
+	plain, err := runtime.DefaultUnstructuredConverter.ToUnstructured(in)
+	g.Expect(err).To(BeNil())
+	result := &unstructured.Unstructured{Object: plain}
+	result.SetAPIVersion(gateway.Group + "/v1")
+	result.SetKind(kind)
+	return result
+}
+
+func rulesOfExactHost(t *testing.T, target *ByHost, host value.WildcardSupportingFqdn) []Rule {
+	g := NewGomegaWithT(t)
+
+	var result []Rule
+	g.Expect(target.AllOfExactHost(host, func(candidate Rule) error {
+		result = append(result, candidate)
+		return nil
+	})).To(Succeed())
+	return result
+}
+
+func conditionsOf(in gateway.RouteParentStatus) map[string]string {
+	result := map[string]string{}
+	for _, condition := range in.Conditions {
+		result[condition.Type] = condition.Reason
+	}
+	return result
+}
+
+func int32Of(v int32) *int32 {
+	return &v
+}
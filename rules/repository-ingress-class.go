@@ -0,0 +1,130 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/support"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+)
+
+const (
+	// annotationIngressClass is the deprecated predecessor of
+	// spec.ingressClassName which is still used by older charts.
+	annotationIngressClass = "kubernetes.io/ingress.class"
+)
+
+// onIngressClassElementAdded is used for all changes of IngressClasses; each
+// of them might change which Ingresses are served.
+func (this *repositoryImplState) onIngressClassElementAdded(support.ObjectReference, metav1.Object) error {
+	return this.revisitIngresses()
+}
+
+// onIngressClassElementUpdated only revisits the Ingresses if something
+// changed which decides if an Ingress is served; so periodic resyncs are
+// ignored.
+func (this *repositoryImplState) onIngressClassElementUpdated(_ support.ObjectReference, old, new metav1.Object) error {
+	if o, ok := old.(*networkingv1.IngressClass); ok {
+		n := new.(*networkingv1.IngressClass)
+		if reflect.DeepEqual(o.Spec, n.Spec) &&
+			o.Annotations[definition.AnnotationIsDefaultIngressClass] == n.Annotations[definition.AnnotationIsDefaultIngressClass] {
+			return nil
+		}
+	}
+	return this.revisitIngresses()
+}
+
+func (this *repositoryImplState) onIngressClassElementRemoved(support.ObjectReference) error {
+	return this.revisitIngresses()
+}
+
+// revisitIngresses evaluates all Ingresses again by one single update of the
+// served rules. An Ingress which cannot be visited does not prevent the others
+// from being updated.
+func (this *repositoryImplState) revisitIngresses() error {
+	ingresses := this.definitions.Ingress.All()
+	var errs []error
+	if err := this.updateByHostRules(func(target *ByHost) error {
+		previous := this.ByHostRules()
+		for _, candidate := range ingresses {
+			ref, err := support.NewObjectReferenceOf(candidate)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if _, err := this.applyIngress(ref, candidate, target); err != nil {
+				errs = append(errs, fmt.Errorf("cannot revisit %v: %w", ref, err))
+				if target != previous {
+					errs = append(errs, restoreRulesOf(ref, previous, target))
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, candidate := range ingresses {
+		this.enqueueIngressStatus(candidate)
+	}
+	return errors.Join(errs...)
+}
+
+// restoreRulesOf replaces the rules of the given source inside target with the
+// ones of previous.
+func restoreRulesOf(ref support.ObjectReference, previous, target *ByHost) error {
+	if err := target.Remove(PredicateByObjectReference(ref)); err != nil {
+		return err
+	}
+	return previous.All(func(candidate Rule) error {
+		if !candidate.Source().Equals(ref) {
+			return nil
+		}
+		return target.Put(candidate)
+	})
+}
+
+func (this *repositoryImplState) matchesIngressClass(what *networkingv1.Ingress) bool {
+	requested := requestedIngressClassOf(what)
+	classes := this.settings.Ingress.GetClasses()
+	controller := this.settings.Ingress.Controller
+	if requested == nil {
+		for _, candidate := range classes {
+			if candidate == "" || candidate == "*" {
+				return true
+			}
+		}
+		return controller != "" && this.definitions.IngressClass.HasDefaultOwnedBy(controller)
+	}
+	for _, candidate := range classes {
+		if candidate == *requested || candidate == "*" {
+			return true
+		}
+	}
+	if controller == "" {
+		return false
+	}
+	owned, err := this.definitions.IngressClass.IsOwnedBy(*requested, controller)
+	if err != nil {
+		this.Logger.
+			WithError(err).
+			With("ingressClass", *requested).
+			Warn("Cannot evaluate ingress class; ignoring...")
+		return false
+	}
+	return owned
+}
+
+// requestedIngressClassOf returns the class the given Ingress requests either
+// by spec.ingressClassName or by the deprecated annotation.
+func requestedIngressClassOf(what *networkingv1.Ingress) *string {
+	if v := what.Spec.IngressClassName; v != nil {
+		return v
+	}
+	if v, ok := what.Annotations[annotationIngressClass]; ok {
+		return &v
+	}
+	return nil
+}
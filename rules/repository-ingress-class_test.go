@@ -0,0 +1,152 @@
+package rules
+
+import (
+	"context"
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func Test_matchesIngressClass(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	ingressClasses, err := definition.NewIngressClass(fake.NewClientset(&networkingv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "owned"},
+		Spec:       networkingv1.IngressClassSpec{Controller: settings.DefaultIngressController},
+	}, &networkingv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "foreign"},
+		Spec:       networkingv1.IngressClassSpec{Controller: "example.org/other"},
+	}), 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(ingressClasses.Init(stop)).To(Succeed())
+
+	s := settings.MustNew()
+	s.Ingress.Classes = []string{"lingress"}
+	state := &repositoryImplState{
+		KubernetesBasedRepository: &KubernetesBasedRepository{settings: &s, Logger: log.GetRootLogger()},
+		definitions:               &definition.Definitions{IngressClass: ingressClasses},
+	}
+	ingressOf := func(className *string, annotations map[string]string) *networkingv1.Ingress {
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+			Spec:       networkingv1.IngressSpec{IngressClassName: className},
+		}
+	}
+	name := func(v string) *string { return &v }
+
+	g.Expect(state.matchesIngressClass(ingressOf(name("lingress"), nil))).To(BeTrue())
+	g.Expect(state.matchesIngressClass(ingressOf(name("owned"), nil))).To(BeTrue())
+	g.Expect(state.matchesIngressClass(ingressOf(name("foreign"), nil))).To(BeFalse())
+	g.Expect(state.matchesIngressClass(ingressOf(nil, map[string]string{"kubernetes.io/ingress.class": "owned"}))).To(BeTrue())
+	g.Expect(state.matchesIngressClass(ingressOf(nil, nil))).To(BeFalse())
+}
+
+func Test_onIngressClassElementUpdated(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	class := &networkingv1.IngressClass{
+		ObjectMeta: metav1.ObjectMeta{Name: "owned"},
+		Spec:       networkingv1.IngressClassSpec{Controller: "example.org/other"},
+	}
+	className := "owned"
+	pathType := networkingv1.PathTypePrefix
+	client := fake.NewClientset(class, &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "app"},
+		Spec: v1.ServiceSpec{
+			Type:      v1.ServiceTypeClusterIP,
+			ClusterIP: "10.0.0.1",
+			Ports:     []v1.ServicePort{{Name: "http", Port: 80}},
+		},
+	}, &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "app"},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: &className,
+			Rules: []networkingv1.IngressRule{{
+				Host: "app.example.org",
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     "/",
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: "app",
+							Port: networkingv1.ServiceBackendPort{Name: "http"},
+						}},
+					}},
+				}},
+			}},
+		},
+	})
+	s := settings.MustNew()
+	s.Ingress.Classes = []string{"lingress"}
+	s.Ingress.PublishAddresses = []string{"1.2.3.4"}
+	definitions := &definition.Definitions{}
+	var err error
+	definitions.IngressClass, err = definition.NewIngressClass(client, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.Service, err = definition.NewService(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.EndpointSlice, err = definition.NewEndpointSlice(&s, client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	definitions.Ingress, err = definition.NewIngress(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(definitions.IngressClass.Init(stop)).To(Succeed())
+	g.Expect(definitions.Service.Init(stop)).To(Succeed())
+	g.Expect(definitions.Ingress.Init(stop)).To(Succeed())
+
+	repository := &KubernetesBasedRepository{
+		settings:           &s,
+		Logger:             log.GetRootLogger(),
+		OptionsFactory:     DefaultOptionsFactory,
+		CertificatesByHost: CertificatesByHost{},
+	}
+	repository.byHostRules.Store(NewByHost(repository.onRuleAdded, repository.onRuleRemoved))
+	state := &repositoryImplState{
+		KubernetesBasedRepository: repository,
+		definitions:               definitions,
+		ingressTls:                map[string]ingressTls{},
+		endpoints:                 map[string]servicePortEndpoints{},
+		basicAuth:                 map[string]basicAuthCredentials{},
+		statusClient:              client,
+	}
+	state.ingressStatusQueue = newStatusQueue("ingress", state.writeIngressStatus, log.GetRootLogger())
+	defer state.ingressStatusQueue.queue.ShutDown()
+	state.initiated.Store(true)
+
+	// A resync delivers the same class again; nothing is revisited.
+	before := repository.ByHostRules()
+	g.Expect(state.onIngressClassElementUpdated(nil, class, class.DeepCopy())).To(Succeed())
+	g.Expect(repository.ByHostRules()).To(BeIdenticalTo(before))
+
+	// Now the class is owned by lingress; so its Ingress is served.
+	updated := class.DeepCopy()
+	updated.Spec.Controller = settings.DefaultIngressController
+	_, err = client.NetworkingV1().IngressClasses().Update(context.Background(), updated, metav1.UpdateOptions{})
+	g.Expect(err).To(BeNil())
+	g.Eventually(func() (bool, error) {
+		return definitions.IngressClass.IsOwnedBy("owned", settings.DefaultIngressController)
+	}).Should(BeTrue())
+	client.ClearActions()
+	g.Expect(state.onIngressClassElementUpdated(nil, class, updated)).To(Succeed())
+	g.Expect(repository.HasHost("app.example.org")).To(BeTrue())
+
+	// The status is not written inside the handler, but queued.
+	g.Expect(client.Actions()).To(BeEmpty())
+	g.Expect(state.ingressStatusQueue.queue.Len()).To(Equal(1))
+	g.Expect(state.ingressStatusQueue.processNext()).To(BeTrue())
+	actual, err := client.NetworkingV1().Ingresses("foo").Get(context.Background(), "app", metav1.GetOptions{})
+	g.Expect(err).To(BeNil())
+	g.Expect(actual.Status.LoadBalancer.Ingress).To(Equal([]networkingv1.IngressLoadBalancerIngress{{IP: "1.2.3.4"}}))
+}
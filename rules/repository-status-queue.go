@@ -0,0 +1,86 @@
+package rules
+
+import (
+	"context"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/slf4g"
+	"k8s.io/client-go/util/workqueue"
+	"time"
+)
+
+const (
+	// statusWriteTimeout limits each single write of a status into the
+	// cluster.
+	statusWriteTimeout = 10 * time.Second
+	// statusWriteMaxRetries is the amount of retries of a failed write of a
+	// status before it is given up (until the object changes again).
+	statusWriteMaxRetries = 5
+)
+
+// statusQueue writes statuses back into the cluster by one worker which
+// consumes the namespace/name keys of the affected objects from a rate-limited
+// queue. So the informer handlers (and leadership callbacks) never wait for
+// the API server; several changes of the same object which are queued at the
+// same time result in one single write.
+type statusQueue struct {
+	kind   string
+	queue  workqueue.TypedRateLimitingInterface[string]
+	write  func(ctx context.Context, key string) error
+	logger log.Logger
+}
+
+func newStatusQueue(kind string, write func(ctx context.Context, key string) error, logger log.Logger) *statusQueue {
+	return &statusQueue{
+		kind: kind,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: kind + "Status"},
+		),
+		write:  write,
+		logger: logger,
+	}
+}
+
+// run starts the worker which runs until stop is broadcast.
+func (this *statusQueue) run(stop support.Channel) {
+	support.ChannelDoOnEvent(stop, this.queue.ShutDown)
+	go func() {
+		for this.processNext() {
+		}
+	}()
+}
+
+func (this *statusQueue) enqueue(key string) {
+	this.queue.Add(key)
+}
+
+// processNext writes the status of the next queued key. It returns false if
+// the queue was shut down.
+func (this *statusQueue) processNext() bool {
+	key, shutdown := this.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer this.queue.Done(key)
+
+	ctx, cancel := context.WithTimeout(context.Background(), statusWriteTimeout)
+	defer cancel()
+
+	err := this.write(ctx, key)
+	if err == nil {
+		this.queue.Forget(key)
+		return true
+	}
+
+	l := this.logger.
+		WithError(err).
+		With(this.kind, key)
+	if this.queue.NumRequeues(key) < statusWriteMaxRetries {
+		l.Debug("Cannot update status; retrying...")
+		this.queue.AddRateLimited(key)
+		return true
+	}
+	l.Warnf("Cannot update status of %s; ignoring...", this.kind)
+	this.queue.Forget(key)
+	return true
+}
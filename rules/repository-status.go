@@ -0,0 +1,204 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"github.com/echocat/lingress/leader"
+	"github.com/echocat/lingress/support"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"net"
+	"slices"
+	"strings"
+)
+
+// initIngressStatus prepares the publishing of the addresses of lingress into
+// status.loadBalancer of the served Ingresses. Only the leader publishes them.
+func (this *repositoryImplState) initIngressStatus(client kubernetes.Interface, stop support.Channel) error {
+	s := this.settings.Ingress
+	if !s.IsPublishStatusEnabled() {
+		return nil
+	}
+	if s.PublishService != "" && !strings.Contains(s.PublishService, "/") && this.settings.Kubernetes.Namespace == "" {
+		return fmt.Errorf("the namespace of lingress is unknown; kubernetes.namespace has to be set to publish the status of ingresses")
+	}
+
+	this.statusClient = client
+	this.ingressStatusQueue = newStatusQueue("ingress", this.writeIngressStatus, this.Logger)
+	this.ingressStatusQueue.run(stop)
+
+	onAdded, onUpdated := this.definitions.Service.OnElementAdded, this.definitions.Service.OnElementUpdated
+	this.definitions.Service.OnElementAdded = func(ref support.ObjectReference, new metav1.Object) error {
+		if onAdded != nil {
+			if err := onAdded(ref, new); err != nil {
+				return err
+			}
+		}
+		return this.onServiceElementAdded(ref, new)
+	}
+	this.definitions.Service.OnElementUpdated = func(ref support.ObjectReference, old, new metav1.Object) error {
+		if onUpdated != nil {
+			if err := onUpdated(ref, old, new); err != nil {
+				return err
+			}
+		}
+		return this.onServiceElementUpdated(ref, old, new)
+	}
+
+	this.subscribeLeadership(leader.Subscriber{
+		OnStartedLeading: this.enqueueAllIngressStatus,
+	})
+
+	return nil
+}
+
+func (this *repositoryImplState) onServiceElementAdded(ref support.ObjectReference, _ metav1.Object) error {
+	if this.isPublishService(ref) {
+		this.enqueueAllIngressStatus()
+	}
+	return nil
+}
+
+func (this *repositoryImplState) onServiceElementUpdated(ref support.ObjectReference, _, _ metav1.Object) error {
+	return this.onServiceElementAdded(ref, nil)
+}
+
+func (this *repositoryImplState) publishServiceKey() string {
+	key := this.settings.Ingress.PublishService
+	if key != "" && !strings.Contains(key, "/") {
+		key = this.settings.Kubernetes.Namespace + "/" + key
+	}
+	return key
+}
+
+func (this *repositoryImplState) isPublishService(ref support.ObjectReference) bool {
+	return len(this.settings.Ingress.PublishAddresses) == 0 &&
+		this.publishServiceKey() != "" &&
+		ref.ShortString() == this.publishServiceKey()
+}
+
+func (this *repositoryImplState) enqueueAllIngressStatus() {
+	for _, candidate := range this.definitions.Ingress.All() {
+		this.enqueueIngressStatus(candidate)
+	}
+}
+
+// enqueueIngressStatus queues the given Ingress for writeIngressStatus.
+func (this *repositoryImplState) enqueueIngressStatus(ingress *networkingv1.Ingress) {
+	if this.ingressStatusQueue == nil || !this.isLeader() {
+		return
+	}
+	this.ingressStatusQueue.enqueue(ingress.Namespace + "/" + ingress.Name)
+}
+
+// writeIngressStatus publishes the status of the current version of the
+// Ingress with the given key (see publishIngressStatus).
+func (this *repositoryImplState) writeIngressStatus(ctx context.Context, key string) error {
+	ingress, err := this.definitions.Ingress.Get(key)
+	if err != nil || ingress == nil {
+		return err
+	}
+	return this.publishIngressStatus(ctx, ingress, this.matchesIngressClass(ingress))
+}
+
+// publishIngressStatus writes the addresses of lingress into the status of the
+// given Ingress if it matches (and is not up-to-date already). If it does not
+// match (anymore) and contains the addresses of lingress, they are removed.
+func (this *repositoryImplState) publishIngressStatus(ctx context.Context, ingress *networkingv1.Ingress, matches bool) error {
+	if this.statusClient == nil || !this.isLeader() {
+		return nil
+	}
+
+	l := this.Logger.
+		With("ingress", ingress.Namespace+"/"+ingress.Name)
+
+	addresses, err := this.ingressStatusAddresses()
+	if err != nil {
+		l.WithError(err).Warn("Cannot resolve addresses to publish into status of ingress; ignoring...")
+		return nil
+	}
+
+	current := ingress.Status.LoadBalancer.Ingress
+	desired := addresses
+	if !matches {
+		if len(current) == 0 || !ingressStatusAddressesEqual(current, addresses) {
+			// Not published by us; so do not touch it.
+			return nil
+		}
+		desired = nil
+	}
+	if ingressStatusAddressesEqual(current, desired) {
+		return nil
+	}
+
+	target := ingress.DeepCopy()
+	target.Status.LoadBalancer.Ingress = desired
+	if _, err := this.statusClient.NetworkingV1().
+		Ingresses(ingress.Namespace).
+		UpdateStatus(ctx, target, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("cannot update status of ingress %s/%s: %w", ingress.Namespace, ingress.Name, err)
+	}
+	l.With("addresses", desired).Debug("Status of ingress updated.")
+	return nil
+}
+
+func (this *repositoryImplState) ingressStatusAddresses() ([]networkingv1.IngressLoadBalancerIngress, error) {
+	if v := this.settings.Ingress.PublishAddresses; len(v) > 0 {
+		return plainToIngressStatusAddresses(v), nil
+	}
+	key := this.publishServiceKey()
+	service, err := this.definitions.Service.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if service == nil {
+		return nil, fmt.Errorf("service %s does not exist", key)
+	}
+	return serviceToIngressStatusAddresses(service), nil
+}
+
+// serviceToIngressStatusAddresses returns the addresses the given service is
+// reachable by: the addresses of its load balancer, its external IPs or its
+// clusterIP - whatever is found first.
+func serviceToIngressStatusAddresses(service *v1.Service) []networkingv1.IngressLoadBalancerIngress {
+	if service.Spec.Type == v1.ServiceTypeLoadBalancer {
+		var result []networkingv1.IngressLoadBalancerIngress
+		for _, candidate := range service.Status.LoadBalancer.Ingress {
+			result = append(result, networkingv1.IngressLoadBalancerIngress{
+				IP:       candidate.IP,
+				Hostname: candidate.Hostname,
+			})
+		}
+		return result
+	}
+	if v := service.Spec.ExternalIPs; len(v) > 0 {
+		return plainToIngressStatusAddresses(v)
+	}
+	if v := service.Spec.ClusterIP; v != "" && v != v1.ClusterIPNone {
+		return plainToIngressStatusAddresses([]string{v})
+	}
+	return nil
+}
+
+func plainToIngressStatusAddresses(in []string) []networkingv1.IngressLoadBalancerIngress {
+	result := make([]networkingv1.IngressLoadBalancerIngress, 0, len(in))
+	for _, plain := range in {
+		if plain = strings.TrimSpace(plain); plain == "" {
+			continue
+		}
+		if net.ParseIP(plain) != nil {
+			result = append(result, networkingv1.IngressLoadBalancerIngress{IP: plain})
+		} else {
+			result = append(result, networkingv1.IngressLoadBalancerIngress{Hostname: plain})
+		}
+	}
+	return result
+}
+
+func ingressStatusAddressesEqual(a, b []networkingv1.IngressLoadBalancerIngress) bool {
+	return slices.EqualFunc(a, b, func(x, y networkingv1.IngressLoadBalancerIngress) bool {
+		return x.IP == y.IP && x.Hostname == y.Hostname
+	})
+}
@@ -0,0 +1,153 @@
+package rules
+
+import (
+	"context"
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/leader"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_serviceToIngressStatusAddresses(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	g.Expect(serviceToIngressStatusAddresses(&v1.Service{
+		Spec: v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer, ClusterIP: "10.0.0.1"},
+		Status: v1.ServiceStatus{LoadBalancer: v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{
+			{IP: "1.2.3.4"},
+			{Hostname: "lb.example.org"},
+		}}},
+	})).To(Equal([]networkingv1.IngressLoadBalancerIngress{{IP: "1.2.3.4"}, {Hostname: "lb.example.org"}}))
+
+	g.Expect(serviceToIngressStatusAddresses(&v1.Service{
+		Spec: v1.ServiceSpec{Type: v1.ServiceTypeClusterIP, ClusterIP: "10.0.0.1", ExternalIPs: []string{"1.2.3.5"}},
+	})).To(Equal([]networkingv1.IngressLoadBalancerIngress{{IP: "1.2.3.5"}}))
+
+	g.Expect(serviceToIngressStatusAddresses(&v1.Service{
+		Spec: v1.ServiceSpec{Type: v1.ServiceTypeClusterIP, ClusterIP: "10.0.0.1"},
+	})).To(Equal([]networkingv1.IngressLoadBalancerIngress{{IP: "10.0.0.1"}}))
+}
+
+func Test_plainToIngressStatusAddresses(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	actual := plainToIngressStatusAddresses([]string{"1.2.3.4", " ingress.example.org ", "", "::1"})
+	g.Expect(actual).To(Equal([]networkingv1.IngressLoadBalancerIngress{{IP: "1.2.3.4"}, {Hostname: "ingress.example.org"}, {IP: "::1"}}))
+	g.Expect(ingressStatusAddressesEqual(actual, plainToIngressStatusAddresses([]string{"1.2.3.4", "ingress.example.org", "::1"}))).To(BeTrue())
+	g.Expect(ingressStatusAddressesEqual(actual, nil)).To(BeFalse())
+}
+
+func Test_publishIngressStatus(t *testing.T) {
+	ours := []networkingv1.IngressLoadBalancerIngress{{IP: "1.2.3.4"}}
+	foreign := []networkingv1.IngressLoadBalancerIngress{{IP: "5.6.7.8"}}
+
+	cases := []struct {
+		name     string
+		current  []networkingv1.IngressLoadBalancerIngress
+		matches  bool
+		leader   bool
+		expected []networkingv1.IngressLoadBalancerIngress
+	}{
+		{"writesIfMatching", nil, true, true, ours},
+		{"replacesForeignIfMatching", foreign, true, true, ours},
+		{"clearsOursIfNotMatching", ours, false, true, nil},
+		{"keepsForeignIfNotMatching", foreign, false, true, foreign},
+		{"doesNothingIfNotLeader", nil, true, false, nil},
+		{"keepsOursIfNotLeader", ours, false, false, ours},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			g := NewGomegaWithT(t)
+
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "app"},
+				Status: networkingv1.IngressStatus{LoadBalancer: networkingv1.IngressLoadBalancerStatus{
+					Ingress: c.current,
+				}},
+			}
+			client := fake.NewClientset(ingress)
+			state := newIngressStatusTestState(t, client)
+			if !c.leader {
+				var err error
+				state.LeaderElection, err = leader.New(state.settings, nil, log.GetRootLogger())
+				g.Expect(err).To(BeNil())
+			}
+
+			g.Expect(state.publishIngressStatus(context.Background(), ingress, c.matches)).To(Succeed())
+			if ingressStatusAddressesEqual(c.current, c.expected) {
+				g.Expect(client.Actions()).To(BeEmpty())
+			}
+
+			actual, err := client.NetworkingV1().Ingresses("foo").Get(context.Background(), "app", metav1.GetOptions{})
+			g.Expect(err).To(BeNil())
+			g.Expect(actual.Status.LoadBalancer.Ingress).To(Equal(c.expected))
+		})
+	}
+}
+
+func Test_initIngressStatus_writesQueuedStatusAndChainsServiceHandlers(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	client := fake.NewClientset(&networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "app"},
+	}, &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "lingress"},
+		Spec:       v1.ServiceSpec{Type: v1.ServiceTypeClusterIP, ClusterIP: "10.0.0.1"},
+	})
+	state := newIngressStatusTestState(t, client)
+	state.settings.Ingress.PublishAddresses = nil
+	state.settings.Ingress.PublishService = "foo/lingress"
+	var err error
+	state.definitions.Ingress, err = definition.NewIngress(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(state.definitions.Ingress.Init(stop)).To(Succeed())
+
+	var previousCalls atomic.Int32
+	state.definitions.Service.OnElementAdded = func(support.ObjectReference, metav1.Object) error {
+		previousCalls.Add(1)
+		return nil
+	}
+	g.Expect(state.initIngressStatus(client, stop)).To(Succeed())
+	g.Expect(state.definitions.Service.Init(stop)).To(Succeed())
+
+	g.Eventually(previousCalls.Load).Should(Equal(int32(1)))
+	g.Eventually(func() []networkingv1.IngressLoadBalancerIngress {
+		actual, err := client.NetworkingV1().Ingresses("foo").Get(context.Background(), "app", metav1.GetOptions{})
+		g.Expect(err).To(BeNil())
+		return actual.Status.LoadBalancer.Ingress
+	}).Should(Equal([]networkingv1.IngressLoadBalancerIngress{{IP: "10.0.0.1"}}))
+}
+
+func newIngressStatusTestState(t *testing.T, client *fake.Clientset) *repositoryImplState {
+	g := NewGomegaWithT(t)
+
+	s := settings.MustNew()
+	s.Ingress.Classes = []string{"*"}
+	s.Ingress.PublishAddresses = []string{"1.2.3.4"}
+	definitions := &definition.Definitions{}
+	var err error
+	definitions.Service, err = definition.NewService(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+
+	state := &repositoryImplState{
+		KubernetesBasedRepository: &KubernetesBasedRepository{
+			settings: &s,
+			Logger:   log.GetRootLogger(),
+		},
+		definitions:  definitions,
+		statusClient: client,
+	}
+	return state
+}
@@ -0,0 +1,191 @@
+package rules
+
+import (
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"github.com/echocat/slf4g"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ingressTls struct {
+	ingress support.ObjectReference
+	entries []ingressTlsEntry
+}
+
+type ingressTlsEntry struct {
+	secret support.ObjectReference
+	hosts  value.WildcardSupportingFqdns
+}
+
+func (this ingressTls) references(secret support.ObjectReference) bool {
+	for _, entry := range this.entries {
+		if entry.secret.Equals(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// visitIngressTls stages the spec.tls entries of the given Ingress. They are
+// applied by commitIngressTls after the rules of the Ingress are served.
+func (this *repositoryImplState) visitIngressTls(ref support.ObjectReference, ingress *networkingv1.Ingress) {
+	l := this.Logger.
+		With("ref", ref)
+
+	var entries []ingressTlsEntry
+	for i, candidate := range ingress.Spec.TLS {
+		l := l.With("tls", i)
+		if candidate.SecretName == "" {
+			l.Warn("There is no secretName configured for tls entry; ignoring...")
+			continue
+		}
+		entry := ingressTlsEntry{
+			secret: ref.WithApiVersionAndKind("v1", "Secret").WithName(candidate.SecretName),
+		}
+		for _, plainHost := range candidate.Hosts {
+			var host value.WildcardSupportingFqdn
+			if err := host.Set(normalizeHostname(plainHost)); err != nil {
+				l.With("host", plainHost).
+					WithError(err).
+					Warn("Illegal host in tls entry; ignoring...")
+				continue
+			}
+			entry.hosts = append(entry.hosts, host)
+		}
+		entries = append(entries, entry)
+	}
+
+	this.stageIngressTls(ref, entries)
+}
+
+// forgetIngressTls stages the removal of the spec.tls entries of the given
+// Ingress.
+func (this *repositoryImplState) forgetIngressTls(ref support.ObjectReference) {
+	this.stageIngressTls(ref, nil)
+}
+
+// stageIngressTls has to be called by the modify function of
+// updateByHostRules only.
+func (this *repositoryImplState) stageIngressTls(ref support.ObjectReference, entries []ingressTlsEntry) {
+	if this.pendingIngressTls == nil {
+		this.pendingIngressTls = map[string]ingressTls{}
+	}
+	this.pendingIngressTls[ref.String()] = ingressTls{
+		ingress: ref,
+		entries: entries,
+	}
+}
+
+// commitIngressTls applies the spec.tls entries which were staged since the
+// last commit. It has to be called by updateByHostRules only.
+func (this *repositoryImplState) commitIngressTls() error {
+	pending := this.pendingIngressTls
+	this.pendingIngressTls = nil
+
+	this.ingressTlsMutex.Lock()
+	defer this.ingressTlsMutex.Unlock()
+
+	for key, candidate := range pending {
+		if len(candidate.entries) > 0 {
+			this.ingressTls[key] = candidate
+		} else {
+			delete(this.ingressTls, key)
+		}
+		if err := this.applyIngressCertificates(candidate.ingress, candidate.entries); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (this *repositoryImplState) applyIngressCertificates(ref support.ObjectReference, entries []ingressTlsEntry) error {
+	l := this.Logger.
+		With("ref", ref)
+
+	var loaded CertificatesByHost
+	for _, entry := range entries {
+		l := l.With("secret", entry.secret)
+		secret, err := this.definitions.Secret.Get(entry.secret.ShortString())
+		if err != nil {
+			return err
+		}
+		if secret == nil {
+			l.Warn("Secret referenced by spec.tls not found; ignoring...")
+			continue
+		}
+
+		hosts := entry.hosts
+		this.forEachCertificateOfSecret(secret, l, func(l log.Logger, certificate, privateKey []byte) {
+			_, skipped, err := loaded.AddBytesForHosts(ref, certificate, privateKey, hosts)
+			if err != nil {
+				l.WithError(err).Warn("Cannot parse certificate and privateKey pair from secret; ignoring...")
+				return
+			}
+			if len(skipped) > 0 {
+				l.With("fqdns", skipped).Warn("Certificate does not cover all hosts of spec.tls; ignoring those hosts...")
+			}
+		})
+	}
+
+	removed, added, err := this.CertificatesByHost.ReplaceBySource(ref, &loaded)
+	if err != nil {
+		return err
+	}
+	if len(removed) > 0 {
+		l.With("fqdns", removed).Info("Certificates for FQNDs removed.")
+	}
+	if len(added) > 0 {
+		l.With("fqdns", added).Info("Certificates for FQNDs added.")
+	}
+
+	return nil
+}
+
+func (this *repositoryImplState) onSecretChanged(ref support.ObjectReference) error {
+	if err := this.onTlsSecretChanged(ref); err != nil {
+		return err
+	}
+	if err := this.onBasicAuthSecretChanged(ref); err != nil {
+		return err
+	}
+	return this.onClientCertificateSecretChanged(ref)
+}
+
+func (this *repositoryImplState) onTlsSecretChanged(ref support.ObjectReference) error {
+	this.ingressTlsMutex.Lock()
+	defer this.ingressTlsMutex.Unlock()
+
+	for _, candidate := range this.ingressTls {
+		if candidate.references(ref) {
+			if err := this.applyIngressCertificates(candidate.ingress, candidate.entries); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (this *repositoryImplState) onSecretElementAdded(ref support.ObjectReference, _ metav1.Object) error {
+	return this.onSecretChanged(ref)
+}
+
+func (this *repositoryImplState) onSecretElementUpdated(ref support.ObjectReference, _, _ metav1.Object) error {
+	return this.onSecretChanged(ref)
+}
+
+func (this *repositoryImplState) onSecretElementRemoved(ref support.ObjectReference) error {
+	return this.onSecretChanged(ref)
+}
+
+// secretOf returns the referenced secret. Secrets which are already held by
+// the store of the service secrets are taken from there.
+func (this *repositoryImplState) secretOf(ref support.ObjectReference) (*v1.Secret, error) {
+	if secret, err := this.definitions.ServiceSecrets.Get(ref.ShortString()); err != nil || secret != nil {
+		return secret, err
+	}
+	return this.definitions.Secret.Get(ref.ShortString())
+}
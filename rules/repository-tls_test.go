@@ -0,0 +1,91 @@
+package rules
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func Test_visitIngressTls_appliedOnlyIfRulesAreStored(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	certificate, key := newTestCertificate(t, "app.example.org", nil, nil)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	g.Expect(err).To(BeNil())
+	client := fake.NewClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "tls"},
+		Data: map[string][]byte{
+			"tls.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Raw}),
+			"tls.key": pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		},
+	})
+
+	s := settings.MustNew()
+	definitions := &definition.Definitions{}
+	definitions.Secret, err = definition.NewSecret(&s, client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(definitions.Secret.Init(stop)).To(Succeed())
+
+	repository := &KubernetesBasedRepository{
+		settings:           &s,
+		Logger:             log.GetRootLogger(),
+		OptionsFactory:     DefaultOptionsFactory,
+		CertificatesByHost: CertificatesByHost{},
+	}
+	repository.byHostRules.Store(NewByHost(repository.onRuleAdded, repository.onRuleRemoved))
+	state := &repositoryImplState{
+		KubernetesBasedRepository: repository,
+		definitions:               definitions,
+		ingressTls:                map[string]ingressTls{},
+		endpoints:                 map[string]servicePortEndpoints{},
+		basicAuth:                 map[string]basicAuthCredentials{},
+	}
+	state.initiated.Store(true)
+
+	ref := support.NewObjectReference("networking.k8s.io/v1", "Ingress", "foo", "app")
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "app"},
+		Spec:       networkingv1.IngressSpec{TLS: []networkingv1.IngressTLS{{SecretName: "tls"}}},
+	}
+	served := func() int {
+		return len(repository.CertificatesByHost.Find("app.example.org"))
+	}
+	failingVisitOf := func(ingress *networkingv1.Ingress) error {
+		return state.updateByHostRules(func(target *ByHost) error {
+			if _, err := state.applyIngress(ref, ingress, target); err != nil {
+				return err
+			}
+			return errors.New("expected")
+		})
+	}
+
+	// Certificates of a visit which fails are not served...
+	g.Expect(failingVisitOf(ingress)).To(MatchError("expected"))
+	g.Expect(served()).To(Equal(0))
+
+	// ... but the ones of a successful one.
+	g.Expect(state.onIngressElementAdded(ref, ingress)).To(Succeed())
+	g.Expect(served()).To(Equal(1))
+
+	// Certificates stay served if the removal of spec.tls fails.
+	withoutTls := ingress.DeepCopy()
+	withoutTls.Spec.TLS = nil
+	g.Expect(failingVisitOf(withoutTls)).To(MatchError("expected"))
+	g.Expect(served()).To(Equal(1))
+
+	g.Expect(state.onIngressElementRemoved(ref)).To(Succeed())
+	g.Expect(served()).To(Equal(0))
+}
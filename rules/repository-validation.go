@@ -0,0 +1,178 @@
+package rules
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"slices"
+	"strings"
+)
+
+// IngressValidator validates Ingresses before they are stored inside the
+// cluster; for example by an admission webhook.
+type IngressValidator interface {
+	// ValidateIngress returns a ValidationError if the given Ingress cannot be
+	// served (at all or partially). Warnings are returned for problems which
+	// might resolve themselves later (like a missing Service) and for host and
+	// path combinations which are already served by other sources.
+	ValidateIngress(*networkingv1.Ingress) (warnings []string, err error)
+}
+
+// ValidationError contains all problems found while validating an object.
+type ValidationError struct {
+	Problems []string
+}
+
+func (this *ValidationError) Error() string {
+	return strings.Join(this.Problems, "; ")
+}
+
+func (this *KubernetesBasedRepository) ValidateIngress(ingress *networkingv1.Ingress) ([]string, error) {
+	state := this.state.Load()
+	if state == nil {
+		return nil, fmt.Errorf("repository is not initialized, yet")
+	}
+	return state.validateIngress(ingress)
+}
+
+func (this *repositoryImplState) validateIngress(ingress *networkingv1.Ingress) ([]string, error) {
+	if !this.matchesIngressClass(ingress) || !this.definitions.Namespaces.Contains(ingress.Namespace) {
+		return nil, nil
+	}
+
+	ref, err := support.NewObjectReferenceOf(ingress)
+	if err != nil {
+		return nil, err
+	}
+
+	v := ingressValidation{repositoryImplState: this, ref: ref}
+
+	options := this.OptionsFactory()
+	for _, part := range options {
+		if err := part.Set(ingress.GetAnnotations()); err != nil {
+			v.problem("metadata.annotations", "%v", err)
+		}
+	}
+	// The order of the options is random; so ensure the message is stable.
+	slices.Sort(v.problems)
+
+	if b := ingress.Spec.DefaultBackend; b != nil {
+		v.backend("spec.defaultBackend", b)
+	}
+
+	for i, forHost := range ingress.Spec.Rules {
+		field := fmt.Sprintf("spec.rules[%d]", i)
+		host, hostErr := this.parseHost(&forHost, options)
+		if hostErr != nil {
+			v.problem(field+".host", "%v", hostErr)
+		}
+		if forHost.HTTP == nil {
+			continue
+		}
+		for j, forPath := range forHost.HTTP.Paths {
+			field := fmt.Sprintf("%s.http.paths[%d]", field, j)
+			path, pathErr := ParsePath(forPath.Path, false)
+			if pathErr != nil {
+				v.problem(field+".path", "%v", pathErr)
+			}
+			pathType, pathTypeErr := ParsePathType(forPath.PathType)
+			if pathTypeErr != nil {
+				v.problem(field+".pathType", "%v", pathTypeErr)
+			}
+			v.backend(field+".backend", &forPath.Backend)
+			if hostErr == nil && pathErr == nil && pathTypeErr == nil {
+				v.conflicts(field, host, path, pathType, options)
+			}
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return v.warnings, &ValidationError{Problems: v.problems}
+	}
+	return v.warnings, nil
+}
+
+type ingressValidation struct {
+	*repositoryImplState
+	ref support.ObjectReference
+
+	problems []string
+	warnings []string
+}
+
+func (this *ingressValidation) problem(field string, format string, args ...any) {
+	this.problems = append(this.problems, field+": "+fmt.Sprintf(format, args...))
+}
+
+func (this *ingressValidation) warning(field string, format string, args ...any) {
+	this.warnings = append(this.warnings, field+": "+fmt.Sprintf(format, args...))
+}
+
+func (this *ingressValidation) backend(field string, ib *networkingv1.IngressBackend) {
+	if ib.Resource != nil {
+		this.problem(field+".resource", "backends of kind resource are not supported")
+		return
+	}
+	forService := ib.Service
+	if forService == nil {
+		this.problem(field+".service", "there is no service configured")
+		return
+	}
+	if forService.Name == "" {
+		this.problem(field+".service.name", "there is no service name configured")
+		return
+	}
+	if forService.Port.Number == 0 && forService.Port.Name == "" {
+		this.problem(field+".service.port", "there is neither a port name nor a port number configured")
+		return
+	}
+
+	serviceRef := this.ingressToServiceReference(this.ref, forService)
+	service, err := this.ingressToService(this.ref, ib)
+	if err != nil {
+		this.warning(field+".service", "cannot resolve service %s: %v", serviceRef.ShortString(), err)
+		return
+	}
+	if service == nil {
+		this.warning(field+".service.name", "service %s does not exist; it will be ignored until it exists", serviceRef.ShortString())
+		return
+	}
+	if service.Spec.Type != v1.ServiceTypeClusterIP && service.Spec.Type != "" {
+		this.warning(field+".service.name", "service %s is of unsupported type %s; it will be ignored", serviceRef.ShortString(), service.Spec.Type)
+		return
+	}
+	if _, err := this.evaluateServicePort(forService.Port, service); err != nil {
+		this.warning(field+".service.port", "%v; it will be ignored", err)
+	}
+}
+
+// conflicts warns about every rule of another source which serves exactly the
+// same host, path and pathType. Canaries and rules restricted by additional
+// matches are expected to share them and are therefore not reported.
+func (this *ingressValidation) conflicts(field string, host value.WildcardSupportingFqdn, path []string, pathType PathType, options Options) {
+	if isSharingOptions(options) {
+		return
+	}
+	_ = this.ByHostRules().AllOfExactHost(host, func(candidate Rule) error {
+		if candidate.Source().Equals(this.ref) ||
+			candidate.PathType() != pathType ||
+			!slices.Equal(candidate.Path(), path) ||
+			isSharingOptions(candidate.Options()) {
+			return nil
+		}
+		this.warning(field, "host %q with path %q (%v) is already served by %v", host, "/"+strings.Join(path, "/"), pathType, candidate.Source())
+		return nil
+	})
+}
+
+func isSharingOptions(options Options) bool {
+	if v, ok := options[optionsCanaryKey].(*OptionsCanary); ok && v.Enabled.GetOr(false) {
+		return true
+	}
+	if v, ok := options[optionsMatchKey].(*OptionsMatch); ok && v.IsRelevant() {
+		return true
+	}
+	return false
+}
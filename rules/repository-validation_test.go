@@ -0,0 +1,98 @@
+package rules
+
+import (
+	"github.com/echocat/lingress/definition"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	log "github.com/echocat/slf4g"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"testing"
+)
+
+func Test_validateIngress(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	stop := support.NewChannel()
+	defer stop.Broadcast()
+
+	client := fake.NewClientset(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "app"},
+		Spec: v1.ServiceSpec{
+			Type:      v1.ServiceTypeClusterIP,
+			ClusterIP: "10.0.0.1",
+			Ports:     []v1.ServicePort{{Name: "http", Port: 80}},
+		},
+	})
+	services, err := definition.NewService(client, nil, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(services.Init(stop)).To(Succeed())
+	ingressClasses, err := definition.NewIngressClass(client, 0, log.GetRootLogger())
+	g.Expect(err).To(BeNil())
+	g.Expect(ingressClasses.Init(stop)).To(Succeed())
+
+	s := settings.MustNew()
+	s.Ingress.Classes = []string{"lingress"}
+	repository := &KubernetesBasedRepository{
+		settings:       &s,
+		Logger:         log.GetRootLogger(),
+		OptionsFactory: DefaultOptionsFactory,
+	}
+	repository.byHostRules.Store(NewByHost(repository.onRuleAdded, repository.onRuleRemoved))
+	state := &repositoryImplState{
+		KubernetesBasedRepository: repository,
+		definitions:               &definition.Definitions{IngressClass: ingressClasses, Service: services},
+	}
+
+	other, err := support.NewObjectReferenceOf(&networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "other"}})
+	g.Expect(err).To(BeNil())
+	g.Expect(repository.ByHostRules().Put(NewRule("app.example.org", []string{"a"}, PathTypePrefix, other, nil, nil, DefaultOptionsFactory()))).To(Succeed())
+
+	ingressOf := func(annotations map[string]string, pathType networkingv1.PathType, service string, port networkingv1.ServiceBackendPort) *networkingv1.Ingress {
+		className := "lingress"
+		return &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "app", Annotations: annotations},
+			Spec: networkingv1.IngressSpec{
+				IngressClassName: &className,
+				Rules: []networkingv1.IngressRule{{
+					Host: "app.example.org",
+					IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     "/a",
+							PathType: &pathType,
+							Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+								Name: service,
+								Port: port,
+							}},
+						}},
+					}},
+				}},
+			},
+		}
+	}
+
+	warnings, err := state.validateIngress(ingressOf(nil, networkingv1.PathTypePrefix, "app", networkingv1.ServiceBackendPort{Name: "http"}))
+	g.Expect(err).To(BeNil())
+	g.Expect(warnings).To(Equal([]string{
+		`spec.rules[0].http.paths[0]: host "app.example.org" with path "/a" (Prefix) is already served by Ingress:foo/other`,
+	}))
+
+	warnings, err = state.validateIngress(ingressOf(map[string]string{
+		annotationCanaryEnabled:     "true",
+		annotationCanaryWeightTotal: "0",
+	}, "Foo", "missing", networkingv1.ServiceBackendPort{Number: 80}))
+	g.Expect(err).To(MatchError(`metadata.annotations: illegal value for annotation lingress.echocat.org/canary-weight-total: has to be greater than 0; spec.rules[0].http.paths[0].pathType: cannot handle path type: Foo`))
+	g.Expect(warnings).To(Equal([]string{
+		`spec.rules[0].http.paths[0].backend.service.name: service foo/missing does not exist; it will be ignored until it exists`,
+	}))
+
+	className := "other"
+	ignored := ingressOf(nil, "Foo", "", networkingv1.ServiceBackendPort{})
+	ignored.Spec.IngressClassName = &className
+	warnings, err = state.validateIngress(ignored)
+	g.Expect(err).To(BeNil())
+	g.Expect(warnings).To(BeNil())
+}
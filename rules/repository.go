@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/echocat/lingress/definition"
 	"github.com/echocat/lingress/kubernetes"
+	"github.com/echocat/lingress/leader"
 	"github.com/echocat/lingress/settings"
 	"github.com/echocat/lingress/support"
 	"github.com/echocat/lingress/value"
@@ -11,9 +12,15 @@ import (
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	clientkubernetes "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"net"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Query struct {
@@ -31,37 +38,96 @@ type Repository interface {
 	FindBy(Query) (Rules, error)
 }
 
+// HostRepository provides the information which hosts are explicitly
+// configured.
+type HostRepository interface {
+	HasHost(value.Fqdn) (bool, error)
+}
+
+// SyncStateRepository provides the state of the synchronization of a
+// repository with its source.
+type SyncStateRepository interface {
+	// HasSynced reports whether the repository has (still) synchronized all
+	// of its sources.
+	HasSynced() bool
+	// LastError returns the last error which occurred while synchronizing
+	// with the source as a whole (like a lost watch of the cluster or a
+	// failed reload of a file) and the time it occurred at. Problems of
+	// single objects are not reported here.
+	LastError() (time.Time, error)
+}
+
 type CombinedRepository interface {
 	Repository
 	CertificateRepository
+	ClientCertificateRepository
+	HostRepository
 }
 
 type KubernetesBasedRepository struct {
 	settings *settings.Settings
 
-	Environment *kubernetes.Environment
-	ByHostRules *ByHost
-	Logger      log.Logger
+	Environment    *kubernetes.Environment
+	LeaderElection *leader.Election
+	Logger         log.Logger
 
 	CertificatesByHost CertificatesByHost
 	OptionsFactory     OptionsFactory
+
+	state atomic.Pointer[repositoryImplState]
+
+	byHostRules            atomic.Pointer[ByHost]
+	byHostRulesMutex       sync.Mutex
+	clientCertificatePools ClientCertificatePools
 }
 
-func NewRepository(s *settings.Settings, logger log.Logger) (CombinedRepository, error) {
-	environment, err := kubernetes.NewEnvironment(s)
-	if err != nil {
-		return nil, err
+// NewRepository creates the repository of the sources configured by
+// rules.source. Several sources are combined by a CompositeRepository in the
+// configured order.
+func NewRepository(s *settings.Settings, environment *kubernetes.Environment, leaderElection *leader.Election, logger log.Logger) (CombinedRepository, error) {
+	sources := s.Rules.GetSources()
+	children := make([]CombinedRepository, len(sources))
+	seen := map[string]bool{}
+	for i, source := range sources {
+		if seen[source] {
+			return nil, fmt.Errorf("rules source configured more than once: %s", source)
+		}
+		seen[source] = true
+
+		child, err := newRepositoryOf(s, source, environment, leaderElection, logger)
+		if err != nil {
+			return nil, err
+		}
+		children[i] = child
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return NewCompositeRepository(s.Rules.ConflictStrategy, children...)
+}
+
+func newRepositoryOf(s *settings.Settings, source string, environment *kubernetes.Environment, leaderElection *leader.Election, logger log.Logger) (CombinedRepository, error) {
+	if file, ok := strings.CutPrefix(source, settings.RulesSourceFilePrefix); ok {
+		return NewFileBasedRepository(s, file, logger)
+	}
+	if source != settings.RulesSourceKubernetes {
+		return nil, fmt.Errorf("unknown rules source: %s", source)
 	}
+	return NewKubernetesBasedRepository(s, environment, leaderElection, logger)
+}
+
+func NewKubernetesBasedRepository(s *settings.Settings, environment *kubernetes.Environment, leaderElection *leader.Election, logger log.Logger) (*KubernetesBasedRepository, error) {
 	result := &KubernetesBasedRepository{
-		settings:    s,
-		Environment: environment,
+		settings:       s,
+		Environment:    environment,
+		LeaderElection: leaderElection,
 
 		OptionsFactory:     DefaultOptionsFactory,
 		CertificatesByHost: CertificatesByHost{},
 
 		Logger: logger,
 	}
-	result.ByHostRules = NewByHost(result.onRuleAdded, result.onRuleRemoved)
+	result.byHostRules.Store(NewByHost(result.onRuleAdded, result.onRuleRemoved))
 	return result, nil
 }
 
@@ -72,7 +138,13 @@ func (this *KubernetesBasedRepository) Init(stop support.Channel) error {
 	if err != nil {
 		return err
 	}
-	definitions, err := definition.New(this.settings, client, this.settings.Discovery.ResyncAfter, this.Logger)
+	var dynamicClient dynamic.Interface
+	if this.settings.Gateway.IsEnabled() {
+		if dynamicClient, err = this.Environment.NewDynamicClient(); err != nil {
+			return err
+		}
+	}
+	definitions, err := definition.New(this.settings, client, dynamicClient, this.settings.Discovery.ResyncAfter, this.Logger)
 	if err != nil {
 		return err
 	}
@@ -81,6 +153,11 @@ func (this *KubernetesBasedRepository) Init(stop support.Channel) error {
 	state := &repositoryImplState{
 		KubernetesBasedRepository: this,
 		definitions:               definitions,
+		ingressTls:                map[string]ingressTls{},
+		endpoints:                 map[string]servicePortEndpoints{},
+		basicAuth:                 map[string]basicAuthCredentials{},
+
+		clientCertificateAuthorities: map[string]clientCertificateAuthorities{},
 	}
 
 	state.initiated.Store(false)
@@ -89,20 +166,96 @@ func (this *KubernetesBasedRepository) Init(stop support.Channel) error {
 	definitions.Ingress.OnElementUpdated = state.onIngressElementUpdated
 	definitions.Ingress.OnElementRemoved = state.onIngressElementRemoved
 
+	definitions.IngressClass.OnElementAdded = state.onIngressClassElementAdded
+	definitions.IngressClass.OnElementUpdated = state.onIngressClassElementUpdated
+	definitions.IngressClass.OnElementRemoved = state.onIngressClassElementRemoved
+
 	definitions.ServiceSecrets.OnElementAdded = state.onServiceSecretsElementAdded
 	definitions.ServiceSecrets.OnElementUpdated = state.onServiceSecretsElementUpdated
 	definitions.ServiceSecrets.OnElementRemoved = state.onServiceSecretsElementRemoved
 
+	definitions.Secret.OnElementAdded = state.onSecretElementAdded
+	definitions.Secret.OnElementUpdated = state.onSecretElementUpdated
+	definitions.Secret.OnElementRemoved = state.onSecretElementRemoved
+
+	definitions.EndpointSlice.OnElementAdded = state.onEndpointSliceElementAdded
+	definitions.EndpointSlice.OnElementUpdated = state.onEndpointSliceElementUpdated
+	definitions.EndpointSlice.OnElementRemoved = state.onEndpointSliceElementRemoved
+
+	state.initGateway(stop)
+	state.initEvents(client, stop)
+
+	if err := state.initIngressStatus(client, stop); err != nil {
+		return err
+	}
+
 	if err := definitions.Init(stop); err != nil {
 		return err
 	}
 
 	state.initiated.Store(true)
+	this.state.Store(state)
 
 	log.Info("Initial sync of definitions... done!")
 	return nil
 }
 
+func (this *KubernetesBasedRepository) HasSynced() bool {
+	state := this.state.Load()
+	return state != nil && state.definitions.HasSynced()
+}
+
+func (this *KubernetesBasedRepository) LastError() (time.Time, error) {
+	state := this.state.Load()
+	if state == nil {
+		return time.Time{}, nil
+	}
+	return state.definitions.LastWatchError()
+}
+
+// ByHostRules returns the rules which are currently served. It has to be
+// treated as read-only; modifications are only done by updateByHostRules.
+func (this *KubernetesBasedRepository) ByHostRules() *ByHost {
+	return this.byHostRules.Load()
+}
+
+// updateByHostRules serializes all modifications of the served rules,
+// regardless of which informer they are caused by. After the initial
+// synchronization modify is applied on a clone which replaces the served
+// rules only if modify succeeds; so readers never see partial updates.
+func (this *repositoryImplState) updateByHostRules(modify func(target *ByHost) error) error {
+	this.byHostRulesMutex.Lock()
+	defer this.byHostRulesMutex.Unlock()
+
+	target := this.ByHostRules()
+	clonedUpdate := this.initiated.Load() == true
+	if clonedUpdate {
+		target = target.Clone()
+	}
+
+	if err := modify(target); err != nil {
+		this.pendingIngressTls = nil
+		return err
+	}
+
+	if clonedUpdate {
+		this.byHostRules.Store(target)
+	}
+	if err := this.commitIngressTls(); err != nil {
+		return err
+	}
+	if clonedUpdate {
+		if err := this.pruneEndpoints(); err != nil {
+			return err
+		}
+		if err := this.pruneBasicAuthCredentials(); err != nil {
+			return err
+		}
+		return this.pruneClientCertificateAuthorities()
+	}
+	return nil
+}
+
 func (this *KubernetesBasedRepository) onRuleAdded(_ []string, r Rule) {
 	this.Logger.With("rule", r).Debug("Rule added.")
 }
@@ -116,6 +269,45 @@ type repositoryImplState struct {
 
 	definitions *definition.Definitions
 	initiated   atomic.Value
+
+	ingressTls      map[string]ingressTls
+	ingressTlsMutex sync.Mutex
+	// pendingIngressTls are the spec.tls entries which are staged by the
+	// running updateByHostRules; guarded by byHostRulesMutex.
+	pendingIngressTls map[string]ingressTls
+
+	endpoints      map[string]servicePortEndpoints
+	endpointsMutex sync.Mutex
+
+	basicAuth      map[string]basicAuthCredentials
+	basicAuthMutex sync.Mutex
+
+	clientCertificateAuthorities      map[string]clientCertificateAuthorities
+	clientCertificateAuthoritiesMutex sync.Mutex
+
+	statusClient       clientkubernetes.Interface
+	ingressStatusQueue *statusQueue
+
+	httpRouteStatusQueue *statusQueue
+	// httpRouteStatus are the latest visits of the HTTPRoutes whose status
+	// is not written yet; guarded by httpRouteStatusMutex.
+	httpRouteStatus      map[string]*httpRouteVisit
+	httpRouteStatusMutex sync.Mutex
+
+	eventRecorder      record.EventRecorder
+	ingressEvents      map[string]*ingressEvents
+	ingressEventsMutex sync.Mutex
+}
+
+// isLeader reports whether this instance should write back into the cluster.
+func (this *repositoryImplState) isLeader() bool {
+	return this.LeaderElection == nil || this.LeaderElection.IsLeader()
+}
+
+func (this *repositoryImplState) subscribeLeadership(subscriber leader.Subscriber) {
+	if this.LeaderElection != nil {
+		this.LeaderElection.Subscribe(subscriber)
+	}
 }
 
 func (this *repositoryImplState) onSecretCertificatesChanged(ref support.ObjectReference, new metav1.Object) error {
@@ -134,6 +326,18 @@ func (this *repositoryImplState) onSecretCertificatesChanged(ref support.ObjectR
 
 	s := new.(*v1.Secret)
 
+	this.forEachCertificateOfSecret(s, l, func(l log.Logger, certificate, privateKey []byte) {
+		if added, err := this.CertificatesByHost.AddBytes(ref, certificate, privateKey); err != nil {
+			l.WithError(err).Warn("Cannot parse certificate and privateKey pair from secret; ignoring...")
+		} else if len(added) > 0 {
+			l.With("fqdns", added).Info("Certificates for FQNDs added.")
+		}
+	})
+
+	return nil
+}
+
+func (this *repositoryImplState) forEachCertificateOfSecret(s *v1.Secret, l log.Logger, consumer func(l log.Logger, certificate, privateKey []byte)) {
 	for file, candidate := range s.Data {
 		l := l.With("certificate", file)
 		base, ext := support.SplitExt(file)
@@ -148,20 +352,15 @@ func (this *repositoryImplState) onSecretCertificatesChanged(ref support.ObjectR
 			if base == "tls" {
 				ca, ok := s.Data["ca.cert"]
 				if ok {
-					candidate = append(ca, '\n')
-					candidate = append(ca, ca...)
+					chain := make([]byte, 0, len(candidate)+len(ca)+1)
+					chain = append(chain, candidate...)
+					chain = append(chain, '\n')
+					candidate = append(chain, ca...)
 				}
 			}
-			if added, err := this.CertificatesByHost.AddBytes(ref, candidate, pk); err != nil {
-				l.WithError(err).Warn("Cannot parse certificate and privateKey pair from secret; ignoring...")
-				continue
-			} else if len(added) > 0 {
-				l.With("fqdns", added).Info("Certificates for FQNDs added.")
-			}
+			consumer(l, candidate, pk)
 		}
 	}
-
-	return nil
 }
 
 func (this *repositoryImplState) isExpectedCertificatesKey(what support.ObjectReference) bool {
@@ -185,6 +384,12 @@ func (this *repositoryImplState) isExpectedCertificatesKey(what support.ObjectRe
 }
 
 func (this *repositoryImplState) onServiceSecretsElementAdded(ref support.ObjectReference, new metav1.Object) error {
+	if err := this.onBasicAuthSecretChanged(ref); err != nil {
+		return err
+	}
+	if err := this.onClientCertificateSecretChanged(ref); err != nil {
+		return err
+	}
 	if this.isExpectedCertificatesKey(ref) {
 		return this.onSecretCertificatesChanged(ref, new)
 	}
@@ -192,6 +397,12 @@ func (this *repositoryImplState) onServiceSecretsElementAdded(ref support.Object
 }
 
 func (this *repositoryImplState) onServiceSecretsElementUpdated(ref support.ObjectReference, _, new metav1.Object) error {
+	if err := this.onBasicAuthSecretChanged(ref); err != nil {
+		return err
+	}
+	if err := this.onClientCertificateSecretChanged(ref); err != nil {
+		return err
+	}
 	if this.isExpectedCertificatesKey(ref) {
 		return this.onSecretCertificatesChanged(ref, new)
 	}
@@ -199,6 +410,12 @@ func (this *repositoryImplState) onServiceSecretsElementUpdated(ref support.Obje
 }
 
 func (this *repositoryImplState) onServiceSecretsElementRemoved(ref support.ObjectReference) error {
+	if err := this.onBasicAuthSecretChanged(ref); err != nil {
+		return err
+	}
+	if err := this.onClientCertificateSecretChanged(ref); err != nil {
+		return err
+	}
 	if this.isExpectedCertificatesKey(ref) {
 		return this.onSecretCertificatesChanged(ref, nil)
 	}
@@ -206,74 +423,49 @@ func (this *repositoryImplState) onServiceSecretsElementRemoved(ref support.Obje
 }
 
 func (this *repositoryImplState) onIngressElementAdded(ref support.ObjectReference, new metav1.Object) error {
-	target := this.ByHostRules
-	clonedUpdate := this.initiated.Load() == true
-	if clonedUpdate {
-		target = target.Clone()
-	}
-
 	candidate := new.(*networkingv1.Ingress)
 
-	if !this.matchesIngressClass(candidate) {
-		return nil
-	}
-
-	if err := this.visitIngress(ref, candidate, target); err != nil {
+	if err := this.updateByHostRules(func(target *ByHost) error {
+		_, err := this.applyIngress(ref, candidate, target)
+		return err
+	}); err != nil {
 		return err
 	}
 
-	if clonedUpdate {
-		this.ByHostRules = target
-	}
+	this.enqueueIngressStatus(candidate)
 	return nil
 }
 
 func (this *repositoryImplState) onIngressElementUpdated(ref support.ObjectReference, _, new metav1.Object) error {
-	candidate := new.(*networkingv1.Ingress)
-
-	if this.matchesIngressClass(candidate) {
-		return this.onIngressElementAdded(ref, new)
-	}
-
-	return this.onIngressElementRemoved(ref)
+	return this.onIngressElementAdded(ref, new)
 }
 
 func (this *repositoryImplState) onIngressElementRemoved(ref support.ObjectReference) error {
-	target := this.ByHostRules
-	clonedUpdate := this.initiated.Load() != true
-	if clonedUpdate {
-		target = target.Clone()
+	return this.updateByHostRules(func(target *ByHost) error {
+		return this.removeIngress(ref, target)
+	})
+}
+
+// applyIngress registers the rules of the given Ingress if it is served by
+// this instance; otherwise its previous rules are removed. It reports whether
+// the Ingress is served.
+func (this *repositoryImplState) applyIngress(ref support.ObjectReference, ingress *networkingv1.Ingress, target *ByHost) (bool, error) {
+	if !this.matchesIngressClass(ingress) {
+		return false, this.removeIngress(ref, target)
 	}
+	return true, this.visitIngress(ref, ingress, target)
+}
 
+func (this *repositoryImplState) removeIngress(ref support.ObjectReference, target *ByHost) error {
 	if err := target.Remove(PredicateByObjectReference(ref)); err != nil {
 		return fmt.Errorf("cannot remove previous element by source %v: %v", ref, err)
 	}
 
-	if clonedUpdate {
-		this.ByHostRules = target
-	}
+	this.forgetIngressTls(ref)
+	this.forgetIngressEvents(ref)
 	return nil
 }
 
-func (this *repositoryImplState) matchesIngressClass(what *networkingv1.Ingress) bool {
-	requested := what.Spec.IngressClassName
-	classes := this.settings.Ingress.GetClasses()
-	if requested == nil {
-		for _, candidate := range classes {
-			if candidate == "" || candidate == "*" {
-				return true
-			}
-		}
-		return false
-	}
-	for _, candidate := range classes {
-		if candidate == *requested || candidate == "*" {
-			return true
-		}
-	}
-	return false
-}
-
 func (this *repositoryImplState) visitIngress(ref support.ObjectReference, ingress *networkingv1.Ingress, target *ByHost) error {
 	if err := target.Remove(PredicateByObjectReference(ref)); err != nil {
 		return err
@@ -282,24 +474,21 @@ func (this *repositoryImplState) visitIngress(ref support.ObjectReference, ingre
 	l := this.Logger.
 		With("ref", ref)
 
-	if len(ingress.Spec.TLS) > 0 {
-		l.Warn("Currently ingress configurations with spec.tls settings are not supported; ignoring...")
-
-		return nil
-	}
+	this.visitIngressTls(ref, ingress)
 
 	if v := ingress.Spec.DefaultBackend; v != nil {
 		l := l.With("kind", "defaultBackend")
-		backend, err := this.ingressToBackend(ref, v, l)
+		backend, endpoints, err := this.ingressToBackend(ref, v, ingress, l)
 		if err != nil {
 			return err
 		}
 		if backend != nil {
-			options, err := this.newOptionsBy(ingress)
+			options, err := this.newOptionsBy(ref, ingress)
 			if err != nil {
+				this.recordIngressProblem(ingress, eventReasonInvalidAnnotations, "%v", err)
 				return err
 			}
-			r := NewRule("", []string{}, PathTypePrefix, ref, backend, options)
+			r := NewRule("", []string{}, PathTypePrefix, ref, backend, endpoints, options)
 			if err := target.Put(r); err != nil {
 				return err
 			}
@@ -317,22 +506,26 @@ func (this *repositoryImplState) visitIngress(ref support.ObjectReference, ingre
 				path, err := ParsePath(forPath.Path, false)
 				if err != nil {
 					l.WithError(err).Warn("Illegal path configured; ingress will not functioning; ignoring...")
+					this.recordIngressProblem(ingress, eventReasonIllegalPath, "Path %q is ignored: %v", forPath.Path, err)
 					continue
 				}
 				l = l.With("path", path)
 
 				if forPath.Backend.Resource != nil {
 					l.Warn("Currently ingress configurations with spec.rules.http.paths.backend.resource settings are not supported; ignoring...")
+					this.recordIngressProblem(ingress, eventReasonUnsupportedBackend, "Path %q is ignored: backends of kind resource are not supported", forPath.Path)
 					continue
 				}
 
 				forService := forPath.Backend.Service
 				if forService == nil {
 					l.Warn("There is no service configured for path; ignoring...")
+					this.recordIngressProblem(ingress, eventReasonIllegalBackend, "Path %q is ignored: there is no service configured", forPath.Path)
 					continue
 				}
 				if forService.Name == "" {
 					l.Warn("There is no service.name configured for path; ignoring...")
+					this.recordIngressProblem(ingress, eventReasonIllegalBackend, "Path %q is ignored: there is no service name configured", forPath.Path)
 					continue
 				}
 				serviceOr := this.ingressToServiceReference(ref, forService)
@@ -344,17 +537,19 @@ func (this *repositoryImplState) visitIngress(ref support.ObjectReference, ingre
 					l = l.With("port", forService.Port.Name)
 				} else {
 					l.Warn("There is neither a service.port.name nor service.port.number configured for path; ignoring...")
+					this.recordIngressProblem(ingress, eventReasonIllegalBackend, "Path %q is ignored: there is neither a port name nor a port number configured", forPath.Path)
 					continue
 				}
 
 				pathType, err := ParsePathType(forPath.PathType)
 				if err != nil {
 					l.WithError(err).Warn("Illegal pathType configured; ingress will not functioning; ignoring...")
+					this.recordIngressProblem(ingress, eventReasonIllegalPathType, "Path %q is ignored: %v", forPath.Path, err)
 					continue
 				}
 				l = l.With("pathType", pathType)
 
-				backend, err := this.ingressToBackend(ref, &forPath.Backend, l)
+				backend, endpoints, err := this.ingressToBackend(ref, &forPath.Backend, ingress, l)
 				if err != nil {
 					return err
 				}
@@ -362,18 +557,20 @@ func (this *repositoryImplState) visitIngress(ref support.ObjectReference, ingre
 					continue
 				}
 
-				options, err := this.newOptionsBy(ingress)
+				options, err := this.newOptionsBy(ref, ingress)
 				if err != nil {
+					this.recordIngressProblem(ingress, eventReasonInvalidAnnotations, "%v", err)
 					return err
 				}
 
 				host, err := this.parseHost(&forHost, options)
 				if err != nil {
 					l.WithError(err).Warn("Illegal host in ingress; ignoring...")
+					this.recordIngressProblem(ingress, eventReasonIllegalHost, "%v", err)
 					continue
 				}
 
-				r := NewRule(host, path, pathType, ref, backend, options)
+				r := NewRule(host, path, pathType, ref, backend, endpoints, options)
 				if err := target.Put(r); err != nil {
 					return err
 				}
@@ -394,36 +591,44 @@ func (this *repositoryImplState) parseHost(ingressRule *networkingv1.IngressRule
 	return result, nil
 }
 
-func (this *repositoryImplState) newOptionsBy(ingress *networkingv1.Ingress) (Options, error) {
+func (this *repositoryImplState) newOptionsBy(ref support.ObjectReference, ingress *networkingv1.Ingress) (Options, error) {
 	result := this.OptionsFactory()
 	if err := result.Set(ingress.GetAnnotations()); err != nil {
 		return nil, err
 	}
+	this.applyBasicAuthCredentials(ref, result)
+	this.applyClientCertificateAuthorities(ref, result)
 
 	return result, nil
 }
 
-func (this *repositoryImplState) ingressToBackend(source support.ObjectReference, ib *networkingv1.IngressBackend, usingLogger log.Logger) (net.Addr, error) {
+// ingressToBackend resolves the address and endpoints of the given backend.
+// Problems are published as Events of involved (if not nil).
+func (this *repositoryImplState) ingressToBackend(source support.ObjectReference, ib *networkingv1.IngressBackend, involved runtime.Object, usingLogger log.Logger) (net.Addr, *Endpoints, error) {
 	service, err := this.ingressToService(source, ib)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	serviceRef := this.ingressToServiceReference(source, ib.Service).ShortString()
 	if service == nil {
 		usingLogger.Warn("Service not found; maybe orphan ingress?; ignoring...")
-		return nil, nil
+		this.recordIngressProblem(involved, eventReasonServiceNotFound, "Service %s does not exist; ignoring its routes until it exists", serviceRef)
+		return nil, nil, nil
 	}
 
 	if service.Spec.Type != v1.ServiceTypeClusterIP && service.Spec.Type != "" {
 		usingLogger.
 			With("serviceType", service.Spec.Type).
 			Warn("Unsupported serviceType; ignoring...")
-		return nil, nil
+		this.recordIngressProblem(involved, eventReasonUnsupportedServiceType, "Service %s is of unsupported type %s; ignoring its routes", serviceRef, service.Spec.Type)
+		return nil, nil, nil
 	}
 
 	if strings.TrimSpace(service.Spec.ClusterIP) == "" {
 		usingLogger.
 			Warnf("serviceType is '%s' but clusterIP of service is not set; ignoring.", v1.ServiceTypeClusterIP)
-		return nil, nil
+		this.recordIngressProblem(involved, eventReasonServiceWithoutClusterIp, "Service %s has no clusterIP; ignoring its routes", serviceRef)
+		return nil, nil, nil
 	}
 
 	port, err := this.evaluateServicePort(ib.Service.Port, service)
@@ -431,7 +636,8 @@ func (this *repositoryImplState) ingressToBackend(source support.ObjectReference
 		usingLogger.
 			WithError(err).
 			Warn("Cannot resolve backend port; ignoring...")
-		return nil, nil
+		this.recordIngressProblem(involved, eventReasonUnresolvablePort, "Cannot resolve port of service %s: %v; ignoring its routes", serviceRef, err)
+		return nil, nil, nil
 	}
 
 	addr, err := this.clusterIpBasedServiceToAddr(service.Spec.ClusterIP, port)
@@ -439,10 +645,11 @@ func (this *repositoryImplState) ingressToBackend(source support.ObjectReference
 		usingLogger.
 			WithError(err).
 			Warn("Cannot resolve backend address; ignoring...")
-		return nil, nil
+		this.recordIngressProblem(involved, eventReasonUnresolvableAddress, "Cannot resolve address of service %s: %v; ignoring its routes", serviceRef, err)
+		return nil, nil, nil
 	}
 
-	return addr, nil
+	return addr, this.ingressToEndpoints(service, ib.Service.Port, addr, usingLogger), nil
 }
 
 func (this *repositoryImplState) evaluateServicePort(in networkingv1.ServiceBackendPort, service *v1.Service) (int32, error) {
@@ -479,7 +686,7 @@ func (this *repositoryImplState) ingressToService(source support.ObjectReference
 }
 
 func (this *KubernetesBasedRepository) All(consumer func(Rule) error) error {
-	return this.ByHostRules.All(consumer)
+	return this.ByHostRules().All(consumer)
 }
 
 func (this *KubernetesBasedRepository) FindBy(q Query) (Rules, error) {
@@ -488,9 +695,17 @@ func (this *KubernetesBasedRepository) FindBy(q Query) (Rules, error) {
 	if err != nil {
 		return nil, err
 	}
-	return this.ByHostRules.Find(host, path)
+	return this.ByHostRules().Find(host, path)
+}
+
+func (this *KubernetesBasedRepository) HasHost(host value.Fqdn) (bool, error) {
+	return this.ByHostRules().HasHost(host), nil
 }
 
 func (this *KubernetesBasedRepository) FindCertificatesBy(q CertificateQuery) (Certificates, error) {
 	return this.CertificatesByHost.Find(q.Host), nil
 }
+
+func (this *KubernetesBasedRepository) AllCertificates() ([]LoadedCertificate, error) {
+	return this.CertificatesByHost.All(), nil
+}
@@ -16,6 +16,7 @@ type Rule interface {
 	PathType() PathType
 	Source() support.ObjectReference
 	Backend() net.Addr
+	Endpoints() *Endpoints
 	Options() Options
 	Statistics() *Statistics
 
@@ -57,17 +58,19 @@ type rule struct {
 	pathType   PathType
 	source     support.ObjectReference
 	backend    net.Addr
+	endpoints  *Endpoints
 	options    Options
 	statistics *Statistics
 }
 
-func NewRule(host value.WildcardSupportingFqdn, path []string, pathType PathType, source support.ObjectReference, backend net.Addr, options Options) Rule {
+func NewRule(host value.WildcardSupportingFqdn, path []string, pathType PathType, source support.ObjectReference, backend net.Addr, endpoints *Endpoints, options Options) Rule {
 	return &rule{
 		host:       host,
 		path:       path,
 		pathType:   pathType,
 		source:     source,
 		backend:    backend,
+		endpoints:  endpoints,
 		options:    options,
 		statistics: &Statistics{},
 	}
@@ -102,6 +105,10 @@ func (this *rule) Backend() net.Addr {
 	return this.backend
 }
 
+func (this *rule) Endpoints() *Endpoints {
+	return this.endpoints
+}
+
 func (this *rule) Options() Options {
 	return this.options
 }
@@ -119,6 +126,11 @@ func (this *rule) MarshalJSON() ([]byte, error) {
 	buf["host"] = this.host.String()
 	buf["path"] = "/" + strings.Join(this.Path(), "/")
 	buf["source"] = this.Source().String()
-	buf["backend"] = this.Backend().String()
+	if v := this.Backend(); v != nil {
+		buf["backend"] = v.String()
+	}
+	if v := this.Endpoints(); v != nil {
+		buf["endpoints"] = v.String()
+	}
 	return json.Marshal(buf)
 }
@@ -0,0 +1,80 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type BackendProtocol string
+
+const (
+	BackendProtocolHttp  = BackendProtocol("http")
+	BackendProtocolHttps = BackendProtocol("https")
+	BackendProtocolH2c   = BackendProtocol("h2c")
+	BackendProtocolH2    = BackendProtocol("h2")
+	BackendProtocolGrpc  = BackendProtocol("grpc")
+	BackendProtocolGrpcs = BackendProtocol("grpcs")
+)
+
+var (
+	ErrIllegalBackendProtocol = errors.New("illegal backend protocol")
+)
+
+func ParseBackendProtocol(plain string) (result BackendProtocol, err error) {
+	err = result.Set(plain)
+	return
+}
+
+func (this *BackendProtocol) Set(plain string) error {
+	switch candidate := BackendProtocol(strings.ToLower(plain)); candidate {
+	case "":
+		*this = ""
+		return nil
+	case BackendProtocolHttp, BackendProtocolHttps, BackendProtocolH2c, BackendProtocolH2, BackendProtocolGrpc, BackendProtocolGrpcs:
+		*this = candidate
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrIllegalBackendProtocol, plain)
+	}
+}
+
+func (this BackendProtocol) String() string {
+	return string(this)
+}
+
+func (this BackendProtocol) Get() BackendProtocol {
+	return this.GetOr(BackendProtocolHttp)
+}
+
+func (this BackendProtocol) GetOr(def BackendProtocol) BackendProtocol {
+	if this == "" {
+		return def
+	}
+	return this
+}
+
+func (this BackendProtocol) IsPresent() bool {
+	return this != ""
+}
+
+// Scheme returns the URL scheme which is used to connect to the backend.
+func (this BackendProtocol) Scheme() string {
+	switch this.Get() {
+	case BackendProtocolHttps, BackendProtocolH2, BackendProtocolGrpcs:
+		return "https"
+	default:
+		return "http"
+	}
+}
+
+// IsHttp2 returns true if the backend has to be spoken to using HTTP/2;
+// either over cleartext (h2c) with prior knowledge or over TLS (see Scheme).
+func (this BackendProtocol) IsHttp2() bool {
+	switch this.Get() {
+	case BackendProtocolH2c, BackendProtocolH2, BackendProtocolGrpc, BackendProtocolGrpcs:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,38 @@
+package value
+
+import (
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func Test_BackendProtocol_Set(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	cases := []struct {
+		plain    string
+		expected BackendProtocol
+		scheme   string
+		http2    bool
+	}{
+		{"", "", "http", false},
+		{"http", BackendProtocolHttp, "http", false},
+		{"HTTPS", BackendProtocolHttps, "https", false},
+		{"h2c", BackendProtocolH2c, "http", true},
+		{"h2", BackendProtocolH2, "https", true},
+		{"grpc", BackendProtocolGrpc, "http", true},
+		{"GRPCS", BackendProtocolGrpcs, "https", true},
+	}
+	for _, c := range cases {
+		actual, err := ParseBackendProtocol(c.plain)
+		g.Expect(err).To(BeNil(), c.plain)
+		g.Expect(actual).To(Equal(c.expected), c.plain)
+		g.Expect(actual.Scheme()).To(Equal(c.scheme), c.plain)
+		g.Expect(actual.IsHttp2()).To(Equal(c.http2), c.plain)
+	}
+
+	g.Expect(BackendProtocol("").Get()).To(Equal(BackendProtocolHttp))
+	g.Expect(BackendProtocol("").IsPresent()).To(BeFalse())
+
+	_, err := ParseBackendProtocol("spdy")
+	g.Expect(err).To(MatchError(ErrIllegalBackendProtocol))
+}
@@ -0,0 +1,61 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type ClientCertificateMode string
+
+const (
+	ClientCertificateModeOff      = ClientCertificateMode("off")
+	ClientCertificateModeOptional = ClientCertificateMode("optional")
+	ClientCertificateModeRequired = ClientCertificateMode("required")
+)
+
+var (
+	ErrIllegalClientCertificateMode = errors.New("illegal client certificate mode")
+)
+
+func ParseClientCertificateMode(plain string) (result ClientCertificateMode, err error) {
+	err = result.Set(plain)
+	return
+}
+
+func (this *ClientCertificateMode) Set(plain string) error {
+	switch candidate := ClientCertificateMode(strings.ToLower(plain)); candidate {
+	case "":
+		*this = ""
+		return nil
+	case ClientCertificateModeOff, ClientCertificateModeOptional, ClientCertificateModeRequired:
+		*this = candidate
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrIllegalClientCertificateMode, plain)
+	}
+}
+
+func (this ClientCertificateMode) String() string {
+	return string(this)
+}
+
+func (this ClientCertificateMode) Get() ClientCertificateMode {
+	return this.GetOr(ClientCertificateModeOff)
+}
+
+func (this ClientCertificateMode) GetOr(def ClientCertificateMode) ClientCertificateMode {
+	if this == "" {
+		return def
+	}
+	return this
+}
+
+func (this ClientCertificateMode) IsPresent() bool {
+	return this != ""
+}
+
+// IsEnabled returns true if client certificates should be requested.
+func (this ClientCertificateMode) IsEnabled() bool {
+	return this == ClientCertificateModeOptional || this == ClientCertificateModeRequired
+}
@@ -0,0 +1,65 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConflictStrategy decides which rules are used if several sources of rules
+// are serving the same request.
+type ConflictStrategy string
+
+const (
+	// ConflictStrategyFirstWins uses the rules of the first source (in the
+	// configured order) which serves the request.
+	ConflictStrategyFirstWins = ConflictStrategy("first-wins")
+	// ConflictStrategyMostSpecificWins uses the rules of the source with the
+	// most specific match (longest path, exact before wildcard host, ...). On
+	// a tie the first source wins.
+	ConflictStrategyMostSpecificWins = ConflictStrategy("most-specific-wins")
+	// ConflictStrategyError fails the request if more than one source serves
+	// it.
+	ConflictStrategyError = ConflictStrategy("error")
+)
+
+var (
+	ErrIllegalConflictStrategy = errors.New("illegal conflict strategy")
+)
+
+func ParseConflictStrategy(plain string) (result ConflictStrategy, err error) {
+	err = result.Set(plain)
+	return
+}
+
+func (this *ConflictStrategy) Set(plain string) error {
+	switch candidate := ConflictStrategy(strings.ToLower(plain)); candidate {
+	case "":
+		*this = ""
+		return nil
+	case ConflictStrategyFirstWins, ConflictStrategyMostSpecificWins, ConflictStrategyError:
+		*this = candidate
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrIllegalConflictStrategy, plain)
+	}
+}
+
+func (this ConflictStrategy) String() string {
+	return string(this)
+}
+
+func (this ConflictStrategy) Get() ConflictStrategy {
+	return this.GetOr(ConflictStrategyFirstWins)
+}
+
+func (this ConflictStrategy) GetOr(def ConflictStrategy) ConflictStrategy {
+	if this == "" {
+		return def
+	}
+	return this
+}
+
+func (this ConflictStrategy) IsPresent() bool {
+	return this != ""
+}
@@ -0,0 +1,112 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"github.com/echocat/lingress/value"
+	"strings"
+)
+
+var (
+	ErrIllegalRateLimitBy = errors.New("illegal rate limit by")
+)
+
+type RateLimitByKind uint8
+
+const (
+	RateLimitByRemote RateLimitByKind = iota
+	RateLimitByHeader
+	RateLimitByRule
+)
+
+// RateLimitBy describes by which key requests are limited. Possible values
+// are: remote, header:<name> and rule.
+type RateLimitBy struct {
+	kind RateLimitByKind
+	name string
+
+	present bool
+}
+
+func ParseRateLimitBy(plain string) (result RateLimitBy, err error) {
+	err = result.Set(plain)
+	return
+}
+
+func (this *RateLimitBy) Set(plain string) error {
+	plain = strings.TrimSpace(plain)
+	if plain == "" {
+		*this = RateLimitBy{}
+		return nil
+	}
+	kind, name, _ := strings.Cut(plain, ":")
+	switch strings.ToLower(kind) {
+	case "remote":
+		*this = RateLimitBy{kind: RateLimitByRemote, present: true}
+	case "rule":
+		*this = RateLimitBy{kind: RateLimitByRule, present: true}
+	case "header":
+		if name == "" {
+			return fmt.Errorf("%w: header name expected: %s", ErrIllegalRateLimitBy, plain)
+		}
+		*this = RateLimitBy{kind: RateLimitByHeader, name: name, present: true}
+	default:
+		return fmt.Errorf("%w: %s", ErrIllegalRateLimitBy, plain)
+	}
+	return nil
+}
+
+func (this RateLimitBy) Kind() RateLimitByKind {
+	return this.kind
+}
+
+func (this RateLimitBy) HeaderName() string {
+	return this.name
+}
+
+func (this RateLimitBy) String() string {
+	if !this.present {
+		return ""
+	}
+	switch this.kind {
+	case RateLimitByRemote:
+		return "remote"
+	case RateLimitByRule:
+		return "rule"
+	case RateLimitByHeader:
+		return "header:" + this.name
+	default:
+		return fmt.Sprintf("unknown-%d", this.kind)
+	}
+}
+
+func (this RateLimitBy) MarshalText() ([]byte, error) {
+	return []byte(this.String()), nil
+}
+
+func (this RateLimitBy) Get() RateLimitBy {
+	return this
+}
+
+func (this RateLimitBy) GetOr(def RateLimitBy) RateLimitBy {
+	if !this.present {
+		return def
+	}
+	return this
+}
+
+func (this RateLimitBy) IsPresent() bool {
+	return this.present
+}
+
+type ForcibleRateLimitBy struct {
+	value.Forcible[RateLimitBy, RateLimitBy, *RateLimitBy]
+}
+
+func NewForcibleRateLimitBy(init RateLimitBy, forced bool) ForcibleRateLimitBy {
+	return ForcibleRateLimitBy{value.NewForcible[RateLimitBy, RateLimitBy, *RateLimitBy](init, forced)}
+}
+
+func (this ForcibleRateLimitBy) Select(target ForcibleRateLimitBy) ForcibleRateLimitBy {
+	return ForcibleRateLimitBy{this.Forcible.Select(target.Forcible)}
+}
@@ -0,0 +1,137 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"github.com/echocat/lingress/value"
+	"strings"
+	"time"
+)
+
+type RetryCondition string
+
+const (
+	RetryOnConnectFailure = RetryCondition("connect-failure")
+	RetryOnReset          = RetryCondition("reset")
+	RetryOnTimeout        = RetryCondition("timeout")
+	RetryOn502            = RetryCondition("502")
+	RetryOn503            = RetryCondition("503")
+	RetryOn504            = RetryCondition("504")
+)
+
+const (
+	// MaxRetryAttempts is the maximum amount of attempts (including the first
+	// one) of a request to the upstream; more would stall a request far too
+	// long.
+	MaxRetryAttempts = 10
+	// MaxRetryBackoff is the maximum time to wait before the next attempt;
+	// the backoff is not doubled beyond it and no greater base is allowed.
+	MaxRetryBackoff = 5 * time.Second
+)
+
+var (
+	ErrIllegalRetryCondition = errors.New("illegal retry condition")
+
+	AllRetryConditions = RetryOn{
+		RetryOnConnectFailure,
+		RetryOnReset,
+		RetryOnTimeout,
+		RetryOn502,
+		RetryOn503,
+		RetryOn504,
+	}
+
+	allRetryConditions = func(in []RetryCondition) map[RetryCondition]bool {
+		result := make(map[RetryCondition]bool, len(in))
+		for _, condition := range in {
+			result[condition] = true
+		}
+		return result
+	}(AllRetryConditions)
+)
+
+func ParseRetryCondition(plain string) (result RetryCondition, err error) {
+	err = result.Set(plain)
+	return
+}
+
+func (this *RetryCondition) Set(plain string) error {
+	candidate := RetryCondition(strings.ToLower(plain))
+	if ok := allRetryConditions[candidate]; !ok {
+		return fmt.Errorf("%w: %s", ErrIllegalRetryCondition, plain)
+	} else {
+		*this = candidate
+		return nil
+	}
+}
+
+func (this RetryCondition) String() string {
+	return string(this)
+}
+
+type RetryOn []RetryCondition
+
+func ParseRetryOn(plain string) (result RetryOn, err error) {
+	err = result.Set(plain)
+	return
+}
+
+func (this *RetryOn) Set(plain string) error {
+	var result RetryOn
+	for _, plainPart := range strings.Split(plain, ",") {
+		plainPart = strings.TrimSpace(plainPart)
+		if plainPart != "" {
+			if part, err := ParseRetryCondition(plainPart); err != nil {
+				return err
+			} else {
+				result = append(result, part)
+			}
+		}
+	}
+	*this = result
+	return nil
+}
+
+func (this RetryOn) String() string {
+	plains := make([]string, len(this))
+	for i, part := range this {
+		plains[i] = part.String()
+	}
+	return strings.Join(plains, ",")
+}
+
+func (this RetryOn) Contains(test RetryCondition) bool {
+	for _, candidate := range this {
+		if candidate == test {
+			return true
+		}
+	}
+	return false
+}
+
+func (this RetryOn) Get() RetryOn {
+	return this
+}
+
+func (this RetryOn) GetOr(def RetryOn) RetryOn {
+	if len(this) == 0 {
+		return def
+	}
+	return this
+}
+
+func (this RetryOn) IsPresent() bool {
+	return len(this) > 0
+}
+
+type ForcibleRetryOn struct {
+	value.Forcible[RetryOn, RetryOn, *RetryOn]
+}
+
+func NewForcibleRetryOn(init RetryOn, forced bool) ForcibleRetryOn {
+	return ForcibleRetryOn{value.NewForcible[RetryOn, RetryOn, *RetryOn](init, forced)}
+}
+
+func (this ForcibleRetryOn) Select(target ForcibleRetryOn) ForcibleRetryOn {
+	return ForcibleRetryOn{this.Forcible.Select(target.Forcible)}
+}
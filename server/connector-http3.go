@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/echocat/lingress/settings"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/slf4g"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"net"
+	"net/http"
+	"time"
+)
+
+var (
+	errQuicConnectionNotReadable = errors.New("QUIC connections can only be read using streams")
+)
+
+// Http3Connector serves HTTP/3 over QUIC (UDP). It shares the certificate
+// resolution of the https connector via the TLSConfig of its Server.
+type Http3Connector struct {
+	settings *settings.Settings
+
+	Id      ConnectorId
+	Handler ConnectorHandler
+	Logger  log.Logger
+
+	Server       http3.Server
+	ListenConfig net.ListenConfig
+}
+
+func NewHttp3Connector(s *settings.Settings, id ConnectorId, logger log.Logger) (*Http3Connector, error) {
+	result := Http3Connector{
+		settings: s,
+
+		Id:     id,
+		Logger: logger,
+
+		ListenConfig: net.ListenConfig{},
+	}
+
+	result.Server.Handler = http.HandlerFunc(result.serveHTTP)
+	result.Server.ConnContext = result.onConnContext
+
+	return &result, nil
+}
+
+func (this *Http3Connector) IsEnabled() bool {
+	return this.settings.Server.Http3.Enabled.Get()
+}
+
+// AltSvc returns the value of the Alt-Svc header which advertises this
+// connector to clients of other connectors.
+func (this *Http3Connector) AltSvc() (string, error) {
+	return this.settings.Server.Http3.AltSvc()
+}
+
+func (this *Http3Connector) Serve(stop support.Channel) error {
+	if !this.IsEnabled() {
+		return nil
+	}
+	if this.Server.TLSConfig == nil {
+		return errors.New("HTTP/3 requires a TLS config")
+	}
+	this.Server.Addr = this.settings.Server.Http3.ListenAddress
+
+	ln, err := (&this.ListenConfig).ListenPacket(context.Background(), "udp", this.Server.Addr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		//noinspection GoUnhandledErrorResult
+		defer ln.Close()
+		if err := this.Server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			this.Logger.
+				WithError(err).
+				With("address", this.Server.Addr).
+				Error("Server is unable to serve proxy interface.")
+			stop.Broadcast()
+		}
+	}()
+	this.Logger.
+		With("address", this.Server.Addr).
+		Info("Serve proxy interface...")
+
+	return nil
+}
+
+func (this *Http3Connector) Shutdown() {
+	if !this.IsEnabled() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	if err := this.Server.Shutdown(ctx); err != nil {
+		this.Logger.
+			WithError(err).
+			Warnf("Cannot graceful shutdown %s proxy interface %s.", this.Id, this.Server.Addr)
+	}
+	cancel()
+}
+
+func (this *Http3Connector) GetId() ConnectorId {
+	return this.Id
+}
+
+func (this *Http3Connector) serveHTTP(resp http.ResponseWriter, req *http.Request) {
+	if v := this.Handler; v != nil {
+		v.ServeHTTP(this, resp, req)
+	}
+}
+
+// onConnContext reports the lifecycle of the QUIC connection like the ones of
+// TCP connections: it is new and active until it is closed.
+func (this *Http3Connector) onConnContext(ctx context.Context, conn *quic.Conn) context.Context {
+	v := this.Handler
+	if v == nil {
+		return ctx
+	}
+	c := &quicConn{
+		conn:                conn,
+		annotatedRemoteAddr: &annotatedAddr{Addr: conn.RemoteAddr()},
+	}
+	v.OnConnState(this, c, http.StateNew)
+	v.OnConnState(this, c, http.StateActive)
+	go func() {
+		<-conn.Context().Done()
+		v.OnConnState(this, c, http.StateClosed)
+	}()
+	return ctx
+}
+
+// quicConn represents a QUIC connection as net.Conn to be able to report its
+// state to ConnectorHandler.OnConnState. The data itself can only be accessed
+// using the streams of the QUIC connection.
+type quicConn struct {
+	conn                *quic.Conn
+	annotatedRemoteAddr AnnotatedAddr
+}
+
+func (this *quicConn) Read([]byte) (int, error) {
+	return 0, errQuicConnectionNotReadable
+}
+
+func (this *quicConn) Write([]byte) (int, error) {
+	return 0, errQuicConnectionNotReadable
+}
+
+func (this *quicConn) Close() error {
+	return this.conn.CloseWithError(0, "")
+}
+
+func (this *quicConn) LocalAddr() net.Addr {
+	return this.conn.LocalAddr()
+}
+
+func (this *quicConn) RemoteAddr() net.Addr {
+	return this.annotatedRemoteAddr
+}
+
+func (this *quicConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+func (this *quicConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func (this *quicConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
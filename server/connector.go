@@ -9,6 +9,7 @@ import (
 const (
 	DefaultConnectorIdHttp  = ConnectorId("http")
 	DefaultConnectorIdHttps = ConnectorId("https")
+	DefaultConnectorIdHttp3 = ConnectorId("http3")
 
 	ConnectorKey = "lingress.server.connector"
 )
@@ -0,0 +1,62 @@
+package settings
+
+import (
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"time"
+)
+
+const (
+	DefaultAcmeDirectoryUrl = "https://acme-v02.api.letsencrypt.org/directory"
+)
+
+func NewAcme() (Acme, error) {
+	return Acme{
+		Enabled:              value.False(),
+		DirectoryUrl:         DefaultAcmeDirectoryUrl,
+		AcceptTermsOfService: value.False(),
+		RenewBefore:          30 * 24 * time.Hour,
+		SecretPrefix:         "lingress-acme-",
+	}, nil
+}
+
+type Acme struct {
+	Enabled              value.Bool    `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	DirectoryUrl         string        `json:"directoryUrl,omitempty" yaml:"directoryUrl,omitempty"`
+	DirectoryCaFile      string        `json:"directoryCaFile,omitempty" yaml:"directoryCaFile,omitempty"`
+	Email                string        `json:"email,omitempty" yaml:"email,omitempty"`
+	AcceptTermsOfService value.Bool    `json:"acceptTermsOfService,omitempty" yaml:"acceptTermsOfService,omitempty"`
+	RenewBefore          time.Duration `json:"renewBefore,omitempty" yaml:"renewBefore,omitempty"`
+	SecretPrefix         string        `json:"secretPrefix,omitempty" yaml:"secretPrefix,omitempty"`
+}
+
+func (this *Acme) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
+	fe.Flag("acme.enabled", "If set to true certificates for hosts of the ingress configurations without any other certificate will be issued on demand using ACME (like Let's Encrypt).").
+		PlaceHolder(this.Enabled.String()).
+		Envar(support.FlagEnvName(appPrefix, "ACME_ENABLED")).
+		SetValue(&this.Enabled)
+	fe.Flag("acme.directoryUrl", "URL of the directory of the ACME server.").
+		PlaceHolder(this.DirectoryUrl).
+		Envar(support.FlagEnvName(appPrefix, "ACME_DIRECTORY_URL")).
+		StringVar(&this.DirectoryUrl)
+	fe.Flag("acme.directoryCaFile", "File which contains PEM encoded CA certificates to trust while communicating with the ACME server. Only required for ACME servers with not publicly trusted certificates (like Pebble).").
+		PlaceHolder("<file>").
+		Envar(support.FlagEnvName(appPrefix, "ACME_DIRECTORY_CA_FILE")).
+		StringVar(&this.DirectoryCaFile)
+	fe.Flag("acme.email", "Contact email address of the ACME account.").
+		PlaceHolder("<email>").
+		Envar(support.FlagEnvName(appPrefix, "ACME_EMAIL")).
+		StringVar(&this.Email)
+	fe.Flag("acme.acceptTermsOfService", "Has to be set to true to accept the terms of service of the ACME server. Without it no certificate will be issued.").
+		PlaceHolder(this.AcceptTermsOfService.String()).
+		Envar(support.FlagEnvName(appPrefix, "ACME_ACCEPT_TERMS_OF_SERVICE")).
+		SetValue(&this.AcceptTermsOfService)
+	fe.Flag("acme.renewBefore", "Issued certificates will be renewed this amount of time before they expire.").
+		PlaceHolder(this.RenewBefore.String()).
+		Envar(support.FlagEnvName(appPrefix, "ACME_RENEW_BEFORE")).
+		DurationVar(&this.RenewBefore)
+	fe.Flag("acme.secretPrefix", "Prefix of the names of the secrets (in the namespace of lingress) the ACME account and all issued certificates are stored in.").
+		PlaceHolder(this.SecretPrefix).
+		Envar(support.FlagEnvName(appPrefix, "ACME_SECRET_PREFIX")).
+		StringVar(&this.SecretPrefix)
+}
@@ -0,0 +1,43 @@
+package settings
+
+import (
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+)
+
+func NewAdmission() (Admission, error) {
+	return Admission{
+		Enabled:       value.False(),
+		ListenAddress: ":9443",
+	}, nil
+}
+
+type Admission struct {
+	Enabled         value.Bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	ListenAddress   string     `yaml:"listenAddress,omitempty" json:"listenAddress,omitempty"`
+	CertificateFile string     `yaml:"certificateFile,omitempty" json:"certificateFile,omitempty"`
+	PrivateKeyFile  string     `yaml:"privateKeyFile,omitempty" json:"privateKeyFile,omitempty"`
+}
+
+func (this *Admission) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
+	fe.Flag("admission.enabled", "If set to true a validating admission webhook is served (at path /validate) which rejects Ingresses that cannot be served by this application.").
+		PlaceHolder(this.Enabled.String()).
+		Envar(support.FlagEnvName(appPrefix, "ADMISSION_ENABLED")).
+		SetValue(&this.Enabled)
+	fe.Flag("admission.listenAddress", "Listen address where the validating admission webhook is listening to serve (using HTTPS).").
+		PlaceHolder(this.ListenAddress).
+		Envar(support.FlagEnvName(appPrefix, "ADMISSION_LISTEN_ADDRESS")).
+		StringVar(&this.ListenAddress)
+	fe.Flag("admission.certificate", "File which contains the PEM encoded certificate (chain) the validating admission webhook is served with. Changes of the file are respected.").
+		PlaceHolder("<file>").
+		Envar(support.FlagEnvName(appPrefix, "ADMISSION_CERTIFICATE")).
+		StringVar(&this.CertificateFile)
+	fe.Flag("admission.privateKey", "File which contains the PEM encoded private key of admission.certificate.").
+		PlaceHolder("<file>").
+		Envar(support.FlagEnvName(appPrefix, "ADMISSION_PRIVATE_KEY")).
+		StringVar(&this.PrivateKeyFile)
+}
+
+func (this *Admission) IsEnabled() bool {
+	return this.Enabled.GetOr(false)
+}
@@ -0,0 +1,23 @@
+package settings
+
+import (
+	"github.com/echocat/lingress/support"
+	"time"
+)
+
+func NewAuth() (Auth, error) {
+	return Auth{
+		Timeout: 5 * time.Second,
+	}, nil
+}
+
+type Auth struct {
+	Timeout time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+func (this *Auth) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
+	fe.Flag("auth.timeout", "Maximum amount of time to wait for the response of the service referenced by annotation lingress.echocat.org/auth-url.").
+		PlaceHolder(this.Timeout.String()).
+		Envar(support.FlagEnvName(appPrefix, "AUTH_TIMEOUT")).
+		DurationVar(&this.Timeout)
+}
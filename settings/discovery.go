@@ -2,17 +2,20 @@ package settings
 
 import (
 	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
 	"time"
 )
 
 func NewDiscovery() (Discovery, error) {
 	return Discovery{
 		ResyncAfter: 10 * time.Minute,
+		Endpoints:   value.False(),
 	}, nil
 }
 
 type Discovery struct {
 	ResyncAfter time.Duration `yaml:"resyncAfter,omitempty" json:"resyncAfter,omitempty"`
+	Endpoints   value.Bool    `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
 }
 
 func (this *Discovery) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
@@ -20,4 +23,8 @@ func (this *Discovery) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
 		PlaceHolder(this.ResyncAfter.String()).
 		Envar(support.FlagEnvName(appPrefix, "DISCOVERY_RESYNC_AFTER")).
 		DurationVar(&this.ResyncAfter)
+	fe.Flag("discovery.endpoints", "If set to true the endpoints (EndpointSlices) of services are watched and requests are balanced by lingress directly across the ready endpoints instead of using the clusterIP of the service.").
+		PlaceHolder(this.Endpoints.String()).
+		Envar(support.FlagEnvName(appPrefix, "DISCOVERY_ENDPOINTS")).
+		SetValue(&this.Endpoints)
 }
@@ -0,0 +1,37 @@
+package settings
+
+import (
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+)
+
+const (
+	DefaultGatewayControllerName = "echocat.org/lingress"
+)
+
+func NewGateway() (Gateway, error) {
+	return Gateway{
+		Enabled:        value.False(),
+		ControllerName: DefaultGatewayControllerName,
+	}, nil
+}
+
+type Gateway struct {
+	Enabled        value.Bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	ControllerName string     `yaml:"controllerName,omitempty" json:"controllerName,omitempty"`
+}
+
+func (this *Gateway) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
+	fe.Flag("gateway.enabled", "If set to true the Gateway API resources (GatewayClass, Gateway and HTTPRoute) are watched and HTTPRoutes are served, too.").
+		PlaceHolder(this.Enabled.String()).
+		Envar(support.FlagEnvName(appPrefix, "GATEWAY_ENABLED")).
+		SetValue(&this.Enabled)
+	fe.Flag("gateway.controllerName", "Name of the controller which GatewayClasses have to reference in spec.controllerName to be handled by this application.").
+		PlaceHolder(this.ControllerName).
+		Envar(support.FlagEnvName(appPrefix, "GATEWAY_CONTROLLER_NAME")).
+		StringVar(&this.ControllerName)
+}
+
+func (this *Gateway) IsEnabled() bool {
+	return this.Enabled.GetOr(false)
+}
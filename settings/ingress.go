@@ -2,6 +2,11 @@ package settings
 
 import (
 	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+)
+
+const (
+	DefaultIngressController = "echocat.org/lingress"
 )
 
 var (
@@ -10,12 +15,19 @@ var (
 
 func NewIngress() (Ingress, error) {
 	return Ingress{
-		Classes: []string{},
+		Classes:          []string{},
+		Controller:       DefaultIngressController,
+		PublishAddresses: []string{},
+		Events:           value.True(),
 	}, nil
 }
 
 type Ingress struct {
-	Classes []string `yaml:"classes,omitempty" json:"classes,omitempty"`
+	Classes          []string   `yaml:"classes,omitempty" json:"classes,omitempty"`
+	Controller       string     `yaml:"controller,omitempty" json:"controller,omitempty"`
+	PublishService   string     `yaml:"publishService,omitempty" json:"publishService,omitempty"`
+	PublishAddresses []string   `yaml:"publishAddresses,omitempty" json:"publishAddresses,omitempty"`
+	Events           value.Bool `yaml:"events,omitempty" json:"events,omitempty"`
 }
 
 func (this *Ingress) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
@@ -23,6 +35,22 @@ func (this *Ingress) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
 		PlaceHolder("<class[,...]>").
 		Envar(support.FlagEnvName(appPrefix, "INGRESS_CLASS")).
 		StringsVar(&this.Classes)
+	fe.Flag("ingress.controller", "Ingresses of all IngressClasses with this spec.controller are respected, too. If such an IngressClass is the default class of the cluster, also Ingresses without any class are respected.").
+		PlaceHolder(this.Controller).
+		Envar(support.FlagEnvName(appPrefix, "INGRESS_CONTROLLER")).
+		StringVar(&this.Controller)
+	fe.Flag("ingress.publishService", "Service (<namespace>/<name> or <name> inside the namespace of this application) whose addresses are written into status.loadBalancer of all served Ingresses.").
+		PlaceHolder("<service>").
+		Envar(support.FlagEnvName(appPrefix, "INGRESS_PUBLISH_SERVICE")).
+		StringVar(&this.PublishService)
+	fe.Flag("ingress.publishAddress", "IPs or host names which are written into status.loadBalancer of all served Ingresses. Takes precedence over ingress.publishService.").
+		PlaceHolder("<address[,...]>").
+		Envar(support.FlagEnvName(appPrefix, "INGRESS_PUBLISH_ADDRESS")).
+		StringsVar(&this.PublishAddresses)
+	fe.Flag("ingress.events", "If set to true problems which prevent Ingresses from being served (completely) are published as Kubernetes Events of the affected Ingresses.").
+		PlaceHolder(this.Events.String()).
+		Envar(support.FlagEnvName(appPrefix, "INGRESS_EVENTS")).
+		SetValue(&this.Events)
 }
 
 func (this *Ingress) GetClasses() []string {
@@ -31,3 +59,9 @@ func (this *Ingress) GetClasses() []string {
 	}
 	return defaultIngressClasses
 }
+
+// IsPublishStatusEnabled reports whether the status of the Ingresses should be
+// written at all.
+func (this *Ingress) IsPublishStatusEnabled() bool {
+	return this.PublishService != "" || len(this.PublishAddresses) > 0
+}
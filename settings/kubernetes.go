@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"strings"
 )
 
 const (
@@ -19,14 +20,35 @@ const (
 	EnvVarKubeconfig    = "KUBE_CONFIG"
 )
 
+var (
+	// defaultSecretFieldSelector excludes the types of secrets which are
+	// never referenced by any Ingress but usually are numerous or huge.
+	defaultSecretFieldSelector = []string{
+		"type!=helm.sh/release.v1",
+		"type!=kubernetes.io/service-account-token",
+		"type!=kubernetes.io/dockercfg",
+		"type!=kubernetes.io/dockerconfigjson",
+		"type!=bootstrap.kubernetes.io/token",
+	}
+)
+
 func NewKubernetes() (Kubernetes, error) {
-	return Kubernetes{}, nil
+	return Kubernetes{
+		Namespaces:          []string{},
+		SecretLabelSelector: []string{},
+		SecretFieldSelector: []string{},
+	}, nil
 }
 
 type Kubernetes struct {
-	Config    KubeconfigPath `json:"config,omitempty" yaml:"config,omitempty"`
-	Context   string         `json:"context,omitempty" yaml:"context,omitempty"`
-	Namespace string         `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Config            KubeconfigPath `json:"config,omitempty" yaml:"config,omitempty"`
+	Context           string         `json:"context,omitempty" yaml:"context,omitempty"`
+	Namespace         string         `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Namespaces        []string       `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	NamespaceSelector string         `json:"namespaceSelector,omitempty" yaml:"namespaceSelector,omitempty"`
+
+	SecretLabelSelector []string `json:"secretLabelSelector,omitempty" yaml:"secretLabelSelector,omitempty"`
+	SecretFieldSelector []string `json:"secretFieldSelector,omitempty" yaml:"secretFieldSelector,omitempty"`
 }
 
 func (this *Kubernetes) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
@@ -48,6 +70,42 @@ func (this *Kubernetes) RegisterFlags(fe support.FlagEnabled, appPrefix string)
 		Short('n').
 		Envar(support.FlagEnvName(appPrefix, "KUBERNETES_NAMESPACE")).
 		StringVar(&this.Namespace)
+	fe.Flag("kubernetes.namespaces", "Namespaces whose Ingresses, Services, Secrets, ... are watched. If empty (and kubernetes.namespaceSelector is not set) all namespaces are watched.").
+		PlaceHolder("<namespace[,...]>").
+		Envar(support.FlagEnvName(appPrefix, "KUBERNETES_NAMESPACES")).
+		StringsVar(&this.Namespaces)
+	fe.Flag("kubernetes.namespaceSelector", "Label selector which namespaces have to match to be watched. Namespaces are included (or excluded) as soon as their labels are changing. Together with kubernetes.namespaces the namespaces of both are watched.").
+		PlaceHolder("<label selector>").
+		Envar(support.FlagEnvName(appPrefix, "KUBERNETES_NAMESPACE_SELECTOR")).
+		StringVar(&this.NamespaceSelector)
+	fe.Flag("kubernetes.secretLabelSelector", "Label selector which secrets referenced by Ingresses (like spec.tls) have to match to be watched.").
+		PlaceHolder("<label selector>").
+		Envar(support.FlagEnvName(appPrefix, "KUBERNETES_SECRET_LABEL_SELECTOR")).
+		StringsVar(&this.SecretLabelSelector)
+	fe.Flag("kubernetes.secretFieldSelector", "Field selector which secrets referenced by Ingresses (like spec.tls) have to match to be watched. Replaces the default; an empty value watches secrets of all types.").
+		PlaceHolder(strings.Join(defaultSecretFieldSelector, ",")).
+		Envar(support.FlagEnvName(appPrefix, "KUBERNETES_SECRET_FIELD_SELECTOR")).
+		StringsVar(&this.SecretFieldSelector)
+}
+
+// GetSecretFieldSelector returns the configured SecretFieldSelector without
+// empty entries or defaultSecretFieldSelector if nothing is configured at all.
+func (this *Kubernetes) GetSecretFieldSelector() []string {
+	if len(this.SecretFieldSelector) == 0 {
+		return defaultSecretFieldSelector
+	}
+	var result []string
+	for _, candidate := range this.SecretFieldSelector {
+		if candidate != "" {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
+
+// IsNamespaceRestricted reports whether not all namespaces are watched.
+func (this *Kubernetes) IsNamespaceRestricted() bool {
+	return len(this.Namespaces) > 0 || this.NamespaceSelector != ""
 }
 
 type KubeconfigPath struct {
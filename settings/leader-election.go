@@ -0,0 +1,53 @@
+package settings
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"time"
+)
+
+func NewLeaderElection() (LeaderElection, error) {
+	return LeaderElection{
+		Enabled:       value.UndefinedBool(),
+		LeaseName:     "lingress-leader",
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}, nil
+}
+
+type LeaderElection struct {
+	Enabled       value.Bool    `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	LeaseName     string        `yaml:"leaseName,omitempty" json:"leaseName,omitempty"`
+	LeaseDuration time.Duration `yaml:"leaseDuration,omitempty" json:"leaseDuration,omitempty"`
+	RenewDeadline time.Duration `yaml:"renewDeadline,omitempty" json:"renewDeadline,omitempty"`
+	RetryPeriod   time.Duration `yaml:"retryPeriod,omitempty" json:"retryPeriod,omitempty"`
+}
+
+func (this *LeaderElection) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
+	fe.Flag("leaderElection.enabled", "If set to true all instances elect a leader using a Lease; only the leader writes back into the cluster (like status of Ingresses). Otherwise each instance acts as leader. If not set explicitly it is enabled as soon as the namespace of this application is known.").
+		PlaceHolder("true").
+		Envar(support.FlagEnvName(appPrefix, "LEADER_ELECTION_ENABLED")).
+		SetValue(&this.Enabled)
+	fe.Flag("leaderElection.leaseName", "Name of the Lease (inside the namespace of this application) which is used to elect the leader.").
+		PlaceHolder(this.LeaseName).
+		Envar(support.FlagEnvName(appPrefix, "LEADER_ELECTION_LEASE_NAME")).
+		StringVar(&this.LeaseName)
+	fe.Flag("leaderElection.leaseDuration", "Duration non-leaders will wait after the last renewal before they try to acquire the leadership.").
+		PlaceHolder(fmt.Sprint(this.LeaseDuration)).
+		Envar(support.FlagEnvName(appPrefix, "LEADER_ELECTION_LEASE_DURATION")).
+		DurationVar(&this.LeaseDuration)
+	fe.Flag("leaderElection.renewDeadline", "Duration the leader will retry to renew its leadership before it gives it up.").
+		PlaceHolder(fmt.Sprint(this.RenewDeadline)).
+		Envar(support.FlagEnvName(appPrefix, "LEADER_ELECTION_RENEW_DEADLINE")).
+		DurationVar(&this.RenewDeadline)
+	fe.Flag("leaderElection.retryPeriod", "Duration between each try to acquire or renew the leadership.").
+		PlaceHolder(fmt.Sprint(this.RetryPeriod)).
+		Envar(support.FlagEnvName(appPrefix, "LEADER_ELECTION_RETRY_PERIOD")).
+		DurationVar(&this.RetryPeriod)
+}
+
+func (this *LeaderElection) IsEnabled() bool {
+	return this.Enabled.GetOr(true)
+}
@@ -17,6 +17,9 @@ func NewManagement() (Management, error) {
 		IdleTimeout:           5 * time.Minute,
 
 		Pprof: value.False(),
+
+		ReadinessErrorPeriod: 1 * time.Minute,
+		ReadinessStrict:      value.False(),
 	}, nil
 }
 
@@ -27,6 +30,8 @@ type Management struct {
 	WriteTimeout          time.Duration `json:"writeTimeout,omitempty" yaml:"writeTimeout,omitempty"`
 	IdleTimeout           time.Duration `json:"idleTimeout,omitempty" yaml:"idleTimeout,omitempty"`
 	Pprof                 value.Bool    `json:"pprof,omitempty" yaml:"pprof,omitempty"`
+	ReadinessErrorPeriod  time.Duration `json:"readinessErrorPeriod,omitempty" yaml:"readinessErrorPeriod,omitempty"`
+	ReadinessStrict       value.Bool    `json:"readinessStrict,omitempty" yaml:"readinessStrict,omitempty"`
 }
 
 func (this *Management) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
@@ -54,6 +59,14 @@ func (this *Management) RegisterFlags(fe support.FlagEnabled, appPrefix string)
 		PlaceHolder(this.Pprof.String()).
 		Envar(support.FlagEnvName(appPrefix, "MANAGEMENT_PPROF")).
 		SetValue(&this.Pprof)
+	fe.Flag("management.readinessErrorPeriod", "Period after the last failed list or watch of the cluster (or reload of a rules file) in which /readyz reports not ready. Problems of single objects are not considered. 0 disables this check.").
+		PlaceHolder(fmt.Sprint(this.ReadinessErrorPeriod)).
+		Envar(support.FlagEnvName(appPrefix, "MANAGEMENT_READINESS_ERROR_PERIOD")).
+		DurationVar(&this.ReadinessErrorPeriod)
+	fe.Flag("management.readinessStrict", "If enabled /readyz reports not ready as long as no rules are loaded.").
+		PlaceHolder(this.ReadinessStrict.String()).
+		Envar(support.FlagEnvName(appPrefix, "MANAGEMENT_READINESS_STRICT")).
+		SetValue(&this.ReadinessStrict)
 }
 
 func (this *Management) ApplyToHttpServer(target *http.Server) error {
@@ -0,0 +1,78 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"strconv"
+	"time"
+)
+
+func NewRateLimit() (RateLimit, error) {
+	by, err := value2.ParseRateLimitBy("remote")
+	if err != nil {
+		return RateLimit{}, err
+	}
+	return RateLimit{
+		Requests: value.NewForcibleUint32(value.NewUint32(0), false),
+		Period:   value.NewForcibleDuration(value.NewDuration(time.Second), false),
+		Burst:    value.NewForcibleUint32(value.Uint32{}, false),
+		By:       value2.NewForcibleRateLimitBy(by, false),
+		MaxKeys:  100000,
+	}, nil
+}
+
+type RateLimit struct {
+	Requests value.ForcibleUint32       `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Period   value.ForcibleDuration     `json:"period,omitempty" yaml:"period,omitempty"`
+	Burst    value.ForcibleUint32       `json:"burst,omitempty" yaml:"burst,omitempty"`
+	By       value2.ForcibleRateLimitBy `json:"by,omitempty" yaml:"by,omitempty"`
+	MaxKeys  uint32                     `json:"maxKeys,omitempty" yaml:"maxKeys,omitempty"`
+}
+
+func (this *RateLimit) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
+	fe.Flag("rateLimit.requests", "Amount of requests which are allowed per rateLimit.period. 0 disables the rate limiting. If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.Requests.String()).
+		Envar(support.FlagEnvName(appPrefix, "RATE_LIMIT_REQUESTS")).
+		SetValue(&this.Requests)
+	fe.Flag("rateLimit.period", "Period in which rateLimit.requests are allowed. If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.Period.String()).
+		Envar(support.FlagEnvName(appPrefix, "RATE_LIMIT_PERIOD")).
+		SetValue(&this.Period)
+	fe.Flag("rateLimit.burst", "Amount of requests which are allowed at once. If absent rateLimit.requests is used. If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.Burst.String()).
+		Envar(support.FlagEnvName(appPrefix, "RATE_LIMIT_BURST")).
+		SetValue(&this.Burst)
+	fe.Flag("rateLimit.by", "Key by which requests are limited. Can be: remote, header:<name> or rule. If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.By.String()).
+		Envar(support.FlagEnvName(appPrefix, "RATE_LIMIT_BY")).
+		SetValue(&this.By)
+	fe.Flag("rateLimit.maxKeys", "Maximum amount of keys for which the state is kept in memory. If exceeded the least recently used keys are forgotten. Has to be at least 1.").
+		PlaceHolder(fmt.Sprint(this.MaxKeys)).
+		Envar(support.FlagEnvName(appPrefix, "RATE_LIMIT_MAX_KEYS")).
+		SetValue(rateLimitMaxKeys{&this.MaxKeys})
+}
+
+// rateLimitMaxKeys rejects 0 as maximum amount of keys; the rate limiting
+// could not remember any key then.
+type rateLimitMaxKeys struct {
+	target *uint32
+}
+
+func (this rateLimitMaxKeys) Set(plain string) error {
+	v, err := strconv.ParseUint(plain, 10, 32)
+	if err != nil {
+		return err
+	}
+	if v == 0 {
+		return errors.New("has to be at least 1")
+	}
+	*this.target = uint32(v)
+	return nil
+}
+
+func (this rateLimitMaxKeys) String() string {
+	return fmt.Sprint(*this.target)
+}
@@ -0,0 +1,53 @@
+package settings
+
+import (
+	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
+	"github.com/echocat/lingress/support"
+	"time"
+)
+
+const (
+	RulesSourceKubernetes = "kubernetes"
+	RulesSourceFilePrefix = "file:"
+)
+
+var (
+	defaultRulesSources = []string{RulesSourceKubernetes}
+)
+
+func NewRules() (Rules, error) {
+	return Rules{
+		Sources:            []string{},
+		FileReloadInterval: 2 * time.Second,
+		ConflictStrategy:   value2.ConflictStrategyFirstWins,
+	}, nil
+}
+
+type Rules struct {
+	Sources            []string                `yaml:"sources,omitempty" json:"sources,omitempty"`
+	FileReloadInterval time.Duration           `yaml:"fileReloadInterval,omitempty" json:"fileReloadInterval,omitempty"`
+	ConflictStrategy   value2.ConflictStrategy `yaml:"conflictStrategy,omitempty" json:"conflictStrategy,omitempty"`
+}
+
+func (this *Rules) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
+	fe.Flag("rules.source", "Source where the rules (and certificates) are read from. Either '"+RulesSourceKubernetes+"' (Ingresses, Secrets, ... of the cluster) or '"+RulesSourceFilePrefix+"<path>' for a YAML or JSON file which is reloaded on changes. If specified multiple times, all sources are combined in the given order; see rules.conflictStrategy.").
+		PlaceHolder("<source[,...]>").
+		Envar(support.FlagEnvName(appPrefix, "RULES_SOURCE")).
+		StringsVar(&this.Sources)
+	fe.Flag("rules.fileReloadInterval", "How often files of sources '"+RulesSourceFilePrefix+"<path>' are checked for changes.").
+		PlaceHolder(fmt.Sprint(this.FileReloadInterval)).
+		Envar(support.FlagEnvName(appPrefix, "RULES_FILE_RELOAD_INTERVAL")).
+		DurationVar(&this.FileReloadInterval)
+	fe.Flag("rules.conflictStrategy", "Decides which rules are used if several sources serve the same request. Can be 'first-wins', 'most-specific-wins' or 'error'.").
+		PlaceHolder(this.ConflictStrategy.String()).
+		Envar(support.FlagEnvName(appPrefix, "RULES_CONFLICT_STRATEGY")).
+		SetValue(&this.ConflictStrategy)
+}
+
+func (this *Rules) GetSources() []string {
+	if v := this.Sources; len(v) != 0 {
+		return v
+	}
+	return defaultRulesSources
+}
@@ -0,0 +1,57 @@
+package settings
+
+import (
+	"fmt"
+	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
+	"net"
+	"strconv"
+	"time"
+)
+
+func NewServerHttp3() (ServerHttp3, error) {
+	return ServerHttp3{
+		Enabled:       value.False(),
+		ListenAddress: ":8443",
+		AltSvcMaxAge:  24 * time.Hour,
+	}, nil
+}
+
+type ServerHttp3 struct {
+	Enabled        value.Bool    `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	ListenAddress  string        `json:"listenAddress,omitempty" yaml:"listenAddress,omitempty"`
+	AdvertisedPort uint16        `json:"advertisedPort,omitempty" yaml:"advertisedPort,omitempty"`
+	AltSvcMaxAge   time.Duration `json:"altSvcMaxAge,omitempty" yaml:"altSvcMaxAge,omitempty"`
+}
+
+func (this *ServerHttp3) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
+	fe.Flag("server.http3.enabled", "If set lingress will also serve HTTP/3 (QUIC) and advertise it via Alt-Svc header at the https connector.").
+		PlaceHolder(this.Enabled.String()).
+		Envar(support.FlagEnvName(appPrefix, "SERVER_HTTP3_ENABLED")).
+		SetValue(&this.Enabled)
+	fe.Flag("server.http3.listenAddress", "UDP listen address where the proxy is listening to serve HTTP/3.").
+		PlaceHolder(this.ListenAddress).
+		Envar(support.FlagEnvName(appPrefix, "SERVER_HTTP3_LISTEN_ADDRESS")).
+		StringVar(&this.ListenAddress)
+	fe.Flag("server.http3.advertisedPort", "Port which is advertised to the clients via Alt-Svc header. If 0 the port of the listen address is used.").
+		PlaceHolder(fmt.Sprint(this.AdvertisedPort)).
+		Envar(support.FlagEnvName(appPrefix, "SERVER_HTTP3_ADVERTISED_PORT")).
+		Uint16Var(&this.AdvertisedPort)
+	fe.Flag("server.http3.altSvcMaxAge", "Amount of time clients should remember that HTTP/3 is available.").
+		PlaceHolder(this.AltSvcMaxAge.String()).
+		Envar(support.FlagEnvName(appPrefix, "SERVER_HTTP3_ALT_SVC_MAX_AGE")).
+		DurationVar(&this.AltSvcMaxAge)
+}
+
+// AltSvc returns the value of the Alt-Svc header which advertises HTTP/3.
+func (this *ServerHttp3) AltSvc() (string, error) {
+	port := strconv.Itoa(int(this.AdvertisedPort))
+	if this.AdvertisedPort == 0 {
+		_, plainPort, err := net.SplitHostPort(this.ListenAddress)
+		if err != nil {
+			return "", fmt.Errorf("cannot determine port of server.http3.listenAddress: %w", err)
+		}
+		port = plainPort
+	}
+	return fmt.Sprintf(`h3=":%s"; ma=%d`, port, int64(this.AltSvcMaxAge.Seconds())), nil
+}
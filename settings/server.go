@@ -15,9 +15,14 @@ func NewServer() (Server, error) {
 	if err != nil {
 		return Server{}, err
 	}
+	http3, err := NewServerHttp3()
+	if err != nil {
+		return Server{}, err
+	}
 	return Server{
 		Http:  http,
 		Https: https,
+		Http3: http3,
 
 		BehindReverseProxy: value.False(),
 	}, nil
@@ -26,12 +31,14 @@ func NewServer() (Server, error) {
 type Server struct {
 	Http               ServerConnector `yaml:"http,omitempty" json:"http,omitempty"`
 	Https              ServerConnector `yaml:"https,omitempty" json:"https,omitempty"`
+	Http3              ServerHttp3     `yaml:"http3,omitempty" json:"http3,omitempty"`
 	BehindReverseProxy value.Bool      `yaml:"behindReverseProxy,omitempty" json:"behindReverseProxy,omitempty"`
 }
 
 func (this *Server) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
 	this.Http.RegisterFlags(fe, appPrefix)
 	this.Https.RegisterFlags(fe, appPrefix)
+	this.Http3.RegisterFlags(fe, appPrefix)
 
 	fe.Flag("server.behindReverseProxy", "If true also X-Forwarded headers are evaluated before send to upstream.").
 		PlaceHolder(this.BehindReverseProxy.String()).
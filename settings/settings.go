@@ -9,6 +9,18 @@ func New() (Settings, error) {
 	if err != nil {
 		return Settings{}, err
 	}
+	admission, err := NewAdmission()
+	if err != nil {
+		return Settings{}, err
+	}
+	acme, err := NewAcme()
+	if err != nil {
+		return Settings{}, err
+	}
+	auth, err := NewAuth()
+	if err != nil {
+		return Settings{}, err
+	}
 	client, err := NewClient()
 	if err != nil {
 		return Settings{}, err
@@ -25,6 +37,10 @@ func New() (Settings, error) {
 	if err != nil {
 		return Settings{}, err
 	}
+	gateway, err := NewGateway()
+	if err != nil {
+		return Settings{}, err
+	}
 	ingress, err := NewIngress()
 	if err != nil {
 		return Settings{}, err
@@ -33,10 +49,18 @@ func New() (Settings, error) {
 	if err != nil {
 		return Settings{}, err
 	}
+	leaderElection, err := NewLeaderElection()
+	if err != nil {
+		return Settings{}, err
+	}
 	management, err := NewManagement()
 	if err != nil {
 		return Settings{}, err
 	}
+	rateLimit, err := NewRateLimit()
+	if err != nil {
+		return Settings{}, err
+	}
 	request, err := NewRequest()
 	if err != nil {
 		return Settings{}, err
@@ -45,6 +69,10 @@ func New() (Settings, error) {
 	if err != nil {
 		return Settings{}, err
 	}
+	rules, err := NewRules()
+	if err != nil {
+		return Settings{}, err
+	}
 	server, err := NewServer()
 	if err != nil {
 		return Settings{}, err
@@ -58,19 +86,26 @@ func New() (Settings, error) {
 		return Settings{}, err
 	}
 	return Settings{
-		AccessLog:  accessLog,
-		Client:     client,
-		Cors:       cors,
-		Discovery:  discovery,
-		Fallback:   fallback,
-		Ingress:    ingress,
-		Kubernetes: kubernetes,
-		Management: management,
-		Request:    request,
-		Response:   response,
-		Server:     server,
-		Tls:        tls,
-		Upstream:   upstream,
+		AccessLog:      accessLog,
+		Acme:           acme,
+		Admission:      admission,
+		Auth:           auth,
+		Client:         client,
+		Cors:           cors,
+		Discovery:      discovery,
+		Fallback:       fallback,
+		Gateway:        gateway,
+		Ingress:        ingress,
+		Kubernetes:     kubernetes,
+		LeaderElection: leaderElection,
+		Management:     management,
+		RateLimit:      rateLimit,
+		Request:        request,
+		Response:       response,
+		Rules:          rules,
+		Server:         server,
+		Tls:            tls,
+		Upstream:       upstream,
 	}, nil
 }
 
@@ -83,32 +118,46 @@ func MustNew() Settings {
 }
 
 type Settings struct {
-	AccessLog  AccessLog  `json:"accessLog,omitempty" yaml:"accessLog,omitempty"`
-	Client     Client     `json:"client,omitempty" yaml:"client,omitempty"`
-	Cors       Cors       `json:"cors,omitempty" yaml:"cors,omitempty"`
-	Discovery  Discovery  `json:"discovery,omitempty" yaml:"discovery,omitempty"`
-	Fallback   Fallback   `json:"fallback,omitempty" yaml:"fallback,omitempty"`
-	Request    Request    `json:"request,omitempty" yaml:"request,omitempty"`
-	Response   Response   `json:"response,omitempty" yaml:"response,omitempty"`
-	Ingress    Ingress    `json:"ingress,omitempty" yaml:"ingress,omitempty"`
-	Kubernetes Kubernetes `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
-	Management Management `json:"management,omitempty" yaml:"management,omitempty"`
-	Server     Server     `json:"server,omitempty" yaml:"server,omitempty"`
-	Tls        Tls        `json:"tls,omitempty" yaml:"tls,omitempty"`
-	Upstream   Upstream   `json:"upstream,omitempty" yaml:"upstream,omitempty"`
+	AccessLog      AccessLog      `json:"accessLog,omitempty" yaml:"accessLog,omitempty"`
+	Acme           Acme           `json:"acme,omitempty" yaml:"acme,omitempty"`
+	Admission      Admission      `json:"admission,omitempty" yaml:"admission,omitempty"`
+	Auth           Auth           `json:"auth,omitempty" yaml:"auth,omitempty"`
+	Client         Client         `json:"client,omitempty" yaml:"client,omitempty"`
+	Cors           Cors           `json:"cors,omitempty" yaml:"cors,omitempty"`
+	Discovery      Discovery      `json:"discovery,omitempty" yaml:"discovery,omitempty"`
+	Fallback       Fallback       `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	Gateway        Gateway        `json:"gateway,omitempty" yaml:"gateway,omitempty"`
+	Request        Request        `json:"request,omitempty" yaml:"request,omitempty"`
+	Response       Response       `json:"response,omitempty" yaml:"response,omitempty"`
+	Ingress        Ingress        `json:"ingress,omitempty" yaml:"ingress,omitempty"`
+	Kubernetes     Kubernetes     `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
+	LeaderElection LeaderElection `json:"leaderElection,omitempty" yaml:"leaderElection,omitempty"`
+	Management     Management     `json:"management,omitempty" yaml:"management,omitempty"`
+	RateLimit      RateLimit      `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty"`
+	Rules          Rules          `json:"rules,omitempty" yaml:"rules,omitempty"`
+	Server         Server         `json:"server,omitempty" yaml:"server,omitempty"`
+	Tls            Tls            `json:"tls,omitempty" yaml:"tls,omitempty"`
+	Upstream       Upstream       `json:"upstream,omitempty" yaml:"upstream,omitempty"`
 }
 
 func (this *Settings) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
 	this.AccessLog.RegisterFlags(fe, appPrefix)
+	this.Acme.RegisterFlags(fe, appPrefix)
+	this.Admission.RegisterFlags(fe, appPrefix)
+	this.Auth.RegisterFlags(fe, appPrefix)
 	this.Client.RegisterFlags(fe, appPrefix)
 	this.Cors.RegisterFlags(fe, appPrefix)
 	this.Discovery.RegisterFlags(fe, appPrefix)
 	this.Fallback.RegisterFlags(fe, appPrefix)
+	this.Gateway.RegisterFlags(fe, appPrefix)
 	this.Ingress.RegisterFlags(fe, appPrefix)
 	this.Kubernetes.RegisterFlags(fe, appPrefix)
+	this.LeaderElection.RegisterFlags(fe, appPrefix)
 	this.Management.RegisterFlags(fe, appPrefix)
+	this.RateLimit.RegisterFlags(fe, appPrefix)
 	this.Request.RegisterFlags(fe, appPrefix)
 	this.Response.RegisterFlags(fe, appPrefix)
+	this.Rules.RegisterFlags(fe, appPrefix)
 	this.Server.RegisterFlags(fe, appPrefix)
 	this.Tls.RegisterFlags(fe, appPrefix)
 	this.Upstream.RegisterFlags(fe, appPrefix)
@@ -5,6 +5,7 @@ import (
 	"github.com/echocat/lingress/support"
 	"github.com/echocat/lingress/value"
 	"regexp"
+	"time"
 )
 
 func NewTls() (Tls, error) {
@@ -15,6 +16,8 @@ func NewTls() (Tls, error) {
 		SecretFieldSelector: []string{},
 		Forced:              value.NewForcibleBool(value.False(), false),
 		FallbackCertificate: value.False(),
+		OcspStapling:        value.True(),
+		ExpiryWarning:       14 * 24 * time.Hour,
 	}, nil
 }
 
@@ -25,6 +28,8 @@ type Tls struct {
 	SecretFieldSelector []string           `yaml:"secretFieldSelector,omitempty" json:"secretFieldSelector,omitempty"`
 	Forced              value.ForcibleBool `yaml:"forced,omitempty" json:"forced,omitempty"`
 	FallbackCertificate value.Bool         `yaml:"fallbackCertificate,omitempty" json:"fallbackCertificate,omitempty"`
+	OcspStapling        value.Bool         `yaml:"ocspStapling,omitempty" json:"ocspStapling,omitempty"`
+	ExpiryWarning       time.Duration      `yaml:"expiryWarning,omitempty" json:"expiryWarning,omitempty"`
 }
 
 func (this *Tls) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
@@ -52,4 +57,12 @@ func (this *Tls) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
 		PlaceHolder(this.FallbackCertificate.String()).
 		Envar(support.FlagEnvName(appPrefix, "TLS_FALLBACK_CERTIFICATE")).
 		SetValue(&this.FallbackCertificate)
+	fe.Flag("tls.ocspStapling", "If set lingress will fetch OCSP responses for all served certificates and staple them into the TLS handshakes.").
+		PlaceHolder(this.OcspStapling.String()).
+		Envar(support.FlagEnvName(appPrefix, "TLS_OCSP_STAPLING")).
+		SetValue(&this.OcspStapling)
+	fe.Flag("tls.expiryWarning", "Served certificates which expire within this amount of time will be reported as expiring.").
+		PlaceHolder(this.ExpiryWarning.String()).
+		Envar(support.FlagEnvName(appPrefix, "TLS_EXPIRY_WARNING")).
+		DurationVar(&this.ExpiryWarning)
 }
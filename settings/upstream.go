@@ -2,7 +2,9 @@ package settings
 
 import (
 	"fmt"
+	value2 "github.com/echocat/lingress/rules/value"
 	"github.com/echocat/lingress/support"
+	"github.com/echocat/lingress/value"
 	"net"
 	"net/http"
 	"time"
@@ -19,6 +21,17 @@ func NewUpstream() (Upstream, error) {
 
 		OverrideHost:   "",
 		OverrideScheme: "",
+
+		LoadBalancer: "round-robin",
+
+		RequestTimeout:        value.NewForcibleDuration(value.Duration{}, false),
+		ResponseHeaderTimeout: value.NewForcibleDuration(value.Duration{}, false),
+
+		RetryAttempts:       value.NewForcibleUint32(value.NewUint32(1), false),
+		RetryOn:             value2.NewForcibleRetryOn(value2.RetryOn{value2.RetryOnConnectFailure}, false),
+		RetryBackoff:        value.NewForcibleDuration(value.NewDuration(25*time.Millisecond), false),
+		RetryPerTryTimeout:  value.NewForcibleDuration(value.Duration{}, false),
+		RetryMaxBufferBytes: 64 << 10,
 	}, nil
 }
 
@@ -32,6 +45,17 @@ type Upstream struct {
 
 	OverrideHost   string `json:"overrideHost,omitempty" yaml:"overrideHost,omitempty"`
 	OverrideScheme string `json:"overrideScheme,omitempty" yaml:"overrideScheme,omitempty"`
+
+	LoadBalancer string `json:"loadBalancer,omitempty" yaml:"loadBalancer,omitempty"`
+
+	RequestTimeout        value.ForcibleDuration `json:"requestTimeout,omitempty" yaml:"requestTimeout,omitempty"`
+	ResponseHeaderTimeout value.ForcibleDuration `json:"responseHeaderTimeout,omitempty" yaml:"responseHeaderTimeout,omitempty"`
+
+	RetryAttempts       value.ForcibleUint32   `json:"retryAttempts,omitempty" yaml:"retryAttempts,omitempty"`
+	RetryOn             value2.ForcibleRetryOn `json:"retryOn,omitempty" yaml:"retryOn,omitempty"`
+	RetryBackoff        value.ForcibleDuration `json:"retryBackoff,omitempty" yaml:"retryBackoff,omitempty"`
+	RetryPerTryTimeout  value.ForcibleDuration `json:"retryPerTryTimeout,omitempty" yaml:"retryPerTryTimeout,omitempty"`
+	RetryMaxBufferBytes uint32                 `json:"retryMaxBufferBytes,omitempty" yaml:"retryMaxBufferBytes,omitempty"`
 }
 
 func (this *Upstream) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
@@ -67,6 +91,74 @@ func (this *Upstream) RegisterFlags(fe support.FlagEnabled, appPrefix string) {
 		PlaceHolder(this.OverrideScheme).
 		Envar(support.FlagEnvName(appPrefix, "UPSTREAM_OVERRIDE_SCHEME")).
 		StringVar(&this.OverrideScheme)
+	fe.Flag("upstream.loadBalancer", "Strategy to select one of the endpoints of a service if --discovery.endpoints is enabled. Can be: round-robin, least-requests or consistent-hash.").
+		PlaceHolder(this.LoadBalancer).
+		Envar(support.FlagEnvName(appPrefix, "UPSTREAM_LOAD_BALANCER")).
+		EnumVar(&this.LoadBalancer, "round-robin", "least-requests", "consistent-hash")
+	fe.Flag("upstream.requestTimeout", "Maximum amount of time of the whole request to the upstream including reading the response body. Empty means no limit. If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.RequestTimeout.String()).
+		Envar(support.FlagEnvName(appPrefix, "UPSTREAM_REQUEST_TIMEOUT")).
+		SetValue(&this.RequestTimeout)
+	fe.Flag("upstream.responseHeaderTimeout", "Maximum amount of time to wait for the response headers of the upstream after the request was sent; applies to each attempt of retried requests. Empty means no limit. If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.ResponseHeaderTimeout.String()).
+		Envar(support.FlagEnvName(appPrefix, "UPSTREAM_RESPONSE_HEADER_TIMEOUT")).
+		SetValue(&this.ResponseHeaderTimeout)
+	fe.Flag("upstream.retry.attempts", "Maximum amount of attempts (including the first one) of a request to the upstream. 1 disables retries; at most "+fmt.Sprint(value2.MaxRetryAttempts)+" are allowed. If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.RetryAttempts.String()).
+		Envar(support.FlagEnvName(appPrefix, "UPSTREAM_RETRY_ATTEMPTS")).
+		SetValue(retryAttempts{&this.RetryAttempts})
+	fe.Flag("upstream.retry.on", "Comma separated conditions on which a request will be retried. Can be: connect-failure, reset, timeout, 502, 503 and 504. If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.RetryOn.String()).
+		Envar(support.FlagEnvName(appPrefix, "UPSTREAM_RETRY_ON")).
+		SetValue(&this.RetryOn)
+	fe.Flag("upstream.retry.backoff", "Base time to wait before the next attempt. It will be doubled with each further attempt up to "+value2.MaxRetryBackoff.String()+". If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.RetryBackoff.String()).
+		Envar(support.FlagEnvName(appPrefix, "UPSTREAM_RETRY_BACKOFF")).
+		SetValue(retryBackoff{&this.RetryBackoff})
+	fe.Flag("upstream.retry.perTryTimeout", "Maximum amount of time of each attempt until the response headers are received. Empty means no limit. If this value is prefixed with ! it overrides everything regardless what was set in the annotation.").
+		PlaceHolder(this.RetryPerTryTimeout.String()).
+		Envar(support.FlagEnvName(appPrefix, "UPSTREAM_RETRY_PER_TRY_TIMEOUT")).
+		SetValue(&this.RetryPerTryTimeout)
+	fe.Flag("upstream.retry.maxBufferSize", "Request bodies up to this size will be buffered to be able to retry requests. Requests of non-idempotent methods are only retried if their body was buffered. 0 disables buffering.").
+		PlaceHolder(fmt.Sprint(this.RetryMaxBufferBytes)).
+		Envar(support.FlagEnvName(appPrefix, "UPSTREAM_RETRY_MAX_BUFFER_SIZE")).
+		Uint32Var(&this.RetryMaxBufferBytes)
+}
+
+// retryAttempts rejects amounts of attempts which are not between 1 and
+// value2.MaxRetryAttempts.
+type retryAttempts struct {
+	*value.ForcibleUint32
+}
+
+func (this retryAttempts) Set(plain string) error {
+	var candidate value.ForcibleUint32
+	if err := candidate.Set(plain); err != nil {
+		return err
+	}
+	if v := candidate.Get(); candidate.IsPresent() && (v == 0 || v > value2.MaxRetryAttempts) {
+		return fmt.Errorf("has to be between 1 and %d", value2.MaxRetryAttempts)
+	}
+	*this.ForcibleUint32 = candidate
+	return nil
+}
+
+// retryBackoff rejects backoffs which are not between 0 and
+// value2.MaxRetryBackoff.
+type retryBackoff struct {
+	*value.ForcibleDuration
+}
+
+func (this retryBackoff) Set(plain string) error {
+	var candidate value.ForcibleDuration
+	if err := candidate.Set(plain); err != nil {
+		return err
+	}
+	if v := candidate.Get(); candidate.IsPresent() && (v < 0 || v > value2.MaxRetryBackoff) {
+		return fmt.Errorf("has to be between 0s and %v", value2.MaxRetryBackoff)
+	}
+	*this.ForcibleDuration = candidate
+	return nil
 }
 
 func (this *Upstream) ApplyToHttpTransport(target *http.Transport) error {
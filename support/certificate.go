@@ -2,9 +2,11 @@ package support
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"fmt"
 	"github.com/google/uuid"
 	"math/big"
@@ -63,3 +65,10 @@ func CreateDummyCertificateFor(name string) (tls.Certificate, error) {
 		Leaf:       leaf,
 	}, nil
 }
+
+// CertificateFingerprint returns the lower case hex encoded SHA-256 hash of the
+// DER encoded certificate.
+func CertificateFingerprint(certificate *x509.Certificate) string {
+	sum := sha256.Sum256(certificate.Raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -36,7 +36,7 @@ func ToChan(channel Channel) chan struct{} {
 	result := make(chan struct{})
 	go func() {
 		channel.Wait()
-		result <- struct{}{}
+		close(result)
 	}()
 	return result
 }
@@ -0,0 +1,47 @@
+package support
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// LastError holds the last error which occurred together with the time it
+// occurred at. It is safe for concurrent use; the zero value is ready to use.
+type LastError struct {
+	v atomic.Pointer[lastErrorEntry]
+}
+
+type lastErrorEntry struct {
+	err error
+	at  time.Time
+}
+
+func (this *LastError) Store(err error) {
+	if err == nil {
+		return
+	}
+	this.v.Store(&lastErrorEntry{
+		err: err,
+		at:  time.Now(),
+	})
+}
+
+// Load returns the last stored error and the time it was stored at. If no
+// error was stored at all, a zero time and nil are returned.
+func (this *LastError) Load() (time.Time, error) {
+	if entry := this.v.Load(); entry != nil {
+		return entry.at, entry.err
+	}
+	return time.Time{}, nil
+}
+
+// LatestError returns the most recent of the given errors (see
+// LastError.Load).
+func LatestError(candidates ...func() (time.Time, error)) (at time.Time, err error) {
+	for _, candidate := range candidates {
+		if cAt, cErr := candidate(); cErr != nil && cAt.After(at) {
+			at, err = cAt, cErr
+		}
+	}
+	return
+}
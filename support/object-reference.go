@@ -53,6 +53,17 @@ func NewObjectReferenceOf(src ObjectReferenceSource) (ObjectReference, error) {
 	}, nil
 }
 
+// NewObjectReference creates a reference of the given coordinates; for example
+// for objects which are not located inside of Kubernetes.
+func NewObjectReference(apiVersion, kind, namespace, name string) ObjectReference {
+	return objectReference{
+		apiVersion: apiVersion,
+		kind:       kind,
+		namespace:  namespace,
+		name:       name,
+	}
+}
+
 func gvkToApiVersionAndKind(gvk *schema.GroupVersionKind) (apiVersion, kind string) {
 	apiVersion = strings.Clone(gvk.Group)
 	if v := gvk.Version; v != "" {
@@ -0,0 +1,70 @@
+package value
+
+import (
+	"strconv"
+)
+
+type Uint32 struct {
+	value *uint32
+}
+
+func NewUint32(value uint32) Uint32 {
+	return Uint32{&value}
+}
+
+func ParseUint32(plain string) (result Uint32, err error) {
+	err = result.Set(plain)
+	return
+}
+
+func (this Uint32) Get() uint32 {
+	if v := this.value; v != nil {
+		return *v
+	}
+	return 0
+}
+
+func (this Uint32) GetOr(def uint32) uint32 {
+	if v := this.value; v != nil {
+		return *v
+	}
+	return def
+}
+
+func (this Uint32) String() string {
+	if v := this.value; v != nil {
+		return strconv.FormatUint(uint64(*v), 10)
+	}
+	return ""
+}
+
+func (this *Uint32) Set(plain string) error {
+	if plain == "" {
+		*this = Uint32{}
+		return nil
+	}
+
+	val, err := strconv.ParseUint(plain, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	*this = NewUint32(uint32(val))
+	return nil
+}
+
+func (this Uint32) IsPresent() bool {
+	return this.value != nil
+}
+
+type ForcibleUint32 struct {
+	Forcible[Uint32, uint32, *Uint32]
+}
+
+func NewForcibleUint32(init Uint32, forced bool) ForcibleUint32 {
+	return ForcibleUint32{NewForcible[Uint32, uint32, *Uint32](init, forced)}
+}
+
+func (this ForcibleUint32) Select(target ForcibleUint32) ForcibleUint32 {
+	return ForcibleUint32{this.Forcible.Select(target.Forcible)}
+}